@@ -0,0 +1,223 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/sfu-teamproject/smartbuy/backend/apperrors"
+	"github.com/sfu-teamproject/smartbuy/backend/models"
+)
+
+// GetAddresses lists saved addresses of a user
+// @Summary      Get User Addresses
+// @Description  Lists shipping addresses of a user, the default one first. Users can see their own addresses; Admins can see anyone's.
+// @Tags         addresses
+// @Security     BearerAuth
+// @Produce      json
+// @Param        user_id path int true "User ID"
+// @Success      200  {array}   models.Address
+// @Failure      401  {object}  apperrors.ErrorResponse "Unauthorized"
+// @Failure      403  {object}  apperrors.ErrorResponse "Forbidden"
+// @Router       /users/{user_id}/addresses [get]
+func (app *App) GetAddresses(w http.ResponseWriter, r *http.Request) {
+	ID, err := app.ExtractPathValue(r, "user_id")
+	if err != nil {
+		app.ErrorJSON(w, r, err)
+		return
+	}
+	userID, role, err := app.GetClaims(r)
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("%w: error extracting claims: %w", apperrors.ErrUnauthorized, err))
+		return
+	}
+	if userID != ID && role != models.RoleAdmin {
+		app.ErrorJSON(w, r, apperrors.ErrForbidden)
+		return
+	}
+	addresses, err := app.DB.GetAddresses(ID)
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("error getting addresses of user %d: %w", ID, err))
+		return
+	}
+	app.Encode(w, r, addresses)
+}
+
+// GetAddress gets a saved address of a user
+// @Summary      Get User Address
+// @Tags         addresses
+// @Security     BearerAuth
+// @Produce      json
+// @Param        user_id path int true "User ID"
+// @Param        address_id path int true "Address ID"
+// @Success      200  {object}  models.Address
+// @Failure      401  {object}  apperrors.ErrorResponse "Unauthorized"
+// @Failure      403  {object}  apperrors.ErrorResponse "Forbidden"
+// @Failure      404  {object}  apperrors.ErrorResponse "Not Found"
+// @Router       /users/{user_id}/addresses/{address_id} [get]
+func (app *App) GetAddress(w http.ResponseWriter, r *http.Request) {
+	address, err := app.userAddress(r)
+	if err != nil {
+		app.ErrorJSON(w, r, err)
+		return
+	}
+	app.Encode(w, r, address)
+}
+
+// CreateAddress saves a shipping address
+// @Summary      Add User Address
+// @Description  Saves a shipping address. The first address of a user or an address with is_default becomes the default one.
+// @Tags         addresses
+// @Security     BearerAuth
+// @Accept       json
+// @Produce      json
+// @Param        user_id path int true "User ID"
+// @Param        address body models.AddressRequest true "Address"
+// @Success      201  {object}  models.Address
+// @Failure      400  {object}  apperrors.ErrorResponse "Bad Request"
+// @Failure      401  {object}  apperrors.ErrorResponse "Unauthorized"
+// @Failure      403  {object}  apperrors.ErrorResponse "Forbidden"
+// @Router       /users/{user_id}/addresses [post]
+func (app *App) CreateAddress(w http.ResponseWriter, r *http.Request) {
+	ID, err := app.ExtractPathValue(r, "user_id")
+	if err != nil {
+		app.ErrorJSON(w, r, err)
+		return
+	}
+	userID, role, err := app.GetClaims(r)
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("%w: error extracting claims: %w", apperrors.ErrUnauthorized, err))
+		return
+	}
+	if userID != ID && role != models.RoleAdmin {
+		app.ErrorJSON(w, r, apperrors.ErrForbidden)
+		return
+	}
+	address, err := decodeAddress(r)
+	if err != nil {
+		app.ErrorJSON(w, r, err)
+		return
+	}
+	address.UserID = ID
+	newAddress, err := app.DB.CreateAddress(address)
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("error creating address of user %d: %w", ID, err))
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	app.Encode(w, r, newAddress)
+}
+
+// UpdateAddress changes a saved address
+// @Summary      Update User Address
+// @Description  Replaces fields of the address. is_default makes it the default address, the default address can not be unset.
+// @Tags         addresses
+// @Security     BearerAuth
+// @Accept       json
+// @Produce      json
+// @Param        user_id path int true "User ID"
+// @Param        address_id path int true "Address ID"
+// @Param        address body models.AddressRequest true "Address"
+// @Success      200  {object}  models.Address
+// @Failure      400  {object}  apperrors.ErrorResponse "Bad Request"
+// @Failure      401  {object}  apperrors.ErrorResponse "Unauthorized"
+// @Failure      403  {object}  apperrors.ErrorResponse "Forbidden"
+// @Failure      404  {object}  apperrors.ErrorResponse "Not Found"
+// @Router       /users/{user_id}/addresses/{address_id} [put]
+func (app *App) UpdateAddress(w http.ResponseWriter, r *http.Request) {
+	existing, err := app.userAddress(r)
+	if err != nil {
+		app.ErrorJSON(w, r, err)
+		return
+	}
+	address, err := decodeAddress(r)
+	if err != nil {
+		app.ErrorJSON(w, r, err)
+		return
+	}
+	address.ID = existing.ID
+	address.UserID = existing.UserID
+	updated, err := app.DB.UpdateAddress(address)
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("error updating address %d: %w", existing.ID, err))
+		return
+	}
+	app.Encode(w, r, updated)
+}
+
+// DeleteAddress deletes a saved address
+// @Summary      Delete User Address
+// @Description  Deletes the address. If it was the default one, the newest remaining address becomes the default.
+// @Tags         addresses
+// @Security     BearerAuth
+// @Produce      json
+// @Param        user_id path int true "User ID"
+// @Param        address_id path int true "Address ID"
+// @Success      200  {object}  models.Address
+// @Failure      401  {object}  apperrors.ErrorResponse "Unauthorized"
+// @Failure      403  {object}  apperrors.ErrorResponse "Forbidden"
+// @Failure      404  {object}  apperrors.ErrorResponse "Not Found"
+// @Router       /users/{user_id}/addresses/{address_id} [delete]
+func (app *App) DeleteAddress(w http.ResponseWriter, r *http.Request) {
+	existing, err := app.userAddress(r)
+	if err != nil {
+		app.ErrorJSON(w, r, err)
+		return
+	}
+	deleted, err := app.DB.DeleteAddress(existing.ID)
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("error deleting address %d: %w", existing.ID, err))
+		return
+	}
+	app.Encode(w, r, deleted)
+}
+
+// userAddress gets the address from the path and checks that it belongs to
+// the user from the path and that the request is made by the user or an admin
+func (app *App) userAddress(r *http.Request) (models.Address, error) {
+	ID, err := app.ExtractPathValue(r, "user_id")
+	if err != nil {
+		return models.Address{}, err
+	}
+	addressID, err := app.ExtractPathValue(r, "address_id")
+	if err != nil {
+		return models.Address{}, err
+	}
+	userID, role, err := app.GetClaims(r)
+	if err != nil {
+		return models.Address{}, fmt.Errorf("%w: error extracting claims: %w", apperrors.ErrUnauthorized, err)
+	}
+	if userID != ID && role != models.RoleAdmin {
+		return models.Address{}, apperrors.ErrForbidden
+	}
+	address, err := app.DB.GetAddress(addressID)
+	if err != nil {
+		return address, fmt.Errorf("error getting address %d: %w", addressID, err)
+	}
+	if address.UserID != ID {
+		return models.Address{}, fmt.Errorf("%w: address %d does not belong to user %d",
+			apperrors.ErrNotFound, addressID, ID)
+	}
+	return address, nil
+}
+
+func decodeAddress(r *http.Request) (models.Address, error) {
+	var req models.AddressRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return models.Address{}, fmt.Errorf("%w: error decoding address: %w", apperrors.ErrBadRequest, err)
+	}
+	address := models.Address{
+		Recipient:  strings.TrimSpace(req.Recipient),
+		Phone:      strings.TrimSpace(req.Phone),
+		City:       strings.TrimSpace(req.City),
+		Street:     strings.TrimSpace(req.Street),
+		PostalCode: strings.TrimSpace(req.PostalCode),
+		IsDefault:  req.IsDefault,
+	}
+	if address.Recipient == "" || address.Phone == "" || address.City == "" || address.Street == "" {
+		return address, fmt.Errorf("%w: recipient, phone, city and street are required", apperrors.ErrBadRequest)
+	}
+	return address, nil
+}
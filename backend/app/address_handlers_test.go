@@ -0,0 +1,85 @@
+package app
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sfu-teamproject/smartbuy/backend/apperrors"
+	"github.com/sfu-teamproject/smartbuy/backend/logger/mocklogger"
+	"github.com/sfu-teamproject/smartbuy/backend/models"
+	"github.com/sfu-teamproject/smartbuy/backend/storage/mockstorage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestCreateAddress(t *testing.T) {
+	ms := new(mockstorage.MockStorage)
+	ml := new(mocklogger.MockLogger)
+	address := models.Address{UserID: 2, Recipient: "Ivan", Phone: "+79990000000", City: "Krasnoyarsk",
+		Street: "Svobodny 79", PostalCode: "660041"}
+	ms.On("CreateAddress", address).Return(models.Address{ID: 1, UserID: 2, IsDefault: true}, nil)
+	ml.On("Errorln", mock.Anything, mock.Anything, mock.Anything)
+
+	app := NewApp(ml, nil, ms)
+	body := `{"recipient": "Ivan", "phone": "+79990000000", "city": "Krasnoyarsk", "street": " Svobodny 79 ", "postal_code": "660041"}`
+	tests := []struct {
+		name   string
+		userID string
+		body   string
+		code   int
+	}{
+		{"Owner adds address", "2", body, http.StatusCreated},
+		{"Another user adds address", "3", body, http.StatusForbidden},
+		{"Missing street", "2", `{"recipient": "Ivan", "phone": "+79990000000", "city": "Krasnoyarsk"}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := createContextWithClaims(tt.userID, models.RoleUser)
+			r := httptest.NewRequestWithContext(ctx, http.MethodPost, "/", bytes.NewBufferString(tt.body))
+			r.SetPathValue("user_id", "2")
+			w := httptest.NewRecorder()
+			app.CreateAddress(w, r)
+			assert.Equal(t, tt.code, w.Code)
+		})
+	}
+	ms.AssertNumberOfCalls(t, "CreateAddress", 1)
+}
+
+func TestDeleteAddress(t *testing.T) {
+	ms := new(mockstorage.MockStorage)
+	ml := new(mocklogger.MockLogger)
+	ms.On("GetAddress", 1).Return(models.Address{ID: 1, UserID: 2}, nil)
+	ms.On("GetAddress", 2).Return(models.Address{}, apperrors.ErrNotFound)
+	ms.On("DeleteAddress", 1).Return(models.Address{ID: 1, UserID: 2}, nil)
+	ml.On("Errorln", mock.Anything, mock.Anything, mock.Anything)
+
+	app := NewApp(ml, nil, ms)
+	tests := []struct {
+		name      string
+		userID    string
+		role      models.Role
+		pathUser  string
+		addressID string
+		code      int
+	}{
+		{"Owner deletes address", "2", models.RoleUser, "2", "1", http.StatusOK},
+		{"Address of another user in path", "3", models.RoleUser, "3", "1", http.StatusNotFound},
+		{"Another user deletes address", "3", models.RoleUser, "2", "1", http.StatusForbidden},
+		{"Admin deletes address", "1", models.RoleAdmin, "2", "1", http.StatusOK},
+		{"Nonexistent address", "2", models.RoleUser, "2", "2", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := createContextWithClaims(tt.userID, tt.role)
+			r := httptest.NewRequestWithContext(ctx, http.MethodDelete, "/", nil)
+			r.SetPathValue("user_id", tt.pathUser)
+			r.SetPathValue("address_id", tt.addressID)
+			w := httptest.NewRecorder()
+			app.DeleteAddress(w, r)
+			assert.Equal(t, tt.code, w.Code)
+		})
+	}
+	ms.AssertNumberOfCalls(t, "DeleteAddress", 2)
+}
@@ -1,29 +1,244 @@
 package app
 
 import (
+	"crypto/rand"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
 	"os"
 	"strconv"
+	"time"
 
 	"github.com/sfu-teamproject/smartbuy/backend/apperrors"
+	"github.com/sfu-teamproject/smartbuy/backend/blobstore"
+	"github.com/sfu-teamproject/smartbuy/backend/contentfilter"
+	"github.com/sfu-teamproject/smartbuy/backend/invoice"
 	"github.com/sfu-teamproject/smartbuy/backend/logger"
+	"github.com/sfu-teamproject/smartbuy/backend/mailer"
 	"github.com/sfu-teamproject/smartbuy/backend/models"
+	"github.com/sfu-teamproject/smartbuy/backend/payments"
+	"github.com/sfu-teamproject/smartbuy/backend/ratelimit"
 	"github.com/sfu-teamproject/smartbuy/backend/storage"
+	"github.com/sfu-teamproject/smartbuy/backend/tax"
 )
 
 type App struct {
 	Log       logger.Logger
 	Server    *http.Server
 	DB        storage.Storage
+	Blobs     blobstore.BlobStore
+	Mail      mailer.Mailer
+	Payments  payments.Provider
 	jwtSecret []byte
+	// limits for photos attached to reviews
+	reviewMaxPhotos    int
+	reviewMaxPhotoSize int64
+	// limit of width * height of a photo
+	reviewMaxPhotoPixels int
+	// number of reports after which a review is hidden until moderation
+	reviewReportThreshold int
+	// filters applied to review comments before they are saved
+	reviewFilter contentfilter.Pipeline
+	// maximum quantity of one smartphone in a cart
+	cartMaxQuantity int
+	// seller details printed on invoices
+	store invoice.Store
+	// time after delivery during which purchases can be returned
+	returnWindow time.Duration
+	// carts not changed for cartReminderAfter are reminded about, the check
+	// runs every cartReminderInterval
+	cartReminderAfter    time.Duration
+	cartReminderInterval time.Duration
+	// guest carts not changed for guestCartIdleTTL are deleted
+	guestCartIdleTTL time.Duration
+	// address of the server used in links sent by email
+	publicURL string
+	// VAT rates per product category
+	tax tax.Config
+	// responses to requests with the Idempotency-Key header are replayed for
+	// idempotencyKeyTTL
+	idempotencyKeyTTL time.Duration
+	// lifetimes of access tokens and of refresh tokens
+	accessTokenTTL  time.Duration
+	refreshTokenTTL time.Duration
+	// counters of rate limits and of failed logins
+	rateLimits ratelimit.Store
+	// limits of login, password restore and password reset requests per client
+	// IP and per email, and of guest carts per client IP
+	loginLimits     rateLimits
+	restoreLimits   rateLimits
+	resetLimits     rateLimits
+	guestCartLimits rateLimits
+	// lockout of emails after repeated failed logins
+	loginLockout ratelimit.Lockout
+	// whether client IP is taken from X-Forwarded-For set by a reverse proxy
+	trustForwardedFor bool
+	// lifetimes of one-time passwords sent on signup and for password reset
+	// and the number of checks of one password
+	signupPasswordTTL      time.Duration
+	resetPasswordTTL       time.Duration
+	tmpPasswordMaxAttempts int
 }
 
 func NewApp(logger logger.Logger, server *http.Server, DB storage.Storage) *App {
 	jwt := os.Getenv("JWT_SECRET")
-	return &App{Log: logger, Server: server, DB: DB, jwtSecret: []byte(jwt)}
+	webhookSecret := os.Getenv("PAYMENTS_WEBHOOK_SECRET")
+	if webhookSecret == "" {
+		// without a configured secret nobody can sign a valid webhook
+		webhookSecret = rand.Text()
+	}
+	uploadsDir := os.Getenv("UPLOADS_DIR")
+	if uploadsDir == "" {
+		uploadsDir = "uploads"
+	}
+	app := &App{
+		Log:                    logger,
+		Server:                 server,
+		DB:                     DB,
+		Blobs:                  blobstore.NewLocalStore(uploadsDir),
+		Mail:                   mailer.NewSMTPMailer(),
+		Payments:               payments.NewFakeProvider(webhookSecret),
+		jwtSecret:              []byte(jwt),
+		reviewMaxPhotos:        envInt("REVIEW_MAX_PHOTOS", 5),
+		reviewMaxPhotoSize:     int64(envInt("REVIEW_MAX_PHOTO_SIZE", 5<<20)),
+		reviewMaxPhotoPixels:   envInt("REVIEW_MAX_PHOTO_PIXELS", 40_000_000),
+		reviewReportThreshold:  envInt("REVIEW_REPORT_THRESHOLD", 3),
+		cartMaxQuantity:        envInt("CART_MAX_QUANTITY", 10),
+		returnWindow:           time.Duration(envInt("RETURN_WINDOW_DAYS", 14)) * 24 * time.Hour,
+		cartReminderAfter:      time.Duration(envInt("CART_REMINDER_AFTER_HOURS", 24)) * time.Hour,
+		cartReminderInterval:   time.Duration(envInt("CART_REMINDER_INTERVAL_MINUTES", 60)) * time.Minute,
+		guestCartIdleTTL:       time.Duration(envInt("GUEST_CART_IDLE_DAYS", 7)) * 24 * time.Hour,
+		publicURL:              envString("PUBLIC_URL", "http://localhost:8081"),
+		idempotencyKeyTTL:      time.Duration(envInt("IDEMPOTENCY_KEY_TTL_HOURS", 24)) * time.Hour,
+		accessTokenTTL:         time.Duration(envInt("ACCESS_TOKEN_TTL_MINUTES", 15)) * time.Minute,
+		refreshTokenTTL:        time.Duration(envInt("REFRESH_TOKEN_TTL_DAYS", 30)) * 24 * time.Hour,
+		trustForwardedFor:      envBool("RATE_LIMIT_TRUST_FORWARDED", false),
+		signupPasswordTTL:      time.Duration(envInt("SIGNUP_PASSWORD_TTL_HOURS", 24)) * time.Hour,
+		resetPasswordTTL:       time.Duration(envInt("RESET_PASSWORD_TTL_MINUTES", 60)) * time.Minute,
+		tmpPasswordMaxAttempts: envInt("TMP_PASSWORD_MAX_ATTEMPTS", 5),
+		store: invoice.Store{
+			Name:    envString("STORE_NAME", invoice.DefaultStore.Name),
+			Address: envString("STORE_ADDRESS", invoice.DefaultStore.Address),
+			Email:   envString("STORE_EMAIL", invoice.DefaultStore.Email),
+			TaxID:   envString("STORE_TAX_ID", invoice.DefaultStore.TaxID),
+		},
+	}
+	filterSpec, ok := os.LookupEnv("REVIEW_FILTERS")
+	if !ok {
+		filterSpec = contentfilter.DefaultSpec
+	}
+	duplicates := func(text string, userID int) (bool, error) {
+		return app.DB.HasDuplicateReview(text, userID)
+	}
+	reviewFilter, err := contentfilter.NewPipeline(filterSpec, duplicates)
+	if err != nil {
+		logger.Errorf("invalid REVIEW_FILTERS, using default filters: %v", err)
+		reviewFilter, _ = contentfilter.NewPipeline(contentfilter.DefaultSpec, duplicates)
+	}
+	app.reviewFilter = reviewFilter
+	taxRates, err := tax.ParseRates(envString("TAX_RATES", tax.DefaultRates))
+	if err != nil {
+		logger.Errorf("invalid TAX_RATES, using default rates: %v", err)
+		taxRates, _ = tax.ParseRates(tax.DefaultRates)
+	}
+	defaultRate := envFloat("TAX_DEFAULT_RATE", 20)
+	if defaultRate < 0 || defaultRate > 100 {
+		logger.Errorf("invalid TAX_DEFAULT_RATE %v, using 20", defaultRate)
+		defaultRate = 20
+	}
+	app.tax = tax.Config{
+		Rates:            taxRates,
+		DefaultRate:      defaultRate,
+		PricesIncludeTax: envBool("PRICES_INCLUDE_TAX", true),
+	}
+	switch backend := envString("RATE_LIMIT_BACKEND", "memory"); backend {
+	case "postgres":
+		app.rateLimits = dbRateLimits{app.DB}
+	default:
+		if backend != "memory" {
+			logger.Errorf("unknown RATE_LIMIT_BACKEND %q, using memory", backend)
+		}
+		app.rateLimits = ratelimit.NewMemoryStore()
+	}
+	app.loginLimits = rateLimits{
+		name:     "login",
+		perIP:    envRule(logger, "LOGIN_RATE_LIMIT_IP", "20/1m"),
+		perEmail: envRule(logger, "LOGIN_RATE_LIMIT_EMAIL", "10/1m"),
+	}
+	app.restoreLimits = rateLimits{
+		name:     "restore",
+		perIP:    envRule(logger, "RESTORE_RATE_LIMIT_IP", "10/1h"),
+		perEmail: envRule(logger, "RESTORE_RATE_LIMIT_EMAIL", "3/1h"),
+	}
+	app.resetLimits = rateLimits{
+		name:     "reset",
+		perIP:    envRule(logger, "RESET_RATE_LIMIT_IP", "20/1m"),
+		perEmail: envRule(logger, "RESET_RATE_LIMIT_EMAIL", "10/1m"),
+	}
+	app.guestCartLimits = rateLimits{
+		name:  "guest_cart",
+		perIP: envRule(logger, "GUEST_CART_RATE_LIMIT_IP", "20/1h"),
+	}
+	app.loginLockout = ratelimit.Lockout{
+		Store:       app.rateLimits,
+		MaxFailures: envInt("LOGIN_MAX_FAILURES", 5),
+		Base:        time.Duration(envInt("LOGIN_LOCKOUT_MINUTES", 1)) * time.Minute,
+		Max:         time.Duration(envInt("LOGIN_LOCKOUT_MAX_MINUTES", 60)) * time.Minute,
+		Window:      time.Duration(envInt("LOGIN_FAILURES_WINDOW_HOURS", 24)) * time.Hour,
+	}
+	return app
+}
+
+// envInt reads an integer setting from the environment, falling back to def
+// if the variable is unset or malformed
+func envInt(key string, def int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return def
+	}
+	return value
+}
+
+// envFloat reads a number setting that may be fractional, falling back to def
+// if the variable is unset or malformed
+func envFloat(key string, def float64) float64 {
+	value, err := strconv.ParseFloat(os.Getenv(key), 64)
+	if err != nil {
+		return def
+	}
+	return value
+}
+
+// envBool reads a boolean setting from the environment, falling back to def
+// if the variable is unset or malformed
+func envBool(key string, def bool) bool {
+	value, err := strconv.ParseBool(os.Getenv(key))
+	if err != nil {
+		return def
+	}
+	return value
+}
+
+// envRule reads a rate limit rule from the environment, falling back to def
+// if the variable is unset or malformed
+func envRule(logger logger.Logger, key, def string) ratelimit.Rule {
+	rule, err := ratelimit.ParseRule(envString(key, def))
+	if err != nil {
+		logger.Errorf("invalid %s, using %s: %v", key, def, err)
+		rule, _ = ratelimit.ParseRule(def)
+	}
+	return rule
+}
+
+// envString reads a setting from the environment, falling back to def if the
+// variable is unset or empty
+func envString(key, def string) string {
+	value := os.Getenv(key)
+	if value == "" {
+		return def
+	}
+	return value
 }
 
 func (app *App) ErrorJSON(w http.ResponseWriter, r *http.Request, err error) {
@@ -46,6 +261,9 @@ func (app *App) ErrorJSON(w http.ResponseWriter, r *http.Request, err error) {
 	} else if errors.Is(err, apperrors.ErrAlreadyExists) {
 		err = apperrors.ErrAlreadyExists
 		code = http.StatusConflict
+	} else if errors.Is(err, apperrors.ErrTooManyRequests) {
+		err = apperrors.ErrTooManyRequests
+		code = http.StatusTooManyRequests
 	} else {
 		err = apperrors.ErrInternal
 		code = http.StatusInternalServerError
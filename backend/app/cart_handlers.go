@@ -32,13 +32,9 @@ func (app *App) GetCart(w http.ResponseWriter, r *http.Request) {
 		app.ErrorJSON(w, r, fmt.Errorf("error getting cart %d: %w", ID, err))
 		return
 	}
-	userID, role, err := app.GetClaims(r)
+	err = app.checkCartAccess(r, cart)
 	if err != nil {
-		app.ErrorJSON(w, r, fmt.Errorf("%w: error extracting claims: %w", apperrors.ErrUnauthorized, err))
-		return
-	}
-	if cart.UserID != userID && role != models.RoleAdmin {
-		app.ErrorJSON(w, r, apperrors.ErrForbidden)
+		app.ErrorJSON(w, r, err)
 		return
 	}
 	cartItems, err := app.DB.GetCartItems(cart.ID)
@@ -46,7 +42,13 @@ func (app *App) GetCart(w http.ResponseWriter, r *http.Request) {
 		app.ErrorJSON(w, r, fmt.Errorf("error getting items for cart %d: %w", cart.ID, err))
 		return
 	}
-	cart.Items = cartItems
+	cart.SetItems(cartItems)
+	err = app.attachCartDiscount(&cart)
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("error computing discount of cart %d: %w", cart.ID, err))
+		return
+	}
+	app.attachCartTax(&cart)
 	app.Encode(w, r, cart)
 }
 
@@ -110,7 +112,13 @@ func (app *App) GetCartByUserID(w http.ResponseWriter, r *http.Request) {
 		app.ErrorJSON(w, r, fmt.Errorf("error getting items for cart %d: %w", cart.ID, err))
 		return
 	}
-	cart.Items = cartItems
+	cart.SetItems(cartItems)
+	err = app.attachCartDiscount(&cart)
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("error computing discount of cart %d: %w", cart.ID, err))
+		return
+	}
+	app.attachCartTax(&cart)
 	app.Encode(w, r, cart)
 }
 
@@ -135,13 +143,9 @@ func (app *App) GetCartItems(w http.ResponseWriter, r *http.Request) {
 		app.ErrorJSON(w, r, fmt.Errorf("error getting cart %d: %w", cartID, err))
 		return
 	}
-	userID, role, err := app.GetClaims(r)
+	err = app.checkCartAccess(r, cart)
 	if err != nil {
-		app.ErrorJSON(w, r, fmt.Errorf("%w: error extracting claims: %w", apperrors.ErrUnauthorized, err))
-		return
-	}
-	if userID != cart.UserID && role != models.RoleAdmin {
-		app.ErrorJSON(w, r, apperrors.ErrForbidden)
+		app.ErrorJSON(w, r, err)
 		return
 	}
 	cartItems, err := app.DB.GetCartItems(cartID)
@@ -149,12 +153,19 @@ func (app *App) GetCartItems(w http.ResponseWriter, r *http.Request) {
 		app.ErrorJSON(w, r, fmt.Errorf("error getting cartItems of cart id(%d): %w", cartID, err))
 		return
 	}
+	cart.SetItems(cartItems)
+	err = app.attachCartDiscount(&cart)
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("error computing discount of cart %d: %w", cart.ID, err))
+		return
+	}
+	app.applyTax(cartItems, cart.Discount)
 	app.Encode(w, r, cartItems)
 }
 
 // AddToCart adds an item to cart
 // @Summary      Add Item to Cart
-// @Description  Adds a smartphone item to the user's cart
+// @Description  Adds a smartphone item to the user's cart. If the smartphone is already in the cart, its quantity is increased
 // @Tags         cart
 // @Security     BearerAuth
 // @Accept       json
@@ -176,13 +187,9 @@ func (app *App) AddToCart(w http.ResponseWriter, r *http.Request) {
 		app.ErrorJSON(w, r, fmt.Errorf("error getting cart %d: %w", cartID, err))
 		return
 	}
-	userID, role, err := app.GetClaims(r)
+	err = app.checkCartAccess(r, cart)
 	if err != nil {
-		app.ErrorJSON(w, r, fmt.Errorf("%w: error extracting claims: %w", apperrors.ErrUnauthorized, err))
-		return
-	}
-	if userID != cart.UserID && role != models.RoleAdmin {
-		app.ErrorJSON(w, r, apperrors.ErrForbidden)
+		app.ErrorJSON(w, r, err)
 		return
 	}
 	var cartItemreq models.CartItemRequest
@@ -196,7 +203,12 @@ func (app *App) AddToCart(w http.ResponseWriter, r *http.Request) {
 	if cartItem.Quantity < 1 {
 		cartItem.Quantity = 1
 	}
-	addedCartItem, err := app.DB.AddToCart(cartItem)
+	if cartItem.Quantity > app.cartMaxQuantity {
+		app.ErrorJSON(w, r, fmt.Errorf("%w: quantity %d is over the limit %d",
+			apperrors.ErrBadRequest, cartItem.Quantity, app.cartMaxQuantity))
+		return
+	}
+	addedCartItem, err := app.DB.AddToCart(cartItem, app.cartMaxQuantity)
 	if err != nil {
 		app.ErrorJSON(w, r, fmt.Errorf("error creating cartItem: %w", err))
 		return
@@ -205,6 +217,110 @@ func (app *App) AddToCart(w http.ResponseWriter, r *http.Request) {
 	app.Encode(w, r, addedCartItem)
 }
 
+// ReplaceCartItems replaces the content of the cart
+// @Summary      Replace Cart items
+// @Description  Replaces all items of the cart in one transaction. Smartphones that stay in the cart keep the price they were added with
+// @Tags         cart
+// @Security     BearerAuth
+// @Accept       json
+// @Produce      json
+// @Param        cart_id path int true "Cart ID"
+// @Param        items body []models.CartItemRequest true "New items of the cart"
+// @Success      200  {object}  models.Cart
+// @Failure      400  {object}  apperrors.ErrorResponse "Bad Request"
+// @Failure      401  {object}  apperrors.ErrorResponse "Unauthorized"
+// @Failure      403  {object}  apperrors.ErrorResponse "Forbidden"
+// @Router       /carts/{cart_id}/items [put]
+func (app *App) ReplaceCartItems(w http.ResponseWriter, r *http.Request) {
+	cartID, err := app.ExtractPathValue(r, "cart_id")
+	if err != nil {
+		app.ErrorJSON(w, r, err)
+		return
+	}
+	cart, err := app.DB.GetCart(cartID)
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("error getting cart %d: %w", cartID, err))
+		return
+	}
+	err = app.checkCartAccess(r, cart)
+	if err != nil {
+		app.ErrorJSON(w, r, err)
+		return
+	}
+	var reqs []models.CartItemRequest
+	err = json.NewDecoder(r.Body).Decode(&reqs)
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("%w: error decoding cart items: %w", apperrors.ErrBadRequest, err))
+		return
+	}
+	items := make([]models.CartItem, len(reqs))
+	seen := make(map[int]bool, len(reqs))
+	for i, req := range reqs {
+		if seen[req.SmartphoneID] {
+			app.ErrorJSON(w, r, fmt.Errorf("%w: smartphone %d is listed twice", apperrors.ErrBadRequest, req.SmartphoneID))
+			return
+		}
+		seen[req.SmartphoneID] = true
+		if req.Quantity < 1 {
+			req.Quantity = 1
+		}
+		if req.Quantity > app.cartMaxQuantity {
+			app.ErrorJSON(w, r, fmt.Errorf("%w: quantity %d is over the limit %d",
+				apperrors.ErrBadRequest, req.Quantity, app.cartMaxQuantity))
+			return
+		}
+		items[i] = models.CartItem{CartID: cartID, SmartphoneID: req.SmartphoneID, Quantity: req.Quantity}
+	}
+	cartItems, err := app.DB.ReplaceCartItems(cartID, items)
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("error replacing items of cart %d: %w", cartID, err))
+		return
+	}
+	cart.SetItems(cartItems)
+	err = app.attachCartDiscount(&cart)
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("error computing discount of cart %d: %w", cart.ID, err))
+		return
+	}
+	app.attachCartTax(&cart)
+	app.Encode(w, r, cart)
+}
+
+// ClearCart removes all items from the cart
+// @Summary      Clear Cart
+// @Tags         cart
+// @Security     BearerAuth
+// @Produce      json
+// @Param        cart_id path int true "Cart ID"
+// @Success      200  {object}  models.Cart
+// @Failure      401  {object}  apperrors.ErrorResponse "Unauthorized"
+// @Failure      403  {object}  apperrors.ErrorResponse "Forbidden"
+// @Router       /carts/{cart_id}/items [delete]
+func (app *App) ClearCart(w http.ResponseWriter, r *http.Request) {
+	cartID, err := app.ExtractPathValue(r, "cart_id")
+	if err != nil {
+		app.ErrorJSON(w, r, err)
+		return
+	}
+	cart, err := app.DB.GetCart(cartID)
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("error getting cart %d: %w", cartID, err))
+		return
+	}
+	err = app.checkCartAccess(r, cart)
+	if err != nil {
+		app.ErrorJSON(w, r, err)
+		return
+	}
+	err = app.DB.ClearCart(cartID)
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("error clearing cart %d: %w", cartID, err))
+		return
+	}
+	cart.SetItems([]models.CartItem{})
+	app.Encode(w, r, cart)
+}
+
 // @Summary      Sets quantity of an item in a cart
 // @Description  Sets quantity of an item in a cart
 // @Tags         cart
@@ -234,13 +350,9 @@ func (app *App) SetQuantity(w http.ResponseWriter, r *http.Request) {
 		app.ErrorJSON(w, r, fmt.Errorf("error getting cart %d: %w", cartID, err))
 		return
 	}
-	userID, role, err := app.GetClaims(r)
+	err = app.checkCartAccess(r, cart)
 	if err != nil {
-		app.ErrorJSON(w, r, fmt.Errorf("%w: error extracting claims: %w", apperrors.ErrUnauthorized, err))
-		return
-	}
-	if userID != cart.UserID && role != models.RoleAdmin {
-		app.ErrorJSON(w, r, apperrors.ErrForbidden)
+		app.ErrorJSON(w, r, err)
 		return
 	}
 	var quant models.SetItemQuantity
@@ -253,6 +365,11 @@ func (app *App) SetQuantity(w http.ResponseWriter, r *http.Request) {
 		app.ErrorJSON(w, r, fmt.Errorf("%w: qunatity must be a positive integer", apperrors.ErrBadRequest))
 		return
 	}
+	if quant.Quantity > app.cartMaxQuantity {
+		app.ErrorJSON(w, r, fmt.Errorf("%w: quantity %d is over the limit %d",
+			apperrors.ErrBadRequest, quant.Quantity, app.cartMaxQuantity))
+		return
+	}
 	cartItem := models.CartItem{Quantity: quant.Quantity}
 	cartItem.ID = itemID
 	cartItem.CartID = cartID
@@ -264,6 +381,61 @@ func (app *App) SetQuantity(w http.ResponseWriter, r *http.Request) {
 	app.Encode(w, r, updatedCartItem)
 }
 
+// SetCartItemState moves an item to the saved for later list and back
+// @Summary      Save a Cart item for later
+// @Description  Moves an item to the saved for later list (state "saved") or back to the cart (state "active"). Saved items are not counted in totals and are not ordered
+// @Tags         cart
+// @Security     BearerAuth
+// @Accept       json
+// @Produce      json
+// @Param        item_id path int true "Item ID"
+// @Param        cart_id path int true "Cart ID"
+// @Param        state body models.SetItemState true "New state of the item"
+// @Success      200  {object}  models.CartItem
+// @Failure      400  {object}  apperrors.ErrorResponse "Bad Request"
+// @Failure      401  {object}  apperrors.ErrorResponse "Unauthorized"
+// @Failure      403  {object}  apperrors.ErrorResponse "Forbidden"
+// @Router       /carts/{cart_id}/items/{item_id}/state [patch]
+func (app *App) SetCartItemState(w http.ResponseWriter, r *http.Request) {
+	itemID, err := app.ExtractPathValue(r, "item_id")
+	if err != nil {
+		app.ErrorJSON(w, r, err)
+		return
+	}
+	cartID, err := app.ExtractPathValue(r, "cart_id")
+	if err != nil {
+		app.ErrorJSON(w, r, err)
+		return
+	}
+	cart, err := app.DB.GetCart(cartID)
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("error getting cart %d: %w", cartID, err))
+		return
+	}
+	err = app.checkCartAccess(r, cart)
+	if err != nil {
+		app.ErrorJSON(w, r, err)
+		return
+	}
+	var req models.SetItemState
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("%w: error decoding state: %w", apperrors.ErrBadRequest, err))
+		return
+	}
+	if req.State != models.CartItemActive && req.State != models.CartItemSaved {
+		app.ErrorJSON(w, r, fmt.Errorf("%w: unknown cart item state %q", apperrors.ErrBadRequest, req.State))
+		return
+	}
+	cartItem := models.CartItem{ID: itemID, CartID: cartID, State: req.State}
+	updatedCartItem, err := app.DB.SetCartItemState(cartItem)
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("error updating state of cartItem %d: %w", itemID, err))
+		return
+	}
+	app.Encode(w, r, updatedCartItem)
+}
+
 // @Summary      Deletes an item from a cart
 // @Description  Deletes an item from a cart
 // @Tags         cart
@@ -291,13 +463,9 @@ func (app *App) DeleteFromCart(w http.ResponseWriter, r *http.Request) {
 		app.ErrorJSON(w, r, fmt.Errorf("error getting cart %d: %w", cartID, err))
 		return
 	}
-	userID, role, err := app.GetClaims(r)
+	err = app.checkCartAccess(r, cart)
 	if err != nil {
-		app.ErrorJSON(w, r, fmt.Errorf("%w: error extracting claims: %w", apperrors.ErrUnauthorized, err))
-		return
-	}
-	if userID != cart.UserID && role != models.RoleAdmin {
-		app.ErrorJSON(w, r, apperrors.ErrForbidden)
+		app.ErrorJSON(w, r, err)
 		return
 	}
 	cartItem, err := app.DB.DeleteFromCart(cartID, itemID)
@@ -1,6 +1,8 @@
 package app
 
 import (
+	"bytes"
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -10,6 +12,7 @@ import (
 	"github.com/sfu-teamproject/smartbuy/backend/logger/mocklogger"
 	"github.com/sfu-teamproject/smartbuy/backend/models"
 	"github.com/sfu-teamproject/smartbuy/backend/storage/mockstorage"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
 
@@ -49,3 +52,164 @@ func TestGetCart(t *testing.T) {
 	}
 	ms.AssertExpectations(t)
 }
+
+func TestGetCartTotals(t *testing.T) {
+	ms := new(mockstorage.MockStorage)
+	ml := new(mocklogger.MockLogger)
+	ms.On("GetCart", 1).Return(models.Cart{ID: 1, UserID: 1}, nil)
+	ms.On("GetCartItems", 1).Return([]models.CartItem{
+		{ID: 1, CartID: 1, SmartphoneID: 1, Quantity: 2, LineTotal: 2000,
+			Smartphone: &models.SmartphoneSummary{ID: 1, Model: "Phone 1", Price: 1000}},
+		{ID: 2, CartID: 1, SmartphoneID: 2, Quantity: 1, LineTotal: 500, AddedPrice: 450, PriceChange: 50,
+			Smartphone: &models.SmartphoneSummary{ID: 2, Model: "Phone 2", Price: 500}},
+	}, nil)
+	ml.On("Errorln", mock.Anything, mock.Anything, mock.Anything)
+
+	app := NewApp(ml, nil, ms)
+	ctx := createContextWithClaims("1", models.RoleUser)
+	r := httptest.NewRequestWithContext(ctx, http.MethodGet, "/", nil)
+	r.SetPathValue("cart_id", "1")
+	w := httptest.NewRecorder()
+	app.GetCart(w, r)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var cart models.Cart
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&cart))
+	assert.Equal(t, 3, cart.ItemCount)
+	assert.Equal(t, 2500, cart.Subtotal)
+	assert.True(t, cart.PriceChanged)
+	assert.Equal(t, "Phone 1", cart.Items[0].Smartphone.Model)
+}
+
+func TestReplaceCartItems(t *testing.T) {
+	ms := new(mockstorage.MockStorage)
+	ml := new(mocklogger.MockLogger)
+	ms.On("GetCart", 1).Return(models.Cart{ID: 1, UserID: 1}, nil)
+	ms.On("ReplaceCartItems", 1, []models.CartItem{
+		{CartID: 1, SmartphoneID: 1, Quantity: 2},
+		{CartID: 1, SmartphoneID: 2, Quantity: 1},
+	}).Return([]models.CartItem{
+		{ID: 1, CartID: 1, SmartphoneID: 1, Quantity: 2, LineTotal: 2000},
+		{ID: 4, CartID: 1, SmartphoneID: 2, Quantity: 1, LineTotal: 500},
+	}, nil)
+	ml.On("Errorln", mock.Anything, mock.Anything, mock.Anything)
+
+	app := NewApp(ml, nil, ms)
+	app.cartMaxQuantity = 10
+	tests := []struct {
+		name   string
+		userID string
+		body   string
+		code   int
+	}{
+		{"Replace items", "1", `[{"smartphone_id": 1, "quantity": 2}, {"smartphone_id": 2}]`, http.StatusOK},
+		{"Duplicate smartphone", "1", `[{"smartphone_id": 1}, {"smartphone_id": 1}]`, http.StatusBadRequest},
+		{"Quantity over limit", "1", `[{"smartphone_id": 1, "quantity": 11}]`, http.StatusBadRequest},
+		{"Replace items of another cart", "2", `[]`, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := createContextWithClaims(tt.userID, models.RoleUser)
+			r := httptest.NewRequestWithContext(ctx, http.MethodPut, "/", bytes.NewBufferString(tt.body))
+			r.SetPathValue("cart_id", "1")
+			w := httptest.NewRecorder()
+			app.ReplaceCartItems(w, r)
+			assert.Equal(t, tt.code, w.Code)
+			if tt.code == http.StatusOK {
+				var cart models.Cart
+				assert.NoError(t, json.NewDecoder(w.Body).Decode(&cart))
+				assert.Equal(t, 3, cart.ItemCount)
+				assert.Equal(t, 2500, cart.Subtotal)
+			}
+		})
+	}
+	ms.AssertNumberOfCalls(t, "ReplaceCartItems", 1)
+}
+
+func TestClearCart(t *testing.T) {
+	ms := new(mockstorage.MockStorage)
+	ml := new(mocklogger.MockLogger)
+	ms.On("GetCart", 1).Return(models.Cart{ID: 1, UserID: 1}, nil)
+	ms.On("ClearCart", 1).Return(nil)
+	ml.On("Errorln", mock.Anything, mock.Anything, mock.Anything)
+
+	app := NewApp(ml, nil, ms)
+	tests := []struct {
+		name   string
+		userID string
+		role   models.Role
+		code   int
+	}{
+		{"Owner clears cart", "1", models.RoleUser, http.StatusOK},
+		{"Another user clears cart", "2", models.RoleUser, http.StatusForbidden},
+		{"Admin clears cart", "2", models.RoleAdmin, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := createContextWithClaims(tt.userID, tt.role)
+			r := httptest.NewRequestWithContext(ctx, http.MethodDelete, "/", nil)
+			r.SetPathValue("cart_id", "1")
+			w := httptest.NewRecorder()
+			app.ClearCart(w, r)
+			assert.Equal(t, tt.code, w.Code)
+		})
+	}
+	ms.AssertNumberOfCalls(t, "ClearCart", 2)
+}
+
+func TestSetCartItemState(t *testing.T) {
+	ms := new(mockstorage.MockStorage)
+	ml := new(mocklogger.MockLogger)
+	ms.On("GetCart", 1).Return(models.Cart{ID: 1, UserID: 1}, nil)
+	ms.On("SetCartItemState", models.CartItem{ID: 2, CartID: 1, State: models.CartItemSaved}).
+		Return(models.CartItem{ID: 2, CartID: 1, SmartphoneID: 3, Quantity: 1, State: models.CartItemSaved}, nil)
+	ml.On("Errorln", mock.Anything, mock.Anything, mock.Anything)
+
+	app := NewApp(ml, nil, ms)
+	tests := []struct {
+		name   string
+		userID string
+		body   string
+		code   int
+	}{
+		{"Save item for later", "1", `{"state": "saved"}`, http.StatusOK},
+		{"Unknown state", "1", `{"state": "deleted"}`, http.StatusBadRequest},
+		{"Save item of another cart", "2", `{"state": "saved"}`, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := createContextWithClaims(tt.userID, models.RoleUser)
+			r := httptest.NewRequestWithContext(ctx, http.MethodPatch, "/", bytes.NewBufferString(tt.body))
+			r.SetPathValue("cart_id", "1")
+			r.SetPathValue("item_id", "2")
+			w := httptest.NewRecorder()
+			app.SetCartItemState(w, r)
+			assert.Equal(t, tt.code, w.Code)
+		})
+	}
+	ms.AssertNumberOfCalls(t, "SetCartItemState", 1)
+}
+
+func TestGetCartSavedItems(t *testing.T) {
+	ms := new(mockstorage.MockStorage)
+	ml := new(mocklogger.MockLogger)
+	ms.On("GetCart", 1).Return(models.Cart{ID: 1, UserID: 1}, nil)
+	ms.On("GetCartItems", 1).Return([]models.CartItem{
+		{ID: 1, CartID: 1, SmartphoneID: 1, Quantity: 2, LineTotal: 2000, State: models.CartItemActive},
+		{ID: 2, CartID: 1, SmartphoneID: 2, Quantity: 1, LineTotal: 500, State: models.CartItemSaved},
+	}, nil)
+	ml.On("Errorln", mock.Anything, mock.Anything, mock.Anything)
+
+	app := NewApp(ml, nil, ms)
+	ctx := createContextWithClaims("1", models.RoleUser)
+	r := httptest.NewRequestWithContext(ctx, http.MethodGet, "/", nil)
+	r.SetPathValue("cart_id", "1")
+	w := httptest.NewRecorder()
+	app.GetCart(w, r)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var cart models.Cart
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&cart))
+	assert.Len(t, cart.Items, 1)
+	assert.Len(t, cart.SavedItems, 1)
+	assert.Equal(t, 2, cart.ItemCount)
+	assert.Equal(t, 2000, cart.Subtotal)
+}
@@ -0,0 +1,149 @@
+package app
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sfu-teamproject/smartbuy/backend/apperrors"
+	"github.com/sfu-teamproject/smartbuy/backend/mailer"
+	"github.com/sfu-teamproject/smartbuy/backend/models"
+)
+
+// StartCartReminders sends abandoned cart reminders every interval until ctx
+// is done. A zero interval disables reminders
+func (app *App) StartCartReminders(ctx context.Context) {
+	if app.cartReminderInterval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(app.cartReminderInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			sent, err := app.SendCartReminders()
+			if err != nil {
+				app.Log.Errorf("error sending cart reminders: %v", err)
+			}
+			if sent > 0 {
+				app.Log.Infof("Sent %d cart reminders", sent)
+			}
+		}
+	}
+}
+
+// SendCartReminders emails owners of carts that were not changed for
+// cartReminderAfter. Every cart is reminded about at most once per change,
+// failures of single carts are logged and do not stop the others
+func (app *App) SendCartReminders() (int, error) {
+	carts, err := app.DB.GetAbandonedCarts(time.Now().Add(-app.cartReminderAfter))
+	if err != nil {
+		return 0, fmt.Errorf("error getting abandoned carts: %w", err)
+	}
+	sent := 0
+	for _, cart := range carts {
+		ok, err := app.sendCartReminder(cart)
+		if err != nil {
+			app.Log.Errorf("error sending reminder about cart %d: %v", cart.ID, err)
+			continue
+		}
+		if ok {
+			sent++
+		}
+	}
+	return sent, nil
+}
+
+// sendCartReminder reports whether the reminder was sent. The reminder is
+// recorded before sending, so a failed email is not retried until the cart
+// changes again
+func (app *App) sendCartReminder(cart models.Cart) (bool, error) {
+	cartItems, err := app.DB.GetCartItems(cart.ID)
+	if err != nil {
+		return false, fmt.Errorf("error getting items: %w", err)
+	}
+	cart.SetItems(cartItems)
+	if len(cart.Items) == 0 {
+		return false, nil
+	}
+	customer, err := app.DB.GetUser(cart.UserID)
+	if err != nil {
+		return false, fmt.Errorf("error getting owner %d: %w", cart.UserID, err)
+	}
+	claimed, err := app.DB.ClaimCartReminder(cart.ID)
+	if err != nil || !claimed {
+		return false, err
+	}
+	err = app.Mail.Send(mailer.Message{
+		To:      customer.Email,
+		Subject: "Smartbuy: в корзине остались товары",
+		Body:    app.cartReminderBody(customer, cart),
+	})
+	return err == nil, err
+}
+
+func (app *App) cartReminderBody(customer models.User, cart models.Cart) string {
+	var b strings.Builder
+	b.WriteString("Здравствуйте, " + customer.Name + "!\n")
+	b.WriteString("Вы оставили в корзине товары:\n")
+	for _, item := range cart.Items {
+		name := fmt.Sprintf("смартфон №%d", item.SmartphoneID)
+		if item.Smartphone != nil {
+			name = item.Smartphone.Producer + " " + item.Smartphone.Model
+		}
+		fmt.Fprintf(&b, "- %s, %d шт. - %d руб.\n", name, item.Quantity, item.LineTotal)
+	}
+	fmt.Fprintf(&b, "Итого: %d руб.\n", cart.Subtotal)
+	b.WriteString("\nОтписаться от напоминаний: " + app.unsubscribeURL(customer.ID))
+	return b.String()
+}
+
+// unsubscribeURL is a link that turns off cart reminders without logging in
+func (app *App) unsubscribeURL(userID int) string {
+	return fmt.Sprintf("%s/api/v1/users/%d/cart-reminders/unsubscribe?token=%s",
+		app.publicURL, userID, url.QueryEscape(app.unsubscribeToken(userID)))
+}
+
+func (app *App) unsubscribeToken(userID int) string {
+	mac := hmac.New(sha256.New, app.jwtSecret)
+	mac.Write([]byte("cart_reminders:" + strconv.Itoa(userID)))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// UnsubscribeCartReminders turns off abandoned cart reminders
+// @Summary      Unsubscribe from Cart reminders
+// @Description  Opens from the link in a reminder email, the token in the link replaces logging in. Reminders can also be turned on and off with the cart_reminders field of the user.
+// @Tags         users
+// @Produce      json
+// @Param        user_id path int true "User ID"
+// @Param        token query string true "Token from the email"
+// @Success      200  {object}  models.User
+// @Failure      401  {object}  apperrors.ErrorResponse "Invalid token"
+// @Router       /users/{user_id}/cart-reminders/unsubscribe [get]
+func (app *App) UnsubscribeCartReminders(w http.ResponseWriter, r *http.Request) {
+	userID, err := app.ExtractPathValue(r, "user_id")
+	if err != nil {
+		app.ErrorJSON(w, r, err)
+		return
+	}
+	token := r.URL.Query().Get("token")
+	if !hmac.Equal([]byte(token), []byte(app.unsubscribeToken(userID))) {
+		app.ErrorJSON(w, r, fmt.Errorf("%w: invalid unsubscribe token for user %d", apperrors.ErrUnauthorized, userID))
+		return
+	}
+	updatedUser, err := app.DB.UpdateUser(userID, map[string]any{"cart_reminders": false})
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("error turning off cart reminders of user %d: %w", userID, err))
+		return
+	}
+	app.Encode(w, r, updatedUser)
+}
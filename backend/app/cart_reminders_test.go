@@ -0,0 +1,95 @@
+package app
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sfu-teamproject/smartbuy/backend/logger/mocklogger"
+	"github.com/sfu-teamproject/smartbuy/backend/mailer"
+	"github.com/sfu-teamproject/smartbuy/backend/mailer/mockmailer"
+	"github.com/sfu-teamproject/smartbuy/backend/models"
+	"github.com/sfu-teamproject/smartbuy/backend/storage/mockstorage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestSendCartReminders(t *testing.T) {
+	ms := new(mockstorage.MockStorage)
+	ml := new(mocklogger.MockLogger)
+	mm := new(mockmailer.MockMailer)
+	ms.On("GetAbandonedCarts", mock.MatchedBy(func(idleSince time.Time) bool {
+		return time.Since(idleSince) > 23*time.Hour
+	})).Return([]models.Cart{{ID: 1, UserID: 2}, {ID: 2, UserID: 3}, {ID: 3, UserID: 4}, {ID: 4, UserID: 5}}, nil)
+	ms.On("GetCartItems", 1).Return([]models.CartItem{
+		{ID: 1, CartID: 1, SmartphoneID: 1, Quantity: 2, LineTotal: 2000,
+			Smartphone: &models.SmartphoneSummary{ID: 1, Producer: "Apple", Model: "iPhone 16", Price: 1000}},
+	}, nil)
+	ms.On("GetCartItems", 2).Return([]models.CartItem{
+		{ID: 2, CartID: 2, SmartphoneID: 1, Quantity: 1, State: models.CartItemSaved},
+	}, nil)
+	ms.On("GetCartItems", 3).Return([]models.CartItem{{ID: 3, CartID: 3, SmartphoneID: 1, Quantity: 1}}, nil)
+	ms.On("GetCartItems", 4).Return([]models.CartItem{{ID: 4, CartID: 4, SmartphoneID: 1, Quantity: 1}}, nil)
+	ms.On("GetUser", 2).Return(models.User{ID: 2, Name: "user1", Email: "user1@example.com"}, nil)
+	ms.On("GetUser", 4).Return(models.User{ID: 4, Name: "user3", Email: "user3@example.com"}, nil)
+	ms.On("GetUser", 5).Return(models.User{ID: 5, Name: "user4", Email: "user4@example.com"}, nil)
+	ms.On("ClaimCartReminder", 1).Return(true, nil)
+	// already reminded by another server
+	ms.On("ClaimCartReminder", 3).Return(false, nil)
+	ms.On("ClaimCartReminder", 4).Return(true, nil)
+	mm.On("Send", mock.MatchedBy(func(msg mailer.Message) bool { return msg.To == "user1@example.com" })).Return(nil)
+	mm.On("Send", mock.MatchedBy(func(msg mailer.Message) bool { return msg.To == "user4@example.com" })).
+		Return(errors.New("smtp is down"))
+	ml.On("Errorf", mock.Anything, mock.Anything)
+
+	app := NewApp(ml, nil, ms)
+	app.Mail = mm
+	app.cartReminderAfter = 24 * time.Hour
+	sent, err := app.SendCartReminders()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, sent)
+	ms.AssertNotCalled(t, "ClaimCartReminder", 2)
+	mm.AssertNumberOfCalls(t, "Send", 2)
+	msg := mm.Calls[0].Arguments.Get(0).(mailer.Message)
+	assert.Contains(t, msg.Body, "Apple iPhone 16, 2 шт. - 2000 руб.")
+	assert.Contains(t, msg.Body, "Итого: 2000 руб.")
+	assert.Contains(t, msg.Body, "/api/v1/users/2/cart-reminders/unsubscribe?token=")
+}
+
+func TestUnsubscribeCartReminders(t *testing.T) {
+	ms := new(mockstorage.MockStorage)
+	ml := new(mocklogger.MockLogger)
+	ms.On("UpdateUser", map[string]any{"cart_reminders": false}).
+		Return(models.User{ID: 2, CartReminders: false}, nil)
+	ml.On("Errorln", mock.Anything, mock.Anything, mock.Anything)
+
+	app := NewApp(ml, nil, ms)
+	link, err := url.Parse(app.unsubscribeURL(2))
+	assert.NoError(t, err)
+	token := link.Query().Get("token")
+	tests := []struct {
+		name   string
+		userID string
+		token  string
+		code   int
+	}{
+		{"Link from email", "2", token, http.StatusOK},
+		{"Token of another user", "3", token, http.StatusUnauthorized},
+		{"No token", "2", "", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/?token="+url.QueryEscape(tt.token), nil)
+			r.SetPathValue("user_id", tt.userID)
+			w := httptest.NewRecorder()
+			app.UnsubscribeCartReminders(w, r)
+			assert.Equal(t, tt.code, w.Code)
+		})
+	}
+	ms.AssertNumberOfCalls(t, "UpdateUser", 1)
+	assert.True(t, strings.HasPrefix(link.Path, "/api/v1/users/2/"))
+}
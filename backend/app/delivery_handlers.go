@@ -0,0 +1,248 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/sfu-teamproject/smartbuy/backend/apperrors"
+	"github.com/sfu-teamproject/smartbuy/backend/models"
+)
+
+// GetDeliveryMethods lists delivery methods
+// @Summary      List Delivery methods
+// @Description  Lists active delivery methods. Admins can get inactive methods too with all=true
+// @Tags         delivery
+// @Produce      json
+// @Param        all query bool false "Include inactive methods (admin only)"
+// @Success      200  {array}   models.DeliveryMethod
+// @Failure      401  {object}  apperrors.ErrorResponse "Unauthorized"
+// @Failure      403  {object}  apperrors.ErrorResponse "Forbidden"
+// @Router       /delivery-methods [get]
+func (app *App) GetDeliveryMethods(w http.ResponseWriter, r *http.Request) {
+	all := r.URL.Query().Get("all") == "true"
+	if all {
+		userID, role, err := app.GetClaims(r)
+		if err != nil {
+			app.ErrorJSON(w, r, fmt.Errorf("%w: error extracting claims: %w", apperrors.ErrUnauthorized, err))
+			return
+		}
+		if role != models.RoleAdmin {
+			app.ErrorJSON(w, r, fmt.Errorf("%w: user %d (role %s) does not have required role",
+				apperrors.ErrForbidden, userID, role))
+			return
+		}
+	}
+	methods, err := app.DB.GetDeliveryMethods(!all)
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("error getting delivery methods: %w", err))
+		return
+	}
+	app.Encode(w, r, methods)
+}
+
+// CreateDeliveryMethod adds a delivery method
+// @Summary      Create a Delivery method
+// @Description  Admin only. Cost is base_cost plus cost_per_kg for every started kilogram, carts from free_from are delivered for free, carts heavier than max_weight grams can not be delivered
+// @Tags         delivery
+// @Security     BearerAuth
+// @Accept       json
+// @Produce      json
+// @Param        method body models.DeliveryMethodRequest true "Delivery method"
+// @Success      201  {object}  models.DeliveryMethod
+// @Failure      400  {object}  apperrors.ErrorResponse "Bad Request"
+// @Failure      401  {object}  apperrors.ErrorResponse "Unauthorized"
+// @Failure      403  {object}  apperrors.ErrorResponse "Forbidden"
+// @Router       /delivery-methods [post]
+func (app *App) CreateDeliveryMethod(w http.ResponseWriter, r *http.Request) {
+	userID, role, err := app.GetClaims(r)
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("%w: error extracting claims: %w", apperrors.ErrUnauthorized, err))
+		return
+	}
+	if role != models.RoleAdmin {
+		app.ErrorJSON(w, r, fmt.Errorf("%w: user %d (role %s) does not have required role",
+			apperrors.ErrForbidden, userID, role))
+		return
+	}
+	method, err := decodeDeliveryMethod(r)
+	if err != nil {
+		app.ErrorJSON(w, r, err)
+		return
+	}
+	newMethod, err := app.DB.CreateDeliveryMethod(method)
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("error creating delivery method: %w", err))
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	app.Encode(w, r, newMethod)
+}
+
+// UpdateDeliveryMethod changes a delivery method
+// @Summary      Update a Delivery method
+// @Description  Admin only. Replaces all fields of the delivery method
+// @Tags         delivery
+// @Security     BearerAuth
+// @Accept       json
+// @Produce      json
+// @Param        method_id path int true "Delivery method ID"
+// @Param        method body models.DeliveryMethodRequest true "Delivery method"
+// @Success      200  {object}  models.DeliveryMethod
+// @Failure      400  {object}  apperrors.ErrorResponse "Bad Request"
+// @Failure      401  {object}  apperrors.ErrorResponse "Unauthorized"
+// @Failure      403  {object}  apperrors.ErrorResponse "Forbidden"
+// @Failure      404  {object}  apperrors.ErrorResponse "Not Found"
+// @Router       /delivery-methods/{method_id} [put]
+func (app *App) UpdateDeliveryMethod(w http.ResponseWriter, r *http.Request) {
+	ID, err := app.ExtractPathValue(r, "method_id")
+	if err != nil {
+		app.ErrorJSON(w, r, err)
+		return
+	}
+	userID, role, err := app.GetClaims(r)
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("%w: error extracting claims: %w", apperrors.ErrUnauthorized, err))
+		return
+	}
+	if role != models.RoleAdmin {
+		app.ErrorJSON(w, r, fmt.Errorf("%w: user %d (role %s) does not have required role",
+			apperrors.ErrForbidden, userID, role))
+		return
+	}
+	method, err := decodeDeliveryMethod(r)
+	if err != nil {
+		app.ErrorJSON(w, r, err)
+		return
+	}
+	method.ID = ID
+	updated, err := app.DB.UpdateDeliveryMethod(method)
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("error updating delivery method %d: %w", ID, err))
+		return
+	}
+	app.Encode(w, r, updated)
+}
+
+// DeleteDeliveryMethod deletes a delivery method
+// @Summary      Delete a Delivery method
+// @Description  Admin only
+// @Tags         delivery
+// @Security     BearerAuth
+// @Produce      json
+// @Param        method_id path int true "Delivery method ID"
+// @Success      200  {object}  models.DeliveryMethod
+// @Failure      401  {object}  apperrors.ErrorResponse "Unauthorized"
+// @Failure      403  {object}  apperrors.ErrorResponse "Forbidden"
+// @Failure      404  {object}  apperrors.ErrorResponse "Not Found"
+// @Router       /delivery-methods/{method_id} [delete]
+func (app *App) DeleteDeliveryMethod(w http.ResponseWriter, r *http.Request) {
+	ID, err := app.ExtractPathValue(r, "method_id")
+	if err != nil {
+		app.ErrorJSON(w, r, err)
+		return
+	}
+	userID, role, err := app.GetClaims(r)
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("%w: error extracting claims: %w", apperrors.ErrUnauthorized, err))
+		return
+	}
+	if role != models.RoleAdmin {
+		app.ErrorJSON(w, r, fmt.Errorf("%w: user %d (role %s) does not have required role",
+			apperrors.ErrForbidden, userID, role))
+		return
+	}
+	deleted, err := app.DB.DeleteDeliveryMethod(ID)
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("error deleting delivery method %d: %w", ID, err))
+		return
+	}
+	app.Encode(w, r, deleted)
+}
+
+// GetShippingQuotes quotes delivery of a cart
+// @Summary      Quote shipping of a Cart
+// @Description  Returns the cost of delivering the cart with every active delivery method that can deliver it. The cart total after the promo code discount and the weight of the cart are used
+// @Tags         delivery
+// @Security     BearerAuth
+// @Produce      json
+// @Param        cart_id path int true "Cart ID"
+// @Success      200  {array}   models.ShippingQuote
+// @Failure      401  {object}  apperrors.ErrorResponse "Unauthorized"
+// @Failure      403  {object}  apperrors.ErrorResponse "Forbidden"
+// @Failure      404  {object}  apperrors.ErrorResponse "Not Found"
+// @Router       /carts/{cart_id}/shipping [get]
+func (app *App) GetShippingQuotes(w http.ResponseWriter, r *http.Request) {
+	cartID, err := app.ExtractPathValue(r, "cart_id")
+	if err != nil {
+		app.ErrorJSON(w, r, err)
+		return
+	}
+	cart, err := app.DB.GetCart(cartID)
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("error getting cart %d: %w", cartID, err))
+		return
+	}
+	err = app.checkCartAccess(r, cart)
+	if err != nil {
+		app.ErrorJSON(w, r, err)
+		return
+	}
+	cartItems, err := app.DB.GetCartItems(cart.ID)
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("error getting items for cart %d: %w", cart.ID, err))
+		return
+	}
+	cart.SetItems(cartItems)
+	err = app.attachCartDiscount(&cart)
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("error computing discount of cart %d: %w", cart.ID, err))
+		return
+	}
+	total := cart.Subtotal
+	if cart.Discount != nil {
+		total = cart.Discount.Total
+	}
+	methods, err := app.DB.GetDeliveryMethods(true)
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("error getting delivery methods: %w", err))
+		return
+	}
+	quotes := []models.ShippingQuote{}
+	for _, method := range methods {
+		cost, ok := method.Quote(total, cart.Weight)
+		if ok {
+			quotes = append(quotes, models.ShippingQuote{Method: method, Cost: cost})
+		}
+	}
+	app.Encode(w, r, quotes)
+}
+
+func decodeDeliveryMethod(r *http.Request) (models.DeliveryMethod, error) {
+	var req models.DeliveryMethodRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return models.DeliveryMethod{}, fmt.Errorf("%w: error decoding delivery method: %w", apperrors.ErrBadRequest, err)
+	}
+	method := models.DeliveryMethod{
+		Name:      strings.TrimSpace(req.Name),
+		Type:      req.Type,
+		BaseCost:  req.BaseCost,
+		CostPerKg: req.CostPerKg,
+		FreeFrom:  req.FreeFrom,
+		MaxWeight: req.MaxWeight,
+		Active:    req.Active,
+	}
+	if method.Name == "" {
+		return method, fmt.Errorf("%w: empty delivery method name", apperrors.ErrBadRequest)
+	}
+	if !method.Type.IsValid() {
+		return method, fmt.Errorf("%w: unknown delivery type %q", apperrors.ErrBadRequest, method.Type)
+	}
+	if method.BaseCost < 0 || method.CostPerKg < 0 || method.FreeFrom != nil && *method.FreeFrom < 0 ||
+		method.MaxWeight != nil && *method.MaxWeight <= 0 {
+		return method, fmt.Errorf("%w: costs and limits of delivery method must not be negative", apperrors.ErrBadRequest)
+	}
+	return method, nil
+}
@@ -0,0 +1,75 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sfu-teamproject/smartbuy/backend/logger/mocklogger"
+	"github.com/sfu-teamproject/smartbuy/backend/models"
+	"github.com/sfu-teamproject/smartbuy/backend/storage/mockstorage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestCreateDeliveryMethod(t *testing.T) {
+	ms := new(mockstorage.MockStorage)
+	ml := new(mocklogger.MockLogger)
+	ms.On("CreateDeliveryMethod", mock.Anything).Return(models.DeliveryMethod{ID: 1}, nil)
+	ml.On("Errorln", mock.Anything, mock.Anything, mock.Anything)
+
+	app := NewApp(ml, nil, ms)
+	tests := []struct {
+		name string
+		role models.Role
+		body string
+		code int
+	}{
+		{"Admin creates method", models.RoleAdmin, `{"name": "Courier", "type": "courier", "base_cost": 300}`, http.StatusCreated},
+		{"User creates method", models.RoleUser, `{"name": "Courier", "type": "courier", "base_cost": 300}`, http.StatusForbidden},
+		{"Unknown type", models.RoleAdmin, `{"name": "Drone", "type": "drone", "base_cost": 300}`, http.StatusBadRequest},
+		{"Negative cost", models.RoleAdmin, `{"name": "Post", "type": "post", "base_cost": -1}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := createContextWithClaims("1", tt.role)
+			r := httptest.NewRequestWithContext(ctx, http.MethodPost, "/", bytes.NewBufferString(tt.body))
+			w := httptest.NewRecorder()
+			app.CreateDeliveryMethod(w, r)
+			assert.Equal(t, tt.code, w.Code)
+		})
+	}
+	ms.AssertNumberOfCalls(t, "CreateDeliveryMethod", 1)
+}
+
+func TestGetShippingQuotes(t *testing.T) {
+	ms := new(mockstorage.MockStorage)
+	ml := new(mocklogger.MockLogger)
+	freeFrom, light := 5000, 500
+	ms.On("GetCart", 1).Return(models.Cart{ID: 1, UserID: 1}, nil)
+	ms.On("GetCartItems", 1).Return([]models.CartItem{
+		{ID: 1, CartID: 1, SmartphoneID: 1, Quantity: 3, LineTotal: 3000,
+			Smartphone: &models.SmartphoneSummary{ID: 1, Price: 1000, Weight: 200}},
+	}, nil)
+	ms.On("GetDeliveryMethods", true).Return([]models.DeliveryMethod{
+		{ID: 1, Name: "Courier", Type: models.DeliveryCourier, BaseCost: 300, CostPerKg: 50, FreeFrom: &freeFrom},
+		{ID: 2, Name: "Light post", Type: models.DeliveryPost, BaseCost: 100, MaxWeight: &light},
+	}, nil)
+	ml.On("Errorln", mock.Anything, mock.Anything, mock.Anything)
+
+	app := NewApp(ml, nil, ms)
+	ctx := createContextWithClaims("1", models.RoleUser)
+	r := httptest.NewRequestWithContext(ctx, http.MethodGet, "/", nil)
+	r.SetPathValue("cart_id", "1")
+	w := httptest.NewRecorder()
+	app.GetShippingQuotes(w, r)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var quotes []models.ShippingQuote
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&quotes))
+	// 600 g are over the limit of the light post
+	assert.Len(t, quotes, 1)
+	assert.Equal(t, 1, quotes[0].Method.ID)
+	assert.Equal(t, 300+50, quotes[0].Cost)
+}
@@ -0,0 +1,289 @@
+package app
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sfu-teamproject/smartbuy/backend/apperrors"
+	"github.com/sfu-teamproject/smartbuy/backend/models"
+)
+
+// exportFlushRows is the number of rows after which the export is sent to the
+// client instead of waiting for the buffer to fill
+const exportFlushRows = 100
+
+var exportHeaders = map[string][]string{
+	"users": {"id", "name", "email", "role", "created_at", "cart_reminders"},
+	"reviews": {"id", "smartphone_id", "smartphone_model", "user_id", "user_name", "rating", "comment",
+		"verified", "hidden", "created_at", "updated_at"},
+	// a row per cart item, carts without items have a single row with empty item columns
+	"carts": {"cart_id", "user_id", "created_at", "updated_at", "promo_code_id", "item_id", "smartphone_id",
+		"model", "producer", "state", "quantity", "price", "added_price", "line_total"},
+}
+
+// Export streams users, reviews or carts created in a date range
+// @Summary      Export data
+// @Description  Admin only. Streams users (without passwords), reviews (with smartphone models) or carts (with priced items) created in [from, to). Dates are RFC 3339 timestamps or YYYY-MM-DD days, a day in to is included. Rows are written as they are read, a broken export ends with an incomplete file.
+// @Tags         admin
+// @Security     BearerAuth
+// @Produce      json
+// @Produce      text/csv
+// @Param        entity query string true "users, reviews or carts"
+// @Param        format query string false "json (default) or csv"
+// @Param        from query string false "Start of the range, inclusive"
+// @Param        to query string false "End of the range, now by default"
+// @Success      200
+// @Failure      400  {object}  apperrors.ErrorResponse "Bad Request"
+// @Failure      401  {object}  apperrors.ErrorResponse "Unauthorized"
+// @Failure      403  {object}  apperrors.ErrorResponse "Forbidden"
+// @Router       /admin/export [get]
+func (app *App) Export(w http.ResponseWriter, r *http.Request) {
+	userID, role, err := app.GetClaims(r)
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("%w: error extracting claims: %w", apperrors.ErrUnauthorized, err))
+		return
+	}
+	if role != models.RoleAdmin {
+		app.ErrorJSON(w, r, fmt.Errorf("%w: user %d (role %s) does not have required role",
+			apperrors.ErrForbidden, userID, role))
+		return
+	}
+	query := r.URL.Query()
+	entity := query.Get("entity")
+	header, ok := exportHeaders[entity]
+	if !ok {
+		app.ErrorJSON(w, r, fmt.Errorf("%w: unknown entity %q", apperrors.ErrBadRequest, entity))
+		return
+	}
+	format := query.Get("format")
+	if format == "" {
+		format = "json"
+	}
+	if format != "json" && format != "csv" {
+		app.ErrorJSON(w, r, fmt.Errorf("%w: unknown format %q", apperrors.ErrBadRequest, format))
+		return
+	}
+	from, err := parseExportDate(query.Get("from"), time.Time{}, false)
+	if err != nil {
+		app.ErrorJSON(w, r, err)
+		return
+	}
+	to, err := parseExportDate(query.Get("to"), time.Now(), true)
+	if err != nil {
+		app.ErrorJSON(w, r, err)
+		return
+	}
+	if !from.Before(to) {
+		app.ErrorJSON(w, r, fmt.Errorf("%w: from %s is not before to %s", apperrors.ErrBadRequest,
+			from.Format(time.RFC3339), to.Format(time.RFC3339)))
+		return
+	}
+
+	out := newExportWriter(w, entity, format, header)
+	switch entity {
+	case "users":
+		err = app.DB.ExportUsers(from, to, func(user models.User) error {
+			return out.write(user, [][]string{userRecord(user)})
+		})
+	case "reviews":
+		err = app.DB.ExportReviews(from, to, func(review models.Review) error {
+			return out.write(review, [][]string{reviewRecord(review)})
+		})
+	case "carts":
+		err = app.DB.ExportCarts(from, to, func(cart models.Cart) error {
+			cart.SetItems(cart.Items)
+			return out.write(cart, cartRecords(cart))
+		})
+	}
+	if err == nil {
+		err = out.close()
+	}
+	if err == nil {
+		return
+	}
+	err = fmt.Errorf("error exporting %s: %w", entity, err)
+	if !out.started {
+		app.ErrorJSON(w, r, err)
+		return
+	}
+	// the status is already sent, the client gets an incomplete file
+	app.Log.Errorln(r.Method, r.URL, err.Error())
+}
+
+// parseExportDate reads a timestamp or a day. A day in the end of the range
+// is included, so it is moved to the start of the next day
+func parseExportDate(value string, def time.Time, end bool) (time.Time, error) {
+	if value == "" {
+		return def, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: invalid date %q, want YYYY-MM-DD or RFC 3339", apperrors.ErrBadRequest, value)
+	}
+	if end {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
+
+// exportWriter writes rows as a JSON array or as CSV records and flushes them
+// to the client every exportFlushRows rows
+type exportWriter struct {
+	w       io.Writer
+	rc      *http.ResponseController
+	columns []string
+	csv     *csv.Writer
+	rows    int
+	started bool
+}
+
+func newExportWriter(w http.ResponseWriter, entity, format string, columns []string) *exportWriter {
+	out := &exportWriter{w: w, rc: http.NewResponseController(w), columns: columns}
+	fileName := entity + "-" + time.Now().Format("20060102-150405") + "." + format
+	w.Header().Set("Content-Disposition", `attachment; filename="`+fileName+`"`)
+	if format == "csv" {
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		out.csv = csv.NewWriter(w)
+	} else {
+		w.Header().Set("Content-Type", "application/json")
+	}
+	return out
+}
+
+// start writes the beginning of the file, it is delayed until the first row
+// so errors of the query can still be reported with a status code
+func (e *exportWriter) start() error {
+	e.started = true
+	if e.csv != nil {
+		return e.csv.Write(e.columns)
+	}
+	_, err := io.WriteString(e.w, "[\n")
+	return err
+}
+
+func (e *exportWriter) write(v any, records [][]string) error {
+	if !e.started {
+		if err := e.start(); err != nil {
+			return err
+		}
+	}
+	if e.csv != nil {
+		if err := e.csv.WriteAll(records); err != nil {
+			return err
+		}
+	} else {
+		data, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		if e.rows > 0 {
+			data = append([]byte(",\n"), data...)
+		}
+		if _, err := e.w.Write(data); err != nil {
+			return err
+		}
+	}
+	e.rows++
+	if e.rows%exportFlushRows == 0 {
+		return e.flush()
+	}
+	return nil
+}
+
+func (e *exportWriter) close() error {
+	if !e.started {
+		if err := e.start(); err != nil {
+			return err
+		}
+	}
+	if e.csv == nil {
+		sep := "\n]\n"
+		if e.rows == 0 {
+			sep = "]\n"
+		}
+		if _, err := io.WriteString(e.w, sep); err != nil {
+			return err
+		}
+	}
+	return e.flush()
+}
+
+func (e *exportWriter) flush() error {
+	if e.csv != nil {
+		e.csv.Flush()
+		if err := e.csv.Error(); err != nil {
+			return err
+		}
+	}
+	err := e.rc.Flush()
+	if errors.Is(err, http.ErrNotSupported) {
+		return nil
+	}
+	return err
+}
+
+func userRecord(user models.User) []string {
+	return []string{strconv.Itoa(user.ID), csvText(user.Name), csvText(user.Email), string(user.Role),
+		user.CreatedAt.Format(time.RFC3339), strconv.FormatBool(user.CartReminders)}
+}
+
+func reviewRecord(review models.Review) []string {
+	comment := ""
+	if review.Comment != nil {
+		comment = *review.Comment
+	}
+	return []string{strconv.Itoa(review.ID), strconv.Itoa(review.SmartphoneID), csvText(review.SmartphoneModel),
+		strconv.Itoa(review.UserID), csvText(review.UserName), strconv.Itoa(review.Rating), csvText(comment),
+		strconv.FormatBool(review.Verified), strconv.FormatBool(review.Hidden),
+		review.CreatedAt.Format(time.RFC3339), review.UpdatedAt.Format(time.RFC3339)}
+}
+
+func cartRecords(cart models.Cart) [][]string {
+	userID := ""
+	if cart.UserID != 0 {
+		userID = strconv.Itoa(cart.UserID)
+	}
+	promoCodeID := ""
+	if cart.PromoCodeID != nil {
+		promoCodeID = strconv.Itoa(*cart.PromoCodeID)
+	}
+	prefix := []string{strconv.Itoa(cart.ID), userID, cart.CreatedAt.Format(time.RFC3339),
+		cart.UpdatedAt.Format(time.RFC3339), promoCodeID}
+	items := slices.Concat(cart.Items, cart.SavedItems)
+	if len(items) == 0 {
+		return [][]string{append(prefix, make([]string, 9)...)}
+	}
+	records := make([][]string, 0, len(items))
+	for _, item := range items {
+		model, producer, price := "", "", ""
+		if item.Smartphone != nil {
+			model, producer = csvText(item.Smartphone.Model), csvText(item.Smartphone.Producer)
+			price = strconv.Itoa(item.Smartphone.Price)
+		}
+		record := append([]string{}, prefix...)
+		record = append(record, strconv.Itoa(item.ID), strconv.Itoa(item.SmartphoneID), model, producer,
+			string(item.State), strconv.Itoa(item.Quantity), price, strconv.Itoa(item.AddedPrice),
+			strconv.Itoa(item.LineTotal))
+		records = append(records, record)
+	}
+	return records
+}
+
+// csvText keeps spreadsheets from treating user texts as formulas
+func csvText(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
@@ -0,0 +1,129 @@
+package app
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sfu-teamproject/smartbuy/backend/logger/mocklogger"
+	"github.com/sfu-teamproject/smartbuy/backend/models"
+	"github.com/sfu-teamproject/smartbuy/backend/storage/mockstorage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestExport(t *testing.T) {
+	ms := new(mockstorage.MockStorage)
+	ml := new(mocklogger.MockLogger)
+	created := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	from := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)
+	password := "hash"
+	ms.On("ExportUsers", from, to).Return([]models.User{
+		{ID: 1, Name: "user1", Email: "user1@mail.ru", Password: &password, Role: models.RoleUser, CreatedAt: created},
+		{ID: 2, Name: "=cmd()", Email: "user2@mail.ru", Role: models.RoleAdmin, CreatedAt: created},
+	}, nil)
+	comment := "Good, but \"loud\""
+	ms.On("ExportReviews", from, mock.Anything).Return([]models.Review{
+		{ID: 1, SmartphoneID: 1, SmartphoneModel: "iPhone 16", UserID: 1, UserName: "user1", Rating: 5,
+			Comment: &comment, CreatedAt: created, UpdatedAt: created},
+	}, nil)
+	ms.On("ExportCarts", from, to).Return([]models.Cart{
+		{ID: 1, UserID: 1, CreatedAt: created, UpdatedAt: created, Items: []models.CartItem{
+			{ID: 1, CartID: 1, SmartphoneID: 1, Quantity: 2, AddedPrice: 900, State: models.CartItemActive, LineTotal: 2000,
+				Smartphone: &models.SmartphoneSummary{ID: 1, Model: "Phone 1", Producer: "Apple", Price: 1000}},
+			{ID: 2, CartID: 1, SmartphoneID: 2, Quantity: 1, AddedPrice: 500, State: models.CartItemSaved, LineTotal: 500,
+				Smartphone: &models.SmartphoneSummary{ID: 2, Model: "Phone 2", Producer: "Xiaomi", Price: 500}},
+		}},
+		{ID: 2, CreatedAt: created, UpdatedAt: created, Items: []models.CartItem{}},
+	}, nil)
+	ms.On("ExportCarts", mock.Anything, mock.Anything).Return([]models.Cart{}, errors.New("connection lost"))
+	ml.On("Errorln", mock.Anything, mock.Anything, mock.Anything)
+
+	app := NewApp(ml, nil, ms)
+	export := func(role models.Role, query string) *httptest.ResponseRecorder {
+		ctx := createContextWithClaims("1", role)
+		r := httptest.NewRequestWithContext(ctx, http.MethodGet, "/api/v1/admin/export?"+query, nil)
+		w := httptest.NewRecorder()
+		app.Export(w, r)
+		return w
+	}
+
+	t.Run("Errors", func(t *testing.T) {
+		tests := []struct {
+			name  string
+			role  models.Role
+			query string
+			code  int
+		}{
+			{"Not admin", models.RoleUser, "entity=users", http.StatusForbidden},
+			{"Unknown entity", models.RoleAdmin, "entity=orders", http.StatusBadRequest},
+			{"Unknown format", models.RoleAdmin, "entity=users&format=xml", http.StatusBadRequest},
+			{"Invalid date", models.RoleAdmin, "entity=users&from=01.06.2025", http.StatusBadRequest},
+			{"Empty range", models.RoleAdmin, "entity=users&from=2025-06-02&to=2025-06-01", http.StatusBadRequest},
+			{"Storage error", models.RoleAdmin, "entity=carts&from=2024-01-01", http.StatusInternalServerError},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				w := export(tt.role, tt.query)
+				assert.Equal(t, tt.code, w.Code)
+				assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+			})
+		}
+	})
+	t.Run("Users JSON", func(t *testing.T) {
+		w := export(models.RoleAdmin, "entity=users&from=2025-06-01&to=2025-06-30")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Header().Get("Content-Disposition"), ".json")
+		assert.NotContains(t, w.Body.String(), "hash")
+		var users []models.User
+		assert.NoError(t, json.NewDecoder(w.Body).Decode(&users))
+		assert.Len(t, users, 2)
+		assert.Equal(t, "user1@mail.ru", users[0].Email)
+	})
+	t.Run("Users CSV", func(t *testing.T) {
+		w := export(models.RoleAdmin, "entity=users&format=csv&from=2025-06-01&to=2025-07-01T00:00:00Z")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "text/csv; charset=utf-8", w.Header().Get("Content-Type"))
+		records, err := csv.NewReader(w.Body).ReadAll()
+		assert.NoError(t, err)
+		assert.Equal(t, [][]string{
+			{"id", "name", "email", "role", "created_at", "cart_reminders"},
+			{"1", "user1", "user1@mail.ru", "user", "2025-06-01T12:00:00Z", "false"},
+			{"2", "'=cmd()", "user2@mail.ru", "admin", "2025-06-01T12:00:00Z", "false"},
+		}, records)
+	})
+	t.Run("Reviews CSV", func(t *testing.T) {
+		w := export(models.RoleAdmin, "entity=reviews&format=csv&from=2025-06-01")
+		assert.Equal(t, http.StatusOK, w.Code)
+		records, err := csv.NewReader(w.Body).ReadAll()
+		assert.NoError(t, err)
+		assert.Len(t, records, 2)
+		assert.Equal(t, "iPhone 16", records[1][2])
+		assert.Equal(t, comment, records[1][6])
+	})
+	t.Run("Carts CSV", func(t *testing.T) {
+		w := export(models.RoleAdmin, "entity=carts&format=csv&from=2025-06-01&to=2025-06-30")
+		assert.Equal(t, http.StatusOK, w.Code)
+		records, err := csv.NewReader(w.Body).ReadAll()
+		assert.NoError(t, err)
+		assert.Equal(t, [][]string{
+			{"1", "1", "2025-06-01T12:00:00Z", "2025-06-01T12:00:00Z", "", "1", "1", "Phone 1", "Apple", "active", "2", "1000", "900", "2000"},
+			{"1", "1", "2025-06-01T12:00:00Z", "2025-06-01T12:00:00Z", "", "2", "2", "Phone 2", "Xiaomi", "saved", "1", "500", "500", "500"},
+			{"2", "", "2025-06-01T12:00:00Z", "2025-06-01T12:00:00Z", "", "", "", "", "", "", "", "", "", ""},
+		}, records[1:])
+	})
+	t.Run("Carts JSON", func(t *testing.T) {
+		w := export(models.RoleAdmin, "entity=carts&from=2025-06-01&to=2025-06-30")
+		assert.Equal(t, http.StatusOK, w.Code)
+		var carts []models.Cart
+		assert.NoError(t, json.NewDecoder(w.Body).Decode(&carts))
+		assert.Len(t, carts, 2)
+		assert.Equal(t, 2000, carts[0].Subtotal)
+		assert.Len(t, carts[0].SavedItems, 1)
+	})
+}
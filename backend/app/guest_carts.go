@@ -0,0 +1,156 @@
+package app
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sfu-teamproject/smartbuy/backend/apperrors"
+	"github.com/sfu-teamproject/smartbuy/backend/models"
+)
+
+const (
+	guestCartCookie = "guest_cart"
+	guestCartTTL    = 30 * 24 * time.Hour
+	// how often stale guest carts are deleted
+	guestCartCleanupPeriod = time.Hour
+)
+
+// CreateGuestCart creates a cart for a user who is not logged in
+// @Summary      Create a guest Cart
+// @Description  Creates an anonymous cart and sets the guest_cart cookie that gives access to it. Items of the guest cart are moved to the user's cart on login.
+// @Tags         cart
+// @Produce      json
+// @Success      201  {object}  models.Cart
+// @Failure      429  {object}  apperrors.ErrorResponse "Too Many Requests"
+// @Router       /carts/guest [post]
+func (app *App) CreateGuestCart(w http.ResponseWriter, r *http.Request) {
+	cart, err := app.DB.CreateGuestCart()
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("error creating guest cart: %w", err))
+		return
+	}
+	expires := time.Now().Add(guestCartTTL)
+	http.SetCookie(w, &http.Cookie{
+		Name:     guestCartCookie,
+		Value:    app.guestCartToken(cart.ID, expires),
+		Path:     "/",
+		Expires:  expires,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	cart.SetItems([]models.CartItem{})
+	w.WriteHeader(http.StatusCreated)
+	app.Encode(w, r, cart)
+}
+
+// checkCartAccess allows the owner of the cart, admins and guests holding the
+// cookie of the guest cart
+func (app *App) checkCartAccess(r *http.Request, cart models.Cart) error {
+	userID, role, err := app.GetClaims(r)
+	if err == nil {
+		if cart.UserID != userID && role != models.RoleAdmin {
+			return apperrors.ErrForbidden
+		}
+		return nil
+	}
+	guestCartID, guestErr := app.guestCartID(r)
+	if guestErr != nil {
+		return fmt.Errorf("%w: error extracting claims: %w, guest cart: %w",
+			apperrors.ErrUnauthorized, err, guestErr)
+	}
+	if cart.UserID != 0 || cart.ID != guestCartID {
+		return apperrors.ErrForbidden
+	}
+	return nil
+}
+
+// mergeGuestCart moves items of the guest cart from the request cookie into
+// the cart of the user and removes the cookie. Logging in must not fail
+// because of the guest cart, so errors are only logged
+func (app *App) mergeGuestCart(w http.ResponseWriter, r *http.Request, cartID int) {
+	guestCartID, err := app.guestCartID(r)
+	if err != nil {
+		return
+	}
+	err = app.DB.MergeCarts(guestCartID, cartID, app.cartMaxQuantity)
+	if err != nil {
+		app.Log.Errorf("error merging guest cart %d into cart %d: %v", guestCartID, cartID, err)
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     guestCartCookie,
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// guestCartToken signs the cart ID and the expiration time, so guests can not
+// get into carts of other guests by changing the ID
+func (app *App) guestCartToken(cartID int, expires time.Time) string {
+	payload := strconv.Itoa(cartID) + "." + strconv.FormatInt(expires.Unix(), 10)
+	return payload + "." + app.guestCartSignature(payload)
+}
+
+func (app *App) guestCartSignature(payload string) string {
+	mac := hmac.New(sha256.New, app.jwtSecret)
+	mac.Write([]byte("guest_cart:" + payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// guestCartID returns the ID of the guest cart from a valid cookie
+func (app *App) guestCartID(r *http.Request) (int, error) {
+	cookie, err := r.Cookie(guestCartCookie)
+	if err != nil {
+		return 0, err
+	}
+	parts := strings.Split(cookie.Value, ".")
+	if len(parts) != 3 {
+		return 0, fmt.Errorf("malformed guest cart token")
+	}
+	payload := parts[0] + "." + parts[1]
+	if !hmac.Equal([]byte(parts[2]), []byte(app.guestCartSignature(payload))) {
+		return 0, fmt.Errorf("invalid guest cart token signature")
+	}
+	expires, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("malformed guest cart token expiration: %w", err)
+	}
+	if time.Now().Unix() > expires {
+		return 0, fmt.Errorf("guest cart token expired")
+	}
+	return strconv.Atoi(parts[0])
+}
+
+// StartGuestCartCleanup deletes guest carts that are idle for
+// guestCartIdleTTL or whose cookies have expired every hour until ctx is done.
+// Carts of guests who logged in are deleted when they are merged
+func (app *App) StartGuestCartCleanup(ctx context.Context) {
+	ticker := time.NewTicker(guestCartCleanupPeriod)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			app.deleteStaleGuestCarts(time.Now())
+		}
+	}
+}
+
+func (app *App) deleteStaleGuestCarts(now time.Time) {
+	deleted, err := app.DB.DeleteStaleGuestCarts(now.Add(-app.guestCartIdleTTL), now.Add(-guestCartTTL))
+	if err != nil {
+		app.Log.Errorf("error deleting stale guest carts: %v", err)
+	}
+	if deleted > 0 {
+		app.Log.Infof("Deleted %d stale guest carts", deleted)
+	}
+}
@@ -0,0 +1,110 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sfu-teamproject/smartbuy/backend/logger/mocklogger"
+	"github.com/sfu-teamproject/smartbuy/backend/models"
+	"github.com/sfu-teamproject/smartbuy/backend/storage/mockstorage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestGuestCart(t *testing.T) {
+	ms := new(mockstorage.MockStorage)
+	ml := new(mocklogger.MockLogger)
+	ms.On("CreateGuestCart").Return(models.Cart{ID: 5}, nil)
+	ms.On("GetCart", 5).Return(models.Cart{ID: 5}, nil)
+	ms.On("GetCart", 6).Return(models.Cart{ID: 6}, nil)
+	ms.On("GetCart", 1).Return(models.Cart{ID: 1, UserID: 1}, nil)
+	ms.On("GetCartItems", mock.Anything).Return([]models.CartItem{}, nil)
+	ml.On("Errorln", mock.Anything, mock.Anything, mock.Anything)
+
+	app := NewApp(ml, nil, ms)
+	r := httptest.NewRequest(http.MethodPost, "/", nil)
+	w := httptest.NewRecorder()
+	app.CreateGuestCart(w, r)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	cookies := w.Result().Cookies()
+	assert.Len(t, cookies, 1)
+	cookie := cookies[0]
+	assert.Equal(t, guestCartCookie, cookie.Name)
+	assert.True(t, cookie.HttpOnly)
+
+	forged := *cookie
+	forged.Value = "6" + cookie.Value[1:]
+	expired := http.Cookie{Name: guestCartCookie, Value: app.guestCartToken(5, time.Now().Add(-time.Minute))}
+	tests := []struct {
+		name   string
+		cartID string
+		cookie *http.Cookie
+		code   int
+	}{
+		{"Guest gets own cart", "5", cookie, http.StatusOK},
+		{"Guest gets other guest cart", "6", cookie, http.StatusForbidden},
+		{"Guest gets user cart", "1", cookie, http.StatusForbidden},
+		{"Forged cookie", "6", &forged, http.StatusUnauthorized},
+		{"Expired cookie", "5", &expired, http.StatusUnauthorized},
+		{"No cookie", "5", nil, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.cookie != nil {
+				r.AddCookie(tt.cookie)
+			}
+			r.SetPathValue("cart_id", tt.cartID)
+			w := httptest.NewRecorder()
+			app.GetCart(w, r)
+			assert.Equal(t, tt.code, w.Code)
+		})
+	}
+}
+
+func TestLoginMergesGuestCart(t *testing.T) {
+	ms := new(mockstorage.MockStorage)
+	ml := new(mocklogger.MockLogger)
+	hash, err := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+	assert.NoError(t, err)
+	password := string(hash)
+	ms.On("GetUserByEmail", "user1@example.com").
+		Return(models.User{ID: 2, Name: "user1", Email: "user1@example.com", Password: &password, Role: models.RoleUser}, nil)
+	ms.On("GetCartByUserID", 2).Return(models.Cart{ID: 2, UserID: 2}, nil)
+	ms.On("GetCartItems", 2).Return([]models.CartItem{}, nil)
+	ms.On("MergeCarts", 5, 2, 10).Return(nil)
+	ms.On("CreateRefreshToken", mock.Anything).Return(models.RefreshToken{}, nil)
+	ml.On("Errorln", mock.Anything, mock.Anything, mock.Anything)
+
+	app := NewApp(ml, nil, ms)
+	app.cartMaxQuantity = 10
+	body := `{"email": "user1@example.com", "password": "password"}`
+	r := httptest.NewRequestWithContext(context.Background(), http.MethodPost, "/", bytes.NewBufferString(body))
+	r.AddCookie(&http.Cookie{Name: guestCartCookie, Value: app.guestCartToken(5, time.Now().Add(time.Hour))})
+	w := httptest.NewRecorder()
+	app.Login(w, r)
+	assert.Equal(t, http.StatusOK, w.Code)
+	ms.AssertCalled(t, "MergeCarts", 5, 2, 10)
+	cookies := w.Result().Cookies()
+	assert.Len(t, cookies, 1)
+	assert.Equal(t, -1, cookies[0].MaxAge)
+}
+
+func TestDeleteStaleGuestCarts(t *testing.T) {
+	ms := new(mockstorage.MockStorage)
+	ml := new(mocklogger.MockLogger)
+	now := time.Now()
+	ms.On("DeleteStaleGuestCarts", now.Add(-7*24*time.Hour), now.Add(-guestCartTTL)).Return(2, nil)
+	ml.On("Infof", mock.Anything, mock.Anything)
+
+	app := NewApp(ml, nil, ms)
+	app.guestCartIdleTTL = 7 * 24 * time.Hour
+	app.deleteStaleGuestCarts(now)
+	ms.AssertExpectations(t)
+	ml.AssertCalled(t, "Infof", "Deleted %d stale guest carts", mock.Anything)
+}
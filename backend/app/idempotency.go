@@ -0,0 +1,202 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/sfu-teamproject/smartbuy/backend/apperrors"
+	"github.com/sfu-teamproject/smartbuy/backend/models"
+)
+
+const (
+	idempotencyKeyHeader = "Idempotency-Key"
+	// set on responses that are replayed from a saved key
+	idempotentReplayedHeader = "Idempotent-Replayed"
+	maxIdempotencyKeyLength  = 255
+	idempotencyCleanupPeriod = time.Hour
+)
+
+// Idempotency makes POST and PATCH requests with the Idempotency-Key header
+// safe to retry. The first request with a key is handled and its response is
+// saved, retries with the same key and the same request get the saved
+// response without handling the request again. Reusing a key for another
+// request is rejected. Responses with 5xx and 429 statuses are not saved, so
+// such requests can be retried. Requests of anonymous clients, requests with
+// invalid tokens and requests that issue tokens are handled without the key
+func (app *App) Idempotency(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(idempotencyKeyHeader)
+		if key == "" || (r.Method != http.MethodPost && r.Method != http.MethodPatch) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		scope, ok := app.idempotencyScope(r)
+		if !ok || tokenPaths[r.URL.Path] {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			app.ErrorJSON(w, r, fmt.Errorf("%w: %s is longer than %d characters",
+				apperrors.ErrBadRequest, idempotencyKeyHeader, maxIdempotencyKeyLength))
+			return
+		}
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			app.ErrorJSON(w, r, fmt.Errorf("%w: error reading request body: %w", apperrors.ErrBadRequest, err))
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		claim := models.IdempotencyKey{Scope: scope, Key: key, RequestHash: requestHash(r, body)}
+		saved, claimed, err := app.DB.ClaimIdempotencyKey(claim, time.Now().Add(-app.idempotencyKeyTTL))
+		if errors.Is(err, apperrors.ErrNotFound) {
+			// the first request failed and freed the key right after the claim
+			err = fmt.Errorf("%w: request with key %s is being handled", apperrors.ErrAlreadyExists, key)
+		}
+		if err != nil {
+			app.ErrorJSON(w, r, fmt.Errorf("error claiming idempotency key %s: %w", key, err))
+			return
+		}
+		if !claimed {
+			app.replayIdempotent(w, r, claim, saved)
+			return
+		}
+
+		rec := &responseRecorder{ResponseWriter: w}
+		stored := false
+		defer func() {
+			// also frees the key if the handler panics
+			if stored {
+				return
+			}
+			err := app.DB.DeleteIdempotencyKey(claim.Scope, claim.Key)
+			if err != nil {
+				app.Log.Errorf("error deleting idempotency key %s: %v", claim.Key, err)
+			}
+		}()
+		next.ServeHTTP(rec, r)
+		if rec.status == 0 {
+			rec.status = http.StatusOK
+		}
+		if rec.status >= http.StatusInternalServerError || rec.status == http.StatusTooManyRequests {
+			return
+		}
+		claim.StatusCode = rec.status
+		claim.Headers = rec.Header().Clone()
+		claim.Body = rec.body.Bytes()
+		err = app.DB.SaveIdempotentResponse(claim)
+		if err != nil {
+			app.Log.Errorf("error saving response for idempotency key %s: %v", claim.Key, err)
+			return
+		}
+		stored = true
+	})
+}
+
+func (app *App) replayIdempotent(w http.ResponseWriter, r *http.Request, claim, saved models.IdempotencyKey) {
+	if saved.RequestHash != claim.RequestHash {
+		app.ErrorJSON(w, r, fmt.Errorf("%w: %s %s was used for another request",
+			apperrors.ErrBadRequest, idempotencyKeyHeader, claim.Key))
+		return
+	}
+	if saved.StatusCode == 0 {
+		app.ErrorJSON(w, r, fmt.Errorf("%w: request with key %s is being handled",
+			apperrors.ErrAlreadyExists, claim.Key))
+		return
+	}
+	for name, values := range saved.Headers {
+		w.Header()[name] = values
+	}
+	w.Header().Set(idempotentReplayedHeader, "true")
+	w.Header().Set("Content-Length", strconv.Itoa(len(saved.Body)))
+	w.WriteHeader(saved.StatusCode)
+	w.Write(saved.Body)
+}
+
+// tokenPaths respond with access and refresh tokens, such responses must not
+// be saved and a retry must not get a token that may be already rotated
+var tokenPaths = map[string]bool{
+	"/api/v1/login":         true,
+	"/api/v1/token/refresh": true,
+}
+
+// idempotencyScope identifies the client by the user ID from its token or by
+// its guest cart, so keys of different clients never clash and a retry with a
+// refreshed token still finds the key. Anonymous clients can't be told apart
+// and invalid tokens are rejected by Auth, ok is false for them
+func (app *App) idempotencyScope(r *http.Request) (scope string, ok bool) {
+	if auth := r.Header.Get("Authorization"); auth != "" {
+		claims, err := app.parseToken(auth)
+		if err != nil || claims.Subject == "" {
+			return "", false
+		}
+		return "user:" + claims.Subject, true
+	}
+	guestCartID, err := app.guestCartID(r)
+	if err != nil {
+		return "", false
+	}
+	return "guest_cart:" + strconv.Itoa(guestCartID), true
+}
+
+func requestHash(r *http.Request, body []byte) string {
+	h := sha256.New()
+	io.WriteString(h, r.Method+" "+r.URL.RequestURI())
+	h.Write([]byte{0})
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// responseRecorder passes the response through and keeps a copy of it
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (rec *responseRecorder) WriteHeader(status int) {
+	if rec.status == 0 {
+		rec.status = status
+	}
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *responseRecorder) Write(b []byte) (int, error) {
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	rec.body.Write(b)
+	return rec.ResponseWriter.Write(b)
+}
+
+func (rec *responseRecorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
+
+// StartIdempotencyKeyCleanup deletes expired idempotency keys every hour
+// until ctx is done
+func (app *App) StartIdempotencyKeyCleanup(ctx context.Context) {
+	ticker := time.NewTicker(idempotencyCleanupPeriod)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			deleted, err := app.DB.DeleteExpiredIdempotencyKeys(time.Now().Add(-app.idempotencyKeyTTL))
+			if err != nil {
+				app.Log.Errorf("error deleting expired idempotency keys: %v", err)
+			}
+			if deleted > 0 {
+				app.Log.Infof("Deleted %d expired idempotency keys", deleted)
+			}
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sfu-teamproject/smartbuy/backend/logger/mocklogger"
+	"github.com/sfu-teamproject/smartbuy/backend/models"
+	"github.com/sfu-teamproject/smartbuy/backend/storage/mockstorage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestIdempotency(t *testing.T) {
+	ms := new(mockstorage.MockStorage)
+	ml := new(mocklogger.MockLogger)
+	ml.On("Errorln", mock.Anything, mock.Anything, mock.Anything)
+
+	app := NewApp(ml, nil, ms)
+	token, err := app.accessToken(models.User{ID: 2, Role: models.RoleUser})
+	assert.NoError(t, err)
+	newRequest := func(method, path, key, body string) *http.Request {
+		r := httptest.NewRequest(method, path, strings.NewReader(body))
+		if path != "/api/v1/carts/guest" {
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+		if key != "" {
+			r.Header.Set(idempotencyKeyHeader, key)
+		}
+		return r
+	}
+	body := `{"smartphone_id": 1}`
+	cartItems := "/api/v1/carts/1/items"
+	hash := requestHash(newRequest(http.MethodPost, cartItems, "", ""), []byte(body))
+	withKey := func(key string) any {
+		return mock.MatchedBy(func(k models.IdempotencyKey) bool { return k.Key == key })
+	}
+	ms.On("ClaimIdempotencyKey", withKey("new"), mock.Anything).Return(models.IdempotencyKey{}, true, nil)
+	ms.On("SaveIdempotentResponse", mock.MatchedBy(func(k models.IdempotencyKey) bool {
+		return k.Key == "new" && k.RequestHash == hash && k.StatusCode == http.StatusCreated &&
+			string(k.Body) == `{"id":1}` && k.Headers["Content-Type"][0] == "application/json"
+	})).Return(nil)
+	ms.On("ClaimIdempotencyKey", withKey("done"), mock.Anything).Return(models.IdempotencyKey{
+		Key: "done", RequestHash: hash, StatusCode: http.StatusCreated,
+		Headers: map[string][]string{"Content-Type": {"application/json"}}, Body: []byte(`{"id":1}`),
+	}, false, nil)
+	ms.On("ClaimIdempotencyKey", withKey("pending"), mock.Anything).Return(models.IdempotencyKey{
+		Key: "pending", RequestHash: hash,
+	}, false, nil)
+	ms.On("ClaimIdempotencyKey", withKey("failing"), mock.Anything).Return(models.IdempotencyKey{}, true, nil)
+	ms.On("DeleteIdempotencyKey", mock.Anything, "failing").Return(nil)
+	ms.On("ClaimIdempotencyKey", withKey("limited"), mock.Anything).Return(models.IdempotencyKey{}, true, nil)
+	ms.On("DeleteIdempotencyKey", mock.Anything, "limited").Return(nil)
+
+	calls := 0
+	handler := app.Idempotency(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		switch r.Header.Get(idempotencyKeyHeader) {
+		case "failing":
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		case "limited":
+			w.Header().Set("Retry-After", "30")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"id":1}`))
+	}))
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		key      string
+		body     string
+		code     int
+		handled  bool
+		replayed bool
+	}{
+		{"Without key", http.MethodPost, cartItems, "", body, http.StatusCreated, true, false},
+		{"Not a POST or PATCH", http.MethodPut, cartItems, "new", body, http.StatusCreated, true, false},
+		{"New key", http.MethodPost, cartItems, "new", body, http.StatusCreated, true, false},
+		{"Retry", http.MethodPost, cartItems, "done", body, http.StatusCreated, false, true},
+		{"Another request", http.MethodPost, cartItems, "done", `{"smartphone_id": 2}`, http.StatusBadRequest, false, false},
+		{"Being handled", http.MethodPost, cartItems, "pending", body, http.StatusConflict, false, false},
+		{"Server error", http.MethodPost, cartItems, "failing", body, http.StatusInternalServerError, true, false},
+		{"Too many requests", http.MethodPost, cartItems, "limited", body, http.StatusTooManyRequests, true, false},
+		{"Long key", http.MethodPost, cartItems, strings.Repeat("k", 256), body, http.StatusBadRequest, false, false},
+		{"Token request", http.MethodPost, "/api/v1/token/refresh", "done", body, http.StatusCreated, true, false},
+		{"Anonymous client", http.MethodPost, "/api/v1/carts/guest", "done", body, http.StatusCreated, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls = 0
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, newRequest(tt.method, tt.path, tt.key, tt.body))
+			assert.Equal(t, tt.code, w.Code)
+			assert.Equal(t, tt.handled, calls == 1)
+			if tt.replayed {
+				assert.Equal(t, "true", w.Header().Get(idempotentReplayedHeader))
+				assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+				assert.Equal(t, `{"id":1}`, w.Body.String())
+			}
+		})
+	}
+	ms.AssertExpectations(t)
+	ms.AssertNotCalled(t, "SaveIdempotentResponse", mock.MatchedBy(func(k models.IdempotencyKey) bool {
+		return k.Key == "failing" || k.Key == "limited"
+	}))
+}
+
+func TestIdempotencyScope(t *testing.T) {
+	ml := new(mocklogger.MockLogger)
+	app := NewApp(ml, nil, new(mockstorage.MockStorage))
+	request := func(token, guestCart string) *http.Request {
+		r := httptest.NewRequest(http.MethodPost, "/", nil)
+		if token != "" {
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+		if guestCart != "" {
+			r.AddCookie(&http.Cookie{Name: guestCartCookie, Value: guestCart})
+		}
+		return r
+	}
+	user1, err := app.accessToken(models.User{ID: 2, Role: models.RoleUser})
+	assert.NoError(t, err)
+	// a token issued later, e.g. by a refresh
+	time.Sleep(time.Millisecond)
+	user1Refreshed, err := app.accessToken(models.User{ID: 2, Role: models.RoleUser})
+	assert.NoError(t, err)
+	assert.NotEqual(t, user1, user1Refreshed)
+	user2, err := app.accessToken(models.User{ID: 3, Role: models.RoleUser})
+	assert.NoError(t, err)
+	guestCart := app.guestCartToken(2, time.Now().Add(time.Hour))
+	scope := func(r *http.Request) string {
+		s, ok := app.idempotencyScope(r)
+		assert.True(t, ok)
+		return s
+	}
+	assert.Equal(t, scope(request(user1, "")), scope(request(user1Refreshed, "")))
+	assert.NotEqual(t, scope(request(user1, "")), scope(request(user2, "")))
+	assert.NotEqual(t, scope(request(user1, "")), scope(request("", guestCart)))
+	// another cookie of the same guest cart
+	assert.Equal(t, scope(request("", guestCart)), scope(request("", app.guestCartToken(2, time.Now().Add(time.Minute)))))
+	for _, r := range []*http.Request{request("", ""), request("token", ""), request("", "cart")} {
+		_, ok := app.idempotencyScope(r)
+		assert.False(t, ok)
+	}
+}
@@ -0,0 +1,65 @@
+package app
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/sfu-teamproject/smartbuy/backend/apperrors"
+	"github.com/sfu-teamproject/smartbuy/backend/invoice"
+	"github.com/sfu-teamproject/smartbuy/backend/mailer"
+	"github.com/sfu-teamproject/smartbuy/backend/models"
+)
+
+// GetOrderInvoice downloads the invoice of an order
+// @Summary      Download Order Invoice
+// @Description  Returns the PDF invoice of the order with the store details, items with prices saved at checkout, VAT by rates and totals. Users can download invoices of their own orders; Admins can download any.
+// @Tags         orders
+// @Security     BearerAuth
+// @Produce      application/pdf
+// @Param        order_id path int true "Order ID"
+// @Success      200  {file}    file
+// @Failure      401  {object}  apperrors.ErrorResponse "Unauthorized"
+// @Failure      403  {object}  apperrors.ErrorResponse "Forbidden"
+// @Failure      404  {object}  apperrors.ErrorResponse "Not Found"
+// @Router       /orders/{order_id}/invoice [get]
+func (app *App) GetOrderInvoice(w http.ResponseWriter, r *http.Request) {
+	userID, role, err := app.GetClaims(r)
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("%w: error extracting claims: %w", apperrors.ErrUnauthorized, err))
+		return
+	}
+	orderID, err := app.ExtractPathValue(r, "order_id")
+	if err != nil {
+		app.ErrorJSON(w, r, err)
+		return
+	}
+	order, err := app.DB.GetOrder(orderID)
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("error getting order %d: %w", orderID, err))
+		return
+	}
+	if order.UserID != userID && role != models.RoleAdmin {
+		app.ErrorJSON(w, r, apperrors.ErrForbidden)
+		return
+	}
+	customer, err := app.DB.GetUser(order.UserID)
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("error getting customer of order %d: %w", order.ID, err))
+		return
+	}
+	attachment := app.orderInvoice(order, customer)
+	w.Header().Set("Content-Type", attachment.ContentType)
+	w.Header().Set("Content-Disposition", `attachment; filename="`+attachment.Name+`"`)
+	w.Header().Set("Content-Length", strconv.Itoa(len(attachment.Data)))
+	w.Write(attachment.Data)
+}
+
+// orderInvoice renders the invoice of the order as an email attachment
+func (app *App) orderInvoice(order models.Order, customer models.User) mailer.Attachment {
+	return mailer.Attachment{
+		Name:        invoice.FileName(order.ID),
+		ContentType: "application/pdf",
+		Data:        invoice.Render(app.store, order, customer),
+	}
+}
@@ -0,0 +1,56 @@
+package app
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sfu-teamproject/smartbuy/backend/apperrors"
+	"github.com/sfu-teamproject/smartbuy/backend/logger/mocklogger"
+	"github.com/sfu-teamproject/smartbuy/backend/models"
+	"github.com/sfu-teamproject/smartbuy/backend/storage/mockstorage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestGetOrderInvoice(t *testing.T) {
+	ms := new(mockstorage.MockStorage)
+	ml := new(mocklogger.MockLogger)
+	sm := 1
+	ms.On("GetOrder", 1).Return(models.Order{ID: 1, UserID: 2, Status: models.OrderPaid, Total: 1000,
+		Items: []models.OrderItem{{SmartphoneID: &sm, Producer: "Apple", Model: "iPhone", Price: 500, Quantity: 2}}}, nil)
+	ms.On("GetOrder", 2).Return(models.Order{}, apperrors.ErrNotFound)
+	ms.On("GetUser", 2).Return(models.User{ID: 2, Name: "user1", Email: "user1@example.com"}, nil)
+	ml.On("Errorln", mock.Anything, mock.Anything, mock.Anything)
+
+	app := NewApp(ml, nil, ms)
+	tests := []struct {
+		name    string
+		userID  string
+		role    models.Role
+		orderID string
+		code    int
+	}{
+		{"Owner downloads invoice", "2", models.RoleUser, "1", http.StatusOK},
+		{"Admin downloads invoice", "1", models.RoleAdmin, "1", http.StatusOK},
+		{"Another user downloads invoice", "3", models.RoleUser, "1", http.StatusForbidden},
+		{"Nonexistent order", "2", models.RoleUser, "2", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := createContextWithClaims(tt.userID, tt.role)
+			r := httptest.NewRequestWithContext(ctx, http.MethodGet, "/", nil)
+			r.SetPathValue("order_id", tt.orderID)
+			w := httptest.NewRecorder()
+			app.GetOrderInvoice(w, r)
+			assert.Equal(t, tt.code, w.Code)
+			if tt.code == http.StatusOK {
+				assert.Equal(t, "application/pdf", w.Header().Get("Content-Type"))
+				assert.Equal(t, `attachment; filename="invoice-SB-000001.pdf"`, w.Header().Get("Content-Disposition"))
+				assert.True(t, bytes.HasPrefix(w.Body.Bytes(), []byte("%PDF-")))
+				assert.Contains(t, w.Body.String(), "(Apple iPhone)")
+			}
+		})
+	}
+}
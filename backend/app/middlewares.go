@@ -46,48 +46,68 @@ func (app *App) RecoverPanic(next http.Handler) http.Handler {
 
 func (app *App) Auth(next http.HandlerFunc) http.HandlerFunc {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		tokenStr := r.Header.Get("Authorization")
-		tokenStrTrim := strings.TrimPrefix(tokenStr, "Bearer ")
-		if strings.TrimSpace(tokenStrTrim) == "" {
-			app.ErrorJSON(w, r, fmt.Errorf("%w: missing token(%s)", apperrors.ErrUnauthorized, tokenStr))
-			return
-		}
-		token, err := jwt.ParseWithClaims(tokenStrTrim, &Claims{}, func(t *jwt.Token) (any, error) {
-			if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fmt.Errorf("%w: unexpected signing method: %v",
-					apperrors.ErrUnauthorized, t.Header["alg"])
-			}
-			return app.jwtSecret, nil
-		})
+		claims, err := app.parseToken(r.Header.Get("Authorization"))
 		if err != nil {
-			app.ErrorJSON(w, r, fmt.Errorf("%w: error parsing token: %w", apperrors.ErrUnauthorized, err))
+			app.ErrorJSON(w, r, err)
 			return
 		}
-		claims, ok := token.Claims.(*Claims)
-		if !ok {
-			app.ErrorJSON(w, r, fmt.Errorf("%w: unsupported claims in token: %v",
-				apperrors.ErrUnauthorized, token.Claims))
-			return
-		}
-		if !token.Valid {
-			app.ErrorJSON(w, r, fmt.Errorf("%w: invalid token", apperrors.ErrUnauthorized))
-			return
-		}
-		iss, err := claims.GetIssuer()
-		if err != nil || iss != "Smartbuy" {
-			app.ErrorJSON(w, r, fmt.Errorf("%w: invalid issuer %s", apperrors.ErrUnauthorized, iss))
-			return
-		}
-		exp, err := claims.GetExpirationTime()
+		err = app.checkRevoked(claims)
 		if err != nil {
-			app.ErrorJSON(w, r, fmt.Errorf("%w: error getting expiration date: %w", apperrors.ErrUnauthorized, err))
-			return
-		}
-		if time.Now().After(exp.Time) {
-			app.ErrorJSON(w, r, fmt.Errorf("%w: token expired at %s", apperrors.ErrUnauthorized, exp.Time.String()))
+			app.ErrorJSON(w, r, err)
 			return
 		}
 		ctx := context.WithValue(r.Context(), ClaimsKey, claims)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
+
+// parseToken checks the signature, the issuer and the expiration of the token
+// from the Authorization header, revocation is checked by Auth
+func (app *App) parseToken(tokenStr string) (*Claims, error) {
+	tokenStrTrim := strings.TrimPrefix(tokenStr, "Bearer ")
+	if strings.TrimSpace(tokenStrTrim) == "" {
+		return nil, fmt.Errorf("%w: missing token(%s)", apperrors.ErrUnauthorized, tokenStr)
+	}
+	token, err := jwt.ParseWithClaims(tokenStrTrim, &Claims{}, func(t *jwt.Token) (any, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("%w: unexpected signing method: %v",
+				apperrors.ErrUnauthorized, t.Header["alg"])
+		}
+		return app.jwtSecret, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: error parsing token: %w", apperrors.ErrUnauthorized, err)
+	}
+	claims, ok := token.Claims.(*Claims)
+	if !ok {
+		return nil, fmt.Errorf("%w: unsupported claims in token: %v", apperrors.ErrUnauthorized, token.Claims)
+	}
+	if !token.Valid {
+		return nil, fmt.Errorf("%w: invalid token", apperrors.ErrUnauthorized)
+	}
+	iss, err := claims.GetIssuer()
+	if err != nil || iss != "Smartbuy" {
+		return nil, fmt.Errorf("%w: invalid issuer %s", apperrors.ErrUnauthorized, iss)
+	}
+	exp, err := claims.GetExpirationTime()
+	if err != nil {
+		return nil, fmt.Errorf("%w: error getting expiration date: %w", apperrors.ErrUnauthorized, err)
+	}
+	if time.Now().After(exp.Time) {
+		return nil, fmt.Errorf("%w: token expired at %s", apperrors.ErrUnauthorized, exp.Time.String())
+	}
+	return claims, nil
+}
+
+// OptionalAuth checks the token like Auth if the request has one, requests
+// without a token are passed on without claims
+func (app *App) OptionalAuth(next http.HandlerFunc) http.HandlerFunc {
+	auth := app.Auth(next)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		auth.ServeHTTP(w, r)
+	})
+}
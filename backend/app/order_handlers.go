@@ -0,0 +1,351 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/sfu-teamproject/smartbuy/backend/apperrors"
+	"github.com/sfu-teamproject/smartbuy/backend/discount"
+	"github.com/sfu-teamproject/smartbuy/backend/mailer"
+	"github.com/sfu-teamproject/smartbuy/backend/models"
+)
+
+// CreateOrder checks out the cart of the user
+// @Summary      Place an Order
+// @Description  Converts the cart of the user into an order. Model names and prices of smartphones are saved in the order, the cart is emptied except saved for later items. The promo code of the cart is checked again and its discount is subtracted from the total. VAT of the order is saved and the total is the gross amount. If prices changed since the items were added, accept_price_changes must be set.
+// @Tags         orders
+// @Security     BearerAuth
+// @Accept       json
+// @Produce      json
+// @Param        request body models.CreateOrderRequest false "Checkout options"
+// @Success      201  {object}  models.Order
+// @Failure      400  {object}  apperrors.ErrorResponse "Cart is empty, prices changed or promo code can not be applied"
+// @Failure      401  {object}  apperrors.ErrorResponse "Unauthorized"
+// @Router       /orders [post]
+func (app *App) CreateOrder(w http.ResponseWriter, r *http.Request) {
+	userID, _, err := app.GetClaims(r)
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("%w: error extracting claims: %w", apperrors.ErrUnauthorized, err))
+		return
+	}
+	var req models.CreateOrderRequest
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil && !errors.Is(err, io.EOF) {
+		app.ErrorJSON(w, r, fmt.Errorf("%w: error decoding order request: %w", apperrors.ErrBadRequest, err))
+		return
+	}
+	cart, err := app.DB.GetCartByUserID(userID)
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("error getting cart by user id(%d): %w", userID, err))
+		return
+	}
+	cartItems, err := app.DB.GetCartItems(cart.ID)
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("error getting items for cart %d: %w", cart.ID, err))
+		return
+	}
+	// saved for later items stay in the cart
+	cart.SetItems(cartItems)
+	cartItems = cart.Items
+	if len(cartItems) == 0 {
+		app.ErrorJSON(w, r, fmt.Errorf("%w: cart %d is empty", apperrors.ErrBadRequest, cart.ID))
+		return
+	}
+	IDs := make([]int, len(cartItems))
+	for i, ci := range cartItems {
+		IDs[i] = ci.SmartphoneID
+	}
+	smartphones, err := app.DB.GetSmartphonesByIDs(IDs)
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("error getting smartphones in cart %d: %w", cart.ID, err))
+		return
+	}
+	byID := make(map[int]models.Smartphone, len(smartphones))
+	for _, sm := range smartphones {
+		byID[sm.ID] = sm
+	}
+	order := models.Order{UserID: userID, Status: models.OrderCreated}
+	// items priced at checkout, the tax is taken from them
+	taxItems := make([]models.CartItem, 0, len(cartItems))
+	for _, ci := range cartItems {
+		sm, ok := byID[ci.SmartphoneID]
+		if !ok {
+			app.ErrorJSON(w, r, fmt.Errorf("%w: smartphone %d in cart %d does not exist",
+				apperrors.ErrBadRequest, ci.SmartphoneID, cart.ID))
+			return
+		}
+		if sm.Price != ci.AddedPrice && !req.AcceptPriceChanges {
+			app.ErrorJSON(w, r, fmt.Errorf("%w: price of smartphone %d in cart %d changed from %d to %d",
+				apperrors.ErrBadRequest, sm.ID, cart.ID, ci.AddedPrice, sm.Price))
+			return
+		}
+		order.Items = append(order.Items, models.OrderItem{
+			SmartphoneID: &sm.ID,
+			Model:        sm.Model,
+			Price:        sm.Price,
+			Quantity:     ci.Quantity,
+			Producer:     sm.Producer,
+		})
+		order.Total += sm.Price * ci.Quantity
+		taxItems = append(taxItems, models.CartItem{
+			SmartphoneID: sm.ID,
+			Quantity:     ci.Quantity,
+			LineTotal:    sm.Price * ci.Quantity,
+			Smartphone:   &models.SmartphoneSummary{ID: sm.ID, Price: sm.Price, Category: sm.Category},
+		})
+	}
+	var promoDiscount *models.DiscountBreakdown
+	if cart.PromoCodeID != nil {
+		promo, err := app.DB.GetPromoCode(*cart.PromoCodeID)
+		if err != nil {
+			app.ErrorJSON(w, r, fmt.Errorf("error getting promo code %d: %w", *cart.PromoCodeID, err))
+			return
+		}
+		lines, usage, err := app.discountInput(promo, userID, cartItems)
+		if err != nil {
+			app.ErrorJSON(w, r, fmt.Errorf("error checking promo code %s: %w", promo.Code, err))
+			return
+		}
+		breakdown, err := discount.Apply(promo, lines, usage, time.Now())
+		if err != nil {
+			app.ErrorJSON(w, r, fmt.Errorf("%w: promo code %s can not be applied to cart %d: %w",
+				apperrors.ErrBadRequest, promo.Code, cart.ID, err))
+			return
+		}
+		order.PromoCodeID = &promo.ID
+		order.Discount = breakdown.Discount
+		promoDiscount = &breakdown
+	}
+	// the customer pays the gross amount, it is more than the discounted
+	// total if the tax is added on top of prices
+	order.Tax = app.applyTax(taxItems, promoDiscount)
+	order.Total = order.Tax.Gross
+	for i := range order.Items {
+		order.Items[i].Paid = taxItems[i].Tax.Gross
+	}
+	newOrder, err := app.DB.CreateOrder(order, cartItems)
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("error creating order from cart %d: %w", cart.ID, err))
+		return
+	}
+	if newOrder.Total == 0 {
+		// a promo code covers the whole order, there is nothing to pay
+		newOrder, err = app.changeOrderStatus(newOrder, models.OrderPaid, nil)
+		if err != nil {
+			app.ErrorJSON(w, r, err)
+			return
+		}
+	}
+	w.WriteHeader(http.StatusCreated)
+	app.Encode(w, r, newOrder)
+}
+
+// GetOrders lists orders
+// @Summary      List Orders
+// @Description  Users get their own orders. Admins get all orders or orders of the user from user_id query
+// @Tags         orders
+// @Security     BearerAuth
+// @Produce      json
+// @Param        user_id query int false "User ID"
+// @Success      200  {array}   models.Order
+// @Failure      400  {object}  apperrors.ErrorResponse "Bad Request"
+// @Failure      401  {object}  apperrors.ErrorResponse "Unauthorized"
+// @Failure      403  {object}  apperrors.ErrorResponse "Forbidden"
+// @Router       /orders [get]
+func (app *App) GetOrders(w http.ResponseWriter, r *http.Request) {
+	userID, role, err := app.GetClaims(r)
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("%w: error extracting claims: %w", apperrors.ErrUnauthorized, err))
+		return
+	}
+	ownerID := userID
+	userIDStr := r.URL.Query().Get("user_id")
+	if userIDStr != "" {
+		ownerID, err = strconv.Atoi(userIDStr)
+		if err != nil {
+			app.ErrorJSON(w, r, fmt.Errorf("%w: incorrect user id(%s): %w", apperrors.ErrBadRequest, userIDStr, err))
+			return
+		}
+	}
+	if ownerID != userID && role != models.RoleAdmin {
+		app.ErrorJSON(w, r, apperrors.ErrForbidden)
+		return
+	}
+	var orders []models.Order
+	if role == models.RoleAdmin && userIDStr == "" {
+		orders, err = app.DB.GetOrders()
+	} else {
+		orders, err = app.DB.GetUserOrders(ownerID)
+	}
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("error getting orders: %w", err))
+		return
+	}
+	app.Encode(w, r, orders)
+}
+
+// GetOrder gets a single order
+// @Summary      Get an Order
+// @Tags         orders
+// @Security     BearerAuth
+// @Produce      json
+// @Param        order_id path int true "Order ID"
+// @Success      200  {object}  models.Order
+// @Failure      401  {object}  apperrors.ErrorResponse "Unauthorized"
+// @Failure      403  {object}  apperrors.ErrorResponse "Forbidden"
+// @Failure      404  {object}  apperrors.ErrorResponse "Not Found"
+// @Router       /orders/{order_id} [get]
+func (app *App) GetOrder(w http.ResponseWriter, r *http.Request) {
+	userID, role, err := app.GetClaims(r)
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("%w: error extracting claims: %w", apperrors.ErrUnauthorized, err))
+		return
+	}
+	orderID, err := app.ExtractPathValue(r, "order_id")
+	if err != nil {
+		app.ErrorJSON(w, r, err)
+		return
+	}
+	order, err := app.DB.GetOrder(orderID)
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("error getting order %d: %w", orderID, err))
+		return
+	}
+	if order.UserID != userID && role != models.RoleAdmin {
+		app.ErrorJSON(w, r, apperrors.ErrForbidden)
+		return
+	}
+	app.Encode(w, r, order)
+}
+
+// SetOrderStatus moves an order to another status
+// @Summary      Change Order Status
+// @Description  Admin only. Allowed transitions: created -> paid or cancelled, paid -> shipped or refunded,
+// @Description  shipped -> delivered or refunded, delivered -> refunded. The customer is notified by email.
+// @Description  Moving a paid order to refunded returns the money through the payment provider. If the provider fails,
+// @Description  the order stays refunded and repeating the request retries the refund of the payment.
+// @Description  Orders with refunded returns can not be refunded, purchases of refunded orders can not be returned.
+// @Tags         orders
+// @Security     BearerAuth
+// @Accept       json
+// @Produce      json
+// @Param        order_id path int true "Order ID"
+// @Param        input body models.OrderStatusRequest true "New status"
+// @Success      200  {object}  models.Order
+// @Failure      400  {object}  apperrors.ErrorResponse "Invalid transition"
+// @Failure      401  {object}  apperrors.ErrorResponse "Unauthorized"
+// @Failure      403  {object}  apperrors.ErrorResponse "Forbidden"
+// @Failure      404  {object}  apperrors.ErrorResponse "Not Found"
+// @Router       /orders/{order_id}/status [patch]
+func (app *App) SetOrderStatus(w http.ResponseWriter, r *http.Request) {
+	userID, role, err := app.GetClaims(r)
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("%w: error extracting claims: %w", apperrors.ErrUnauthorized, err))
+		return
+	}
+	if role != models.RoleAdmin {
+		app.ErrorJSON(w, r, fmt.Errorf("%w: user %d (role %s) does not have required role",
+			apperrors.ErrForbidden, userID, role))
+		return
+	}
+	orderID, err := app.ExtractPathValue(r, "order_id")
+	if err != nil {
+		app.ErrorJSON(w, r, err)
+		return
+	}
+	var statusreq models.OrderStatusRequest
+	err = json.NewDecoder(r.Body).Decode(&statusreq)
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("%w: error decoding status: %w", apperrors.ErrBadRequest, err))
+		return
+	}
+	order, err := app.DB.GetOrder(orderID)
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("error getting order %d: %w", orderID, err))
+		return
+	}
+	if statusreq.Status == models.OrderRefunded && order.Status == models.OrderRefunded {
+		// repeating the request retries the refund of the payment if it failed
+		err = app.refundOrder(order)
+		if err != nil {
+			app.ErrorJSON(w, r, err)
+			return
+		}
+		app.Encode(w, r, order)
+		return
+	}
+	updatedOrder, err := app.changeOrderStatus(order, statusreq.Status, &userID)
+	if err != nil {
+		app.ErrorJSON(w, r, err)
+		return
+	}
+	if updatedOrder.Status == models.OrderRefunded {
+		// the order is moved first, so concurrent requests do not both reach
+		// the provider
+		err = app.refundOrder(updatedOrder)
+		if err != nil {
+			app.ErrorJSON(w, r, fmt.Errorf("order %d is refunded, but its payment is not, repeat the request: %w",
+				order.ID, err))
+			return
+		}
+	}
+	app.Encode(w, r, updatedOrder)
+}
+
+// changeOrderStatus validates the transition, saves it and notifies the
+// customer, actorID is nil when the status is changed by the system
+func (app *App) changeOrderStatus(order models.Order, to models.OrderStatus, actorID *int) (models.Order, error) {
+	if !to.IsValid() {
+		return order, fmt.Errorf("%w: invalid order status(%s)", apperrors.ErrBadRequest, to)
+	}
+	if !order.Status.CanTransitionTo(to) {
+		return order, fmt.Errorf("%w: order %d can not be moved from %s to %s",
+			apperrors.ErrBadRequest, order.ID, order.Status, to)
+	}
+	updatedOrder, err := app.DB.SetOrderStatus(order.ID, order.Status, to, actorID)
+	if err != nil {
+		return order, fmt.Errorf("error setting status of order %d: %w", order.ID, err)
+	}
+	app.notifyOrderStatus(updatedOrder)
+	return updatedOrder, nil
+}
+
+var orderStatusNames = map[models.OrderStatus]string{
+	models.OrderCreated:   "создан",
+	models.OrderPaid:      "оплачен",
+	models.OrderShipped:   "отправлен",
+	models.OrderDelivered: "доставлен",
+	models.OrderCancelled: "отменен",
+	models.OrderRefunded:  "деньги возвращены",
+}
+
+// notifyOrderStatus emails the customer about the new status of the order,
+// failures are logged and do not fail the request
+func (app *App) notifyOrderStatus(order models.Order) {
+	customer, err := app.DB.GetUser(order.UserID)
+	if err != nil {
+		app.Log.Errorf("error getting customer of order %d: %v", order.ID, err)
+		return
+	}
+	body := "Здравствуйте, " + customer.Name + "!\n" +
+		fmt.Sprintf("Статус вашего заказа №%d: %s.", order.ID, orderStatusNames[order.Status])
+	msg := mailer.Message{
+		To:      customer.Email,
+		Subject: fmt.Sprintf("Smartbuy: заказ №%d %s", order.ID, orderStatusNames[order.Status]),
+		Body:    body,
+	}
+	// the payment confirmation comes with the invoice
+	if order.Status == models.OrderPaid {
+		msg.Body += "\nСчет к заказу во вложении."
+		msg.Attachments = []mailer.Attachment{app.orderInvoice(order, customer)}
+	}
+	err = app.Mail.Send(msg)
+	if err != nil {
+		app.Log.Errorf("error sending order notification to %s: %v", customer.Email, err)
+	}
+}
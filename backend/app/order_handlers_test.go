@@ -0,0 +1,255 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sfu-teamproject/smartbuy/backend/apperrors"
+	"github.com/sfu-teamproject/smartbuy/backend/logger/mocklogger"
+	"github.com/sfu-teamproject/smartbuy/backend/mailer"
+	"github.com/sfu-teamproject/smartbuy/backend/mailer/mockmailer"
+	"github.com/sfu-teamproject/smartbuy/backend/models"
+	"github.com/sfu-teamproject/smartbuy/backend/storage/mockstorage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestCreateOrder(t *testing.T) {
+	ms := new(mockstorage.MockStorage)
+	ml := new(mocklogger.MockLogger)
+	cartItems := []models.CartItem{
+		{ID: 1, CartID: 1, SmartphoneID: 1, Quantity: 2, AddedPrice: 500},
+		{ID: 2, CartID: 1, SmartphoneID: 3, Quantity: 1, AddedPrice: 900},
+	}
+	changedItems := []models.CartItem{{ID: 3, CartID: 3, SmartphoneID: 1, Quantity: 1, AddedPrice: 400}}
+	savedItems := []models.CartItem{{ID: 4, CartID: 4, SmartphoneID: 1, Quantity: 1, State: models.CartItemSaved}}
+	sm1, sm3 := 1, 3
+	order := models.Order{UserID: 1, Status: models.OrderCreated, Total: 2*500 + 900, Items: []models.OrderItem{
+		{SmartphoneID: &sm1, Model: "Phone 1", Price: 500, Quantity: 2, Paid: 1000},
+		{SmartphoneID: &sm3, Model: "Phone 3", Price: 900, Quantity: 1, Paid: 900},
+	}, Tax: &models.TaxBreakdown{PricesIncludeTax: true, Net: 1583, Tax: 317, Gross: 1900,
+		Rates: []models.RateTax{{Rate: 20, Net: 1583, Tax: 317}}}}
+	ms.On("GetCartByUserID", 1).Return(models.Cart{ID: 1, UserID: 1}, nil)
+	ms.On("GetCartByUserID", 2).Return(models.Cart{ID: 2, UserID: 2}, nil)
+	ms.On("GetCartItems", 1).Return(cartItems, nil)
+	ms.On("GetCartItems", 2).Return([]models.CartItem{}, nil)
+	ms.On("GetCartByUserID", 3).Return(models.Cart{ID: 3, UserID: 3}, nil)
+	ms.On("GetCartByUserID", 4).Return(models.Cart{ID: 4, UserID: 4}, nil)
+	ms.On("GetCartItems", 4).Return(savedItems, nil)
+	ms.On("GetCartItems", 3).Return(changedItems, nil)
+	ms.On("GetSmartphonesByIDs", []int{1}).Return([]models.Smartphone{{ID: 1, Model: "Phone 1", Price: 500}}, nil)
+	ms.On("GetSmartphonesByIDs", []int{1, 3}).Return([]models.Smartphone{
+		{ID: 1, Model: "Phone 1", Price: 500},
+		{ID: 3, Model: "Phone 3", Price: 900},
+	}, nil)
+	ms.On("CreateOrder", order, cartItems).Return(models.Order{ID: 1, UserID: 1, Total: order.Total}, nil)
+	ms.On("CreateOrder", mock.MatchedBy(func(o models.Order) bool { return o.UserID == 3 && o.Total == 500 }),
+		changedItems).Return(models.Order{ID: 2, UserID: 3, Total: 500}, nil)
+	ml.On("Errorln", mock.Anything, mock.Anything, mock.Anything)
+
+	app := NewApp(ml, nil, ms)
+	tests := []struct {
+		name   string
+		userID string
+		body   string
+		code   int
+	}{
+		{"Checkout cart", "1", "", http.StatusCreated},
+		{"Checkout empty cart", "2", "", http.StatusBadRequest},
+		{"Checkout cart with only saved items", "4", "", http.StatusBadRequest},
+		{"Checkout with changed prices", "3", "", http.StatusBadRequest},
+		{"Checkout accepting changed prices", "3", `{"accept_price_changes": true}`, http.StatusCreated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := createContextWithClaims(tt.userID, models.RoleUser)
+			r := httptest.NewRequestWithContext(ctx, http.MethodPost, "/", bytes.NewBufferString(tt.body))
+			w := httptest.NewRecorder()
+			app.CreateOrder(w, r)
+			assert.Equal(t, tt.code, w.Code)
+		})
+	}
+	ms.AssertNumberOfCalls(t, "CreateOrder", 2)
+}
+
+func TestCreateOrderWithPromoCode(t *testing.T) {
+	ms := new(mockstorage.MockStorage)
+	ml := new(mocklogger.MockLogger)
+	validID, expiredID, freeID := 1, 2, 3
+	past := time.Now().Add(-time.Hour)
+	cartItems := []models.CartItem{{ID: 1, CartID: 1, SmartphoneID: 1, Quantity: 2, AddedPrice: 500}}
+	sm1 := 1
+	order := models.Order{UserID: 1, Status: models.OrderCreated, Total: 900, PromoCodeID: &validID, Discount: 100,
+		Items: []models.OrderItem{{SmartphoneID: &sm1, Model: "Phone 1", Price: 500, Quantity: 2, Paid: 900}},
+		Tax: &models.TaxBreakdown{PricesIncludeTax: true, Net: 750, Tax: 150, Gross: 900,
+			Rates: []models.RateTax{{Rate: 20, Net: 750, Tax: 150}}}}
+	ms.On("GetCartByUserID", 1).Return(models.Cart{ID: 1, UserID: 1, PromoCodeID: &validID}, nil)
+	ms.On("GetCartByUserID", 2).Return(models.Cart{ID: 2, UserID: 2, PromoCodeID: &expiredID}, nil)
+	ms.On("GetCartItems", mock.Anything).Return(cartItems, nil)
+	ms.On("GetSmartphonesByIDs", []int{1}).Return([]models.Smartphone{{ID: 1, Model: "Phone 1", Price: 500}}, nil)
+	ms.On("GetPromoCode", 1).Return(models.PromoCode{ID: 1, Code: "FIX", Type: models.DiscountFixed, Value: 100}, nil)
+	ms.On("GetPromoCode", 2).Return(models.PromoCode{ID: 2, Code: "OLD", Type: models.DiscountFixed, Value: 100,
+		EndsAt: &past}, nil)
+	ms.On("GetCartByUserID", 3).Return(models.Cart{ID: 3, UserID: 3, PromoCodeID: &freeID}, nil)
+	ms.On("GetPromoCode", 3).Return(models.PromoCode{ID: 3, Code: "FREE", Type: models.DiscountPercent, Value: 100}, nil)
+	ms.On("GetPromoCodeUserUsage", mock.Anything, mock.Anything).Return(0, nil)
+	ms.On("CreateOrder", order, cartItems).Return(models.Order{ID: 1, UserID: 1, Total: order.Total}, nil)
+	free := models.Order{UserID: 3, Status: models.OrderCreated, Total: 0, PromoCodeID: &freeID, Discount: 1000,
+		Items: []models.OrderItem{{SmartphoneID: &sm1, Model: "Phone 1", Price: 500, Quantity: 2}},
+		Tax:   &models.TaxBreakdown{PricesIncludeTax: true, Rates: []models.RateTax{{Rate: 20}}}}
+	ms.On("CreateOrder", free, cartItems).
+		Return(models.Order{ID: 2, UserID: 3, Status: models.OrderCreated, Total: 0}, nil)
+	ms.On("SetOrderStatus", 2, models.OrderCreated, models.OrderPaid, (*int)(nil)).
+		Return(models.Order{ID: 2, UserID: 3, Status: models.OrderPaid, Total: 0}, nil)
+	ms.On("GetUser", 3).Return(models.User{ID: 3, Email: "user3@example.com"}, nil)
+	mm := new(mockmailer.MockMailer)
+	mm.On("Send", mock.Anything).Return(nil)
+	ml.On("Errorln", mock.Anything, mock.Anything, mock.Anything)
+
+	app := NewApp(ml, nil, ms)
+	app.Mail = mm
+	tests := []struct {
+		name   string
+		userID string
+		code   int
+	}{
+		{"Checkout with promo code", "1", http.StatusCreated},
+		{"Checkout with expired promo code", "2", http.StatusBadRequest},
+		{"Checkout with free order", "3", http.StatusCreated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := createContextWithClaims(tt.userID, models.RoleUser)
+			r := httptest.NewRequestWithContext(ctx, http.MethodPost, "/", nil)
+			w := httptest.NewRecorder()
+			app.CreateOrder(w, r)
+			assert.Equal(t, tt.code, w.Code)
+		})
+	}
+	ms.AssertNumberOfCalls(t, "CreateOrder", 2)
+	ms.AssertCalled(t, "SetOrderStatus", 2, models.OrderCreated, models.OrderPaid, (*int)(nil))
+	mm.AssertNumberOfCalls(t, "Send", 1)
+}
+
+func TestGetOrders(t *testing.T) {
+	ms := new(mockstorage.MockStorage)
+	ml := new(mocklogger.MockLogger)
+	ms.On("GetOrders").Return([]models.Order{{ID: 1, UserID: 1}, {ID: 2, UserID: 2}}, nil)
+	ms.On("GetUserOrders", 2).Return([]models.Order{{ID: 2, UserID: 2}}, nil)
+	ml.On("Errorln", mock.Anything, mock.Anything, mock.Anything)
+
+	app := NewApp(ml, nil, ms)
+	tests := []struct {
+		name   string
+		userID string
+		role   models.Role
+		query  string
+		code   int
+		count  int
+	}{
+		{"Admin gets all orders", "1", models.RoleAdmin, "", http.StatusOK, 2},
+		{"Admin gets orders of user", "1", models.RoleAdmin, "?user_id=2", http.StatusOK, 1},
+		{"User gets own orders", "2", models.RoleUser, "", http.StatusOK, 1},
+		{"User gets orders of another user", "3", models.RoleUser, "?user_id=2", http.StatusForbidden, 0},
+		{"Invalid user id", "1", models.RoleAdmin, "?user_id=abc", http.StatusBadRequest, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := createContextWithClaims(tt.userID, tt.role)
+			r := httptest.NewRequestWithContext(ctx, http.MethodGet, "/"+tt.query, nil)
+			w := httptest.NewRecorder()
+			app.GetOrders(w, r)
+			assert.Equal(t, tt.code, w.Code)
+			if tt.code != http.StatusOK {
+				return
+			}
+			var orders []models.Order
+			err := json.NewDecoder(w.Body).Decode(&orders)
+			assert.NoError(t, err, "Decoding orders failed")
+			assert.Len(t, orders, tt.count)
+		})
+	}
+}
+
+func TestGetOrder(t *testing.T) {
+	ms := new(mockstorage.MockStorage)
+	ml := new(mocklogger.MockLogger)
+	ms.On("GetOrder", 1).Return(models.Order{ID: 1, UserID: 2}, nil)
+	ms.On("GetOrder", 2).Return(models.Order{}, apperrors.ErrNotFound)
+	ml.On("Errorln", mock.Anything, mock.Anything, mock.Anything)
+
+	app := NewApp(ml, nil, ms)
+	tests := []struct {
+		name    string
+		userID  string
+		role    models.Role
+		orderID string
+		code    int
+	}{
+		{"Owner gets order", "2", models.RoleUser, "1", http.StatusOK},
+		{"Admin gets order", "1", models.RoleAdmin, "1", http.StatusOK},
+		{"Another user gets order", "3", models.RoleUser, "1", http.StatusForbidden},
+		{"Non-existing order", "2", models.RoleUser, "2", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := createContextWithClaims(tt.userID, tt.role)
+			r := httptest.NewRequestWithContext(ctx, http.MethodGet, "/", nil)
+			r.SetPathValue("order_id", tt.orderID)
+			w := httptest.NewRecorder()
+			app.GetOrder(w, r)
+			assert.Equal(t, tt.code, w.Code)
+		})
+	}
+}
+
+func TestSetOrderStatus(t *testing.T) {
+	ms := new(mockstorage.MockStorage)
+	ml := new(mocklogger.MockLogger)
+	mm := new(mockmailer.MockMailer)
+	adminID := 1
+	ms.On("GetOrder", 1).Return(models.Order{ID: 1, UserID: 2, Status: models.OrderCreated}, nil)
+	ms.On("GetOrder", 2).Return(models.Order{ID: 2, UserID: 2, Status: models.OrderCancelled}, nil)
+	ms.On("SetOrderStatus", 1, models.OrderCreated, models.OrderPaid, &adminID).
+		Return(models.Order{ID: 1, UserID: 2, Status: models.OrderPaid}, nil)
+	ms.On("GetUser", 2).Return(models.User{ID: 2, Name: "user1", Email: "user1@example.com"}, nil)
+	mm.On("Send", mock.MatchedBy(func(msg mailer.Message) bool {
+		return msg.To == "user1@example.com" && len(msg.Attachments) == 1 &&
+			msg.Attachments[0].ContentType == "application/pdf"
+	})).Return(nil).Once()
+	ml.On("Errorln", mock.Anything, mock.Anything, mock.Anything)
+
+	app := NewApp(ml, nil, ms)
+	app.Mail = mm
+	tests := []struct {
+		name    string
+		role    models.Role
+		orderID string
+		status  models.OrderStatus
+		code    int
+	}{
+		{"Admin marks order paid", models.RoleAdmin, "1", models.OrderPaid, http.StatusOK},
+		{"User marks order paid", models.RoleUser, "1", models.OrderPaid, http.StatusForbidden},
+		{"Skip a status", models.RoleAdmin, "1", models.OrderDelivered, http.StatusBadRequest},
+		{"Unknown status", models.RoleAdmin, "1", "lost", http.StatusBadRequest},
+		{"Reopen cancelled order", models.RoleAdmin, "2", models.OrderPaid, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jsonBytes, err := json.Marshal(models.OrderStatusRequest{Status: tt.status})
+			assert.NoError(t, err, "Marshalling status failed")
+			ctx := createContextWithClaims("1", tt.role)
+			r := httptest.NewRequestWithContext(ctx, http.MethodPatch, "/", bytes.NewBuffer(jsonBytes))
+			r.SetPathValue("order_id", tt.orderID)
+			w := httptest.NewRecorder()
+			app.SetOrderStatus(w, r)
+			assert.Equal(t, tt.code, w.Code)
+		})
+	}
+	ms.AssertNumberOfCalls(t, "SetOrderStatus", 1)
+	mm.AssertExpectations(t)
+}
@@ -0,0 +1,209 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/sfu-teamproject/smartbuy/backend/apperrors"
+	"github.com/sfu-teamproject/smartbuy/backend/models"
+	"github.com/sfu-teamproject/smartbuy/backend/payments"
+)
+
+const maxWebhookSize = 64 << 10
+
+// CreatePayment starts a payment of an order
+// @Summary      Pay for an Order
+// @Description  Creates a payment intent for the total of the order. The customer completes the payment with the provider, the result comes to the webhook.
+// @Tags         payments
+// @Security     BearerAuth
+// @Produce      json
+// @Param        order_id path int true "Order ID"
+// @Success      201  {object}  payments.Intent
+// @Failure      400  {object}  apperrors.ErrorResponse "Order is already paid"
+// @Failure      401  {object}  apperrors.ErrorResponse "Unauthorized"
+// @Failure      403  {object}  apperrors.ErrorResponse "Forbidden"
+// @Failure      404  {object}  apperrors.ErrorResponse "Not Found"
+// @Router       /orders/{order_id}/payments [post]
+func (app *App) CreatePayment(w http.ResponseWriter, r *http.Request) {
+	userID, role, err := app.GetClaims(r)
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("%w: error extracting claims: %w", apperrors.ErrUnauthorized, err))
+		return
+	}
+	orderID, err := app.ExtractPathValue(r, "order_id")
+	if err != nil {
+		app.ErrorJSON(w, r, err)
+		return
+	}
+	order, err := app.DB.GetOrder(orderID)
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("error getting order %d: %w", orderID, err))
+		return
+	}
+	if order.UserID != userID && role != models.RoleAdmin {
+		app.ErrorJSON(w, r, apperrors.ErrForbidden)
+		return
+	}
+	if order.Status != models.OrderCreated {
+		app.ErrorJSON(w, r, fmt.Errorf("%w: order %d can not be paid in status %s",
+			apperrors.ErrBadRequest, order.ID, order.Status))
+		return
+	}
+	if order.Total <= 0 {
+		app.ErrorJSON(w, r, fmt.Errorf("%w: order %d has nothing to pay", apperrors.ErrBadRequest, order.ID))
+		return
+	}
+	intent, err := app.Payments.CreateIntent(order.ID, order.Total)
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("error creating payment for order %d: %w", order.ID, err))
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	app.Encode(w, r, intent)
+}
+
+// PaymentWebhook receives payment events from the payment provider
+// @Summary      Payment Webhook
+// @Description  Called by the payment provider. The body is signed with HMAC-SHA256 in the X-Payment-Signature header.
+// @Description  Events are recorded once, redelivered events are acknowledged without changes. A successful payment is captured and marks the order as paid, a payment that does not match the order total is recorded as failed.
+// @Description  If the order can not be paid anymore, e.g. it was cancelled meanwhile, the captured payment is refunded.
+// @Tags         payments
+// @Accept       json
+// @Produce      json
+// @Param        X-Payment-Signature header string true "sha256=<hex hmac of the body>"
+// @Param        input body payments.Event true "Payment event"
+// @Success      200  {object}  models.Payment
+// @Failure      400  {object}  apperrors.ErrorResponse "Bad Request"
+// @Failure      401  {object}  apperrors.ErrorResponse "Invalid signature"
+// @Failure      404  {object}  apperrors.ErrorResponse "Not Found"
+// @Router       /payments/webhook [post]
+func (app *App) PaymentWebhook(w http.ResponseWriter, r *http.Request) {
+	payload, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookSize))
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("%w: error reading webhook: %w", apperrors.ErrBadRequest, err))
+		return
+	}
+	event, err := app.Payments.VerifyWebhook(payload, r.Header.Get("X-Payment-Signature"))
+	if errors.Is(err, payments.ErrInvalidSignature) {
+		app.ErrorJSON(w, r, fmt.Errorf("%w: %w", apperrors.ErrUnauthorized, err))
+		return
+	}
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("%w: %w", apperrors.ErrBadRequest, err))
+		return
+	}
+	payment := models.Payment{
+		OrderID:  event.OrderID,
+		IntentID: event.IntentID,
+		EventID:  event.ID,
+		Amount:   event.Amount,
+	}
+	switch event.Type {
+	case payments.EventSucceeded:
+		payment.Status = models.PaymentSucceeded
+	case payments.EventFailed:
+		payment.Status = models.PaymentFailed
+	case payments.EventRefunded:
+		payment.Status = models.PaymentRefunded
+	default:
+		app.ErrorJSON(w, r, fmt.Errorf("%w: unknown event type(%s)", apperrors.ErrBadRequest, event.Type))
+		return
+	}
+	order, err := app.DB.GetOrder(event.OrderID)
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("error getting order %d: %w", event.OrderID, err))
+		return
+	}
+	// the order is moved only if it is not there yet, so a redelivered event
+	// or an event for an order changed by an admin does not fail
+	switch {
+	case payment.Status == models.PaymentSucceeded && order.Status == models.OrderCreated:
+		if event.Amount != order.Total {
+			// the payment is neither captured nor counted as successful, so
+			// the order is not paid and the payment is never refunded
+			app.Log.Errorf("payment %s of order %d is %d, order total is %d",
+				event.ID, order.ID, event.Amount, order.Total)
+			payment.Status = models.PaymentFailed
+			break
+		}
+		err = app.capturePayment(event.IntentID)
+		if err != nil {
+			break
+		}
+		_, err = app.changeOrderStatus(order, models.OrderPaid, nil)
+		if errors.Is(err, apperrors.ErrBadRequest) {
+			// the order was changed after it was read, e.g. cancelled, so the
+			// captured money is given back. Other errors are retried by the
+			// provider, the payment is already captured then
+			_, refundErr := app.Payments.Refund(event.IntentID)
+			if refundErr != nil {
+				err = fmt.Errorf("%w, error refunding payment %s: %w", err, event.IntentID, refundErr)
+				break
+			}
+			app.Log.Errorf("payment %s of order %d is refunded: %v", event.ID, order.ID, err)
+			payment.Status = models.PaymentRefunded
+			err = nil
+		}
+	case payment.Status == models.PaymentRefunded && order.Status.CanTransitionTo(models.OrderRefunded):
+		_, err = app.changeOrderStatus(order, models.OrderRefunded, nil)
+	}
+	if err != nil {
+		app.ErrorJSON(w, r, err)
+		return
+	}
+	newPayment, err := app.DB.RecordPayment(payment)
+	if errors.Is(err, apperrors.ErrAlreadyExists) {
+		app.Log.Infof("payment event %s is already recorded", event.ID)
+		app.Encode(w, r, payment)
+		return
+	}
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("error recording payment event %s: %w", event.ID, err))
+		return
+	}
+	app.Encode(w, r, newPayment)
+}
+
+// capturePayment charges the money of a successful payment at the provider,
+// only captured payments can be refunded. Payments the provider has already
+// captured are skipped
+func (app *App) capturePayment(intentID string) error {
+	intent, err := app.Payments.Capture(intentID)
+	if errors.Is(err, payments.ErrInvalidState) && intent.Status == payments.IntentSucceeded {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error capturing payment %s: %w", intentID, err)
+	}
+	return nil
+}
+
+// refundOrder asks the payment provider to return the money of the last
+// successful payment of the order, orders without payments are skipped.
+// Payments the provider has already refunded are skipped too, so a refund
+// can be retried
+func (app *App) refundOrder(order models.Order) error {
+	orderPayments, err := app.DB.GetOrderPayments(order.ID)
+	if err != nil {
+		return fmt.Errorf("error getting payments of order %d: %w", order.ID, err)
+	}
+	for i := len(orderPayments) - 1; i >= 0; i-- {
+		switch orderPayments[i].Status {
+		case models.PaymentRefunded:
+			return nil
+		case models.PaymentSucceeded:
+			intent, err := app.Payments.Refund(orderPayments[i].IntentID)
+			if errors.Is(err, payments.ErrInvalidState) && intent.Status == payments.IntentRefunded {
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("error refunding payment %s of order %d: %w",
+					orderPayments[i].IntentID, order.ID, err)
+			}
+			return nil
+		}
+	}
+	return nil
+}
@@ -0,0 +1,199 @@
+package app
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sfu-teamproject/smartbuy/backend/apperrors"
+	"github.com/sfu-teamproject/smartbuy/backend/logger/mocklogger"
+	"github.com/sfu-teamproject/smartbuy/backend/mailer/mockmailer"
+	"github.com/sfu-teamproject/smartbuy/backend/models"
+	"github.com/sfu-teamproject/smartbuy/backend/payments"
+	"github.com/sfu-teamproject/smartbuy/backend/storage/mockstorage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestCreatePayment(t *testing.T) {
+	ms := new(mockstorage.MockStorage)
+	ml := new(mocklogger.MockLogger)
+	ms.On("GetOrder", 1).Return(models.Order{ID: 1, UserID: 2, Status: models.OrderCreated, Total: 500}, nil)
+	ms.On("GetOrder", 2).Return(models.Order{ID: 2, UserID: 2, Status: models.OrderPaid, Total: 500}, nil)
+	ms.On("GetOrder", 3).Return(models.Order{ID: 3, UserID: 2, Status: models.OrderCreated, Total: 0}, nil)
+	ml.On("Errorln", mock.Anything, mock.Anything, mock.Anything)
+
+	app := NewApp(ml, nil, ms)
+	tests := []struct {
+		name    string
+		userID  string
+		orderID string
+		code    int
+	}{
+		{"Owner pays order", "2", "1", http.StatusCreated},
+		{"Another user pays order", "3", "1", http.StatusForbidden},
+		{"Pay paid order", "2", "2", http.StatusBadRequest},
+		{"Pay free order", "2", "3", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := createContextWithClaims(tt.userID, models.RoleUser)
+			r := httptest.NewRequestWithContext(ctx, http.MethodPost, "/", nil)
+			r.SetPathValue("order_id", tt.orderID)
+			w := httptest.NewRecorder()
+			app.CreatePayment(w, r)
+			assert.Equal(t, tt.code, w.Code)
+		})
+	}
+}
+
+func TestPaymentWebhook(t *testing.T) {
+	ms := new(mockstorage.MockStorage)
+	ml := new(mocklogger.MockLogger)
+	mm := new(mockmailer.MockMailer)
+	provider := payments.NewFakeProvider("secret")
+	intent, err := provider.CreateIntent(1, 500)
+	assert.NoError(t, err)
+	payload, signature, err := provider.SignedEvent(intent.ID, payments.EventSucceeded)
+	assert.NoError(t, err)
+	wrongIntent, err := provider.CreateIntent(3, 100)
+	assert.NoError(t, err)
+	wrongPayload, wrongSignature, err := provider.SignedEvent(wrongIntent.ID, payments.EventSucceeded)
+	assert.NoError(t, err)
+	cancelledIntent, err := provider.CreateIntent(4, 500)
+	assert.NoError(t, err)
+	cancelledPayload, cancelledSignature, err := provider.SignedEvent(cancelledIntent.ID, payments.EventSucceeded)
+	assert.NoError(t, err)
+
+	created := models.Order{ID: 1, UserID: 2, Status: models.OrderCreated, Total: 500}
+	paid := models.Order{ID: 1, UserID: 2, Status: models.OrderPaid, Total: 500}
+	ms.On("GetOrder", 1).Return(created, nil).Once()
+	ms.On("GetOrder", 1).Return(paid, nil)
+	ms.On("GetOrder", 3).Return(models.Order{ID: 3, UserID: 2, Status: models.OrderCreated, Total: 500}, nil)
+	ms.On("SetOrderStatus", 1, models.OrderCreated, models.OrderPaid, (*int)(nil)).Return(paid, nil).Once()
+	// order 4 is cancelled after the webhook reads it
+	ms.On("GetOrder", 4).Return(models.Order{ID: 4, UserID: 2, Status: models.OrderCreated, Total: 500}, nil)
+	ms.On("SetOrderStatus", 4, models.OrderCreated, models.OrderPaid, (*int)(nil)).
+		Return(models.Order{}, fmt.Errorf("%w: order 4 is not in status created", apperrors.ErrBadRequest))
+	ms.On("RecordPayment", mock.MatchedBy(func(p models.Payment) bool {
+		return p.OrderID == 4 && p.Status == models.PaymentRefunded
+	})).Return(models.Payment{ID: 3, OrderID: 4, Status: models.PaymentRefunded}, nil)
+	ms.On("GetUser", 2).Return(models.User{ID: 2, Name: "user1", Email: "user1@example.com"}, nil)
+	ms.On("RecordPayment", mock.MatchedBy(func(p models.Payment) bool { return p.OrderID == 1 })).
+		Return(models.Payment{ID: 1, OrderID: 1, Status: models.PaymentSucceeded}, nil).Once()
+	ms.On("RecordPayment", mock.MatchedBy(func(p models.Payment) bool { return p.OrderID == 1 })).
+		Return(models.Payment{}, apperrors.ErrAlreadyExists)
+	ms.On("RecordPayment", mock.MatchedBy(func(p models.Payment) bool {
+		return p.OrderID == 3 && p.Status == models.PaymentFailed
+	})).Return(models.Payment{ID: 2, OrderID: 3, Status: models.PaymentFailed}, nil)
+	mm.On("Send", mock.Anything).Return(nil)
+	ml.On("Errorln", mock.Anything, mock.Anything, mock.Anything)
+	ml.On("Errorf", mock.Anything, mock.Anything)
+	ml.On("Infof", mock.Anything, mock.Anything)
+
+	app := NewApp(ml, nil, ms)
+	app.Mail = mm
+	app.Payments = provider
+	tests := []struct {
+		name      string
+		payload   []byte
+		signature string
+		code      int
+	}{
+		{"Payment succeeded", payload, signature, http.StatusOK},
+		{"Event redelivered", payload, signature, http.StatusOK},
+		{"Invalid signature", payload, "sha256=00", http.StatusUnauthorized},
+		{"Amount does not match order", wrongPayload, wrongSignature, http.StatusOK},
+		{"Order cancelled meanwhile", cancelledPayload, cancelledSignature, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/", bytes.NewBuffer(tt.payload))
+			r.Header.Set("X-Payment-Signature", tt.signature)
+			w := httptest.NewRecorder()
+			app.PaymentWebhook(w, r)
+			assert.Equal(t, tt.code, w.Code)
+		})
+	}
+	ms.AssertNumberOfCalls(t, "SetOrderStatus", 2)
+	ms.AssertNumberOfCalls(t, "RecordPayment", 4)
+	mm.AssertNumberOfCalls(t, "Send", 1)
+	_, err = provider.Capture(intent.ID)
+	assert.ErrorIs(t, err, payments.ErrInvalidState, "payment is not captured by the webhook")
+	_, err = provider.Capture(wrongIntent.ID)
+	assert.NoError(t, err, "payment with a wrong amount is captured")
+	_, err = provider.Refund(cancelledIntent.ID)
+	assert.ErrorIs(t, err, payments.ErrInvalidState, "payment of a cancelled order is not refunded")
+}
+
+func TestRefundOrder(t *testing.T) {
+	ms := new(mockstorage.MockStorage)
+	ml := new(mocklogger.MockLogger)
+	mm := new(mockmailer.MockMailer)
+	provider := payments.NewFakeProvider("secret")
+	intent, err := provider.CreateIntent(1, 500)
+	assert.NoError(t, err)
+	payload, signature, err := provider.SignedEvent(intent.ID, payments.EventSucceeded)
+	assert.NoError(t, err)
+	adminID := 1
+	created := models.Order{ID: 1, UserID: 2, Status: models.OrderCreated, Total: 500}
+	paid := models.Order{ID: 1, UserID: 2, Status: models.OrderPaid, Total: 500}
+	refunded := models.Order{ID: 1, UserID: 2, Status: models.OrderRefunded, Total: 500}
+	ms.On("GetOrder", 1).Return(created, nil).Once()
+	ms.On("GetOrder", 1).Return(paid, nil).Once()
+	ms.On("GetOrder", 1).Return(refunded, nil)
+	ms.On("SetOrderStatus", 1, models.OrderCreated, models.OrderPaid, (*int)(nil)).Return(paid, nil)
+	ms.On("RecordPayment", mock.Anything).
+		Return(models.Payment{ID: 1, OrderID: 1, IntentID: intent.ID, Status: models.PaymentSucceeded, Amount: 500}, nil)
+	ms.On("GetOrderPayments", 1).Return([]models.Payment{
+		{ID: 1, OrderID: 1, IntentID: intent.ID, Status: models.PaymentSucceeded, Amount: 500},
+	}, nil)
+	ms.On("SetOrderStatus", 1, models.OrderPaid, models.OrderRefunded, &adminID).Return(refunded, nil)
+	// the provider does not know the payment of order 2
+	ms.On("GetOrder", 2).Return(models.Order{ID: 2, UserID: 2, Status: models.OrderPaid, Total: 500}, nil)
+	ms.On("GetOrderPayments", 2).Return([]models.Payment{
+		{ID: 2, OrderID: 2, IntentID: "pi_unknown", Status: models.PaymentSucceeded, Amount: 500},
+	}, nil)
+	ms.On("SetOrderStatus", 2, models.OrderPaid, models.OrderRefunded, &adminID).
+		Return(models.Order{ID: 2, UserID: 2, Status: models.OrderRefunded, Total: 500}, nil)
+	ms.On("GetUser", 2).Return(models.User{ID: 2, Email: "user1@example.com"}, nil)
+	mm.On("Send", mock.Anything).Return(nil)
+	ml.On("Errorln", mock.Anything, mock.Anything, mock.Anything)
+
+	app := NewApp(ml, nil, ms)
+	app.Mail = mm
+	app.Payments = provider
+	r := httptest.NewRequest(http.MethodPost, "/", bytes.NewBuffer(payload))
+	r.Header.Set("X-Payment-Signature", signature)
+	w := httptest.NewRecorder()
+	app.PaymentWebhook(w, r)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	tests := []struct {
+		name    string
+		orderID string
+		code    int
+	}{
+		{"Refund order", "1", http.StatusOK},
+		{"Repeat refund", "1", http.StatusOK},
+		{"Provider fails", "2", http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := createContextWithClaims("1", models.RoleAdmin)
+			body := bytes.NewBufferString(`{"status": "refunded"}`)
+			r := httptest.NewRequestWithContext(ctx, http.MethodPatch, "/", body)
+			r.SetPathValue("order_id", tt.orderID)
+			w := httptest.NewRecorder()
+			app.SetOrderStatus(w, r)
+			assert.Equal(t, tt.code, w.Code)
+		})
+	}
+	_, err = provider.Refund(intent.ID)
+	assert.ErrorIs(t, err, payments.ErrInvalidState, "payment is not refunded by the provider")
+	// the order is moved before the provider is called
+	ms.AssertCalled(t, "SetOrderStatus", 2, models.OrderPaid, models.OrderRefunded, &adminID)
+	ms.AssertNumberOfCalls(t, "SetOrderStatus", 3)
+}
@@ -0,0 +1,338 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sfu-teamproject/smartbuy/backend/apperrors"
+	"github.com/sfu-teamproject/smartbuy/backend/discount"
+	"github.com/sfu-teamproject/smartbuy/backend/models"
+)
+
+// @Summary      List Promo codes
+// @Description  Gets all promo codes. Admin only
+// @Tags         promo
+// @Security     BearerAuth
+// @Produce      json
+// @Success      200  {array}   models.PromoCode
+// @Failure      401  {object}  apperrors.ErrorResponse "Unauthorized"
+// @Failure      403  {object}  apperrors.ErrorResponse "Forbidden"
+// @Router       /promo-codes [get]
+func (app *App) GetPromoCodes(w http.ResponseWriter, r *http.Request) {
+	userID, role, err := app.GetClaims(r)
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("%w: error extracting claims: %w", apperrors.ErrUnauthorized, err))
+		return
+	}
+	if role != models.RoleAdmin {
+		app.ErrorJSON(w, r, fmt.Errorf("%w: user %d (role %s) does not have required role",
+			apperrors.ErrForbidden, userID, role))
+		return
+	}
+	promos, err := app.DB.GetPromoCodes()
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("error getting promo codes: %w", err))
+		return
+	}
+	app.Encode(w, r, promos)
+}
+
+// @Summary      Create a Promo code
+// @Description  Creates a percent or fixed promo code. Codes are case-insensitive. Admin only
+// @Tags         promo
+// @Security     BearerAuth
+// @Accept       json
+// @Produce      json
+// @Param        promo body models.PromoCodeRequest true "Promo code"
+// @Success      201  {object}  models.PromoCode
+// @Failure      400  {object}  apperrors.ErrorResponse "Bad Request"
+// @Failure      401  {object}  apperrors.ErrorResponse "Unauthorized"
+// @Failure      403  {object}  apperrors.ErrorResponse "Forbidden"
+// @Failure      409  {object}  apperrors.ErrorResponse "Code already exists"
+// @Router       /promo-codes [post]
+func (app *App) CreatePromoCode(w http.ResponseWriter, r *http.Request) {
+	userID, role, err := app.GetClaims(r)
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("%w: error extracting claims: %w", apperrors.ErrUnauthorized, err))
+		return
+	}
+	if role != models.RoleAdmin {
+		app.ErrorJSON(w, r, fmt.Errorf("%w: user %d (role %s) does not have required role",
+			apperrors.ErrForbidden, userID, role))
+		return
+	}
+	var req models.PromoCodeRequest
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("%w: error decoding promo code: %w", apperrors.ErrBadRequest, err))
+		return
+	}
+	req.Code = strings.TrimSpace(req.Code)
+	if req.Code == "" {
+		app.ErrorJSON(w, r, fmt.Errorf("%w: empty promo code", apperrors.ErrBadRequest))
+		return
+	}
+	if req.Type != models.DiscountPercent && req.Type != models.DiscountFixed {
+		app.ErrorJSON(w, r, fmt.Errorf("%w: unknown discount type %q", apperrors.ErrBadRequest, req.Type))
+		return
+	}
+	if req.Value <= 0 || req.Type == models.DiscountPercent && req.Value > 100 {
+		app.ErrorJSON(w, r, fmt.Errorf("%w: incorrect discount value %d", apperrors.ErrBadRequest, req.Value))
+		return
+	}
+	if req.MinTotal < 0 {
+		app.ErrorJSON(w, r, fmt.Errorf("%w: negative minimum total %d", apperrors.ErrBadRequest, req.MinTotal))
+		return
+	}
+	if req.UsageLimit != nil && *req.UsageLimit <= 0 || req.PerUserLimit != nil && *req.PerUserLimit <= 0 {
+		app.ErrorJSON(w, r, fmt.Errorf("%w: usage limits must be positive", apperrors.ErrBadRequest))
+		return
+	}
+	producers := []string{}
+	for _, producer := range req.Producers {
+		producer = strings.TrimSpace(producer)
+		if producer == "" {
+			app.ErrorJSON(w, r, fmt.Errorf("%w: empty producer", apperrors.ErrBadRequest))
+			return
+		}
+		producers = append(producers, producer)
+	}
+	smartphoneIDs := []int{}
+	for _, ID := range req.SmartphoneIDs {
+		if ID <= 0 {
+			app.ErrorJSON(w, r, fmt.Errorf("%w: incorrect smartphone id %d", apperrors.ErrBadRequest, ID))
+			return
+		}
+		smartphoneIDs = append(smartphoneIDs, ID)
+	}
+	if req.StartsAt != nil && req.EndsAt != nil && !req.StartsAt.Before(*req.EndsAt) {
+		app.ErrorJSON(w, r, fmt.Errorf("%w: promo code ends before it starts", apperrors.ErrBadRequest))
+		return
+	}
+	promo, err := app.DB.CreatePromoCode(models.PromoCode{
+		Code:          req.Code,
+		Type:          req.Type,
+		Value:         req.Value,
+		MinTotal:      req.MinTotal,
+		StartsAt:      req.StartsAt,
+		EndsAt:        req.EndsAt,
+		UsageLimit:    req.UsageLimit,
+		PerUserLimit:  req.PerUserLimit,
+		Producers:     producers,
+		SmartphoneIDs: smartphoneIDs,
+	})
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("error creating promo code %s: %w", req.Code, err))
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	app.Encode(w, r, promo)
+}
+
+// @Summary      Delete a Promo code
+// @Description  Deletes a promo code and removes it from carts. Admin only
+// @Tags         promo
+// @Security     BearerAuth
+// @Produce      json
+// @Param        promo_id path int true "Promo code ID"
+// @Success      200  {object}  models.PromoCode
+// @Failure      401  {object}  apperrors.ErrorResponse "Unauthorized"
+// @Failure      403  {object}  apperrors.ErrorResponse "Forbidden"
+// @Failure      404  {object}  apperrors.ErrorResponse "Not Found"
+// @Router       /promo-codes/{promo_id} [delete]
+func (app *App) DeletePromoCode(w http.ResponseWriter, r *http.Request) {
+	ID, err := app.ExtractPathValue(r, "promo_id")
+	if err != nil {
+		app.ErrorJSON(w, r, err)
+		return
+	}
+	userID, role, err := app.GetClaims(r)
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("%w: error extracting claims: %w", apperrors.ErrUnauthorized, err))
+		return
+	}
+	if role != models.RoleAdmin {
+		app.ErrorJSON(w, r, fmt.Errorf("%w: user %d (role %s) does not have required role",
+			apperrors.ErrForbidden, userID, role))
+		return
+	}
+	promo, err := app.DB.DeletePromoCode(ID)
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("error deleting promo code %d: %w", ID, err))
+		return
+	}
+	app.Encode(w, r, promo)
+}
+
+// @Summary      Apply a Promo code to a Cart
+// @Description  Checks the promo code against the cart and saves it. The response contains the discount breakdown
+// @Tags         cart
+// @Security     BearerAuth
+// @Accept       json
+// @Produce      json
+// @Param        cart_id path int true "Cart ID"
+// @Param        code body models.ApplyPromoCodeRequest true "Promo code"
+// @Success      200  {object}  models.Cart
+// @Failure      400  {object}  apperrors.ErrorResponse "Promo code can not be applied"
+// @Failure      401  {object}  apperrors.ErrorResponse "Unauthorized"
+// @Failure      403  {object}  apperrors.ErrorResponse "Forbidden"
+// @Failure      404  {object}  apperrors.ErrorResponse "Not Found"
+// @Router       /carts/{cart_id}/promo [put]
+func (app *App) ApplyPromoCode(w http.ResponseWriter, r *http.Request) {
+	cartID, err := app.ExtractPathValue(r, "cart_id")
+	if err != nil {
+		app.ErrorJSON(w, r, err)
+		return
+	}
+	cart, err := app.DB.GetCart(cartID)
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("error getting cart %d: %w", cartID, err))
+		return
+	}
+	userID, role, err := app.GetClaims(r)
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("%w: error extracting claims: %w", apperrors.ErrUnauthorized, err))
+		return
+	}
+	if cart.UserID != userID && role != models.RoleAdmin {
+		app.ErrorJSON(w, r, apperrors.ErrForbidden)
+		return
+	}
+	var req models.ApplyPromoCodeRequest
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("%w: error decoding promo code: %w", apperrors.ErrBadRequest, err))
+		return
+	}
+	promo, err := app.DB.GetPromoCodeByCode(strings.TrimSpace(req.Code))
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("error getting promo code %s: %w", req.Code, err))
+		return
+	}
+	cartItems, err := app.DB.GetCartItems(cart.ID)
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("error getting items for cart %d: %w", cart.ID, err))
+		return
+	}
+	cart.SetItems(cartItems)
+	lines, usage, err := app.discountInput(promo, cart.UserID, cart.Items)
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("error checking promo code %s: %w", promo.Code, err))
+		return
+	}
+	breakdown, err := discount.Apply(promo, lines, usage, time.Now())
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("%w: promo code %s can not be applied to cart %d: %w",
+			apperrors.ErrBadRequest, promo.Code, cart.ID, err))
+		return
+	}
+	cart, err = app.DB.SetCartPromoCode(cart.ID, &promo.ID)
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("error saving promo code of cart %d: %w", cartID, err))
+		return
+	}
+	cart.SetItems(cartItems)
+	cart.Discount = &breakdown
+	app.attachCartTax(&cart)
+	app.Encode(w, r, cart)
+}
+
+// @Summary      Remove a Promo code from a Cart
+// @Tags         cart
+// @Security     BearerAuth
+// @Produce      json
+// @Param        cart_id path int true "Cart ID"
+// @Success      200  {object}  models.Cart
+// @Failure      401  {object}  apperrors.ErrorResponse "Unauthorized"
+// @Failure      403  {object}  apperrors.ErrorResponse "Forbidden"
+// @Failure      404  {object}  apperrors.ErrorResponse "Not Found"
+// @Router       /carts/{cart_id}/promo [delete]
+func (app *App) RemovePromoCode(w http.ResponseWriter, r *http.Request) {
+	cartID, err := app.ExtractPathValue(r, "cart_id")
+	if err != nil {
+		app.ErrorJSON(w, r, err)
+		return
+	}
+	cart, err := app.DB.GetCart(cartID)
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("error getting cart %d: %w", cartID, err))
+		return
+	}
+	userID, role, err := app.GetClaims(r)
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("%w: error extracting claims: %w", apperrors.ErrUnauthorized, err))
+		return
+	}
+	if cart.UserID != userID && role != models.RoleAdmin {
+		app.ErrorJSON(w, r, apperrors.ErrForbidden)
+		return
+	}
+	cart, err = app.DB.SetCartPromoCode(cart.ID, nil)
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("error removing promo code of cart %d: %w", cart.ID, err))
+		return
+	}
+	app.Encode(w, r, cart)
+}
+
+// discountInput loads what the discount engine needs to check promo against
+// the cart items of the user with current prices
+func (app *App) discountInput(promo models.PromoCode, userID int, cartItems []models.CartItem) ([]discount.Line, discount.Usage, error) {
+	IDs := make([]int, len(cartItems))
+	for i, ci := range cartItems {
+		IDs[i] = ci.SmartphoneID
+	}
+	smartphones, err := app.DB.GetSmartphonesByIDs(IDs)
+	if err != nil {
+		return nil, discount.Usage{}, fmt.Errorf("error getting smartphones: %w", err)
+	}
+	byID := make(map[int]models.Smartphone, len(smartphones))
+	for _, sm := range smartphones {
+		byID[sm.ID] = sm
+	}
+	lines := make([]discount.Line, 0, len(cartItems))
+	for _, ci := range cartItems {
+		sm, ok := byID[ci.SmartphoneID]
+		if !ok {
+			continue
+		}
+		lines = append(lines, discount.Line{
+			SmartphoneID: sm.ID,
+			Producer:     sm.Producer,
+			Price:        sm.Price,
+			Quantity:     ci.Quantity,
+		})
+	}
+	byUser, err := app.DB.GetPromoCodeUserUsage(promo.ID, userID)
+	if err != nil {
+		return nil, discount.Usage{}, fmt.Errorf("error getting usage of promo code %d: %w", promo.ID, err)
+	}
+	return lines, discount.Usage{Total: promo.UsedCount, ByUser: byUser}, nil
+}
+
+// attachCartDiscount recomputes the discount of the promo code saved in the
+// cart. If the code can not be applied anymore the reason is put into
+// PromoError instead
+func (app *App) attachCartDiscount(cart *models.Cart) error {
+	if cart.PromoCodeID == nil {
+		return nil
+	}
+	promo, err := app.DB.GetPromoCode(*cart.PromoCodeID)
+	if err != nil {
+		return fmt.Errorf("error getting promo code %d: %w", *cart.PromoCodeID, err)
+	}
+	lines, usage, err := app.discountInput(promo, cart.UserID, cart.Items)
+	if err != nil {
+		return err
+	}
+	breakdown, err := discount.Apply(promo, lines, usage, time.Now())
+	if err != nil {
+		cart.PromoError = err.Error()
+		return nil
+	}
+	cart.Discount = &breakdown
+	return nil
+}
@@ -0,0 +1,167 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sfu-teamproject/smartbuy/backend/apperrors"
+	"github.com/sfu-teamproject/smartbuy/backend/logger/mocklogger"
+	"github.com/sfu-teamproject/smartbuy/backend/models"
+	"github.com/sfu-teamproject/smartbuy/backend/storage/mockstorage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestCreatePromoCode(t *testing.T) {
+	ms := new(mockstorage.MockStorage)
+	ml := new(mocklogger.MockLogger)
+	ms.On("CreatePromoCode", mock.MatchedBy(func(p models.PromoCode) bool {
+		return p.Code == "SALE10" && p.Producers != nil && p.SmartphoneIDs != nil
+	})).
+		Return(models.PromoCode{ID: 1, Code: "SALE10", Type: models.DiscountPercent, Value: 10}, nil)
+	ms.On("CreatePromoCode", mock.MatchedBy(func(p models.PromoCode) bool { return p.Code == "TAKEN" })).
+		Return(models.PromoCode{}, apperrors.ErrAlreadyExists)
+	ml.On("Errorln", mock.Anything, mock.Anything, mock.Anything)
+
+	app := NewApp(ml, nil, ms)
+	tests := []struct {
+		name string
+		role models.Role
+		body string
+		code int
+	}{
+		{"Create percent code", models.RoleAdmin, `{"code": " SALE10 ", "type": "percent", "value": 10}`, http.StatusCreated},
+		{"User creates code", models.RoleUser, `{"code": "SALE10", "type": "percent", "value": 10}`, http.StatusForbidden},
+		{"Percent over 100", models.RoleAdmin, `{"code": "SALE10", "type": "percent", "value": 150}`, http.StatusBadRequest},
+		{"Zero value", models.RoleAdmin, `{"code": "SALE10", "type": "fixed", "value": 0}`, http.StatusBadRequest},
+		{"Unknown type", models.RoleAdmin, `{"code": "SALE10", "type": "free", "value": 10}`, http.StatusBadRequest},
+		{"Empty code", models.RoleAdmin, `{"code": " ", "type": "fixed", "value": 10}`, http.StatusBadRequest},
+		{"Ends before start", models.RoleAdmin,
+			`{"code": "SALE10", "type": "fixed", "value": 10, "starts_at": "2025-02-01T00:00:00Z", "ends_at": "2025-01-01T00:00:00Z"}`,
+			http.StatusBadRequest},
+		{"Duplicate code", models.RoleAdmin, `{"code": "TAKEN", "type": "fixed", "value": 10}`, http.StatusConflict},
+		{"Negative minimum total", models.RoleAdmin, `{"code": "SALE10", "type": "fixed", "value": 10, "min_total": -1}`,
+			http.StatusBadRequest},
+		{"Zero usage limit", models.RoleAdmin, `{"code": "SALE10", "type": "fixed", "value": 10, "usage_limit": 0}`,
+			http.StatusBadRequest},
+		{"Negative per user limit", models.RoleAdmin,
+			`{"code": "SALE10", "type": "fixed", "value": 10, "per_user_limit": -2}`, http.StatusBadRequest},
+		{"Empty producer", models.RoleAdmin, `{"code": "SALE10", "type": "fixed", "value": 10, "producers": [" "]}`,
+			http.StatusBadRequest},
+		{"Incorrect smartphone id", models.RoleAdmin,
+			`{"code": "SALE10", "type": "fixed", "value": 10, "smartphone_ids": [0]}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := createContextWithClaims("1", tt.role)
+			r := httptest.NewRequestWithContext(ctx, http.MethodPost, "/", bytes.NewBufferString(tt.body))
+			w := httptest.NewRecorder()
+			app.CreatePromoCode(w, r)
+			assert.Equal(t, tt.code, w.Code)
+		})
+	}
+}
+
+func TestApplyPromoCode(t *testing.T) {
+	ms := new(mockstorage.MockStorage)
+	ml := new(mocklogger.MockLogger)
+	past := time.Now().Add(-time.Hour)
+	cartItems := []models.CartItem{
+		{ID: 1, CartID: 1, SmartphoneID: 1, Quantity: 2},
+		{ID: 2, CartID: 1, SmartphoneID: 2, Quantity: 1},
+	}
+	promoID := 1
+	ms.On("GetCart", 1).Return(models.Cart{ID: 1, UserID: 2}, nil)
+	ms.On("GetCartItems", 1).Return(cartItems, nil)
+	ms.On("GetSmartphonesByIDs", []int{1, 2}).Return([]models.Smartphone{
+		{ID: 1, Producer: "Apple", Price: 1000},
+		{ID: 2, Producer: "Samsung", Price: 500},
+	}, nil)
+	ms.On("GetPromoCodeByCode", "apple10").Return(models.PromoCode{ID: 1, Code: "APPLE10",
+		Type: models.DiscountPercent, Value: 10, Producers: []string{"apple"}}, nil)
+	ms.On("GetPromoCodeByCode", "OLD").Return(models.PromoCode{ID: 2, Code: "OLD",
+		Type: models.DiscountFixed, Value: 100, EndsAt: &past}, nil)
+	ms.On("GetPromoCodeByCode", "NOPE").Return(models.PromoCode{}, apperrors.ErrNotFound)
+	ms.On("GetPromoCodeUserUsage", mock.Anything, 2).Return(0, nil)
+	ms.On("SetCartPromoCode", 1, &promoID).Return(models.Cart{ID: 1, UserID: 2, PromoCodeID: &promoID}, nil)
+	ml.On("Errorln", mock.Anything, mock.Anything, mock.Anything)
+
+	app := NewApp(ml, nil, ms)
+	tests := []struct {
+		name     string
+		userID   string
+		body     string
+		code     int
+		discount int
+	}{
+		{"Apply producer code", "2", `{"code": "apple10"}`, http.StatusOK, 200},
+		{"Apply expired code", "2", `{"code": "OLD"}`, http.StatusBadRequest, 0},
+		{"Apply unknown code", "2", `{"code": "NOPE"}`, http.StatusNotFound, 0},
+		{"Apply to another cart", "3", `{"code": "apple10"}`, http.StatusForbidden, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := createContextWithClaims(tt.userID, models.RoleUser)
+			r := httptest.NewRequestWithContext(ctx, http.MethodPut, "/", bytes.NewBufferString(tt.body))
+			r.SetPathValue("cart_id", "1")
+			w := httptest.NewRecorder()
+			app.ApplyPromoCode(w, r)
+			assert.Equal(t, tt.code, w.Code)
+			if tt.code == http.StatusOK {
+				var cart models.Cart
+				assert.NoError(t, json.NewDecoder(w.Body).Decode(&cart))
+				assert.Equal(t, tt.discount, cart.Discount.Discount)
+				assert.Equal(t, 2500-tt.discount, cart.Discount.Total)
+			}
+		})
+	}
+	ms.AssertNumberOfCalls(t, "SetCartPromoCode", 1)
+}
+
+func TestGetCartWithPromoCode(t *testing.T) {
+	ms := new(mockstorage.MockStorage)
+	ml := new(mocklogger.MockLogger)
+	limit := 1
+	validID, usedID := 1, 2
+	cartItems := []models.CartItem{{ID: 1, CartID: 1, SmartphoneID: 1, Quantity: 1}}
+	ms.On("GetCart", 1).Return(models.Cart{ID: 1, UserID: 2, PromoCodeID: &validID}, nil)
+	ms.On("GetCart", 2).Return(models.Cart{ID: 2, UserID: 2, PromoCodeID: &usedID}, nil)
+	ms.On("GetCartItems", mock.Anything).Return(cartItems, nil)
+	ms.On("GetSmartphonesByIDs", []int{1}).Return([]models.Smartphone{{ID: 1, Price: 1000}}, nil)
+	ms.On("GetPromoCode", 1).Return(models.PromoCode{ID: 1, Code: "FIX", Type: models.DiscountFixed, Value: 300}, nil)
+	ms.On("GetPromoCode", 2).Return(models.PromoCode{ID: 2, Code: "ONCE", Type: models.DiscountFixed, Value: 300,
+		UsageLimit: &limit, UsedCount: 1}, nil)
+	ms.On("GetPromoCodeUserUsage", mock.Anything, 2).Return(0, nil)
+	ml.On("Errorln", mock.Anything, mock.Anything, mock.Anything)
+
+	app := NewApp(ml, nil, ms)
+	tests := []struct {
+		name       string
+		cartID     string
+		discount   int
+		promoError bool
+	}{
+		{"Valid promo code", "1", 300, false},
+		{"Used up promo code", "2", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := createContextWithClaims("2", models.RoleUser)
+			r := httptest.NewRequestWithContext(ctx, http.MethodGet, "/", nil)
+			r.SetPathValue("cart_id", tt.cartID)
+			w := httptest.NewRecorder()
+			app.GetCart(w, r)
+			assert.Equal(t, http.StatusOK, w.Code)
+			var cart models.Cart
+			assert.NoError(t, json.NewDecoder(w.Body).Decode(&cart))
+			assert.Equal(t, tt.promoError, cart.PromoError != "")
+			if !tt.promoError {
+				assert.Equal(t, tt.discount, cart.Discount.Discount)
+			}
+		})
+	}
+}
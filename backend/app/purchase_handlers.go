@@ -0,0 +1,109 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/sfu-teamproject/smartbuy/backend/apperrors"
+	"github.com/sfu-teamproject/smartbuy/backend/models"
+)
+
+// GetPurchases lists purchases of a user
+// @Summary      Get User Purchases
+// @Description  Lists smartphones purchased by a user. Users can see their own purchases; Admins can see anyone's.
+// @Tags         purchases
+// @Security     BearerAuth
+// @Produce      json
+// @Param        user_id path int true "User ID"
+// @Success      200  {array}   models.Purchase
+// @Failure      401  {object}  apperrors.ErrorResponse "Unauthorized"
+// @Failure      403  {object}  apperrors.ErrorResponse "Forbidden"
+// @Router       /users/{user_id}/purchases [get]
+func (app *App) GetPurchases(w http.ResponseWriter, r *http.Request) {
+	ID, err := app.ExtractPathValue(r, "user_id")
+	if err != nil {
+		app.ErrorJSON(w, r, err)
+		return
+	}
+	userID, role, err := app.GetClaims(r)
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("%w: error extracting claims: %w", apperrors.ErrUnauthorized, err))
+		return
+	}
+	if userID != ID && role != models.RoleAdmin {
+		app.ErrorJSON(w, r, apperrors.ErrForbidden)
+		return
+	}
+	purchases, err := app.DB.GetPurchases(ID)
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("error getting purchases of user %d: %w", ID, err))
+		return
+	}
+	app.Encode(w, r, purchases)
+}
+
+// CreatePurchase records a purchase
+// @Summary      Record a Purchase
+// @Description  Admin only. Records that a user has bought a smartphone at its current price.
+// @Tags         purchases
+// @Security     BearerAuth
+// @Accept       json
+// @Produce      json
+// @Param        user_id path int true "User ID"
+// @Param        input body models.PurchaseRequest true "Purchased smartphone"
+// @Success      201  {object}  models.Purchase
+// @Failure      400  {object}  apperrors.ErrorResponse "Bad Request"
+// @Failure      403  {object}  apperrors.ErrorResponse "Forbidden"
+// @Failure      404  {object}  apperrors.ErrorResponse "Not Found"
+// @Router       /users/{user_id}/purchases [post]
+func (app *App) CreatePurchase(w http.ResponseWriter, r *http.Request) {
+	ID, err := app.ExtractPathValue(r, "user_id")
+	if err != nil {
+		app.ErrorJSON(w, r, err)
+		return
+	}
+	userID, role, err := app.GetClaims(r)
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("%w: error extracting claims: %w", apperrors.ErrUnauthorized, err))
+		return
+	}
+	if role != models.RoleAdmin {
+		app.ErrorJSON(w, r, fmt.Errorf("%w: user %d (role %s) does not have required role",
+			apperrors.ErrForbidden, userID, role))
+		return
+	}
+	var purchasereq models.PurchaseRequest
+	err = json.NewDecoder(r.Body).Decode(&purchasereq)
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("%w: error decoding purchase: %w", apperrors.ErrBadRequest, err))
+		return
+	}
+	if purchasereq.Quantity < 1 {
+		purchasereq.Quantity = 1
+	}
+	_, err = app.DB.GetUser(ID)
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("error getting user %d: %w", ID, err))
+		return
+	}
+	sm, err := app.DB.GetSmartphone(purchasereq.SmartphoneID)
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("error getting smartphone %d: %w", purchasereq.SmartphoneID, err))
+		return
+	}
+	purchase := models.Purchase{
+		UserID:       ID,
+		SmartphoneID: sm.ID,
+		Quantity:     purchasereq.Quantity,
+		Price:        sm.Price,
+		Paid:         sm.Price * purchasereq.Quantity,
+	}
+	newPurchase, err := app.DB.CreatePurchase(purchase)
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("error creating purchase: %w", err))
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	app.Encode(w, r, newPurchase)
+}
@@ -0,0 +1,53 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sfu-teamproject/smartbuy/backend/apperrors"
+	"github.com/sfu-teamproject/smartbuy/backend/logger/mocklogger"
+	"github.com/sfu-teamproject/smartbuy/backend/models"
+	"github.com/sfu-teamproject/smartbuy/backend/storage/mockstorage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestCreatePurchase(t *testing.T) {
+	ms := new(mockstorage.MockStorage)
+	ml := new(mocklogger.MockLogger)
+	sm := models.Smartphone{ID: 1, Price: 500}
+	purchase := models.Purchase{UserID: 2, SmartphoneID: 1, Quantity: 1, Price: 500, Paid: 500}
+	ms.On("GetUser", 2).Return(models.User{ID: 2}, nil)
+	ms.On("GetSmartphone", 1).Return(sm, nil)
+	ms.On("GetSmartphone", 2).Return(models.Smartphone{}, apperrors.ErrNotFound)
+	ms.On("CreatePurchase", purchase).Return(purchase, nil)
+	ml.On("Errorln", mock.Anything, mock.Anything, mock.Anything)
+
+	app := NewApp(ml, nil, ms)
+	tests := []struct {
+		name         string
+		role         models.Role
+		smartphoneID int
+		code         int
+	}{
+		{"Admin records purchase", models.RoleAdmin, 1, http.StatusCreated},
+		{"User records purchase", models.RoleUser, 1, http.StatusForbidden},
+		{"Non-existing smartphone", models.RoleAdmin, 2, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jsonBytes, err := json.Marshal(models.PurchaseRequest{SmartphoneID: tt.smartphoneID})
+			assert.NoError(t, err, "Marshalling purchase failed")
+			ctx := createContextWithClaims("1", tt.role)
+			r := httptest.NewRequestWithContext(ctx, http.MethodPost, "/", bytes.NewBuffer(jsonBytes))
+			r.SetPathValue("user_id", "2")
+			w := httptest.NewRecorder()
+			app.CreatePurchase(w, r)
+			assert.Equal(t, tt.code, w.Code)
+		})
+	}
+	ms.AssertExpectations(t)
+}
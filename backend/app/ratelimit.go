@@ -0,0 +1,152 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sfu-teamproject/smartbuy/backend/apperrors"
+	"github.com/sfu-teamproject/smartbuy/backend/ratelimit"
+	"github.com/sfu-teamproject/smartbuy/backend/storage"
+)
+
+// rateLimitCleanupPeriod is how often expired rate limit counters are deleted
+const rateLimitCleanupPeriod = time.Hour
+
+// rateLimits are the limits of one endpoint
+type rateLimits struct {
+	// name separates counters of different endpoints
+	name     string
+	perIP    ratelimit.Rule
+	perEmail ratelimit.Rule
+}
+
+// dbRateLimits keeps rate limit counters in the database, so they are shared
+// by all instances of the server
+type dbRateLimits struct {
+	db storage.Storage
+}
+
+func (s dbRateLimits) Hit(key string, window time.Duration, now time.Time) (int, time.Time, error) {
+	return s.db.HitRateLimit(key, window, now)
+}
+
+func (s dbRateLimits) Get(key string, now time.Time) (int, time.Time, error) {
+	return s.db.GetRateLimit(key, now)
+}
+
+func (s dbRateLimits) Reset(key string) error {
+	return s.db.ResetRateLimit(key)
+}
+
+func (s dbRateLimits) Sweep(now time.Time) error {
+	_, err := s.db.DeleteExpiredRateLimits(now)
+	return err
+}
+
+// RateLimit rejects requests over the limits with 429 and the Retry-After
+// header. Requests are counted per client IP and per email in the JSON body
+func (app *App) RateLimit(limits rateLimits, next http.HandlerFunc) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			app.ErrorJSON(w, r, fmt.Errorf("%w: error reading request body: %w", apperrors.ErrBadRequest, err))
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		now := time.Now()
+		ip := app.clientIP(r)
+		allowed, wait, err := ratelimit.Allow(app.rateLimits, limits.perIP, limits.name+":ip:"+ip, now)
+		if err != nil {
+			app.ErrorJSON(w, r, fmt.Errorf("error checking rate limit: %w", err))
+			return
+		}
+		if !allowed {
+			app.tooManyRequests(w, r, wait, fmt.Errorf("%w: %s limit exceeded by ip %s",
+				apperrors.ErrTooManyRequests, limits.name, ip))
+			return
+		}
+		var request struct {
+			Email string `json:"email"`
+		}
+		// malformed bodies are rejected by the handler itself
+		json.Unmarshal(body, &request)
+		if email := normalizeEmail(request.Email); email != "" {
+			allowed, wait, err = ratelimit.Allow(app.rateLimits, limits.perEmail, limits.name+":email:"+email, now)
+			if err != nil {
+				app.ErrorJSON(w, r, fmt.Errorf("error checking rate limit: %w", err))
+				return
+			}
+			if !allowed {
+				app.tooManyRequests(w, r, wait, fmt.Errorf("%w: %s limit exceeded for email %s",
+					apperrors.ErrTooManyRequests, limits.name, email))
+				return
+			}
+		}
+		next(w, r)
+	})
+}
+
+// tooManyRequests responds with 429 telling the client to retry after wait
+func (app *App) tooManyRequests(w http.ResponseWriter, r *http.Request, wait time.Duration, err error) {
+	w.Header().Set("Retry-After", strconv.Itoa(max(1, int(math.Ceil(wait.Seconds())))))
+	app.ErrorJSON(w, r, err)
+}
+
+// clientIP returns the address of the client. Behind a trusted reverse proxy
+// it is the last address in X-Forwarded-For, the one added by the proxy
+func (app *App) clientIP(r *http.Request) string {
+	if app.trustForwardedFor {
+		forwarded := strings.Split(r.Header.Get("X-Forwarded-For"), ",")
+		if ip := strings.TrimSpace(forwarded[len(forwarded)-1]); ip != "" {
+			return ip
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// loginFailed counts a failed login of the email towards its lockout
+func (app *App) loginFailed(email string) {
+	lockout, err := app.loginLockout.Fail(email, time.Now())
+	if err != nil {
+		app.Log.Errorf("error counting failed login of %s: %v", email, err)
+		return
+	}
+	if lockout > 0 {
+		app.Log.Infof("Login of %s is locked out for %s", email, lockout)
+	}
+}
+
+// StartRateLimitCleanup deletes expired rate limit counters every hour until
+// ctx is done
+func (app *App) StartRateLimitCleanup(ctx context.Context) {
+	ticker := time.NewTicker(rateLimitCleanupPeriod)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			err := app.rateLimits.Sweep(time.Now())
+			if err != nil {
+				app.Log.Errorf("error deleting expired rate limits: %v", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sfu-teamproject/smartbuy/backend/apperrors"
+	"github.com/sfu-teamproject/smartbuy/backend/logger/mocklogger"
+	"github.com/sfu-teamproject/smartbuy/backend/models"
+	"github.com/sfu-teamproject/smartbuy/backend/ratelimit"
+	"github.com/sfu-teamproject/smartbuy/backend/storage/mockstorage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestRateLimit(t *testing.T) {
+	ml := new(mocklogger.MockLogger)
+	ml.On("Errorln", mock.Anything, mock.Anything, mock.Anything)
+	app := NewApp(ml, nil, new(mockstorage.MockStorage))
+	app.rateLimits = ratelimit.NewMemoryStore()
+	limits := rateLimits{
+		name:     "test",
+		perIP:    ratelimit.Rule{Limit: 3, Window: time.Minute},
+		perEmail: ratelimit.Rule{Limit: 2, Window: time.Hour},
+	}
+	handler := app.RateLimit(limits, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	send := func(ip, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		r.RemoteAddr = ip + ":1234"
+		w := httptest.NewRecorder()
+		handler(w, r)
+		return w
+	}
+
+	t.Run("Per email", func(t *testing.T) {
+		assert.Equal(t, http.StatusNoContent, send("10.0.0.1", `{"email": "user@example.com"}`).Code)
+		assert.Equal(t, http.StatusNoContent, send("10.0.0.2", `{"email": " User@Example.com"}`).Code)
+		w := send("10.0.0.3", `{"email": "user@example.com"}`)
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+		assert.Equal(t, "3600", w.Header().Get("Retry-After"))
+		assert.Equal(t, http.StatusNoContent, send("10.0.0.3", `{"email": "other@example.com"}`).Code)
+	})
+	t.Run("Per IP", func(t *testing.T) {
+		for i := range 3 {
+			w := send("10.0.1.1", `{"email": "user`+string(rune('a'+i))+`@example.com"}`)
+			assert.Equal(t, http.StatusNoContent, w.Code)
+		}
+		w := send("10.0.1.1", `{"email": "new@example.com"}`)
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+		assert.Equal(t, "60", w.Header().Get("Retry-After"))
+		assert.Equal(t, http.StatusNoContent, send("10.0.1.2", `not json`).Code)
+	})
+	t.Run("Forwarded", func(t *testing.T) {
+		app.trustForwardedFor = true
+		defer func() { app.trustForwardedFor = false }()
+		r := httptest.NewRequest(http.MethodPost, "/", nil)
+		r.Header.Set("X-Forwarded-For", "1.2.3.4, 10.0.2.1")
+		assert.Equal(t, "10.0.2.1", app.clientIP(r))
+	})
+}
+
+func TestLoginLockout(t *testing.T) {
+	ms := new(mockstorage.MockStorage)
+	ml := new(mocklogger.MockLogger)
+	hash, err := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+	assert.NoError(t, err)
+	password := string(hash)
+	ms.On("GetUserByEmail", "user1@example.com").
+		Return(models.User{ID: 2, Name: "user1", Email: "user1@example.com", Password: &password, Role: models.RoleUser}, nil)
+	ms.On("AttemptTmpPassword", "user1@example.com", models.TmpPasswordSignup).
+		Return(models.TmpPassword{}, apperrors.ErrNotFound)
+	ml.On("Errorln", mock.Anything, mock.Anything, mock.Anything)
+	ml.On("Infof", mock.Anything, mock.Anything)
+
+	app := NewApp(ml, nil, ms)
+	app.loginLockout = ratelimit.Lockout{Store: ratelimit.NewMemoryStore(), MaxFailures: 2,
+		Base: time.Minute, Max: time.Hour, Window: time.Hour}
+	login := func(password string) *httptest.ResponseRecorder {
+		body := `{"email": "user1@example.com", "password": "` + password + `"}`
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		app.Login(w, r)
+		return w
+	}
+	assert.Equal(t, http.StatusUnauthorized, login("wrong").Code)
+	assert.Equal(t, http.StatusUnauthorized, login("wrong").Code)
+	w := login("password")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code, "correct password is accepted while locked out")
+	assert.Equal(t, "60", w.Header().Get("Retry-After"))
+	ms.AssertNumberOfCalls(t, "GetUserByEmail", 2)
+}
@@ -0,0 +1,442 @@
+package app
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sfu-teamproject/smartbuy/backend/apperrors"
+	"github.com/sfu-teamproject/smartbuy/backend/mailer"
+	"github.com/sfu-teamproject/smartbuy/backend/models"
+)
+
+// returnPhotosPrefix is the blob key prefix of photos of returns, they are
+// private and are not served from /uploads/
+const returnPhotosPrefix = "returns/"
+
+// CreateReturn requests a return of a purchase
+// @Summary      Request a Return
+// @Description  Customers can return smartphones of their purchases within the return window counted from the delivery.
+// @Description  The request can be sent as json or as multipart/form-data with "purchase_id", "quantity", "reason", "comment" and "photos" fields.
+// @Description  Reasons: defective, not_as_described, wrong_item, changed_mind, other. The customer is notified by email.
+// @Tags         returns
+// @Security     BearerAuth
+// @Accept       json
+// @Accept       multipart/form-data
+// @Produce      json
+// @Param        input body models.ReturnRequest true "Return request"
+// @Success      201  {object}  models.Return
+// @Failure      400  {object}  apperrors.ErrorResponse "Invalid request, return window is over or too many smartphones"
+// @Failure      401  {object}  apperrors.ErrorResponse "Unauthorized"
+// @Failure      403  {object}  apperrors.ErrorResponse "Forbidden"
+// @Failure      404  {object}  apperrors.ErrorResponse "Purchase not found"
+// @Router       /returns [post]
+func (app *App) CreateReturn(w http.ResponseWriter, r *http.Request) {
+	userID, _, err := app.GetClaims(r)
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("%w: error extracting claims: %w", apperrors.ErrUnauthorized, err))
+		return
+	}
+	returnreq, photos, err := app.decodeReturnRequest(w, r)
+	if err != nil {
+		app.ErrorJSON(w, r, err)
+		return
+	}
+	if !returnreq.Reason.IsValid() {
+		app.ErrorJSON(w, r, fmt.Errorf("%w: invalid return reason(%s)", apperrors.ErrBadRequest, returnreq.Reason))
+		return
+	}
+	if returnreq.Quantity <= 0 {
+		app.ErrorJSON(w, r, fmt.Errorf("%w: invalid quantity(%d)", apperrors.ErrBadRequest, returnreq.Quantity))
+		return
+	}
+	purchase, err := app.DB.GetPurchase(returnreq.PurchaseID)
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("error getting purchase %d: %w", returnreq.PurchaseID, err))
+		return
+	}
+	if purchase.UserID != userID {
+		app.ErrorJSON(w, r, apperrors.ErrForbidden)
+		return
+	}
+	if time.Since(purchase.PurchasedAt) > app.returnWindow {
+		app.ErrorJSON(w, r, fmt.Errorf("%w: return window of purchase %d is over",
+			apperrors.ErrBadRequest, purchase.ID))
+		return
+	}
+	newReturn, err := app.DB.CreateReturn(models.Return{
+		PurchaseID: purchase.ID,
+		UserID:     userID,
+		Quantity:   returnreq.Quantity,
+		Reason:     returnreq.Reason,
+		Comment:    returnreq.Comment,
+	})
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("error creating return of purchase %d: %w", purchase.ID, err))
+		return
+	}
+	newReturn.Photos, err = app.saveReturnPhotos(newReturn.ID, photos)
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("error saving photos of return %d: %w", newReturn.ID, err))
+		return
+	}
+	app.notifyReturnStatus(newReturn)
+	w.WriteHeader(http.StatusCreated)
+	app.Encode(w, r, newReturn)
+}
+
+// GetReturns lists returns
+// @Summary      List Returns
+// @Description  Users get their own returns. Admins get all returns or returns of the user from user_id query
+// @Tags         returns
+// @Security     BearerAuth
+// @Produce      json
+// @Param        user_id query int false "User ID"
+// @Success      200  {array}   models.Return
+// @Failure      400  {object}  apperrors.ErrorResponse "Bad Request"
+// @Failure      401  {object}  apperrors.ErrorResponse "Unauthorized"
+// @Failure      403  {object}  apperrors.ErrorResponse "Forbidden"
+// @Router       /returns [get]
+func (app *App) GetReturns(w http.ResponseWriter, r *http.Request) {
+	userID, role, err := app.GetClaims(r)
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("%w: error extracting claims: %w", apperrors.ErrUnauthorized, err))
+		return
+	}
+	ownerID := userID
+	userIDStr := r.URL.Query().Get("user_id")
+	if userIDStr != "" {
+		ownerID, err = strconv.Atoi(userIDStr)
+		if err != nil {
+			app.ErrorJSON(w, r, fmt.Errorf("%w: incorrect user id(%s): %w", apperrors.ErrBadRequest, userIDStr, err))
+			return
+		}
+	}
+	if ownerID != userID && role != models.RoleAdmin {
+		app.ErrorJSON(w, r, apperrors.ErrForbidden)
+		return
+	}
+	var returns []models.Return
+	if role == models.RoleAdmin && userIDStr == "" {
+		returns, err = app.DB.GetReturns()
+	} else {
+		returns, err = app.DB.GetUserReturns(ownerID)
+	}
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("error getting returns: %w", err))
+		return
+	}
+	app.Encode(w, r, returns)
+}
+
+// GetReturn gets a single return
+// @Summary      Get a Return
+// @Tags         returns
+// @Security     BearerAuth
+// @Produce      json
+// @Param        return_id path int true "Return ID"
+// @Success      200  {object}  models.Return
+// @Failure      401  {object}  apperrors.ErrorResponse "Unauthorized"
+// @Failure      403  {object}  apperrors.ErrorResponse "Forbidden"
+// @Failure      404  {object}  apperrors.ErrorResponse "Not Found"
+// @Router       /returns/{return_id} [get]
+func (app *App) GetReturn(w http.ResponseWriter, r *http.Request) {
+	userID, role, err := app.GetClaims(r)
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("%w: error extracting claims: %w", apperrors.ErrUnauthorized, err))
+		return
+	}
+	returnID, err := app.ExtractPathValue(r, "return_id")
+	if err != nil {
+		app.ErrorJSON(w, r, err)
+		return
+	}
+	ret, err := app.DB.GetReturn(returnID)
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("error getting return %d: %w", returnID, err))
+		return
+	}
+	if ret.UserID != userID && role != models.RoleAdmin {
+		app.ErrorJSON(w, r, apperrors.ErrForbidden)
+		return
+	}
+	app.Encode(w, r, ret)
+}
+
+// GetReturnPhoto downloads a photo of a return
+// @Summary      Get a Return Photo
+// @Description  Photos of returns are not served from /uploads/, only the customer and admins can get them. file is the last element of path or thumbnail_path of the photo.
+// @Tags         returns
+// @Security     BearerAuth
+// @Produce      image/jpeg,image/png,image/gif
+// @Param        return_id path int true "Return ID"
+// @Param        file path string true "File name"
+// @Success      200  {file}    file
+// @Failure      401  {object}  apperrors.ErrorResponse "Unauthorized"
+// @Failure      403  {object}  apperrors.ErrorResponse "Forbidden"
+// @Failure      404  {object}  apperrors.ErrorResponse "Not Found"
+// @Router       /returns/{return_id}/photos/{file} [get]
+func (app *App) GetReturnPhoto(w http.ResponseWriter, r *http.Request) {
+	userID, role, err := app.GetClaims(r)
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("%w: error extracting claims: %w", apperrors.ErrUnauthorized, err))
+		return
+	}
+	returnID, err := app.ExtractPathValue(r, "return_id")
+	if err != nil {
+		app.ErrorJSON(w, r, err)
+		return
+	}
+	ret, err := app.DB.GetReturn(returnID)
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("error getting return %d: %w", returnID, err))
+		return
+	}
+	if ret.UserID != userID && role != models.RoleAdmin {
+		app.ErrorJSON(w, r, apperrors.ErrForbidden)
+		return
+	}
+	key := fmt.Sprintf("%s%d/%s", returnPhotosPrefix, ret.ID, r.PathValue("file"))
+	found := false
+	for _, photo := range ret.Photos {
+		if photo.Path == key || photo.ThumbnailPath == key {
+			found = true
+			break
+		}
+	}
+	if !found {
+		app.ErrorJSON(w, r, fmt.Errorf("%w: file %s is not a photo of return %d",
+			apperrors.ErrNotFound, r.PathValue("file"), ret.ID))
+		return
+	}
+	blobReq := r.Clone(r.Context())
+	blobReq.URL.Path = "/" + key
+	blobReq.URL.RawPath = ""
+	w.Header().Set("Cache-Control", "private")
+	app.Blobs.Handler().ServeHTTP(w, blobReq)
+}
+
+// ServeUploads serves uploaded files publicly, except photos of returns that
+// are served by GetReturnPhoto
+func (app *App) ServeUploads() http.Handler {
+	files := http.StripPrefix("/uploads/", app.Blobs.Handler())
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(path.Clean(r.URL.Path), "/uploads/"+returnPhotosPrefix) {
+			app.ErrorJSON(w, r, fmt.Errorf("%w: photos of returns are private", apperrors.ErrNotFound))
+			return
+		}
+		files.ServeHTTP(w, r)
+	})
+}
+
+// SetReturnStatus moves a return to another status
+// @Summary      Change Return Status
+// @Description  Admin only. Allowed transitions: requested -> approved or rejected, approved -> received (the goods came back),
+// @Description  received -> refunded. refund_amount is required for refunded and can not exceed what was paid for the returned smartphones
+// @Description  after discounts and with VAT, minus earlier refunds of the same purchase.
+// @Description  The comment is shown to the customer, who is notified by email.
+// @Tags         returns
+// @Security     BearerAuth
+// @Accept       json
+// @Produce      json
+// @Param        return_id path int true "Return ID"
+// @Param        input body models.ReturnStatusRequest true "New status"
+// @Success      200  {object}  models.Return
+// @Failure      400  {object}  apperrors.ErrorResponse "Invalid transition or refund amount"
+// @Failure      401  {object}  apperrors.ErrorResponse "Unauthorized"
+// @Failure      403  {object}  apperrors.ErrorResponse "Forbidden"
+// @Failure      404  {object}  apperrors.ErrorResponse "Not Found"
+// @Router       /returns/{return_id}/status [patch]
+func (app *App) SetReturnStatus(w http.ResponseWriter, r *http.Request) {
+	userID, role, err := app.GetClaims(r)
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("%w: error extracting claims: %w", apperrors.ErrUnauthorized, err))
+		return
+	}
+	if role != models.RoleAdmin {
+		app.ErrorJSON(w, r, fmt.Errorf("%w: user %d (role %s) does not have required role",
+			apperrors.ErrForbidden, userID, role))
+		return
+	}
+	returnID, err := app.ExtractPathValue(r, "return_id")
+	if err != nil {
+		app.ErrorJSON(w, r, err)
+		return
+	}
+	var statusreq models.ReturnStatusRequest
+	err = json.NewDecoder(r.Body).Decode(&statusreq)
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("%w: error decoding status: %w", apperrors.ErrBadRequest, err))
+		return
+	}
+	if !statusreq.Status.IsValid() {
+		app.ErrorJSON(w, r, fmt.Errorf("%w: invalid return status(%s)", apperrors.ErrBadRequest, statusreq.Status))
+		return
+	}
+	ret, err := app.DB.GetReturn(returnID)
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("error getting return %d: %w", returnID, err))
+		return
+	}
+	if !ret.Status.CanTransitionTo(statusreq.Status) {
+		app.ErrorJSON(w, r, fmt.Errorf("%w: return %d can not be moved from %s to %s",
+			apperrors.ErrBadRequest, ret.ID, ret.Status, statusreq.Status))
+		return
+	}
+	if statusreq.Status != models.ReturnRefunded && statusreq.RefundAmount != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("%w: refund amount can only be set for refunded returns", apperrors.ErrBadRequest))
+		return
+	}
+	if statusreq.Status == models.ReturnRefunded {
+		err = app.checkRefundAmount(ret, statusreq.RefundAmount)
+		if err != nil {
+			app.ErrorJSON(w, r, err)
+			return
+		}
+	}
+	updated, err := app.DB.SetReturnStatus(ret.ID, ret.Status, statusreq.Status, statusreq.RefundAmount, statusreq.Comment)
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("error setting status of return %d: %w", ret.ID, err))
+		return
+	}
+	app.notifyReturnStatus(updated)
+	app.Encode(w, r, updated)
+}
+
+// checkRefundAmount allows refunding at most the share of the returned
+// smartphones in what was paid for the purchase. The share is rounded up, the
+// storage keeps refunds of the whole purchase within what was paid
+func (app *App) checkRefundAmount(ret models.Return, amount *int) error {
+	if amount == nil {
+		return fmt.Errorf("%w: refund amount is required", apperrors.ErrBadRequest)
+	}
+	purchase, err := app.DB.GetPurchase(ret.PurchaseID)
+	if err != nil {
+		return fmt.Errorf("error getting purchase %d: %w", ret.PurchaseID, err)
+	}
+	paid := (purchase.Paid*ret.Quantity + purchase.Quantity - 1) / purchase.Quantity
+	if *amount < 0 || *amount > paid {
+		return fmt.Errorf("%w: refund amount %d of return %d must be between 0 and %d",
+			apperrors.ErrBadRequest, *amount, ret.ID, paid)
+	}
+	return nil
+}
+
+// decodeReturnRequest reads a return request either from a json body or from
+// a multipart/form-data body with photos
+func (app *App) decodeReturnRequest(w http.ResponseWriter, r *http.Request) (models.ReturnRequest, []uploadedPhoto, error) {
+	var returnreq models.ReturnRequest
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "multipart/form-data" {
+		err := json.NewDecoder(r.Body).Decode(&returnreq)
+		if err != nil {
+			return returnreq, nil, fmt.Errorf("%w: error decoding return: %w", apperrors.ErrBadRequest, err)
+		}
+		return returnreq, nil, nil
+	}
+	maxBody := int64(app.reviewMaxPhotos)*app.reviewMaxPhotoSize + 1<<20
+	r.Body = http.MaxBytesReader(w, r.Body, maxBody)
+	err := r.ParseMultipartForm(1 << 20)
+	if err != nil {
+		return returnreq, nil, fmt.Errorf("%w: error parsing multipart form: %w", apperrors.ErrBadRequest, err)
+	}
+	returnreq.PurchaseID, err = strconv.Atoi(r.FormValue("purchase_id"))
+	if err != nil {
+		return returnreq, nil, fmt.Errorf("%w: invalid purchase id(%s): %w",
+			apperrors.ErrBadRequest, r.FormValue("purchase_id"), err)
+	}
+	returnreq.Quantity, err = strconv.Atoi(r.FormValue("quantity"))
+	if err != nil {
+		return returnreq, nil, fmt.Errorf("%w: invalid quantity(%s): %w",
+			apperrors.ErrBadRequest, r.FormValue("quantity"), err)
+	}
+	returnreq.Reason = models.ReturnReason(r.FormValue("reason"))
+	if comment, ok := r.MultipartForm.Value["comment"]; ok && len(comment) > 0 {
+		returnreq.Comment = &comment[0]
+	}
+	photos, err := app.readPhotos(r.MultipartForm.File["photos"])
+	return returnreq, photos, err
+}
+
+// saveReturnPhotos writes photos to the blob store and records them in the database
+func (app *App) saveReturnPhotos(returnID int, photos []uploadedPhoto) ([]models.ReturnPhoto, error) {
+	saved := make([]models.ReturnPhoto, 0, len(photos))
+	for _, photo := range photos {
+		b := make([]byte, 8)
+		if _, err := rand.Read(b); err != nil {
+			return saved, fmt.Errorf("error generating photo name: %w", err)
+		}
+		name := fmt.Sprintf("%s%d/%s", returnPhotosPrefix, returnID, hex.EncodeToString(b))
+		p := models.ReturnPhoto{
+			ReturnID:      returnID,
+			Path:          name + photo.ext,
+			ThumbnailPath: name + "_thumb.jpg",
+		}
+		err := app.Blobs.Save(p.Path, photo.data)
+		if err != nil {
+			return saved, fmt.Errorf("error saving photo: %w", err)
+		}
+		err = app.Blobs.Save(p.ThumbnailPath, photo.thumbnail)
+		if err != nil {
+			app.deleteBlobs(p.Path)
+			return saved, fmt.Errorf("error saving thumbnail: %w", err)
+		}
+		added, err := app.DB.AddReturnPhoto(p)
+		if err != nil {
+			app.deleteBlobs(p.Path, p.ThumbnailPath)
+			return saved, fmt.Errorf("error adding photo to database: %w", err)
+		}
+		saved = append(saved, added)
+	}
+	return saved, nil
+}
+
+// deleteBlobs removes files from the blob store, failures are only logged
+func (app *App) deleteBlobs(keys ...string) {
+	for _, key := range keys {
+		err := app.Blobs.Delete(key)
+		if err != nil {
+			app.Log.Errorf("error deleting file %s: %v", key, err)
+		}
+	}
+}
+
+var returnStatusMessages = map[models.ReturnStatus]string{
+	models.ReturnRequested: "Заявка на возврат №%d принята и ожидает рассмотрения.",
+	models.ReturnApproved:  "Заявка на возврат №%d одобрена. Отправьте товар в магазин.",
+	models.ReturnRejected:  "Заявка на возврат №%d отклонена.",
+	models.ReturnReceived:  "Товар по заявке на возврат №%d получен магазином.",
+	models.ReturnRefunded:  "Деньги по заявке на возврат №%d возвращены.",
+}
+
+// notifyReturnStatus emails the customer about the status of the return,
+// failures are logged and do not fail the request
+func (app *App) notifyReturnStatus(ret models.Return) {
+	customer, err := app.DB.GetUser(ret.UserID)
+	if err != nil {
+		app.Log.Errorf("error getting customer of return %d: %v", ret.ID, err)
+		return
+	}
+	body := "Здравствуйте, " + customer.Name + "!\n" + fmt.Sprintf(returnStatusMessages[ret.Status], ret.ID)
+	if ret.Status == models.ReturnRefunded && ret.RefundAmount != nil {
+		body += fmt.Sprintf("\nСумма возврата: %d руб.", *ret.RefundAmount)
+	}
+	if ret.AdminComment != nil {
+		body += "\nКомментарий магазина: " + *ret.AdminComment
+	}
+	err = app.Mail.Send(mailer.Message{
+		To:      customer.Email,
+		Subject: fmt.Sprintf("Smartbuy: возврат №%d", ret.ID),
+		Body:    body,
+	})
+	if err != nil {
+		app.Log.Errorf("error sending return notification to %s: %v", customer.Email, err)
+	}
+}
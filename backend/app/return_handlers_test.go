@@ -0,0 +1,222 @@
+package app
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sfu-teamproject/smartbuy/backend/apperrors"
+	"github.com/sfu-teamproject/smartbuy/backend/blobstore"
+	"github.com/sfu-teamproject/smartbuy/backend/logger/mocklogger"
+	"github.com/sfu-teamproject/smartbuy/backend/mailer"
+	"github.com/sfu-teamproject/smartbuy/backend/mailer/mockmailer"
+	"github.com/sfu-teamproject/smartbuy/backend/models"
+	"github.com/sfu-teamproject/smartbuy/backend/storage/mockstorage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestCreateReturn(t *testing.T) {
+	ms := new(mockstorage.MockStorage)
+	ml := new(mocklogger.MockLogger)
+	mm := new(mockmailer.MockMailer)
+	ms.On("GetPurchase", 1).Return(models.Purchase{ID: 1, UserID: 2, Quantity: 2, Price: 500,
+		PurchasedAt: time.Now().Add(-24 * time.Hour)}, nil)
+	ms.On("GetPurchase", 2).Return(models.Purchase{ID: 2, UserID: 2, Quantity: 1, Price: 500,
+		PurchasedAt: time.Now().Add(-30 * 24 * time.Hour)}, nil)
+	ms.On("GetPurchase", 3).Return(models.Purchase{}, apperrors.ErrNotFound)
+	ms.On("CreateReturn", mock.MatchedBy(func(ret models.Return) bool { return ret.Quantity <= 2 })).
+		Return(models.Return{ID: 1, PurchaseID: 1, UserID: 2, Quantity: 1, Status: models.ReturnRequested}, nil)
+	ms.On("CreateReturn", mock.Anything).Return(models.Return{}, apperrors.ErrBadRequest)
+	ms.On("GetUser", 2).Return(models.User{ID: 2, Name: "user1", Email: "user1@example.com"}, nil)
+	mm.On("Send", mock.MatchedBy(func(msg mailer.Message) bool { return msg.To == "user1@example.com" })).Return(nil)
+	ml.On("Errorln", mock.Anything, mock.Anything, mock.Anything)
+
+	app := NewApp(ml, nil, ms)
+	app.Mail = mm
+	app.returnWindow = 14 * 24 * time.Hour
+	tests := []struct {
+		name   string
+		userID string
+		body   string
+		code   int
+	}{
+		{"Return defective phone", "2", `{"purchase_id": 1, "quantity": 1, "reason": "defective"}`, http.StatusCreated},
+		{"Return window is over", "2", `{"purchase_id": 2, "quantity": 1, "reason": "defective"}`, http.StatusBadRequest},
+		{"Return more than purchased", "2", `{"purchase_id": 1, "quantity": 3, "reason": "defective"}`, http.StatusBadRequest},
+		{"Unknown reason", "2", `{"purchase_id": 1, "quantity": 1, "reason": "bored"}`, http.StatusBadRequest},
+		{"Zero quantity", "2", `{"purchase_id": 1, "quantity": 0, "reason": "other"}`, http.StatusBadRequest},
+		{"Purchase of another user", "3", `{"purchase_id": 1, "quantity": 1, "reason": "defective"}`, http.StatusForbidden},
+		{"Nonexistent purchase", "2", `{"purchase_id": 3, "quantity": 1, "reason": "defective"}`, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := createContextWithClaims(tt.userID, models.RoleUser)
+			r := httptest.NewRequestWithContext(ctx, http.MethodPost, "/", bytes.NewBufferString(tt.body))
+			w := httptest.NewRecorder()
+			app.CreateReturn(w, r)
+			assert.Equal(t, tt.code, w.Code)
+		})
+	}
+	mm.AssertNumberOfCalls(t, "Send", 1)
+}
+
+func TestCreateReturnWithPhotos(t *testing.T) {
+	ms := new(mockstorage.MockStorage)
+	ml := new(mocklogger.MockLogger)
+	mm := new(mockmailer.MockMailer)
+	comment := "broken screen"
+	ms.On("GetPurchase", 1).Return(models.Purchase{ID: 1, UserID: 2, Quantity: 1, Price: 500,
+		PurchasedAt: time.Now()}, nil)
+	ms.On("CreateReturn", models.Return{PurchaseID: 1, UserID: 2, Quantity: 1, Reason: models.ReturnReasonDefective,
+		Comment: &comment}).Return(models.Return{ID: 4, PurchaseID: 1, UserID: 2, Quantity: 1}, nil)
+	ms.On("AddReturnPhoto", mock.Anything).Return(models.ReturnPhoto{ID: 1, ReturnID: 4}, nil)
+	ms.On("GetUser", 2).Return(models.User{ID: 2, Email: "user1@example.com"}, nil)
+	mm.On("Send", mock.Anything).Return(nil)
+	ml.On("Errorln", mock.Anything, mock.Anything, mock.Anything)
+
+	app := NewApp(ml, nil, ms)
+	app.Mail = mm
+	dir := t.TempDir()
+	app.Blobs = blobstore.NewLocalStore(dir)
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	assert.NoError(t, mw.WriteField("purchase_id", "1"))
+	assert.NoError(t, mw.WriteField("quantity", "1"))
+	assert.NoError(t, mw.WriteField("reason", "defective"))
+	assert.NoError(t, mw.WriteField("comment", comment))
+	fw, err := mw.CreateFormFile("photos", "photo.png")
+	assert.NoError(t, err)
+	_, err = fw.Write(testPNG(t, 40, 40))
+	assert.NoError(t, err)
+	assert.NoError(t, mw.Close())
+
+	ctx := createContextWithClaims("2", models.RoleUser)
+	r := httptest.NewRequestWithContext(ctx, http.MethodPost, "/", &body)
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+	w := httptest.NewRecorder()
+	app.CreateReturn(w, r)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	files, err := filepath.Glob(filepath.Join(dir, "returns", "4", "*"))
+	assert.NoError(t, err)
+	assert.Len(t, files, 2, "expected a photo and a thumbnail")
+	ms.AssertNumberOfCalls(t, "AddReturnPhoto", 1)
+}
+
+func TestSetReturnStatus(t *testing.T) {
+	ms := new(mockstorage.MockStorage)
+	ml := new(mocklogger.MockLogger)
+	mm := new(mockmailer.MockMailer)
+	amount := 900
+	ms.On("GetReturn", 1).Return(models.Return{ID: 1, PurchaseID: 1, UserID: 2, Quantity: 2,
+		Status: models.ReturnRequested}, nil)
+	ms.On("GetReturn", 2).Return(models.Return{ID: 2, PurchaseID: 1, UserID: 2, Quantity: 2,
+		Status: models.ReturnReceived}, nil)
+	// 3 smartphones paid 1351 with a discount, 2 of them are worth at most 901
+	ms.On("GetPurchase", 1).Return(models.Purchase{ID: 1, UserID: 2, Quantity: 3, Price: 500, Paid: 1351}, nil)
+	ms.On("SetReturnStatus", 1, models.ReturnRequested, models.ReturnApproved, (*int)(nil), mock.Anything).
+		Return(models.Return{ID: 1, UserID: 2, Status: models.ReturnApproved}, nil)
+	ms.On("SetReturnStatus", 2, models.ReturnReceived, models.ReturnRefunded, &amount, (*string)(nil)).
+		Return(models.Return{ID: 2, UserID: 2, Status: models.ReturnRefunded, RefundAmount: &amount}, nil)
+	ms.On("GetUser", 2).Return(models.User{ID: 2, Email: "user1@example.com"}, nil)
+	mm.On("Send", mock.Anything).Return(nil)
+	ml.On("Errorln", mock.Anything, mock.Anything, mock.Anything)
+
+	app := NewApp(ml, nil, ms)
+	app.Mail = mm
+	tests := []struct {
+		name     string
+		role     models.Role
+		returnID string
+		body     string
+		code     int
+	}{
+		{"Approve return", models.RoleAdmin, "1", `{"status": "approved", "comment": "send it to us"}`, http.StatusOK},
+		{"User approves return", models.RoleUser, "1", `{"status": "approved"}`, http.StatusForbidden},
+		{"Refund before receipt", models.RoleAdmin, "1", `{"status": "refunded", "refund_amount": 900}`, http.StatusBadRequest},
+		{"Refund amount on approval", models.RoleAdmin, "1", `{"status": "approved", "refund_amount": 900}`, http.StatusBadRequest},
+		{"Refund", models.RoleAdmin, "2", `{"status": "refunded", "refund_amount": 900}`, http.StatusOK},
+		{"Refund without amount", models.RoleAdmin, "2", `{"status": "refunded"}`, http.StatusBadRequest},
+		{"Refund more than paid", models.RoleAdmin, "2", `{"status": "refunded", "refund_amount": 902}`, http.StatusBadRequest},
+		{"Refund list price", models.RoleAdmin, "2", `{"status": "refunded", "refund_amount": 1000}`, http.StatusBadRequest},
+		{"Unknown status", models.RoleAdmin, "1", `{"status": "lost"}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := createContextWithClaims("1", tt.role)
+			r := httptest.NewRequestWithContext(ctx, http.MethodPatch, "/", bytes.NewBufferString(tt.body))
+			r.SetPathValue("return_id", tt.returnID)
+			w := httptest.NewRecorder()
+			app.SetReturnStatus(w, r)
+			assert.Equal(t, tt.code, w.Code)
+		})
+	}
+	ms.AssertNumberOfCalls(t, "SetReturnStatus", 2)
+	mm.AssertNumberOfCalls(t, "Send", 2)
+}
+
+func TestGetReturnPhoto(t *testing.T) {
+	ms := new(mockstorage.MockStorage)
+	ml := new(mocklogger.MockLogger)
+	ms.On("GetReturn", 4).Return(models.Return{ID: 4, UserID: 2, Photos: []models.ReturnPhoto{
+		{ID: 1, ReturnID: 4, Path: "returns/4/photo.png", ThumbnailPath: "returns/4/photo_thumb.jpg"},
+	}}, nil)
+	ml.On("Errorln", mock.Anything, mock.Anything, mock.Anything)
+
+	app := NewApp(ml, nil, ms)
+	app.Blobs = blobstore.NewLocalStore(t.TempDir())
+	assert.NoError(t, app.Blobs.Save("returns/4/photo.png", testPNG(t, 10, 10)))
+	assert.NoError(t, app.Blobs.Save("returns/4/other.png", testPNG(t, 10, 10)))
+	tests := []struct {
+		name   string
+		userID string
+		role   models.Role
+		file   string
+		code   int
+	}{
+		{"Customer gets photo", "2", models.RoleUser, "photo.png", http.StatusOK},
+		{"Admin gets photo", "1", models.RoleAdmin, "photo.png", http.StatusOK},
+		{"Another user gets photo", "3", models.RoleUser, "photo.png", http.StatusForbidden},
+		{"File of no photo", "2", models.RoleUser, "other.png", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := createContextWithClaims(tt.userID, tt.role)
+			r := httptest.NewRequestWithContext(ctx, http.MethodGet, "/", nil)
+			r.SetPathValue("return_id", "4")
+			r.SetPathValue("file", tt.file)
+			w := httptest.NewRecorder()
+			app.GetReturnPhoto(w, r)
+			assert.Equal(t, tt.code, w.Code)
+			if tt.code == http.StatusOK {
+				assert.Equal(t, "image/png", w.Header().Get("Content-Type"))
+			}
+		})
+	}
+}
+
+func TestServeUploads(t *testing.T) {
+	ml := new(mocklogger.MockLogger)
+	ml.On("Errorln", mock.Anything, mock.Anything, mock.Anything)
+	app := NewApp(ml, nil, new(mockstorage.MockStorage))
+	app.Blobs = blobstore.NewLocalStore(t.TempDir())
+	assert.NoError(t, app.Blobs.Save("reviews/1/photo.png", []byte("review")))
+	assert.NoError(t, app.Blobs.Save("returns/4/photo.png", []byte("return")))
+	tests := []struct {
+		path string
+		code int
+	}{
+		{"/uploads/reviews/1/photo.png", http.StatusOK},
+		{"/uploads/returns/4/photo.png", http.StatusNotFound},
+		{"/uploads/reviews/../returns/4/photo.png", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		app.ServeUploads().ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		assert.Equal(t, tt.code, w.Code, tt.path)
+	}
+}
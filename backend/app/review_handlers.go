@@ -1,11 +1,13 @@
 package app
 
 import (
-	"encoding/json"
 	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/sfu-teamproject/smartbuy/backend/apperrors"
+	"github.com/sfu-teamproject/smartbuy/backend/contentfilter"
 	"github.com/sfu-teamproject/smartbuy/backend/models"
 )
 
@@ -29,16 +31,22 @@ func (app *App) GetReview(w http.ResponseWriter, r *http.Request) {
 		app.ErrorJSON(w, r, fmt.Errorf("error getting review %d: %w", reviewID, err))
 		return
 	}
+	if review.Hidden {
+		app.ErrorJSON(w, r, fmt.Errorf("%w: review %d is hidden", apperrors.ErrNotFound, reviewID))
+		return
+	}
 	app.Encode(w, r, review)
 }
 
 // GetReviews gets reviews for a phone
 // @Summary      List Reviews
-// @Description  Get all reviews for a specific smartphone
+// @Description  Get all reviews for a specific smartphone. If verified=true, return only reviews of buyers
 // @Tags         reviews
 // @Produce      json
 // @Param        smartphone_id path int true "Smartphone ID"
+// @Param        verified query bool false "Only verified purchase reviews"
 // @Success      200  {array}   models.Review
+// @Failure      400  {object}  apperrors.ErrorResponse "Bad Request"
 // @Failure      404  {object}  apperrors.ErrorResponse "Not Found"
 // @Router       /smartphones/{smartphone_id}/reviews [get]
 func (app *App) GetReviews(w http.ResponseWriter, r *http.Request) {
@@ -47,7 +55,22 @@ func (app *App) GetReviews(w http.ResponseWriter, r *http.Request) {
 		app.ErrorJSON(w, r, err)
 		return
 	}
-	reviews, err := app.DB.GetReviews(smartphoneID)
+	verifiedOnly := false
+	verifiedStr := r.URL.Query().Get("verified")
+	if verifiedStr != "" {
+		verifiedOnly, err = strconv.ParseBool(verifiedStr)
+		if err != nil {
+			app.ErrorJSON(w, r, fmt.Errorf("%w: invalid verified value(%s): %w",
+				apperrors.ErrBadRequest, verifiedStr, err))
+			return
+		}
+	}
+	var reviews []models.Review
+	if verifiedOnly {
+		reviews, err = app.DB.GetVerifiedReviews(smartphoneID)
+	} else {
+		reviews, err = app.DB.GetReviews(smartphoneID)
+	}
 	if err != nil {
 		app.ErrorJSON(w, r, fmt.Errorf("error getting reviews(%d): %w", smartphoneID, err))
 		return
@@ -57,14 +80,19 @@ func (app *App) GetReviews(w http.ResponseWriter, r *http.Request) {
 
 // CreateReview adds a review
 // @Summary      Post a Review
+// @Description  Review is marked as verified if the user has purchased the smartphone.
+// @Description  The comment is checked by content filters: it can be rejected, masked or sent to moderation (the review is hidden until an admin approves it).
+// @Description  Photos can be attached by sending multipart/form-data with rating, comment and photos fields.
 // @Tags         reviews
 // @Security     BearerAuth
-// @Accept       json
+// @Accept       json,mpfd
 // @Produce      json
 // @Param        smartphone_id path int true "Smartphone ID"
 // @Param        input body models.ReviewRequest true "Review Body"
 // @Success      201  {object}  models.Review
+// @Failure      400  {object}  apperrors.ErrorResponse "Bad Request"
 // @Failure      401  {object}  apperrors.ErrorResponse "Unauthorized"
+// @Failure      404  {object}  apperrors.ErrorResponse "Not Found"
 // @Router       /smartphones/{smartphone_id}/reviews [post]
 func (app *App) CreateReview(w http.ResponseWriter, r *http.Request) {
 	userID, _, err := app.GetClaims(r)
@@ -77,29 +105,50 @@ func (app *App) CreateReview(w http.ResponseWriter, r *http.Request) {
 		app.ErrorJSON(w, r, err)
 		return
 	}
-	var reviewreq models.ReviewRequest
-	err = json.NewDecoder(r.Body).Decode(&reviewreq)
+	reviewreq, photos, err := app.decodeReviewRequest(w, r)
+	if err != nil {
+		app.ErrorJSON(w, r, err)
+		return
+	}
+	_, err = app.DB.GetSmartphone(smartphoneID)
 	if err != nil {
-		app.ErrorJSON(w, r, fmt.Errorf("%w: error decoding review: %w", apperrors.ErrBadRequest, err))
+		app.ErrorJSON(w, r, fmt.Errorf("error getting smartphone %d: %w", smartphoneID, err))
 		return
 	}
-	review := models.Review{Rating: reviewreq.Rating, Comment: reviewreq.Comment}
+	verified, err := app.DB.HasPurchased(userID, smartphoneID)
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("error checking purchase of smartphone %d: %w", smartphoneID, err))
+		return
+	}
+	review := models.Review{Rating: reviewreq.Rating, Comment: reviewreq.Comment, Verified: verified}
 	review.SmartphoneID = smartphoneID
 	review.UserID = userID
+	err = app.filterReview(&review)
+	if err != nil {
+		app.ErrorJSON(w, r, err)
+		return
+	}
 	newReview, err := app.DB.CreateReview(review)
 	if err != nil {
 		app.ErrorJSON(w, r, fmt.Errorf("error creating review: %w", err))
 		return
 	}
+	newReview.Photos, err = app.saveReviewPhotos(newReview.ID, photos)
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("error saving photos of review %d: %w", newReview.ID, err))
+		return
+	}
 	w.WriteHeader(http.StatusCreated)
 	app.Encode(w, r, newReview)
 }
 
 // UpdateReview edits a review
 // @Summary      Edit a Review
+// @Description  Photos sent as multipart/form-data are added to the photos already attached to the review.
+// @Description  The comment is checked by content filters the same way as for a new review.
 // @Tags         reviews
 // @Security     BearerAuth
-// @Accept       json
+// @Accept       json,mpfd
 // @Produce      json
 // @Param        smartphone_id path int true "Smartphone ID"
 // @Param        review_id path int true "Review ID"
@@ -118,10 +167,9 @@ func (app *App) UpdateReview(w http.ResponseWriter, r *http.Request) {
 		app.ErrorJSON(w, r, err)
 		return
 	}
-	var reviewreq models.ReviewRequest
-	err = json.NewDecoder(r.Body).Decode(&reviewreq)
+	reviewreq, photos, err := app.decodeReviewRequest(w, r)
 	if err != nil {
-		app.ErrorJSON(w, r, fmt.Errorf("%w: error decoding review: %w", apperrors.ErrBadRequest, err))
+		app.ErrorJSON(w, r, err)
 		return
 	}
 	existingReview, err := app.DB.GetReview(reviewID)
@@ -145,11 +193,28 @@ func (app *App) UpdateReview(w http.ResponseWriter, r *http.Request) {
 			apperrors.ErrBadRequest, review.ID, smID))
 		return
 	}
+	if len(existingReview.Photos)+len(photos) > app.reviewMaxPhotos {
+		app.ErrorJSON(w, r, fmt.Errorf("%w: review %d already has %d photos, at most %d allowed",
+			apperrors.ErrBadRequest, review.ID, len(existingReview.Photos), app.reviewMaxPhotos))
+		return
+	}
+	review.UserID = existingReview.UserID
+	err = app.filterReview(&review)
+	if err != nil {
+		app.ErrorJSON(w, r, err)
+		return
+	}
 	updatedReview, err := app.DB.UpdateReview(review)
 	if err != nil {
 		app.ErrorJSON(w, r, fmt.Errorf("error updating review: %w", err))
 		return
 	}
+	newPhotos, err := app.saveReviewPhotos(updatedReview.ID, photos)
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("error saving photos of review %d: %w", updatedReview.ID, err))
+		return
+	}
+	updatedReview.Photos = append(existingReview.Photos, newPhotos...)
 	app.Encode(w, r, updatedReview)
 }
 
@@ -199,5 +264,32 @@ func (app *App) DeleteReview(w http.ResponseWriter, r *http.Request) {
 		app.ErrorJSON(w, r, fmt.Errorf("error deleting review: %w", err))
 		return
 	}
+	app.deletePhotoFiles(existingReview.Photos)
+	deletedReview.Photos = existingReview.Photos
 	app.Encode(w, r, deletedReview)
 }
+
+// filterReview runs the comment of a review through the content filters,
+// masked comments are replaced and reviews sent to moderation are hidden
+func (app *App) filterReview(review *models.Review) error {
+	review.FilterAction = string(contentfilter.ActionAllow)
+	review.FilterReasons = []string{}
+	if review.Comment == nil {
+		return nil
+	}
+	decision, err := app.reviewFilter.Run(contentfilter.Input{UserID: review.UserID, Text: *review.Comment})
+	if err != nil {
+		return fmt.Errorf("error filtering comment: %w", err)
+	}
+	if decision.Action == contentfilter.ActionReject {
+		return fmt.Errorf("%w: comment is rejected: %s",
+			apperrors.ErrBadRequest, strings.Join(decision.Reasons, "; "))
+	}
+	review.Comment = &decision.Text
+	review.Hidden = decision.Action == contentfilter.ActionModerate
+	review.FilterAction = string(decision.Action)
+	if len(decision.Reasons) > 0 {
+		review.FilterReasons = decision.Reasons
+	}
+	return nil
+}
@@ -10,9 +10,11 @@ import (
 	"time"
 
 	"github.com/sfu-teamproject/smartbuy/backend/apperrors"
+	"github.com/sfu-teamproject/smartbuy/backend/contentfilter"
 	"github.com/sfu-teamproject/smartbuy/backend/logger/mocklogger"
 	"github.com/sfu-teamproject/smartbuy/backend/models"
 	"github.com/sfu-teamproject/smartbuy/backend/storage/mockstorage"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
 
@@ -23,22 +25,26 @@ func TestGetReviews(t *testing.T) {
 	ms.On("GetReviews", 1).Return([]models.Review{{ID: 1, SmartphoneID: 1, UserID: 1, UserName: "user1", Rating: 5, Comment: nil, CreatedAt: currTime, UpdatedAt: currTime}}, nil)
 	ms.On("GetReviews", 2).Return([]models.Review{}, nil)
 	ms.On("GetReviews", 3).Return([]models.Review{}, apperrors.ErrNotFound)
+	ms.On("GetVerifiedReviews", 1).Return([]models.Review{}, nil)
 	ml.On("Errorln", mock.Anything, mock.Anything, mock.Anything)
 
 	app := NewApp(ml, nil, ms)
 	tests := []struct {
 		name         string
 		smartphoneID string
+		query        string
 		err          error
 	}{
-		{"Existing smartphone with reviews", "1", nil},
-		{"Existing smartphone without reviews", "2", nil},
-		{"Nonexisting smartphone", "3", apperrors.ErrNotFound},
+		{"Existing smartphone with reviews", "1", "", nil},
+		{"Existing smartphone without reviews", "2", "", nil},
+		{"Nonexisting smartphone", "3", "", apperrors.ErrNotFound},
+		{"Verified reviews only", "1", "?verified=true", nil},
+		{"Invalid verified value", "1", "?verified=maybe", apperrors.ErrBadRequest},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 
-			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r := httptest.NewRequest(http.MethodGet, "/"+tt.query, nil)
 			r.SetPathValue("smartphone_id", tt.smartphoneID)
 			w := httptest.NewRecorder()
 			app.GetReviews(w, r)
@@ -51,10 +57,13 @@ func TestCreateReview(t *testing.T) {
 	ms := new(mockstorage.MockStorage)
 	ml := new(mocklogger.MockLogger)
 	currTime := time.Now().Round(0)
-	r := models.Review{ID: 1, SmartphoneID: 1, UserID: 1, UserName: "user", Rating: 5, Comment: nil, CreatedAt: currTime, UpdatedAt: currTime}
+	r := models.Review{ID: 1, SmartphoneID: 1, UserID: 1, UserName: "user", Rating: 5, Comment: nil, CreatedAt: currTime, UpdatedAt: currTime, Verified: true}
 	rErr := models.Review{ID: 1, SmartphoneID: 2, UserID: 1, UserName: "user", Rating: 5, Comment: nil, CreatedAt: currTime, UpdatedAt: currTime}
-	ms.On("CreateReview", r).Return(r, nil)
-	ms.On("GetSmartphone", 1).Return(models.Smartphone{}, nil)
+	ms.On("CreateReview", models.Review{SmartphoneID: 1, UserID: 1, Rating: 5, Verified: true, FilterAction: "allow",
+		FilterReasons: []string{}}).
+		Return(r, nil)
+	ms.On("HasPurchased", 1, 1).Return(true, nil)
+	ms.On("GetSmartphone", 1).Return(models.Smartphone{ID: 1}, nil)
 	ms.On("GetSmartphone", 2).Return(models.Smartphone{}, apperrors.ErrNotFound)
 	ml.On("Errorln", mock.Anything, mock.Anything, mock.Anything)
 
@@ -83,3 +92,46 @@ func TestCreateReview(t *testing.T) {
 	}
 	ms.AssertExpectations(t)
 }
+
+func TestCreateReviewFiltered(t *testing.T) {
+	ms := new(mockstorage.MockStorage)
+	ml := new(mocklogger.MockLogger)
+	masked := "S***** camera"
+	spam := "Buy it at www.example.com"
+	ms.On("GetSmartphone", 1).Return(models.Smartphone{ID: 1}, nil)
+	ms.On("HasPurchased", 1, 1).Return(false, nil)
+	ms.On("CreateReview", models.Review{SmartphoneID: 1, UserID: 1, Rating: 2, Comment: &masked,
+		FilterAction: "mask", FilterReasons: []string{"profanity: offensive words"}}).
+		Return(models.Review{ID: 1}, nil)
+	ms.On("CreateReview", models.Review{SmartphoneID: 1, UserID: 1, Rating: 2, Comment: &spam, Hidden: true,
+		FilterAction: "moderate", FilterReasons: []string{"spam: links"}}).
+		Return(models.Review{ID: 2, Hidden: true}, nil)
+	ml.On("Errorln", mock.Anything, mock.Anything, mock.Anything)
+
+	app := NewApp(ml, nil, ms)
+	reviewFilter, err := contentfilter.NewPipeline("profanity:mask,spam:moderate,caps:reject", nil)
+	assert.NoError(t, err)
+	app.reviewFilter = reviewFilter
+	tests := []struct {
+		name    string
+		comment string
+		code    int
+	}{
+		{"Masked comment", "Shitty camera", http.StatusCreated},
+		{"Comment sent to moderation", spam, http.StatusCreated},
+		{"Rejected comment", "DO NOT BUY THIS PHONE", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jsonBytes, err := json.Marshal(models.ReviewRequest{Rating: 2, Comment: &tt.comment})
+			assert.NoError(t, err, "Marshalling review failed")
+			ctx := createContextWithClaims("1", models.RoleUser)
+			r := httptest.NewRequestWithContext(ctx, http.MethodPost, "/", bytes.NewBuffer(jsonBytes))
+			r.SetPathValue("smartphone_id", "1")
+			w := httptest.NewRecorder()
+			app.CreateReview(w, r)
+			assert.Equal(t, tt.code, w.Code)
+		})
+	}
+	ms.AssertNumberOfCalls(t, "CreateReview", 2)
+}
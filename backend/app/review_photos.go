@@ -0,0 +1,263 @@
+package app
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"image"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+
+	"github.com/sfu-teamproject/smartbuy/backend/apperrors"
+	"github.com/sfu-teamproject/smartbuy/backend/models"
+)
+
+const thumbnailSize = 256
+
+var photoExtensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+}
+
+// uploadedPhoto is an uploaded photo that passed validation but is not saved yet
+type uploadedPhoto struct {
+	data      []byte
+	ext       string
+	thumbnail []byte
+}
+
+// decodeReviewRequest reads a review either from a json body or from a
+// multipart/form-data body with "rating", "comment" and "photos" fields
+func (app *App) decodeReviewRequest(w http.ResponseWriter, r *http.Request) (models.ReviewRequest, []uploadedPhoto, error) {
+	var reviewreq models.ReviewRequest
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "multipart/form-data" {
+		err := json.NewDecoder(r.Body).Decode(&reviewreq)
+		if err != nil {
+			return reviewreq, nil, fmt.Errorf("%w: error decoding review: %w", apperrors.ErrBadRequest, err)
+		}
+		return reviewreq, nil, nil
+	}
+	maxBody := int64(app.reviewMaxPhotos)*app.reviewMaxPhotoSize + 1<<20
+	r.Body = http.MaxBytesReader(w, r.Body, maxBody)
+	err := r.ParseMultipartForm(1 << 20)
+	if err != nil {
+		return reviewreq, nil, fmt.Errorf("%w: error parsing multipart form: %w", apperrors.ErrBadRequest, err)
+	}
+	reviewreq.Rating, err = strconv.Atoi(r.FormValue("rating"))
+	if err != nil {
+		return reviewreq, nil, fmt.Errorf("%w: invalid rating(%s): %w",
+			apperrors.ErrBadRequest, r.FormValue("rating"), err)
+	}
+	if comment, ok := r.MultipartForm.Value["comment"]; ok && len(comment) > 0 {
+		reviewreq.Comment = &comment[0]
+	}
+	photos, err := app.readPhotos(r.MultipartForm.File["photos"])
+	return reviewreq, photos, err
+}
+
+// readPhotos validates photos from a multipart form against the limits for
+// review photos, the same limits apply to photos of returns
+func (app *App) readPhotos(files []*multipart.FileHeader) ([]uploadedPhoto, error) {
+	if len(files) > app.reviewMaxPhotos {
+		return nil, fmt.Errorf("%w: too many photos(%d), at most %d allowed",
+			apperrors.ErrBadRequest, len(files), app.reviewMaxPhotos)
+	}
+	photos := make([]uploadedPhoto, 0, len(files))
+	for _, fh := range files {
+		if fh.Size > app.reviewMaxPhotoSize {
+			return nil, fmt.Errorf("%w: photo %s is too large(%d bytes), at most %d allowed",
+				apperrors.ErrBadRequest, fh.Filename, fh.Size, app.reviewMaxPhotoSize)
+		}
+		f, err := fh.Open()
+		if err != nil {
+			return nil, fmt.Errorf("error opening photo %s: %w", fh.Filename, err)
+		}
+		data, err := io.ReadAll(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("error reading photo %s: %w", fh.Filename, err)
+		}
+		photo, err := processPhoto(data, app.reviewMaxPhotoPixels)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid photo %s: %w", apperrors.ErrBadRequest, fh.Filename, err)
+		}
+		photos = append(photos, photo)
+	}
+	return photos, nil
+}
+
+// processPhoto checks that data is a supported image of at most maxPixels
+// pixels and renders its thumbnail. The size is read from the header before
+// decoding, so a small file can't make the server allocate a huge image
+func processPhoto(data []byte, maxPixels int) (uploadedPhoto, error) {
+	contentType := http.DetectContentType(data)
+	ext, ok := photoExtensions[contentType]
+	if !ok {
+		return uploadedPhoto{}, fmt.Errorf("unsupported image type %s", contentType)
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return uploadedPhoto{}, fmt.Errorf("error decoding image header: %w", err)
+	}
+	if config.Width <= 0 || config.Height <= 0 || config.Width > maxPixels/config.Height {
+		return uploadedPhoto{}, fmt.Errorf("image is too large(%dx%d), at most %d pixels allowed",
+			config.Width, config.Height, maxPixels)
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return uploadedPhoto{}, fmt.Errorf("error decoding image: %w", err)
+	}
+	var thumb bytes.Buffer
+	err = jpeg.Encode(&thumb, makeThumbnail(img, thumbnailSize), &jpeg.Options{Quality: 80})
+	if err != nil {
+		return uploadedPhoto{}, fmt.Errorf("error encoding thumbnail: %w", err)
+	}
+	return uploadedPhoto{data: data, ext: ext, thumbnail: thumb.Bytes()}, nil
+}
+
+// makeThumbnail scales img down to fit into a size x size square keeping the
+// aspect ratio, every thumbnail pixel is an average of the source pixels it covers
+func makeThumbnail(img image.Image, size int) image.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w <= size && h <= size {
+		size = max(w, h)
+	}
+	tw, th := size, size
+	if w > h {
+		th = max(1, h*size/w)
+	} else {
+		tw = max(1, w*size/h)
+	}
+	thumb := image.NewRGBA(image.Rect(0, 0, tw, th))
+	for y := range th {
+		y0, y1 := b.Min.Y+y*h/th, b.Min.Y+max((y+1)*h/th, y*h/th+1)
+		for x := range tw {
+			x0, x1 := b.Min.X+x*w/tw, b.Min.X+max((x+1)*w/tw, x*w/tw+1)
+			var r, g, bl, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					pr, pg, pb, pa := img.At(sx, sy).RGBA()
+					r, g, bl, a = r+uint64(pr), g+uint64(pg), bl+uint64(pb), a+uint64(pa)
+					n++
+				}
+			}
+			i := thumb.PixOffset(x, y)
+			thumb.Pix[i] = uint8(r / n >> 8)
+			thumb.Pix[i+1] = uint8(g / n >> 8)
+			thumb.Pix[i+2] = uint8(bl / n >> 8)
+			thumb.Pix[i+3] = uint8(a / n >> 8)
+		}
+	}
+	return thumb
+}
+
+// saveReviewPhotos writes photos to the blob store and records them in the database
+func (app *App) saveReviewPhotos(reviewID int, photos []uploadedPhoto) ([]models.ReviewPhoto, error) {
+	saved := make([]models.ReviewPhoto, 0, len(photos))
+	for _, photo := range photos {
+		b := make([]byte, 8)
+		if _, err := rand.Read(b); err != nil {
+			return saved, fmt.Errorf("error generating photo name: %w", err)
+		}
+		name := fmt.Sprintf("reviews/%d/%s", reviewID, hex.EncodeToString(b))
+		p := models.ReviewPhoto{
+			ReviewID:      reviewID,
+			Path:          name + photo.ext,
+			ThumbnailPath: name + "_thumb.jpg",
+		}
+		err := app.Blobs.Save(p.Path, photo.data)
+		if err != nil {
+			return saved, fmt.Errorf("error saving photo: %w", err)
+		}
+		err = app.Blobs.Save(p.ThumbnailPath, photo.thumbnail)
+		if err != nil {
+			app.deletePhotoFiles([]models.ReviewPhoto{p})
+			return saved, fmt.Errorf("error saving thumbnail: %w", err)
+		}
+		added, err := app.DB.AddReviewPhoto(p)
+		if err != nil {
+			app.deletePhotoFiles([]models.ReviewPhoto{p})
+			return saved, fmt.Errorf("error adding photo to database: %w", err)
+		}
+		saved = append(saved, added)
+	}
+	return saved, nil
+}
+
+// deletePhotoFiles removes photo files from the blob store, failures are only
+// logged as the database records are already gone
+func (app *App) deletePhotoFiles(photos []models.ReviewPhoto) {
+	for _, photo := range photos {
+		for _, key := range []string{photo.Path, photo.ThumbnailPath} {
+			err := app.Blobs.Delete(key)
+			if err != nil {
+				app.Log.Errorf("error deleting photo file %s: %v", key, err)
+			}
+		}
+	}
+}
+
+// DeleteReviewPhoto removes a photo from a review
+// @Summary      Delete a Review Photo
+// @Tags         reviews
+// @Security     BearerAuth
+// @Produce      json
+// @Param        smartphone_id path int true "Smartphone ID"
+// @Param        review_id path int true "Review ID"
+// @Param        photo_id path int true "Photo ID"
+// @Success      200  {object}  models.ReviewPhoto
+// @Failure      401  {object}  apperrors.ErrorResponse "Unauthorized"
+// @Failure      403  {object}  apperrors.ErrorResponse "Forbidden"
+// @Failure      404  {object}  apperrors.ErrorResponse "Not Found"
+// @Router       /smartphones/{smartphone_id}/reviews/{review_id}/photos/{photo_id} [delete]
+func (app *App) DeleteReviewPhoto(w http.ResponseWriter, r *http.Request) {
+	userID, role, err := app.GetClaims(r)
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("%w: error extracting claims: %w", apperrors.ErrUnauthorized, err))
+		return
+	}
+	photoID, err := app.ExtractPathValue(r, "photo_id")
+	if err != nil {
+		app.ErrorJSON(w, r, err)
+		return
+	}
+	existingReview, err := app.reviewFromPath(r)
+	if err != nil {
+		app.ErrorJSON(w, r, err)
+		return
+	}
+	if existingReview.UserID != userID && role != models.RoleAdmin {
+		app.ErrorJSON(w, r, apperrors.ErrForbidden)
+		return
+	}
+	found := false
+	for _, photo := range existingReview.Photos {
+		if photo.ID == photoID {
+			found = true
+			break
+		}
+	}
+	if !found {
+		app.ErrorJSON(w, r, fmt.Errorf("%w: photo %d is not attached to review %d",
+			apperrors.ErrNotFound, photoID, existingReview.ID))
+		return
+	}
+	deletedPhoto, err := app.DB.DeleteReviewPhoto(photoID)
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("error deleting photo %d: %w", photoID, err))
+		return
+	}
+	app.deletePhotoFiles([]models.ReviewPhoto{deletedPhoto})
+	app.Encode(w, r, deletedPhoto)
+}
@@ -0,0 +1,163 @@
+package app
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sfu-teamproject/smartbuy/backend/blobstore"
+	"github.com/sfu-teamproject/smartbuy/backend/logger/mocklogger"
+	"github.com/sfu-teamproject/smartbuy/backend/models"
+	"github.com/sfu-teamproject/smartbuy/backend/storage/mockstorage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func testPNG(t *testing.T, w, h int) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := range h {
+		for x := range w {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 100, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	err := png.Encode(&buf, img)
+	assert.NoError(t, err, "encoding png failed")
+	return buf.Bytes()
+}
+
+func TestMakeThumbnail(t *testing.T) {
+	tests := []struct {
+		name       string
+		w, h       int
+		tw, th     int
+		thumbnailW int
+	}{
+		{"Landscape", 1000, 500, 256, 128, 256},
+		{"Portrait", 300, 600, 128, 256, 256},
+		{"Small image is not upscaled", 100, 50, 100, 50, 256},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img := image.NewRGBA(image.Rect(0, 0, tt.w, tt.h))
+			thumb := makeThumbnail(img, tt.thumbnailW)
+			assert.Equal(t, tt.tw, thumb.Bounds().Dx())
+			assert.Equal(t, tt.th, thumb.Bounds().Dy())
+		})
+	}
+}
+
+func TestProcessPhoto(t *testing.T) {
+	t.Run("Valid png", func(t *testing.T) {
+		photo, err := processPhoto(testPNG(t, 400, 300), 400*300)
+		assert.NoError(t, err)
+		assert.Equal(t, ".png", photo.ext)
+		assert.Equal(t, "image/jpeg", http.DetectContentType(photo.thumbnail))
+	})
+	t.Run("Not an image", func(t *testing.T) {
+		_, err := processPhoto([]byte("definitely not an image"), 400*300)
+		assert.Error(t, err)
+	})
+	t.Run("Too many pixels", func(t *testing.T) {
+		_, err := processPhoto(testPNG(t, 400, 301), 400*300)
+		assert.ErrorContains(t, err, "too large")
+	})
+	t.Run("Corrupted image", func(t *testing.T) {
+		data := testPNG(t, 10, 10)
+		_, err := processPhoto(data[:len(data)/2], 400*300)
+		assert.Error(t, err)
+	})
+}
+
+func multipartReview(t *testing.T, rating string, photos ...[]byte) (*bytes.Buffer, string) {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	assert.NoError(t, mw.WriteField("rating", rating))
+	assert.NoError(t, mw.WriteField("comment", "nice photos"))
+	for _, photo := range photos {
+		fw, err := mw.CreateFormFile("photos", "photo.png")
+		assert.NoError(t, err)
+		_, err = fw.Write(photo)
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, mw.Close())
+	return &body, mw.FormDataContentType()
+}
+
+func TestCreateReviewWithPhotos(t *testing.T) {
+	ms := new(mockstorage.MockStorage)
+	ml := new(mocklogger.MockLogger)
+	comment := "nice photos"
+	ms.On("GetSmartphone", 1).Return(models.Smartphone{ID: 1}, nil)
+	ms.On("HasPurchased", 1, 1).Return(false, nil)
+	ms.On("CreateReview", models.Review{SmartphoneID: 1, UserID: 1, Rating: 4, Comment: &comment, FilterAction: "allow",
+		FilterReasons: []string{}}).
+		Return(models.Review{ID: 7, SmartphoneID: 1, UserID: 1, Rating: 4, Comment: &comment}, nil)
+	ms.On("AddReviewPhoto", mock.Anything).Return(models.ReviewPhoto{ID: 1, ReviewID: 7}, nil)
+	ml.On("Errorln", mock.Anything, mock.Anything, mock.Anything)
+
+	app := NewApp(ml, nil, ms)
+	dir := t.TempDir()
+	app.Blobs = blobstore.NewLocalStore(dir)
+	app.reviewMaxPhotos = 2
+	tests := []struct {
+		name   string
+		photos [][]byte
+		code   int
+	}{
+		{"Review with photos", [][]byte{testPNG(t, 50, 50), testPNG(t, 20, 30)}, http.StatusCreated},
+		{"Too many photos", [][]byte{testPNG(t, 5, 5), testPNG(t, 5, 5), testPNG(t, 5, 5)}, http.StatusBadRequest},
+		{"Not an image", [][]byte{[]byte("text file")}, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, contentType := multipartReview(t, "4", tt.photos...)
+			ctx := createContextWithClaims("1", models.RoleUser)
+			r := httptest.NewRequestWithContext(ctx, http.MethodPost, "/", body)
+			r.Header.Set("Content-Type", contentType)
+			r.SetPathValue("smartphone_id", "1")
+			w := httptest.NewRecorder()
+			app.CreateReview(w, r)
+			assert.Equal(t, tt.code, w.Code)
+		})
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "reviews", "7", "*"))
+	assert.NoError(t, err)
+	assert.Len(t, files, 4, "expected 2 photos and 2 thumbnails")
+	ms.AssertNumberOfCalls(t, "AddReviewPhoto", 2)
+}
+
+func TestDeleteReviewRemovesPhotos(t *testing.T) {
+	ms := new(mockstorage.MockStorage)
+	ml := new(mocklogger.MockLogger)
+	dir := t.TempDir()
+	photo := models.ReviewPhoto{ID: 1, ReviewID: 3, Path: "reviews/3/a.png", ThumbnailPath: "reviews/3/a_thumb.jpg"}
+	review := models.Review{ID: 3, SmartphoneID: 1, UserID: 1, Photos: []models.ReviewPhoto{photo}}
+	ms.On("GetReview", 3).Return(review, nil)
+	ms.On("DeleteReview", 3).Return(models.Review{ID: 3, SmartphoneID: 1, UserID: 1}, nil)
+
+	app := NewApp(ml, nil, ms)
+	app.Blobs = blobstore.NewLocalStore(dir)
+	assert.NoError(t, app.Blobs.Save(photo.Path, []byte("photo")))
+	assert.NoError(t, app.Blobs.Save(photo.ThumbnailPath, []byte("thumb")))
+
+	ctx := createContextWithClaims("1", models.RoleUser)
+	r := httptest.NewRequestWithContext(ctx, http.MethodDelete, "/", nil)
+	r.SetPathValue("smartphone_id", "1")
+	r.SetPathValue("review_id", "3")
+	w := httptest.NewRecorder()
+	app.DeleteReview(w, r)
+	assert.Equal(t, http.StatusOK, w.Code)
+	_, err := os.Stat(filepath.Join(dir, "reviews", "3", "a.png"))
+	assert.True(t, os.IsNotExist(err), "photo file is not deleted")
+	_, err = os.Stat(filepath.Join(dir, "reviews", "3", "a_thumb.jpg"))
+	assert.True(t, os.IsNotExist(err), "thumbnail file is not deleted")
+	ms.AssertExpectations(t)
+}
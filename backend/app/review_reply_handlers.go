@@ -0,0 +1,154 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/sfu-teamproject/smartbuy/backend/apperrors"
+	"github.com/sfu-teamproject/smartbuy/backend/mailer"
+	"github.com/sfu-teamproject/smartbuy/backend/models"
+)
+
+// SetReviewReply posts or edits the official reply to a review
+// @Summary      Reply to a Review
+// @Description  Admin only. Creates the reply to a review or edits it if it already exists. The author of the review is notified by email about a new reply.
+// @Tags         reviews
+// @Security     BearerAuth
+// @Accept       json
+// @Produce      json
+// @Param        smartphone_id path int true "Smartphone ID"
+// @Param        review_id path int true "Review ID"
+// @Param        input body models.ReviewReplyRequest true "Reply text"
+// @Success      200  {object}  models.ReviewReply "Reply is edited"
+// @Success      201  {object}  models.ReviewReply "Reply is created"
+// @Failure      400  {object}  apperrors.ErrorResponse "Bad Request"
+// @Failure      403  {object}  apperrors.ErrorResponse "Forbidden"
+// @Failure      404  {object}  apperrors.ErrorResponse "Not Found"
+// @Router       /smartphones/{smartphone_id}/reviews/{review_id}/reply [put]
+func (app *App) SetReviewReply(w http.ResponseWriter, r *http.Request) {
+	userID, role, err := app.GetClaims(r)
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("%w: error extracting claims: %w", apperrors.ErrUnauthorized, err))
+		return
+	}
+	if role != models.RoleAdmin {
+		app.ErrorJSON(w, r, fmt.Errorf("%w: user %d (role %s) does not have required role",
+			apperrors.ErrForbidden, userID, role))
+		return
+	}
+	review, err := app.reviewFromPath(r)
+	if err != nil {
+		app.ErrorJSON(w, r, err)
+		return
+	}
+	var replyreq models.ReviewReplyRequest
+	err = json.NewDecoder(r.Body).Decode(&replyreq)
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("%w: error decoding reply: %w", apperrors.ErrBadRequest, err))
+		return
+	}
+	replyreq.Text = strings.TrimSpace(replyreq.Text)
+	if replyreq.Text == "" {
+		app.ErrorJSON(w, r, fmt.Errorf("%w: empty reply text", apperrors.ErrBadRequest))
+		return
+	}
+	reply := models.ReviewReply{ReviewID: review.ID, UserID: &userID, Text: replyreq.Text}
+	newReply, err := app.DB.SetReviewReply(reply)
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("error saving reply to review %d: %w", review.ID, err))
+		return
+	}
+	if review.Reply != nil {
+		app.Encode(w, r, newReply)
+		return
+	}
+	app.notifyReviewReply(review, newReply)
+	w.WriteHeader(http.StatusCreated)
+	app.Encode(w, r, newReply)
+}
+
+// DeleteReviewReply deletes the official reply to a review
+// @Summary      Delete a Reply to a Review
+// @Description  Admin only.
+// @Tags         reviews
+// @Security     BearerAuth
+// @Produce      json
+// @Param        smartphone_id path int true "Smartphone ID"
+// @Param        review_id path int true "Review ID"
+// @Success      200  {object}  models.ReviewReply
+// @Failure      403  {object}  apperrors.ErrorResponse "Forbidden"
+// @Failure      404  {object}  apperrors.ErrorResponse "Not Found"
+// @Router       /smartphones/{smartphone_id}/reviews/{review_id}/reply [delete]
+func (app *App) DeleteReviewReply(w http.ResponseWriter, r *http.Request) {
+	userID, role, err := app.GetClaims(r)
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("%w: error extracting claims: %w", apperrors.ErrUnauthorized, err))
+		return
+	}
+	if role != models.RoleAdmin {
+		app.ErrorJSON(w, r, fmt.Errorf("%w: user %d (role %s) does not have required role",
+			apperrors.ErrForbidden, userID, role))
+		return
+	}
+	review, err := app.reviewFromPath(r)
+	if err != nil {
+		app.ErrorJSON(w, r, err)
+		return
+	}
+	deletedReply, err := app.DB.DeleteReviewReply(review.ID)
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("error deleting reply to review %d: %w", review.ID, err))
+		return
+	}
+	app.Encode(w, r, deletedReply)
+}
+
+// reviewFromPath gets the review from review_id path value and checks that
+// it belongs to the smartphone from smartphone_id path value
+func (app *App) reviewFromPath(r *http.Request) (models.Review, error) {
+	reviewID, err := app.ExtractPathValue(r, "review_id")
+	if err != nil {
+		return models.Review{}, err
+	}
+	smID, err := app.ExtractPathValue(r, "smartphone_id")
+	if err != nil {
+		return models.Review{}, err
+	}
+	review, err := app.DB.GetReview(reviewID)
+	if err != nil {
+		return models.Review{}, fmt.Errorf("error getting review %d: %w", reviewID, err)
+	}
+	if review.SmartphoneID != smID {
+		return models.Review{}, fmt.Errorf("%w: review %d is not for smartphone %d",
+			apperrors.ErrBadRequest, reviewID, smID)
+	}
+	return review, nil
+}
+
+// notifyReviewReply emails the author of a review about the reply, failures
+// are logged and do not fail the request
+func (app *App) notifyReviewReply(review models.Review, reply models.ReviewReply) {
+	author, err := app.DB.GetUser(review.UserID)
+	if err != nil {
+		app.Log.Errorf("error getting author of review %d: %v", review.ID, err)
+		return
+	}
+	sm, err := app.DB.GetSmartphone(review.SmartphoneID)
+	if err != nil {
+		app.Log.Errorf("error getting smartphone %d: %v", review.SmartphoneID, err)
+		return
+	}
+	body := "Здравствуйте, " + author.Name + "!\n" +
+		"Магазин Smartbuy ответил на ваш отзыв о смартфоне " + sm.Model + ":\n\n" +
+		reply.Text
+	err = app.Mail.Send(mailer.Message{
+		To:      author.Email,
+		Subject: "Smartbuy: ответ на ваш отзыв",
+		Body:    body,
+	})
+	if err != nil {
+		app.Log.Errorf("error sending reply notification to %s: %v", author.Email, err)
+	}
+}
@@ -0,0 +1,67 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sfu-teamproject/smartbuy/backend/logger/mocklogger"
+	"github.com/sfu-teamproject/smartbuy/backend/mailer"
+	"github.com/sfu-teamproject/smartbuy/backend/mailer/mockmailer"
+	"github.com/sfu-teamproject/smartbuy/backend/models"
+	"github.com/sfu-teamproject/smartbuy/backend/storage/mockstorage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestSetReviewReply(t *testing.T) {
+	ms := new(mockstorage.MockStorage)
+	ml := new(mocklogger.MockLogger)
+	mm := new(mockmailer.MockMailer)
+	adminID := 1
+	existingReply := &models.ReviewReply{ID: 1, ReviewID: 2, UserID: &adminID, Text: "old"}
+	ms.On("GetReview", 1).Return(models.Review{ID: 1, SmartphoneID: 1, UserID: 2}, nil)
+	ms.On("GetReview", 2).Return(models.Review{ID: 2, SmartphoneID: 1, UserID: 2, Reply: existingReply}, nil)
+	ms.On("SetReviewReply", models.ReviewReply{ReviewID: 1, UserID: &adminID, Text: "Thank you!"}).
+		Return(models.ReviewReply{ID: 2, ReviewID: 1, UserID: &adminID, Text: "Thank you!"}, nil)
+	ms.On("SetReviewReply", models.ReviewReply{ReviewID: 2, UserID: &adminID, Text: "Thank you!"}).
+		Return(models.ReviewReply{ID: 1, ReviewID: 2, UserID: &adminID, Text: "Thank you!"}, nil)
+	ms.On("GetUser", 2).Return(models.User{ID: 2, Name: "user1", Email: "user1@example.com"}, nil)
+	ms.On("GetSmartphone", 1).Return(models.Smartphone{ID: 1, Model: "iPhone 16"}, nil)
+	mm.On("Send", mock.MatchedBy(func(msg mailer.Message) bool {
+		return msg.To == "user1@example.com"
+	})).Return(nil).Once()
+	ml.On("Errorln", mock.Anything, mock.Anything, mock.Anything)
+
+	app := NewApp(ml, nil, ms)
+	app.Mail = mm
+	tests := []struct {
+		name     string
+		reviewID string
+		role     models.Role
+		text     string
+		code     int
+	}{
+		{"Admin replies", "1", models.RoleAdmin, "Thank you!", http.StatusCreated},
+		{"Admin edits reply", "2", models.RoleAdmin, "Thank you!", http.StatusOK},
+		{"User replies", "1", models.RoleUser, "Thank you!", http.StatusForbidden},
+		{"Empty reply", "1", models.RoleAdmin, "  ", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jsonBytes, err := json.Marshal(models.ReviewReplyRequest{Text: tt.text})
+			assert.NoError(t, err, "Marshalling reply failed")
+			ctx := createContextWithClaims("1", tt.role)
+			r := httptest.NewRequestWithContext(ctx, http.MethodPut, "/", bytes.NewBuffer(jsonBytes))
+			r.SetPathValue("smartphone_id", "1")
+			r.SetPathValue("review_id", tt.reviewID)
+			w := httptest.NewRecorder()
+			app.SetReviewReply(w, r)
+			assert.Equal(t, tt.code, w.Code)
+		})
+	}
+	ms.AssertExpectations(t)
+	mm.AssertExpectations(t)
+}
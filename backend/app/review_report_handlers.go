@@ -0,0 +1,151 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/sfu-teamproject/smartbuy/backend/apperrors"
+	"github.com/sfu-teamproject/smartbuy/backend/models"
+)
+
+// CreateReviewReport flags a review as abusive
+// @Summary      Report a Review
+// @Description  Reason is one of spam, offensive, other. A user can report a review only once.
+// @Description  The review is hidden until moderation when the number of reports reaches the threshold.
+// @Tags         reviews
+// @Security     BearerAuth
+// @Accept       json
+// @Produce      json
+// @Param        smartphone_id path int true "Smartphone ID"
+// @Param        review_id path int true "Review ID"
+// @Param        input body models.ReviewReportRequest true "Report reason"
+// @Success      201  {object}  models.ReviewReport
+// @Failure      400  {object}  apperrors.ErrorResponse "Bad Request"
+// @Failure      401  {object}  apperrors.ErrorResponse "Unauthorized"
+// @Failure      404  {object}  apperrors.ErrorResponse "Not Found"
+// @Failure      409  {object}  apperrors.ErrorResponse "Already reported"
+// @Router       /smartphones/{smartphone_id}/reviews/{review_id}/reports [post]
+func (app *App) CreateReviewReport(w http.ResponseWriter, r *http.Request) {
+	userID, _, err := app.GetClaims(r)
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("%w: error extracting claims: %w", apperrors.ErrUnauthorized, err))
+		return
+	}
+	review, err := app.reviewFromPath(r)
+	if err != nil {
+		app.ErrorJSON(w, r, err)
+		return
+	}
+	if review.Hidden {
+		app.ErrorJSON(w, r, fmt.Errorf("%w: review %d is hidden", apperrors.ErrNotFound, review.ID))
+		return
+	}
+	if review.UserID == userID {
+		app.ErrorJSON(w, r, fmt.Errorf("%w: user %d can not report own review %d",
+			apperrors.ErrBadRequest, userID, review.ID))
+		return
+	}
+	var reportreq models.ReviewReportRequest
+	err = json.NewDecoder(r.Body).Decode(&reportreq)
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("%w: error decoding report: %w", apperrors.ErrBadRequest, err))
+		return
+	}
+	if !reportreq.Reason.IsValid() {
+		app.ErrorJSON(w, r, fmt.Errorf("%w: invalid report reason(%s)", apperrors.ErrBadRequest, reportreq.Reason))
+		return
+	}
+	report := models.ReviewReport{
+		ReviewID: review.ID,
+		UserID:   userID,
+		Reason:   reportreq.Reason,
+		Comment:  reportreq.Comment,
+	}
+	newReport, err := app.DB.CreateReviewReport(report)
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("error reporting review %d: %w", review.ID, err))
+		return
+	}
+	hidden, err := app.DB.HideReportedReview(review.ID, app.reviewReportThreshold)
+	if err != nil {
+		app.Log.Errorf("error hiding reported review %d: %v", review.ID, err)
+	} else if hidden {
+		app.Log.Infof("review %d is hidden after %d reports", review.ID, app.reviewReportThreshold)
+	}
+	w.WriteHeader(http.StatusCreated)
+	app.Encode(w, r, newReport)
+}
+
+// GetReportedReviews lists reviews waiting for moderation
+// @Summary      List Reported Reviews
+// @Description  Admin only. Returns hidden reviews and reviews with reports, the most reported first.
+// @Tags         reviews
+// @Security     BearerAuth
+// @Produce      json
+// @Success      200  {array}   models.ReportedReview
+// @Failure      401  {object}  apperrors.ErrorResponse "Unauthorized"
+// @Failure      403  {object}  apperrors.ErrorResponse "Forbidden"
+// @Router       /reviews/reported [get]
+func (app *App) GetReportedReviews(w http.ResponseWriter, r *http.Request) {
+	userID, role, err := app.GetClaims(r)
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("%w: error extracting claims: %w", apperrors.ErrUnauthorized, err))
+		return
+	}
+	if role != models.RoleAdmin {
+		app.ErrorJSON(w, r, fmt.Errorf("%w: user %d (role %s) does not have required role",
+			apperrors.ErrForbidden, userID, role))
+		return
+	}
+	reviews, err := app.DB.GetReportedReviews()
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("error getting reported reviews: %w", err))
+		return
+	}
+	app.Encode(w, r, reviews)
+}
+
+// ModerateReview hides or restores a reported review
+// @Summary      Moderate a Reported Review
+// @Description  Admin only. Sets visibility of the review and resolves all its reports.
+// @Tags         reviews
+// @Security     BearerAuth
+// @Accept       json
+// @Produce      json
+// @Param        review_id path int true "Review ID"
+// @Param        input body models.ModerateReviewRequest true "Moderation decision"
+// @Success      200  {object}  models.Review
+// @Failure      400  {object}  apperrors.ErrorResponse "Bad Request"
+// @Failure      403  {object}  apperrors.ErrorResponse "Forbidden"
+// @Failure      404  {object}  apperrors.ErrorResponse "Not Found"
+// @Router       /reviews/reported/{review_id} [patch]
+func (app *App) ModerateReview(w http.ResponseWriter, r *http.Request) {
+	userID, role, err := app.GetClaims(r)
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("%w: error extracting claims: %w", apperrors.ErrUnauthorized, err))
+		return
+	}
+	if role != models.RoleAdmin {
+		app.ErrorJSON(w, r, fmt.Errorf("%w: user %d (role %s) does not have required role",
+			apperrors.ErrForbidden, userID, role))
+		return
+	}
+	reviewID, err := app.ExtractPathValue(r, "review_id")
+	if err != nil {
+		app.ErrorJSON(w, r, err)
+		return
+	}
+	var modreq models.ModerateReviewRequest
+	err = json.NewDecoder(r.Body).Decode(&modreq)
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("%w: error decoding moderation decision: %w", apperrors.ErrBadRequest, err))
+		return
+	}
+	review, err := app.DB.ModerateReview(reviewID, modreq.Hidden)
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("error moderating review %d: %w", reviewID, err))
+		return
+	}
+	app.Encode(w, r, review)
+}
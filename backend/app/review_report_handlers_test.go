@@ -0,0 +1,87 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sfu-teamproject/smartbuy/backend/logger/mocklogger"
+	"github.com/sfu-teamproject/smartbuy/backend/models"
+	"github.com/sfu-teamproject/smartbuy/backend/storage/mockstorage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestCreateReviewReport(t *testing.T) {
+	ms := new(mockstorage.MockStorage)
+	ml := new(mocklogger.MockLogger)
+	ms.On("GetReview", 1).Return(models.Review{ID: 1, SmartphoneID: 1, UserID: 2}, nil)
+	ms.On("GetReview", 2).Return(models.Review{ID: 2, SmartphoneID: 1, UserID: 2, Hidden: true}, nil)
+	ms.On("CreateReviewReport", models.ReviewReport{ReviewID: 1, UserID: 1, Reason: models.ReportReasonSpam}).
+		Return(models.ReviewReport{ID: 1, ReviewID: 1, UserID: 1, Reason: models.ReportReasonSpam}, nil)
+	ms.On("HideReportedReview", 1, 3).Return(true, nil).Once()
+	ml.On("Errorln", mock.Anything, mock.Anything, mock.Anything)
+	ml.On("Infof", mock.Anything, mock.Anything)
+
+	app := NewApp(ml, nil, ms)
+	app.reviewReportThreshold = 3
+	tests := []struct {
+		name     string
+		userID   string
+		reviewID string
+		reason   models.ReportReason
+		code     int
+	}{
+		{"Report spam", "1", "1", models.ReportReasonSpam, http.StatusCreated},
+		{"Invalid reason", "1", "1", "boring", http.StatusBadRequest},
+		{"Report own review", "2", "1", models.ReportReasonSpam, http.StatusBadRequest},
+		{"Report hidden review", "1", "2", models.ReportReasonSpam, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jsonBytes, err := json.Marshal(models.ReviewReportRequest{Reason: tt.reason})
+			assert.NoError(t, err, "Marshalling report failed")
+			ctx := createContextWithClaims(tt.userID, models.RoleUser)
+			r := httptest.NewRequestWithContext(ctx, http.MethodPost, "/", bytes.NewBuffer(jsonBytes))
+			r.SetPathValue("smartphone_id", "1")
+			r.SetPathValue("review_id", tt.reviewID)
+			w := httptest.NewRecorder()
+			app.CreateReviewReport(w, r)
+			assert.Equal(t, tt.code, w.Code)
+		})
+	}
+	ms.AssertNumberOfCalls(t, "CreateReviewReport", 1)
+	ml.AssertCalled(t, "Infof", mock.Anything, mock.Anything)
+}
+
+func TestModerateReview(t *testing.T) {
+	ms := new(mockstorage.MockStorage)
+	ml := new(mocklogger.MockLogger)
+	ms.On("ModerateReview", 1, false).Return(models.Review{ID: 1, SmartphoneID: 1, UserID: 2}, nil)
+	ml.On("Errorln", mock.Anything, mock.Anything, mock.Anything)
+
+	app := NewApp(ml, nil, ms)
+	tests := []struct {
+		name string
+		role models.Role
+		code int
+	}{
+		{"Admin restores review", models.RoleAdmin, http.StatusOK},
+		{"User moderates review", models.RoleUser, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jsonBytes, err := json.Marshal(models.ModerateReviewRequest{Hidden: false})
+			assert.NoError(t, err, "Marshalling request failed")
+			ctx := createContextWithClaims("1", tt.role)
+			r := httptest.NewRequestWithContext(ctx, http.MethodPatch, "/", bytes.NewBuffer(jsonBytes))
+			r.SetPathValue("review_id", "1")
+			w := httptest.NewRecorder()
+			app.ModerateReview(w, r)
+			assert.Equal(t, tt.code, w.Code)
+		})
+	}
+	ms.AssertNumberOfCalls(t, "ModerateReview", 1)
+}
@@ -14,30 +14,87 @@ func (app *App) NewRouter() http.Handler {
 
 	router.HandleFunc("GET /api/v1/smartphones", app.GetSmartphones)
 	router.HandleFunc("GET /api/v1/smartphones/{smartphone_id}", app.GetSmartphone)
+	router.HandleFunc("POST /api/v1/smartphones/ratings/reconcile", app.Auth(app.ReconcileRatings))
 
 	router.HandleFunc("GET /api/v1/users", app.Auth(app.GetUsers))
 	router.HandleFunc("GET /api/v1/users/{user_id}", app.Auth(app.GetUser))
-	router.HandleFunc("POST /api/v1/login", app.Login)
+	router.HandleFunc("POST /api/v1/login", app.RateLimit(app.loginLimits, app.Login))
 	router.HandleFunc("POST /api/v1/signup", app.Signup)
+	router.HandleFunc("POST /api/v1/token/refresh", app.RefreshToken)
+	router.HandleFunc("POST /api/v1/logout", app.Auth(app.Logout))
+	router.HandleFunc("POST /api/v1/logout/all", app.Auth(app.LogoutAll))
 	router.HandleFunc("PATCH /api/v1/users/{user_id}", app.Auth(app.UpdateUser))
 	router.HandleFunc("DELETE /api/v1/users/{user_id}", app.Auth(app.DeleteUser))
-	router.HandleFunc("POST /api/v1/users/restore", app.SendTmpPassword)
+	router.HandleFunc("POST /api/v1/users/restore", app.RateLimit(app.restoreLimits, app.SendTmpPassword))
+	router.HandleFunc("POST /api/v1/users/restore/confirm", app.RateLimit(app.resetLimits, app.ResetPassword))
+	router.HandleFunc("GET /api/v1/users/{user_id}/cart-reminders/unsubscribe", app.UnsubscribeCartReminders)
+
+	router.HandleFunc("GET /api/v1/users/{user_id}/purchases", app.Auth(app.GetPurchases))
+	router.HandleFunc("POST /api/v1/users/{user_id}/purchases", app.Auth(app.CreatePurchase))
+
+	router.HandleFunc("GET /api/v1/users/{user_id}/addresses", app.Auth(app.GetAddresses))
+	router.HandleFunc("POST /api/v1/users/{user_id}/addresses", app.Auth(app.CreateAddress))
+	router.HandleFunc("GET /api/v1/users/{user_id}/addresses/{address_id}", app.Auth(app.GetAddress))
+	router.HandleFunc("PUT /api/v1/users/{user_id}/addresses/{address_id}", app.Auth(app.UpdateAddress))
+	router.HandleFunc("DELETE /api/v1/users/{user_id}/addresses/{address_id}", app.Auth(app.DeleteAddress))
 
 	router.HandleFunc("GET /api/v1/smartphones/{smartphone_id}/reviews", app.GetReviews)
 	router.HandleFunc("GET /api/v1/smartphones/{smartphone_id}/reviews/{review_id}", app.GetReview)
 	router.HandleFunc("POST /api/v1/smartphones/{smartphone_id}/reviews", app.Auth(app.CreateReview))
 	router.HandleFunc("PATCH /api/v1/smartphones/{smartphone_id}/reviews/{review_id}", app.Auth(app.UpdateReview))
 	router.HandleFunc("DELETE /api/v1/smartphones/{smartphone_id}/reviews/{review_id}", app.Auth(app.DeleteReview))
+	router.HandleFunc("DELETE /api/v1/smartphones/{smartphone_id}/reviews/{review_id}/photos/{photo_id}",
+		app.Auth(app.DeleteReviewPhoto))
+	router.HandleFunc("PUT /api/v1/smartphones/{smartphone_id}/reviews/{review_id}/reply", app.Auth(app.SetReviewReply))
+	router.HandleFunc("DELETE /api/v1/smartphones/{smartphone_id}/reviews/{review_id}/reply", app.Auth(app.DeleteReviewReply))
+	router.HandleFunc("POST /api/v1/smartphones/{smartphone_id}/reviews/{review_id}/reports",
+		app.Auth(app.CreateReviewReport))
+	router.HandleFunc("GET /api/v1/reviews/reported", app.Auth(app.GetReportedReviews))
+	router.HandleFunc("PATCH /api/v1/reviews/reported/{review_id}", app.Auth(app.ModerateReview))
+	router.Handle("GET /uploads/", app.ServeUploads())
 
 	router.HandleFunc("GET /api/v1/carts", app.Auth(app.GetCarts))
-	router.HandleFunc("GET /api/v1/carts/{cart_id}", app.Auth(app.GetCart))
+	router.HandleFunc("POST /api/v1/carts/guest", app.RateLimit(app.guestCartLimits, app.CreateGuestCart))
+	router.HandleFunc("GET /api/v1/carts/{cart_id}", app.OptionalAuth(app.GetCart))
+
+	router.HandleFunc("GET /api/v1/carts/{cart_id}/items", app.OptionalAuth(app.GetCartItems))
+	router.HandleFunc("POST /api/v1/carts/{cart_id}/items", app.OptionalAuth(app.AddToCart))
+	router.HandleFunc("PUT /api/v1/carts/{cart_id}/items", app.OptionalAuth(app.ReplaceCartItems))
+	router.HandleFunc("DELETE /api/v1/carts/{cart_id}/items", app.OptionalAuth(app.ClearCart))
+	router.HandleFunc("PATCH /api/v1/carts/{cart_id}/items/{item_id}", app.OptionalAuth(app.SetQuantity))
+	router.HandleFunc("PATCH /api/v1/carts/{cart_id}/items/{item_id}/state", app.OptionalAuth(app.SetCartItemState))
+	router.HandleFunc("DELETE /api/v1/carts/{cart_id}/items/{item_id}", app.OptionalAuth(app.DeleteFromCart))
+
+	router.HandleFunc("GET /api/v1/carts/{cart_id}/shipping", app.OptionalAuth(app.GetShippingQuotes))
+	router.HandleFunc("GET /api/v1/delivery-methods", app.OptionalAuth(app.GetDeliveryMethods))
+	router.HandleFunc("POST /api/v1/delivery-methods", app.Auth(app.CreateDeliveryMethod))
+	router.HandleFunc("PUT /api/v1/delivery-methods/{method_id}", app.Auth(app.UpdateDeliveryMethod))
+	router.HandleFunc("DELETE /api/v1/delivery-methods/{method_id}", app.Auth(app.DeleteDeliveryMethod))
 
-	router.HandleFunc("GET /api/v1/carts/{cart_id}/items", app.Auth(app.GetCartItems))
-	router.HandleFunc("POST /api/v1/carts/{cart_id}/items", app.Auth(app.AddToCart))
-	router.HandleFunc("PATCH /api/v1/carts/{cart_id}/items/{item_id}", app.Auth(app.SetQuantity))
-	router.HandleFunc("DELETE /api/v1/carts/{cart_id}/items/{item_id}", app.Auth(app.DeleteFromCart))
+	router.HandleFunc("PUT /api/v1/carts/{cart_id}/promo", app.Auth(app.ApplyPromoCode))
+	router.HandleFunc("DELETE /api/v1/carts/{cart_id}/promo", app.Auth(app.RemovePromoCode))
+	router.HandleFunc("GET /api/v1/promo-codes", app.Auth(app.GetPromoCodes))
+	router.HandleFunc("POST /api/v1/promo-codes", app.Auth(app.CreatePromoCode))
+	router.HandleFunc("DELETE /api/v1/promo-codes/{promo_id}", app.Auth(app.DeletePromoCode))
+
+	router.HandleFunc("GET /api/v1/orders", app.Auth(app.GetOrders))
+	router.HandleFunc("GET /api/v1/orders/{order_id}", app.Auth(app.GetOrder))
+	router.HandleFunc("GET /api/v1/orders/{order_id}/invoice", app.Auth(app.GetOrderInvoice))
+	router.HandleFunc("POST /api/v1/orders", app.Auth(app.CreateOrder))
+	router.HandleFunc("PATCH /api/v1/orders/{order_id}/status", app.Auth(app.SetOrderStatus))
+	router.HandleFunc("POST /api/v1/orders/{order_id}/payments", app.Auth(app.CreatePayment))
+
+	router.HandleFunc("GET /api/v1/returns", app.Auth(app.GetReturns))
+	router.HandleFunc("GET /api/v1/returns/{return_id}", app.Auth(app.GetReturn))
+	router.HandleFunc("GET /api/v1/returns/{return_id}/photos/{file}", app.Auth(app.GetReturnPhoto))
+	router.HandleFunc("POST /api/v1/returns", app.Auth(app.CreateReturn))
+	router.HandleFunc("PATCH /api/v1/returns/{return_id}/status", app.Auth(app.SetReturnStatus))
+
+	router.HandleFunc("POST /api/v1/payments/webhook", app.PaymentWebhook)
 
 	router.HandleFunc("POST /api/v1/language", app.SetLanguage)
 
-	return app.RecoverPanic(app.LogRequests(router))
+	router.HandleFunc("GET /api/v1/admin/export", app.Auth(app.Export))
+
+	return app.RecoverPanic(app.LogRequests(app.Idempotency(router)))
 }
@@ -1,8 +1,10 @@
 package app
 
 import (
+	"cmp"
 	"fmt"
 	"net/http"
+	"slices"
 	"strconv"
 	"strings"
 
@@ -40,17 +42,23 @@ func (app *App) GetSmartphone(w http.ResponseWriter, r *http.Request) {
 
 // GetSmartphones lists smartphones
 // @Summary      List Smartphones
-// @Description  Get a list of all smartphones or filter by IDs
+// @Description  Get a list of all smartphones or filter by IDs. Sorted by price unless sort is rating or score (best first)
 // @Tags         smartphones
 // @Accept       json
 // @Produce      json
 // @Param        ids  query string false "Comma separated IDs (e.g. 1,2,3)"
+// @Param        sort query string false "Sort order" Enums(price, rating, score)
 // @Success      200  {array}   models.Smartphone
 // @Failure      400  {object}  apperrors.ErrorResponse "Bad Request"
 // @Router       /smartphones [get]
 func (app *App) GetSmartphones(w http.ResponseWriter, r *http.Request) {
 	var sm []models.Smartphone
 	var err error
+	sortBy := r.URL.Query().Get("sort")
+	if sortBy != "" && sortBy != "price" && sortBy != "rating" && sortBy != "score" {
+		app.ErrorJSON(w, r, fmt.Errorf("%w: invalid sort value(%s)", apperrors.ErrBadRequest, sortBy))
+		return
+	}
 	IDsParam := r.URL.Query().Get("ids")
 	if IDsParam == "" {
 		sm, err = app.DB.GetSmartphones()
@@ -70,5 +78,41 @@ func (app *App) GetSmartphones(w http.ResponseWriter, r *http.Request) {
 		app.ErrorJSON(w, r, fmt.Errorf("error getting smartphones: %w", err))
 		return
 	}
+	switch sortBy {
+	case "rating":
+		slices.SortStableFunc(sm, func(a, b models.Smartphone) int { return cmp.Compare(b.Rating, a.Rating) })
+	case "score":
+		slices.SortStableFunc(sm, func(a, b models.Smartphone) int { return cmp.Compare(b.Score, a.Score) })
+	}
 	app.Encode(w, r, sm)
 }
+
+// ReconcileRatings recomputes ratings of all smartphones from their reviews
+// @Summary      Reconcile Smartphone Ratings
+// @Description  Admin only. Recomputes ratings_sum and ratings_count of all smartphones from reviews and returns the smartphones that were fixed.
+// @Tags         smartphones
+// @Security     BearerAuth
+// @Produce      json
+// @Success      200  {array}   models.Smartphone
+// @Failure      401  {object}  apperrors.ErrorResponse "Unauthorized"
+// @Failure      403  {object}  apperrors.ErrorResponse "Forbidden"
+// @Router       /smartphones/ratings/reconcile [post]
+func (app *App) ReconcileRatings(w http.ResponseWriter, r *http.Request) {
+	userID, role, err := app.GetClaims(r)
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("%w: error extracting claims: %w", apperrors.ErrUnauthorized, err))
+		return
+	}
+	if role != models.RoleAdmin {
+		app.ErrorJSON(w, r, fmt.Errorf("%w: user %d (role %s) does not have required role",
+			apperrors.ErrForbidden, userID, role))
+		return
+	}
+	fixed, err := app.DB.ReconcileRatings()
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("error reconciling ratings: %w", err))
+		return
+	}
+	app.Log.Infof("ratings of %d smartphones reconciled", len(fixed))
+	app.Encode(w, r, fixed)
+}
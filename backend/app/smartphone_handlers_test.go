@@ -81,3 +81,76 @@ func TestGetSmartphone(t *testing.T) {
 	}
 	ms.AssertExpectations(t)
 }
+
+func TestGetSmartphonesSorted(t *testing.T) {
+	ms := new(mockstorage.MockStorage)
+	ml := new(mocklogger.MockLogger)
+	sms := []models.Smartphone{
+		{ID: 1, Price: 100, Rating: 5, Score: 4.2},
+		{ID: 2, Price: 200, Rating: 4.5, Score: 4.4},
+		{ID: 3, Price: 300, Rating: 3, Score: 3.5},
+	}
+	ms.On("GetSmartphones").Return(sms, nil)
+	ml.On("Errorln", mock.Anything, mock.Anything, mock.Anything)
+
+	app := NewApp(ml, nil, ms)
+	tests := []struct {
+		name string
+		sort string
+		code int
+		IDs  []int
+	}{
+		{"Default sort", "", http.StatusOK, []int{1, 2, 3}},
+		{"Sort by rating", "rating", http.StatusOK, []int{1, 2, 3}},
+		{"Sort by score", "score", http.StatusOK, []int{2, 1, 3}},
+		{"Invalid sort", "name", http.StatusBadRequest, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/?sort="+tt.sort, nil)
+			w := httptest.NewRecorder()
+			app.GetSmartphones(w, r)
+			assert.Equal(t, tt.code, w.Code)
+			if tt.code != http.StatusOK {
+				return
+			}
+			var resp []models.Smartphone
+			err := json.NewDecoder(w.Body).Decode(&resp)
+			assert.NoError(t, err, "Decoding smartphones failed")
+			IDs := make([]int, len(resp))
+			for i, sm := range resp {
+				IDs[i] = sm.ID
+			}
+			assert.Equal(t, tt.IDs, IDs)
+		})
+	}
+}
+
+func TestReconcileRatings(t *testing.T) {
+	ms := new(mockstorage.MockStorage)
+	ml := new(mocklogger.MockLogger)
+	fixed := []models.Smartphone{{ID: 1, RatingsSum: 9, RatingsCount: 2}}
+	ms.On("ReconcileRatings").Return(fixed, nil)
+	ml.On("Errorln", mock.Anything, mock.Anything, mock.Anything)
+	ml.On("Infof", mock.Anything, mock.Anything)
+
+	app := NewApp(ml, nil, ms)
+	tests := []struct {
+		name string
+		role models.Role
+		code int
+	}{
+		{"Admin reconciles ratings", models.RoleAdmin, http.StatusOK},
+		{"User reconciles ratings", models.RoleUser, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := createContextWithClaims("1", tt.role)
+			r := httptest.NewRequestWithContext(ctx, http.MethodPost, "/", nil)
+			w := httptest.NewRecorder()
+			app.ReconcileRatings(w, r)
+			assert.Equal(t, tt.code, w.Code)
+		})
+	}
+	ms.AssertNumberOfCalls(t, "ReconcileRatings", 1)
+}
@@ -0,0 +1,36 @@
+package app
+
+import "github.com/sfu-teamproject/smartbuy/backend/models"
+
+// attachCartTax computes VAT of the cart items and their total. Must be
+// called after the discount is attached, the tax is taken from discounted
+// line costs
+func (app *App) attachCartTax(cart *models.Cart) {
+	cart.Tax = app.applyTax(cart.Items, cart.Discount)
+}
+
+// applyTax sets the VAT of every item that is not saved for later and returns
+// the total. Items without smartphone details are taxed with the default rate
+func (app *App) applyTax(items []models.CartItem, discount *models.DiscountBreakdown) *models.TaxBreakdown {
+	lineDiscounts := map[int]int{}
+	if discount != nil {
+		for _, line := range discount.Lines {
+			lineDiscounts[line.SmartphoneID] += line.Discount
+		}
+	}
+	lines := []models.LineTax{}
+	for i := range items {
+		if items[i].State == models.CartItemSaved {
+			continue
+		}
+		category := ""
+		if items[i].Smartphone != nil {
+			category = items[i].Smartphone.Category
+		}
+		line := app.tax.Line(category, items[i].LineTotal-lineDiscounts[items[i].SmartphoneID])
+		items[i].Tax = &line
+		lines = append(lines, line)
+	}
+	total := app.tax.Total(lines)
+	return &total
+}
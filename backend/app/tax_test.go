@@ -0,0 +1,112 @@
+package app
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sfu-teamproject/smartbuy/backend/logger/mocklogger"
+	"github.com/sfu-teamproject/smartbuy/backend/models"
+	"github.com/sfu-teamproject/smartbuy/backend/storage/mockstorage"
+	"github.com/sfu-teamproject/smartbuy/backend/tax"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func taxedCartMocks() (*mockstorage.MockStorage, *mocklogger.MockLogger) {
+	ms := new(mockstorage.MockStorage)
+	ml := new(mocklogger.MockLogger)
+	promoID := 1
+	ms.On("GetCart", 1).Return(models.Cart{ID: 1, UserID: 1, PromoCodeID: &promoID}, nil)
+	ms.On("GetCartItems", 1).Return([]models.CartItem{
+		{ID: 1, CartID: 1, SmartphoneID: 1, Quantity: 1, LineTotal: 1200,
+			Smartphone: &models.SmartphoneSummary{ID: 1, Price: 1200, Category: "smartphone"}},
+		{ID: 2, CartID: 1, SmartphoneID: 2, Quantity: 2, LineTotal: 1100,
+			Smartphone: &models.SmartphoneSummary{ID: 2, Price: 550, Category: "accessory"}},
+		{ID: 3, CartID: 1, SmartphoneID: 3, Quantity: 1, LineTotal: 700, State: models.CartItemSaved,
+			Smartphone: &models.SmartphoneSummary{ID: 3, Price: 700, Category: "smartphone"}},
+	}, nil)
+	ms.On("GetPromoCode", 1).Return(models.PromoCode{ID: 1, Code: "TEN", Type: models.DiscountPercent, Value: 10}, nil)
+	ms.On("GetSmartphonesByIDs", []int{1, 2}).Return([]models.Smartphone{
+		{ID: 1, Price: 1200}, {ID: 2, Price: 550},
+	}, nil)
+	ms.On("GetPromoCodeUserUsage", 1, 1).Return(0, nil)
+	ml.On("Errorln", mock.Anything, mock.Anything, mock.Anything)
+	return ms, ml
+}
+
+func TestGetCartTax(t *testing.T) {
+	ms, ml := taxedCartMocks()
+	app := NewApp(ml, nil, ms)
+	app.tax = tax.Config{Rates: map[string]float64{"smartphone": 20, "accessory": 10}, PricesIncludeTax: true}
+
+	ctx := createContextWithClaims("1", models.RoleUser)
+	r := httptest.NewRequestWithContext(ctx, http.MethodGet, "/", nil)
+	r.SetPathValue("cart_id", "1")
+	w := httptest.NewRecorder()
+	app.GetCart(w, r)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var cart models.Cart
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&cart))
+	// taxes are taken from costs after the 10% discount
+	assert.Equal(t, &models.LineTax{Category: "smartphone", Rate: 20, Net: 900, Tax: 180, Gross: 1080}, cart.Items[0].Tax)
+	assert.Equal(t, &models.LineTax{Category: "accessory", Rate: 10, Net: 900, Tax: 90, Gross: 990}, cart.Items[1].Tax)
+	assert.Nil(t, cart.SavedItems[0].Tax)
+	assert.Equal(t, &models.TaxBreakdown{
+		PricesIncludeTax: true, Net: 1800, Tax: 270, Gross: 2070,
+		Rates: []models.RateTax{{Rate: 20, Net: 900, Tax: 180}, {Rate: 10, Net: 900, Tax: 90}},
+	}, cart.Tax)
+}
+
+func TestGetCartItemsTax(t *testing.T) {
+	ms, ml := taxedCartMocks()
+	app := NewApp(ml, nil, ms)
+	app.tax = tax.Config{DefaultRate: 20}
+
+	ctx := createContextWithClaims("1", models.RoleUser)
+	r := httptest.NewRequestWithContext(ctx, http.MethodGet, "/", nil)
+	r.SetPathValue("cart_id", "1")
+	w := httptest.NewRecorder()
+	app.GetCartItems(w, r)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var items []models.CartItem
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&items))
+	assert.Len(t, items, 3)
+	// the tax is added on top of prices
+	assert.Equal(t, &models.LineTax{Category: "smartphone", Rate: 20, Net: 1080, Tax: 216, Gross: 1296}, items[0].Tax)
+	assert.Equal(t, &models.LineTax{Category: "accessory", Rate: 20, Net: 990, Tax: 198, Gross: 1188}, items[1].Tax)
+	assert.Nil(t, items[2].Tax)
+}
+
+func TestCreateOrderTax(t *testing.T) {
+	ms := new(mockstorage.MockStorage)
+	ml := new(mocklogger.MockLogger)
+	cartItems := []models.CartItem{
+		{ID: 1, CartID: 1, SmartphoneID: 1, Quantity: 1, AddedPrice: 1000},
+		{ID: 2, CartID: 1, SmartphoneID: 2, Quantity: 2, AddedPrice: 50},
+	}
+	ms.On("GetCartByUserID", 1).Return(models.Cart{ID: 1, UserID: 1}, nil)
+	ms.On("GetCartItems", 1).Return(cartItems, nil)
+	ms.On("GetSmartphonesByIDs", []int{1, 2}).Return([]models.Smartphone{
+		{ID: 1, Model: "Phone", Price: 1000, Category: "smartphone"},
+		{ID: 2, Model: "Case", Price: 50, Category: "accessory"},
+	}, nil)
+	ms.On("CreateOrder", mock.Anything, cartItems).Return(models.Order{ID: 1, UserID: 1, Total: 1310}, nil)
+	ml.On("Errorln", mock.Anything, mock.Anything, mock.Anything)
+	app := NewApp(ml, nil, ms)
+	app.tax = tax.Config{Rates: map[string]float64{"smartphone": 20, "accessory": 10}}
+
+	ctx := createContextWithClaims("1", models.RoleUser)
+	r := httptest.NewRequestWithContext(ctx, http.MethodPost, "/", nil)
+	w := httptest.NewRecorder()
+	app.CreateOrder(w, r)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	// the tax is added on top of prices, the customer pays the gross amount
+	ms.AssertCalled(t, "CreateOrder", mock.MatchedBy(func(o models.Order) bool {
+		return o.Total == 1310 && assert.ObjectsAreEqual(&models.TaxBreakdown{
+			Net: 1100, Tax: 210, Gross: 1310,
+			Rates: []models.RateTax{{Rate: 20, Net: 1000, Tax: 200}, {Rate: 10, Net: 100, Tax: 10}},
+		}, o.Tax)
+	}), cartItems)
+}
@@ -1,32 +1,33 @@
 package app
 
 import (
+	"crypto/hmac"
 	"crypto/rand"
-	"crypto/tls"
+	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
-	"net/smtp"
-	"os"
 	"strings"
 	"time"
 
 	"github.com/sfu-teamproject/smartbuy/backend/apperrors"
+	"github.com/sfu-teamproject/smartbuy/backend/mailer"
 	"github.com/sfu-teamproject/smartbuy/backend/models"
+	"golang.org/x/crypto/bcrypt"
 )
 
 // SendTmpPassword creates and sends temporary passwords
-// @Summary      Creates a temporary password for a user with a certain email, saves it to a database and sends to the user
+// @Summary      Creates a one-time password for resetting the password of a user with a certain email, saves its hash to a database and sends the password to the user
 // @Tags         auth
-// @Security     BearerAuth
 // @Accept       json
 // @Param        email body models.TmpRequest true "Email of a user"
 // @Success      204
+// @Failure      429  {object}  apperrors.ErrorResponse "Too Many Requests"
 // @Router       /users/restore [post]
 func (app *App) SendTmpPassword(w http.ResponseWriter, r *http.Request) {
-	t := models.TmpPassword{}
+	t := models.TmpRequest{}
 	err := json.NewDecoder(r.Body).Decode(&t)
 	if err != nil {
 		app.ErrorJSON(w, r, fmt.Errorf("%w: error decoding email: %w", apperrors.ErrBadRequest, err))
@@ -38,115 +39,171 @@ func (app *App) SendTmpPassword(w http.ResponseWriter, r *http.Request) {
 		app.ErrorJSON(w, r, fmt.Errorf("error getting user: %w", err))
 		return
 	}
-	_, err = app.DB.DeleteTmpPassword(t.Email)
-	if err != nil && !errors.Is(err, apperrors.ErrNotFound) {
-		app.ErrorJSON(w, r, fmt.Errorf("error deleting old tmp password: %w", err))
+	err = app.sendNewTmpPassword(t.Email, models.TmpPasswordReset)
+	if err != nil {
+		app.ErrorJSON(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ResetPassword sets a new password by a one-time password
+// @Summary      Reset Password
+// @Description  Checks a one-time password sent by /users/restore and sets a new password. Sessions of the user are ended, the user logs in with the new password
+// @Tags         auth
+// @Accept       json
+// @Param        request body models.ResetPasswordRequest true "Email, one-time password and new password"
+// @Success      204
+// @Failure      400  {object}  apperrors.ErrorResponse "Bad Request"
+// @Failure      401  {object}  apperrors.ErrorResponse "Unauthorized"
+// @Failure      429  {object}  apperrors.ErrorResponse "Too Many Requests"
+// @Router       /users/restore/confirm [post]
+func (app *App) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var reset models.ResetPasswordRequest
+	err := json.NewDecoder(r.Body).Decode(&reset)
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("%w: error decoding password reset: %w", apperrors.ErrBadRequest, err))
 		return
 	}
-	newT, err := GenerateTmpPassword()
+	reset.Email = strings.TrimSpace(reset.Email)
+	if reset.NewPassword == "" {
+		app.ErrorJSON(w, r, fmt.Errorf("%w: new password is empty", apperrors.ErrBadRequest))
+		return
+	}
+	err = app.checkTmpPassword(reset.Email, reset.Password, models.TmpPasswordReset)
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("%w: invalid one-time password: %w", apperrors.ErrInvalidCredentials, err))
+		return
+	}
+	user, err := app.DB.GetUserByEmail(reset.Email)
 	if err != nil {
-		app.ErrorJSON(w, r, fmt.Errorf("error generating tmp password: %w", err))
+		app.ErrorJSON(w, r, fmt.Errorf("error getting user: %w", err))
 		return
 	}
-	newT.Email = t.Email
-	newT, err = app.DB.CreateTmpPassword(newT)
+	hashedPass, err := bcrypt.GenerateFromPassword([]byte(reset.NewPassword), bcrypt.DefaultCost)
 	if err != nil {
-		app.ErrorJSON(w, r, fmt.Errorf("error saving tmp password into database: %w", err))
+		app.ErrorJSON(w, r, fmt.Errorf("error hashing password: %w", err))
 		return
 	}
-	err = SendTmpPassword(newT)
+	_, err = app.DB.UpdateUser(user.ID, map[string]any{"password": string(hashedPass)})
 	if err != nil {
-		app.ErrorJSON(w, r, fmt.Errorf("error sending tmp password: %w", err))
+		app.ErrorJSON(w, r, fmt.Errorf("error updating password of user %d: %w", user.ID, err))
+		return
+	}
+	err = app.DB.RevokeUserTokens(user.ID, time.Now())
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("error revoking tokens of user %d: %w", user.ID, err))
+		return
+	}
+	// the email is confirmed by the reset, a signup password must not log in
+	// after the password is changed
+	_, err = app.DB.DeleteTmpPassword(reset.Email, models.TmpPasswordSignup)
+	if err != nil && !errors.Is(err, apperrors.ErrNotFound) {
+		app.ErrorJSON(w, r, fmt.Errorf("error deleting signup tmp password: %w", err))
 		return
 	}
 	w.WriteHeader(http.StatusNoContent)
 }
 
-func GenerateTmpPassword() (models.TmpPassword, error) {
-	t := models.TmpPassword{}
+// GenerateTmpPassword returns a random one-time password valid for ttl
+func GenerateTmpPassword(purpose models.TmpPasswordPurpose, ttl time.Duration) (models.TmpPassword, error) {
+	t := models.TmpPassword{Purpose: purpose}
 	b := make([]byte, 5)
 	if _, err := rand.Read(b); err != nil {
 		return t, err
 	}
 	pass := hex.EncodeToString(b)
 	t.Password = pass
-	t.ExpiresAt = time.Now().Add(time.Hour * 24)
+	t.ExpiresAt = time.Now().Add(ttl)
 	return t, nil
 }
 
-func SendTmpPassword(tmpPassword models.TmpPassword) error {
-	smtpHost := "smtp.mail.ru"
-	from := "smartbuy.store@mail.ru"
-	password := os.Getenv("SMTP_PASSWORD")
-	smtpPort := "465"
-	subject := "Smartbuy temporary password"
+// mailTmpPassword sends the one-time password to its email, the text depends
+// on the purpose of the password
+func (app *App) mailTmpPassword(tmpPassword models.TmpPassword) error {
+	if tmpPassword.Purpose == models.TmpPasswordReset {
+		return app.Mail.Send(mailer.Message{
+			To:      tmpPassword.Email,
+			Subject: "Smartbuy password reset",
+			Body: "Ваш код для восстановления пароля: " + tmpPassword.Password +
+				"\nДействителен до: " + tmpPassword.ExpiresAt.String() +
+				"\nВведите его вместе с новым паролем. Если вы не запрашивали восстановление, проигнорируйте это письмо",
+		})
+	}
 	body := "Ваш одноразовый пароль для входа в систему: " + tmpPassword.Password +
 		"\nДействителен до: " + tmpPassword.ExpiresAt.String() +
 		"\nПосле входа в систему поменяйте пароль в личном кабинете"
-	// 1. Формирование сообщения
-	msg := []byte(
-		"To: " + tmpPassword.Email + "\r\n" +
-			"From: " + from + "\r\n" +
-			"Subject: " + subject + "\r\n\r\n" +
-			body + "\r\n")
-	// 2. Аутентификация
-	auth := smtp.PlainAuth("", from, password, smtpHost)
-	// 3. Установка безопасного TLS соединения (Implicit TLS для порта 465)
-	conn, err := tls.Dial("tcp", smtpHost+":"+smtpPort, &tls.Config{
-		ServerName: smtpHost,
+	return app.Mail.Send(mailer.Message{
+		To:      tmpPassword.Email,
+		Subject: "Smartbuy temporary password",
+		Body:    body,
 	})
+}
+
+// hashTmpPassword keys the hash with the server secret, so leaked hashes of
+// short passwords can't be brute forced without it
+func (app *App) hashTmpPassword(password string) string {
+	mac := hmac.New(sha256.New, app.jwtSecret)
+	mac.Write([]byte(password))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// sendNewTmpPassword replaces the one-time password of the email for the
+// purpose with a new one and sends it
+func (app *App) sendNewTmpPassword(email string, purpose models.TmpPasswordPurpose) error {
+	ttl := app.signupPasswordTTL
+	if purpose == models.TmpPasswordReset {
+		ttl = app.resetPasswordTTL
+	}
+	pass, err := GenerateTmpPassword(purpose, ttl)
 	if err != nil {
-		return fmt.Errorf("TLS Dial failed: %w", err)
-	}
-	defer conn.Close()
-	// 4. Создание SMTP клиента
-	client, err := smtp.NewClient(conn, smtpHost)
-	if err != nil {
-		return fmt.Errorf("NewClient failed: %w", err)
-	}
-	defer client.Close()
-	// 5. Аутентификация и отправка
-	if err = client.Auth(auth); err != nil {
-		return fmt.Errorf("SMTP Auth failed: %w", err)
-	}
-	if err = client.Mail(from); err != nil {
-		return fmt.Errorf("mail command failed: %w", err)
-	}
-	if err = client.Rcpt(tmpPassword.Email); err != nil {
-		return fmt.Errorf("rcpt command failed: %w", err)
-	}
-	w, err := client.Data()
-	if err != nil {
-		return fmt.Errorf("data command failed: %w", err)
+		return fmt.Errorf("error generating tmp password: %w", err)
 	}
-	_, err = w.Write(msg)
+	pass.Email = email
+	pass.PasswordHash = app.hashTmpPassword(pass.Password)
+	_, err = app.DB.CreateTmpPassword(pass)
 	if err != nil {
-		return fmt.Errorf("write failed: %w", err)
+		return fmt.Errorf("error saving tmp password to database: %w", err)
 	}
-	err = w.Close()
+	err = app.mailTmpPassword(pass)
 	if err != nil {
-		return fmt.Errorf("close failed: %w", err)
+		return fmt.Errorf("error sending tmp password to email: %w", err)
 	}
-	return client.Quit()
+	return nil
 }
 
-func (app *App) LoginWithTmpPassword(login models.LoginRequest) error {
-	tmpPass, err := app.DB.GetTmpPassword(login.Email)
-	if err != nil && !errors.Is(err, apperrors.ErrNotFound) {
-		return err
-	}
+// checkTmpPassword checks the one-time password of the email for the purpose
+// and deletes it once it is used. A password is also deleted when it expires
+// or runs out of attempts
+func (app *App) checkTmpPassword(email, password string, purpose models.TmpPasswordPurpose) error {
+	tmpPass, err := app.DB.AttemptTmpPassword(email, purpose)
 	if errors.Is(err, apperrors.ErrNotFound) {
-		return fmt.Errorf("tmp password not present")
+		return fmt.Errorf("%s tmp password not present", purpose)
 	}
-	if tmpPass.Password != login.Password {
-		return fmt.Errorf("tmp password not incorrect")
+	if err != nil {
+		return err
 	}
-	if time.Now().After(tmpPass.ExpiresAt) {
-		return fmt.Errorf("tmp password expired")
+	expired := time.Now().After(tmpPass.ExpiresAt)
+	outOfAttempts := tmpPass.Attempts > app.tmpPasswordMaxAttempts
+	matches := hmac.Equal([]byte(app.hashTmpPassword(password)), []byte(tmpPass.PasswordHash))
+	if !matches && !expired && tmpPass.Attempts < app.tmpPasswordMaxAttempts {
+		return fmt.Errorf("%s tmp password incorrect", purpose)
+	}
+	_, err = app.DB.DeleteTmpPassword(email, purpose)
+	if errors.Is(err, apperrors.ErrNotFound) {
+		// a concurrent request has used or deleted it
+		return fmt.Errorf("%s tmp password already used", purpose)
 	}
-	_, err = app.DB.DeleteTmpPassword(tmpPass.Email)
 	if err != nil {
 		return fmt.Errorf("error deleting tmp password from database: %w", err)
 	}
+	switch {
+	case expired:
+		return fmt.Errorf("%s tmp password expired", purpose)
+	case outOfAttempts:
+		return fmt.Errorf("%s tmp password out of attempts", purpose)
+	case !matches:
+		return fmt.Errorf("%s tmp password incorrect, no attempts left", purpose)
+	}
 	return nil
 }
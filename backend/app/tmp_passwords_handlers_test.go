@@ -0,0 +1,133 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sfu-teamproject/smartbuy/backend/apperrors"
+	"github.com/sfu-teamproject/smartbuy/backend/logger/mocklogger"
+	"github.com/sfu-teamproject/smartbuy/backend/models"
+	"github.com/sfu-teamproject/smartbuy/backend/storage/mockstorage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestCheckTmpPassword(t *testing.T) {
+	email := "user1@example.com"
+	valid := time.Now().Add(time.Hour)
+	tests := []struct {
+		name      string
+		password  string
+		attempts  int
+		expiresAt time.Time
+		ok        bool
+		deleted   bool
+	}{
+		{"Correct", "secret", 1, valid, true, true},
+		{"Incorrect", "wrong", 1, valid, false, false},
+		{"Incorrect last attempt", "wrong", 3, valid, false, true},
+		{"Correct out of attempts", "secret", 4, valid, false, true},
+		{"Correct expired", "secret", 1, time.Now().Add(-time.Minute), false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ms := new(mockstorage.MockStorage)
+			app := NewApp(new(mocklogger.MockLogger), nil, ms)
+			app.tmpPasswordMaxAttempts = 3
+			ms.On("AttemptTmpPassword", email, models.TmpPasswordReset).Return(models.TmpPassword{
+				Email: email, PasswordHash: app.hashTmpPassword("secret"), ExpiresAt: tt.expiresAt,
+				Purpose: models.TmpPasswordReset, Attempts: tt.attempts,
+			}, nil)
+			ms.On("DeleteTmpPassword", email, models.TmpPasswordReset).Return(models.TmpPassword{}, nil)
+			err := app.checkTmpPassword(email, tt.password, models.TmpPasswordReset)
+			if tt.ok {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+			if tt.deleted {
+				ms.AssertCalled(t, "DeleteTmpPassword", email, models.TmpPasswordReset)
+			} else {
+				ms.AssertNotCalled(t, "DeleteTmpPassword", email, models.TmpPasswordReset)
+			}
+		})
+	}
+
+	t.Run("Not present", func(t *testing.T) {
+		ms := new(mockstorage.MockStorage)
+		app := NewApp(new(mocklogger.MockLogger), nil, ms)
+		ms.On("AttemptTmpPassword", email, models.TmpPasswordSignup).Return(models.TmpPassword{}, apperrors.ErrNotFound)
+		err := app.checkTmpPassword(email, "secret", models.TmpPasswordSignup)
+		assert.Error(t, err)
+		assert.NotErrorIs(t, err, apperrors.ErrNotFound, "missing password is reported as not found")
+	})
+}
+
+func TestResetPassword(t *testing.T) {
+	email := "user1@example.com"
+	password := "old"
+	user := models.User{ID: 2, Name: "user1", Email: email, Password: &password, Role: models.RoleUser}
+	setup := func() (*App, *mockstorage.MockStorage) {
+		ms := new(mockstorage.MockStorage)
+		ml := new(mocklogger.MockLogger)
+		ml.On("Errorln", mock.Anything, mock.Anything, mock.Anything)
+		app := NewApp(ml, nil, ms)
+		ms.On("AttemptTmpPassword", email, models.TmpPasswordReset).Return(models.TmpPassword{
+			Email: email, PasswordHash: app.hashTmpPassword("secret"), ExpiresAt: time.Now().Add(time.Hour),
+			Purpose: models.TmpPasswordReset, Attempts: 1,
+		}, nil)
+		ms.On("DeleteTmpPassword", email, models.TmpPasswordReset).Return(models.TmpPassword{}, nil)
+		ms.On("DeleteTmpPassword", email, models.TmpPasswordSignup).Return(models.TmpPassword{}, apperrors.ErrNotFound)
+		ms.On("GetUserByEmail", email).Return(user, nil)
+		ms.On("UpdateUser", mock.Anything).Return(user, nil)
+		ms.On("RevokeUserTokens", 2, mock.Anything).Return(nil)
+		return app, ms
+	}
+	send := func(app *App, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		app.ResetPassword(w, r)
+		return w
+	}
+
+	t.Run("Reset", func(t *testing.T) {
+		app, ms := setup()
+		w := send(app, `{"email": "user1@example.com", "password": "secret", "new_password": "newpass"}`)
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		ms.AssertCalled(t, "RevokeUserTokens", 2, mock.Anything)
+		ms.AssertCalled(t, "DeleteTmpPassword", email, models.TmpPasswordSignup)
+		for _, call := range ms.Calls {
+			if call.Method == "UpdateUser" {
+				hash := call.Arguments.Get(0).(map[string]any)["password"].(string)
+				err := bcrypt.CompareHashAndPassword([]byte(hash), []byte("newpass"))
+				assert.NoError(t, err, "new password is not saved")
+			}
+		}
+		ms.AssertNumberOfCalls(t, "UpdateUser", 1)
+	})
+	t.Run("Wrong password", func(t *testing.T) {
+		app, ms := setup()
+		w := send(app, `{"email": "user1@example.com", "password": "wrong", "new_password": "newpass"}`)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		ms.AssertNotCalled(t, "UpdateUser", mock.Anything)
+	})
+	t.Run("Empty new password", func(t *testing.T) {
+		app, ms := setup()
+		w := send(app, `{"email": "user1@example.com", "password": "secret"}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		ms.AssertNotCalled(t, "AttemptTmpPassword", email, models.TmpPasswordReset)
+	})
+	t.Run("Reset password does not log in", func(t *testing.T) {
+		app, ms := setup()
+		ms.On("AttemptTmpPassword", email, models.TmpPasswordSignup).Return(models.TmpPassword{}, apperrors.ErrNotFound)
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"email": "user1@example.com", "password": "secret"}`))
+		w := httptest.NewRecorder()
+		app.Login(w, r)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		ms.AssertNotCalled(t, "AttemptTmpPassword", email, models.TmpPasswordReset)
+	})
+}
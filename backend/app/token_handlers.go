@@ -0,0 +1,194 @@
+package app
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/sfu-teamproject/smartbuy/backend/apperrors"
+	"github.com/sfu-teamproject/smartbuy/backend/models"
+)
+
+func init() {
+	// tokens issued right after a revocation must not look issued before it
+	jwt.TimePrecision = time.Millisecond
+}
+
+// accessToken signs a short-lived token that authorizes requests of the user
+func (app *App) accessToken(user models.User) (string, error) {
+	now := time.Now()
+	claims := Claims{
+		Role: user.Role,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   strconv.Itoa(user.ID),
+			Issuer:    "Smartbuy",
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(app.accessTokenTTL)),
+		},
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, &claims).SignedString(app.jwtSecret)
+}
+
+// newRefreshToken generates a refresh token, only its hash is saved
+func (app *App) newRefreshToken(userID int, familyID string) (string, models.RefreshToken) {
+	value := rand.Text()
+	return value, models.RefreshToken{
+		UserID:    userID,
+		TokenHash: hashRefreshToken(value),
+		FamilyID:  familyID,
+		ExpiresAt: time.Now().Add(app.refreshTokenTTL),
+	}
+}
+
+func hashRefreshToken(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:])
+}
+
+// issueTokens starts a new session of the user
+func (app *App) issueTokens(user models.User) (models.TokenResponse, error) {
+	access, err := app.accessToken(user)
+	if err != nil {
+		return models.TokenResponse{}, fmt.Errorf("error signing token: %w", err)
+	}
+	refresh, token := app.newRefreshToken(user.ID, rand.Text())
+	_, err = app.DB.CreateRefreshToken(token)
+	if err != nil {
+		return models.TokenResponse{}, fmt.Errorf("error saving refresh token: %w", err)
+	}
+	return models.TokenResponse{Token: access, RefreshToken: refresh}, nil
+}
+
+func decodeRefreshRequest(r *http.Request) (string, error) {
+	var req models.RefreshRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return "", fmt.Errorf("%w: error decoding refresh token: %w", apperrors.ErrBadRequest, err)
+	}
+	if req.RefreshToken == "" {
+		return "", fmt.Errorf("%w: empty refresh token", apperrors.ErrBadRequest)
+	}
+	return req.RefreshToken, nil
+}
+
+// RefreshToken exchanges a refresh token for new tokens
+// @Summary      Refresh tokens
+// @Description  Returns a new access token and a new refresh token. Every refresh token can be used once, using it again logs out the session it belongs to.
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        refresh_token body models.RefreshRequest true "Refresh token"
+// @Success      200  {object}  models.TokenResponse
+// @Failure      400  {object}  apperrors.ErrorResponse "Bad Request"
+// @Failure      401  {object}  apperrors.ErrorResponse "Invalid, used or expired refresh token"
+// @Router       /token/refresh [post]
+func (app *App) RefreshToken(w http.ResponseWriter, r *http.Request) {
+	refresh, err := decodeRefreshRequest(r)
+	if err != nil {
+		app.ErrorJSON(w, r, err)
+		return
+	}
+	next, token := app.newRefreshToken(0, "")
+	rotated, err := app.DB.RotateRefreshToken(hashRefreshToken(refresh), token)
+	if errors.Is(err, apperrors.ErrNotFound) {
+		err = fmt.Errorf("%w: unknown refresh token", apperrors.ErrUnauthorized)
+	}
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("error rotating refresh token: %w", err))
+		return
+	}
+	user, err := app.DB.GetUser(rotated.UserID)
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("error getting user %d: %w", rotated.UserID, err))
+		return
+	}
+	access, err := app.accessToken(user)
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("error signing token: %w", err))
+		return
+	}
+	app.Encode(w, r, models.TokenResponse{Token: access, RefreshToken: next})
+}
+
+// Logout ends the session of the refresh token
+// @Summary      Log out
+// @Description  Deletes the refresh token and all tokens rotated from the same login. The access token stays valid until it expires.
+// @Tags         auth
+// @Security     BearerAuth
+// @Accept       json
+// @Param        refresh_token body models.RefreshRequest true "Refresh token"
+// @Success      204
+// @Failure      400  {object}  apperrors.ErrorResponse "Bad Request"
+// @Failure      401  {object}  apperrors.ErrorResponse "Unauthorized"
+// @Router       /logout [post]
+func (app *App) Logout(w http.ResponseWriter, r *http.Request) {
+	userID, _, err := app.GetClaims(r)
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("%w: error extracting claims: %w", apperrors.ErrUnauthorized, err))
+		return
+	}
+	refresh, err := decodeRefreshRequest(r)
+	if err != nil {
+		app.ErrorJSON(w, r, err)
+		return
+	}
+	err = app.DB.DeleteRefreshTokenFamily(userID, hashRefreshToken(refresh))
+	// the session may already be ended
+	if err != nil && !errors.Is(err, apperrors.ErrNotFound) {
+		app.ErrorJSON(w, r, fmt.Errorf("error deleting refresh tokens of user %d: %w", userID, err))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// LogoutAll ends all sessions of the user
+// @Summary      Log out everywhere
+// @Description  Revokes all access and refresh tokens of the user, including the one of this request.
+// @Tags         auth
+// @Security     BearerAuth
+// @Success      204
+// @Failure      401  {object}  apperrors.ErrorResponse "Unauthorized"
+// @Router       /logout/all [post]
+func (app *App) LogoutAll(w http.ResponseWriter, r *http.Request) {
+	userID, _, err := app.GetClaims(r)
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("%w: error extracting claims: %w", apperrors.ErrUnauthorized, err))
+		return
+	}
+	err = app.DB.RevokeUserTokens(userID, time.Now())
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("error revoking tokens of user %d: %w", userID, err))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// checkRevoked rejects tokens of deleted users and tokens issued before the
+// tokens of the user were revoked
+func (app *App) checkRevoked(claims *Claims) error {
+	userID, err := strconv.Atoi(claims.Subject)
+	if err != nil {
+		return fmt.Errorf("%w: invalid subject %q", apperrors.ErrUnauthorized, claims.Subject)
+	}
+	if claims.IssuedAt == nil {
+		return fmt.Errorf("%w: token has no issue time", apperrors.ErrUnauthorized)
+	}
+	revokedAt, err := app.DB.GetTokensRevokedAt(userID)
+	if errors.Is(err, apperrors.ErrNotFound) {
+		return fmt.Errorf("%w: user %d does not exist", apperrors.ErrUnauthorized, userID)
+	}
+	if err != nil {
+		return fmt.Errorf("error checking tokens of user %d: %w", userID, err)
+	}
+	if revokedAt != nil && claims.IssuedAt.Before(*revokedAt) {
+		return fmt.Errorf("%w: token was revoked at %s", apperrors.ErrUnauthorized, revokedAt)
+	}
+	return nil
+}
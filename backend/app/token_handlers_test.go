@@ -0,0 +1,164 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sfu-teamproject/smartbuy/backend/apperrors"
+	"github.com/sfu-teamproject/smartbuy/backend/logger/mocklogger"
+	"github.com/sfu-teamproject/smartbuy/backend/models"
+	"github.com/sfu-teamproject/smartbuy/backend/storage/mockstorage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestAuthRevokedTokens(t *testing.T) {
+	ms := new(mockstorage.MockStorage)
+	ml := new(mocklogger.MockLogger)
+	past, future := time.Now().Add(-time.Hour), time.Now().Add(time.Hour)
+	ms.On("GetTokensRevokedAt", 1).Return((*time.Time)(nil), nil)
+	ms.On("GetTokensRevokedAt", 2).Return(&past, nil)
+	ms.On("GetTokensRevokedAt", 3).Return(&future, nil)
+	ms.On("GetTokensRevokedAt", 4).Return((*time.Time)(nil), apperrors.ErrNotFound)
+	ml.On("Errorln", mock.Anything, mock.Anything, mock.Anything)
+
+	app := NewApp(ml, nil, ms)
+	handler := app.Auth(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	tests := []struct {
+		name   string
+		userID int
+		code   int
+	}{
+		{"Never revoked", 1, http.StatusOK},
+		{"Issued after revocation", 2, http.StatusOK},
+		{"Issued before revocation", 3, http.StatusUnauthorized},
+		{"Deleted user", 4, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := app.accessToken(models.User{ID: tt.userID, Role: models.RoleUser})
+			assert.NoError(t, err)
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.Header.Set("Authorization", "Bearer "+token)
+			w := httptest.NewRecorder()
+			handler(w, r)
+			assert.Equal(t, tt.code, w.Code)
+		})
+	}
+}
+
+func TestAccessTokenRevokedRightAfterIssue(t *testing.T) {
+	ms := new(mockstorage.MockStorage)
+	ml := new(mocklogger.MockLogger)
+	app := NewApp(ml, nil, ms)
+	// a token issued in the same second as the revocation, but after it
+	revokedAt := time.Now()
+	time.Sleep(2 * time.Millisecond)
+	token, err := app.accessToken(models.User{ID: 1, Role: models.RoleUser})
+	assert.NoError(t, err)
+	ms.On("GetTokensRevokedAt", 1).Return(&revokedAt, nil)
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Authorization", token)
+	w := httptest.NewRecorder()
+	app.Auth(func(w http.ResponseWriter, r *http.Request) {})(w, r)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestRefreshToken(t *testing.T) {
+	ms := new(mockstorage.MockStorage)
+	ml := new(mocklogger.MockLogger)
+	ms.On("RotateRefreshToken", hashRefreshToken("valid"), mock.Anything).
+		Return(models.RefreshToken{ID: 2, UserID: 1, FamilyID: "family"}, nil)
+	ms.On("RotateRefreshToken", hashRefreshToken("used"), mock.Anything).
+		Return(models.RefreshToken{}, fmt.Errorf("%w: refresh token was already used", apperrors.ErrUnauthorized))
+	ms.On("RotateRefreshToken", hashRefreshToken("unknown"), mock.Anything).
+		Return(models.RefreshToken{}, apperrors.ErrNotFound)
+	ms.On("GetUser", 1).Return(models.User{ID: 1, Role: models.RoleUser}, nil)
+	ms.On("GetTokensRevokedAt", 1).Return((*time.Time)(nil), nil)
+	ml.On("Errorln", mock.Anything, mock.Anything, mock.Anything)
+
+	app := NewApp(ml, nil, ms)
+	tests := []struct {
+		name string
+		body string
+		code int
+	}{
+		{"Valid token", `{"refresh_token": "valid"}`, http.StatusOK},
+		{"Used token", `{"refresh_token": "used"}`, http.StatusUnauthorized},
+		{"Unknown token", `{"refresh_token": "unknown"}`, http.StatusUnauthorized},
+		{"Empty token", `{}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+			app.RefreshToken(w, r)
+			assert.Equal(t, tt.code, w.Code)
+			if tt.code != http.StatusOK {
+				return
+			}
+			var tokens models.TokenResponse
+			assert.NoError(t, json.NewDecoder(w.Body).Decode(&tokens))
+			assert.NotEmpty(t, tokens.Token)
+			assert.NotEqual(t, "valid", tokens.RefreshToken)
+			// the new access token is accepted
+			r = httptest.NewRequest(http.MethodGet, "/", nil)
+			r.Header.Set("Authorization", "Bearer "+tokens.Token)
+			w = httptest.NewRecorder()
+			app.Auth(func(w http.ResponseWriter, r *http.Request) {})(w, r)
+			assert.Equal(t, http.StatusOK, w.Code)
+		})
+	}
+	// only the hash of the new token is saved
+	ms.AssertCalled(t, "RotateRefreshToken", hashRefreshToken("valid"), mock.MatchedBy(func(next models.RefreshToken) bool {
+		return len(next.TokenHash) == 64 && next.ExpiresAt.After(time.Now().Add(24*time.Hour))
+	}))
+}
+
+func TestLogout(t *testing.T) {
+	ms := new(mockstorage.MockStorage)
+	ml := new(mocklogger.MockLogger)
+	ms.On("DeleteRefreshTokenFamily", 1, hashRefreshToken("token")).Return(nil)
+	ms.On("DeleteRefreshTokenFamily", 1, hashRefreshToken("ended")).Return(apperrors.ErrNotFound)
+	ms.On("RevokeUserTokens", 1, mock.Anything).Return(nil)
+	ms.On("UpdateUser", mock.Anything).Return(models.User{ID: 1}, nil)
+	ml.On("Errorln", mock.Anything, mock.Anything, mock.Anything)
+
+	app := NewApp(ml, nil, ms)
+	ctx := createContextWithClaims("1", models.RoleUser)
+	for _, refresh := range []string{"token", "ended"} {
+		r := httptest.NewRequestWithContext(ctx, http.MethodPost, "/", strings.NewReader(`{"refresh_token": "`+refresh+`"}`))
+		w := httptest.NewRecorder()
+		app.Logout(w, r)
+		assert.Equal(t, http.StatusNoContent, w.Code)
+	}
+	ms.AssertNotCalled(t, "RevokeUserTokens", mock.Anything, mock.Anything)
+
+	r := httptest.NewRequestWithContext(ctx, http.MethodPost, "/", nil)
+	w := httptest.NewRecorder()
+	app.LogoutAll(w, r)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	ms.AssertNumberOfCalls(t, "RevokeUserTokens", 1)
+
+	// changing the password logs out everywhere
+	r = httptest.NewRequestWithContext(ctx, http.MethodPatch, "/", strings.NewReader(`{"password": "new password"}`))
+	r.SetPathValue("user_id", "1")
+	w = httptest.NewRecorder()
+	app.UpdateUser(w, r)
+	assert.Equal(t, http.StatusOK, w.Code)
+	ms.AssertNumberOfCalls(t, "RevokeUserTokens", 2)
+
+	r = httptest.NewRequestWithContext(ctx, http.MethodPatch, "/", strings.NewReader(`{"name": "new name"}`))
+	r.SetPathValue("user_id", "1")
+	w = httptest.NewRecorder()
+	app.UpdateUser(w, r)
+	assert.Equal(t, http.StatusOK, w.Code)
+	ms.AssertNumberOfCalls(t, "RevokeUserTokens", 2)
+}
@@ -7,8 +7,6 @@ import (
 	"fmt"
 	"net/http"
 	"net/mail"
-
-	"strconv"
 	"strings"
 	"time"
 
@@ -64,7 +62,13 @@ func (app *App) GetUser(w http.ResponseWriter, r *http.Request) {
 		app.ErrorJSON(w, r, fmt.Errorf("error getting cart items: %w", err))
 		return
 	}
-	cart.Items = cartItems
+	cart.SetItems(cartItems)
+	err = app.attachCartDiscount(&cart)
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("error computing discount of cart %d: %w", cart.ID, err))
+		return
+	}
+	app.attachCartTax(&cart)
 	user.Cart = cart
 	app.Encode(w, r, user)
 }
@@ -114,7 +118,7 @@ func (app *App) GetUserByEmail(w http.ResponseWriter, r *http.Request) {
 
 // UpdateUser updates profile
 // @Summary      Update User
-// @Description  Update name, avatar, or password.
+// @Description  Update name, avatar, password or cart_reminders (abandoned cart reminder emails on or off). Changing the password revokes all tokens of the user.
 // @Tags         users
 // @Security     BearerAuth
 // @Accept       json
@@ -151,9 +155,10 @@ func (app *App) UpdateUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	allowedFields := map[string]bool{
-		"name":     true,
-		"avatar":   true,
-		"password": true,
+		"name":           true,
+		"avatar":         true,
+		"password":       true,
+		"cart_reminders": true,
 	}
 	for field := range updates {
 		if !allowedFields[field] {
@@ -161,6 +166,12 @@ func (app *App) UpdateUser(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 	}
+	if reminders, ok := updates["cart_reminders"]; ok {
+		if _, ok := reminders.(bool); !ok {
+			app.ErrorJSON(w, r, fmt.Errorf("%w: cart_reminders is not of type bool", apperrors.ErrBadRequest))
+			return
+		}
+	}
 	pass, ok := updates["password"]
 	if ok {
 		pass, ok := pass.(string)
@@ -180,6 +191,14 @@ func (app *App) UpdateUser(w http.ResponseWriter, r *http.Request) {
 		app.ErrorJSON(w, r, fmt.Errorf("error hashing updating user in database: %w", err))
 		return
 	}
+	if _, ok := updates["password"]; ok {
+		// sessions started with the old password are ended
+		err = app.DB.RevokeUserTokens(userID, time.Now())
+		if err != nil {
+			app.ErrorJSON(w, r, fmt.Errorf("error revoking tokens of user %d: %w", userID, err))
+			return
+		}
+	}
 	app.Encode(w, r, updatedUser)
 }
 
@@ -212,25 +231,9 @@ func (app *App) Signup(w http.ResponseWriter, r *http.Request) {
 		app.ErrorJSON(w, r, fmt.Errorf("error creating user: %w", err))
 		return
 	}
-	pass, err := GenerateTmpPassword()
-	if err != nil {
-		app.ErrorJSON(w, r, fmt.Errorf("error generating tmp password: %w", err))
-		return
-	}
-	pass.Email = signup.Email
-	_, err = app.DB.DeleteTmpPassword(pass.Email)
-	if err != nil && !errors.Is(err, apperrors.ErrNotFound) {
-		app.ErrorJSON(w, r, fmt.Errorf("error deleting tmp password from database: %w", err))
-		return
-	}
-	pass, err = app.DB.CreateTmpPassword(pass)
-	if err != nil {
-		app.ErrorJSON(w, r, fmt.Errorf("error saving tmp password to database: %w", err))
-		return
-	}
-	err = SendTmpPassword(pass)
+	err = app.sendNewTmpPassword(signup.Email, models.TmpPasswordSignup)
 	if err != nil {
-		app.ErrorJSON(w, r, fmt.Errorf("error sending tmp password to email: %w", err))
+		app.ErrorJSON(w, r, err)
 		return
 	}
 	w.WriteHeader(http.StatusCreated)
@@ -239,7 +242,7 @@ func (app *App) Signup(w http.ResponseWriter, r *http.Request) {
 
 // Login logs in a user
 // @Summary      User Login
-// @Description  Authenticates a user and signs a JWT token
+// @Description  Authenticates a user and returns a short-lived JWT access token and a refresh token
 // @Tags         auth
 // @Accept       json
 // @Produce      json
@@ -247,6 +250,7 @@ func (app *App) Signup(w http.ResponseWriter, r *http.Request) {
 // @Success      200  {object}  models.LoginResponse
 // @Failure      400  {object}  apperrors.ErrorResponse "Bad Request"
 // @Failure      401  {object}  apperrors.ErrorResponse "Unauthorized"
+// @Failure      429  {object}  apperrors.ErrorResponse "Too Many Requests"
 // @Router       /login [post]
 func (app *App) Login(w http.ResponseWriter, r *http.Request) {
 	var login models.LoginRequest
@@ -255,9 +259,21 @@ func (app *App) Login(w http.ResponseWriter, r *http.Request) {
 		app.ErrorJSON(w, r, fmt.Errorf("%w: error decoding login credentials: %w", apperrors.ErrBadRequest, err))
 		return
 	}
+	email := normalizeEmail(login.Email)
+	lockout, err := app.loginLockout.Check(email, time.Now())
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("error checking login lockout: %w", err))
+		return
+	}
+	if lockout > 0 {
+		app.tooManyRequests(w, r, lockout, fmt.Errorf("%w: login of %s is locked out",
+			apperrors.ErrTooManyRequests, email))
+		return
+	}
 	existingUser, err := app.DB.GetUserByEmail(login.Email)
 	if err != nil {
 		if errors.Is(err, apperrors.ErrNotFound) {
+			app.loginFailed(email)
 			app.ErrorJSON(w, r, fmt.Errorf("%w: %w", apperrors.ErrInvalidCredentials, err))
 			return
 		}
@@ -272,25 +288,20 @@ func (app *App) Login(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 	if !loggedIn {
-		err = app.LoginWithTmpPassword(login)
+		err = app.checkTmpPassword(login.Email, login.Password, models.TmpPasswordSignup)
 		if err != nil {
+			app.loginFailed(email)
 			app.ErrorJSON(w, r, fmt.Errorf("%w: invalid credentials: %w", apperrors.ErrInvalidCredentials, err))
 			return
 		}
 	}
-	claims := Claims{
-		Role: existingUser.Role,
-		RegisteredClaims: jwt.RegisteredClaims{
-			Subject:   strconv.Itoa(existingUser.ID),
-			Issuer:    "Smartbuy",
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour * 24)),
-		},
+	err = app.loginLockout.Succeed(email)
+	if err != nil {
+		app.Log.Errorf("error resetting failed logins of %s: %v", email, err)
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, &claims)
-	jwt, err := token.SignedString(app.jwtSecret)
+	tokens, err := app.issueTokens(existingUser)
 	if err != nil {
-		app.ErrorJSON(w, r, fmt.Errorf("error signing token: %w", err))
+		app.ErrorJSON(w, r, err)
 		return
 	}
 	cart, err := app.DB.GetCartByUserID(existingUser.ID)
@@ -298,14 +309,21 @@ func (app *App) Login(w http.ResponseWriter, r *http.Request) {
 		app.ErrorJSON(w, r, fmt.Errorf("error getting cart: %w", err))
 		return
 	}
+	app.mergeGuestCart(w, r, cart.ID)
 	cartItems, err := app.DB.GetCartItems(cart.ID)
 	if err != nil {
 		app.ErrorJSON(w, r, fmt.Errorf("error getting cart items: %w", err))
 		return
 	}
-	cart.Items = cartItems
+	cart.SetItems(cartItems)
+	err = app.attachCartDiscount(&cart)
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("error computing discount of cart %d: %w", cart.ID, err))
+		return
+	}
+	app.attachCartTax(&cart)
 	existingUser.Cart = cart
-	loginResponse := models.LoginResponse{User: existingUser, Token: jwt}
+	loginResponse := models.LoginResponse{User: existingUser, Token: tokens.Token, RefreshToken: tokens.RefreshToken}
 	app.Encode(w, r, loginResponse)
 }
 
@@ -336,11 +354,17 @@ func (app *App) DeleteUser(w http.ResponseWriter, r *http.Request) {
 		app.ErrorJSON(w, r, fmt.Errorf("%w: you can only delete your own account", apperrors.ErrForbidden))
 		return
 	}
+	photos, err := app.DB.GetUserReviewPhotos(targetUserID)
+	if err != nil {
+		app.ErrorJSON(w, r, fmt.Errorf("error getting review photos of user %d: %w", targetUserID, err))
+		return
+	}
 	deletedUser, err := app.DB.DeleteUser(targetUserID)
 	if err != nil {
 		app.ErrorJSON(w, r, fmt.Errorf("error deleting user %d: %w", targetUserID, err))
 		return
 	}
+	app.deletePhotoFiles(photos)
 	app.Encode(w, r, deletedUser)
 }
 
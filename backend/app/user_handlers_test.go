@@ -7,11 +7,14 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"strconv"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/sfu-teamproject/smartbuy/backend/apperrors"
 	"github.com/sfu-teamproject/smartbuy/backend/logger/mocklogger"
+	"github.com/sfu-teamproject/smartbuy/backend/mailer"
+	"github.com/sfu-teamproject/smartbuy/backend/mailer/mockmailer"
 	"github.com/sfu-teamproject/smartbuy/backend/models"
 	"github.com/sfu-teamproject/smartbuy/backend/storage/mockstorage"
 	"github.com/stretchr/testify/assert"
@@ -101,15 +104,22 @@ func TestSignup(t *testing.T) {
 }
 
 func TestEmail(t *testing.T) {
+	mm := new(mockmailer.MockMailer)
+	mm.On("Send", mock.MatchedBy(func(msg mailer.Message) bool {
+		return msg.To == "slayer-sv@mail.ru" && strings.Contains(msg.Body, "secretpassword")
+	})).Return(nil)
+	app := NewApp(new(mocklogger.MockLogger), nil, new(mockstorage.MockStorage))
+	app.Mail = mm
 	token := models.TmpPassword{
 		Email:     "slayer-sv@mail.ru",
 		Password:  "secretpassword",
 		ExpiresAt: time.Now().Add(time.Hour * 24),
 	}
 	t.Run("test email", func(t *testing.T) {
-		err := SendTmpPassword(token)
+		err := app.mailTmpPassword(token)
 		if err != nil {
 			t.Errorf("failed to send email %s", err)
 		}
 	})
+	mm.AssertExpectations(t)
 }
@@ -12,6 +12,7 @@ var (
 	ErrForbidden          = errors.New("forbidden")
 	ErrNotFound           = errors.New("not found")
 	ErrAlreadyExists      = errors.New("already exists")
+	ErrTooManyRequests    = errors.New("too many requests")
 )
 
 type ErrorResponse struct {
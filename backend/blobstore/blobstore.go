@@ -0,0 +1,86 @@
+package blobstore
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+type BlobStore interface {
+	Save(key string, data []byte) error
+	Delete(key string) error
+	Handler() http.Handler
+}
+
+// LocalStore keeps blobs as files under Dir, keys are slash separated relative paths
+type LocalStore struct {
+	Dir string
+}
+
+func NewLocalStore(dir string) *LocalStore {
+	return &LocalStore{Dir: dir}
+}
+
+func (ls *LocalStore) Save(key string, data []byte) error {
+	path, err := ls.path(key)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return fmt.Errorf("error creating directory for blob %s: %w", key, err)
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// Delete removes a blob, deleting a missing blob is not an error
+func (ls *LocalStore) Delete(key string) error {
+	path, err := ls.path(key)
+	if err != nil {
+		return err
+	}
+	err = os.Remove(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// Handler serves blobs by their keys, directories are not listed
+func (ls *LocalStore) Handler() http.Handler {
+	return http.FileServer(filesOnly{http.Dir(ls.Dir)})
+}
+
+// filesOnly hides directories, so requests for them get 404 instead of a
+// listing of the blobs inside
+type filesOnly struct {
+	http.FileSystem
+}
+
+func (fsys filesOnly) Open(name string) (http.File, error) {
+	f, err := fsys.FileSystem.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if info.IsDir() {
+		f.Close()
+		return nil, fs.ErrNotExist
+	}
+	return f, nil
+}
+
+func (ls *LocalStore) path(key string) (string, error) {
+	if key == "" || strings.Contains(key, "..") || strings.HasPrefix(key, "/") {
+		return "", fmt.Errorf("invalid blob key %q", key)
+	}
+	return filepath.Join(ls.Dir, filepath.FromSlash(key)), nil
+}
@@ -0,0 +1,45 @@
+package blobstore
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocalStore(t *testing.T) {
+	ls := NewLocalStore(t.TempDir())
+	t.Run("save blob", func(t *testing.T) {
+		err := ls.Save("reviews/1/photo.jpg", []byte("data"))
+		assert.NoError(t, err, "saving blob failed")
+		data, err := os.ReadFile(filepath.Join(ls.Dir, "reviews", "1", "photo.jpg"))
+		assert.NoError(t, err, "reading blob failed")
+		assert.Equal(t, []byte("data"), data)
+	})
+	t.Run("serve blob", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "/reviews/1/photo.jpg", nil)
+		w := httptest.NewRecorder()
+		ls.Handler().ServeHTTP(w, r)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "data", w.Body.String())
+	})
+	t.Run("directories are not listed", func(t *testing.T) {
+		for _, path := range []string{"/", "/reviews/", "/reviews/1"} {
+			r := httptest.NewRequest(http.MethodGet, path, nil)
+			w := httptest.NewRecorder()
+			ls.Handler().ServeHTTP(w, r)
+			assert.Equal(t, http.StatusNotFound, w.Code, path)
+		}
+	})
+	t.Run("delete blob", func(t *testing.T) {
+		assert.NoError(t, ls.Delete("reviews/1/photo.jpg"), "deleting blob failed")
+		assert.NoError(t, ls.Delete("reviews/1/photo.jpg"), "deleting missing blob failed")
+	})
+	t.Run("invalid key", func(t *testing.T) {
+		assert.Error(t, ls.Save("../photo.jpg", []byte("data")))
+		assert.Error(t, ls.Delete("/etc/passwd"))
+	})
+}
@@ -0,0 +1,137 @@
+// Package contentfilter checks user generated texts before they are saved
+package contentfilter
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Action is what should be done with a text, actions are ordered by severity
+type Action string
+
+const (
+	ActionAllow    Action = "allow"
+	ActionMask     Action = "mask"
+	ActionModerate Action = "moderate"
+	ActionReject   Action = "reject"
+)
+
+func (a Action) severity() int {
+	switch a {
+	case ActionMask:
+		return 1
+	case ActionModerate:
+		return 2
+	case ActionReject:
+		return 3
+	}
+	return 0
+}
+
+func parseAction(s string) (Action, error) {
+	switch a := Action(s); a {
+	case ActionAllow, ActionMask, ActionModerate, ActionReject:
+		return a, nil
+	}
+	return "", fmt.Errorf("unknown action %q", s)
+}
+
+// Input is a text to check together with its author
+type Input struct {
+	UserID int
+	Text   string
+}
+
+// Result is the verdict of a single filter. Text is the masked text and is
+// used only when Action is ActionMask
+type Result struct {
+	Action Action
+	Text   string
+	Reason string
+}
+
+// Filter checks a text
+type Filter interface {
+	Name() string
+	Check(in Input) (Result, error)
+}
+
+// Decision is the verdict of the whole pipeline
+type Decision struct {
+	Action  Action
+	Text    string
+	Reasons []string
+}
+
+// Pipeline runs filters one after another, every filter gets the text masked
+// by the previous ones. The most severe action wins, a rejection stops the pipeline
+type Pipeline []Filter
+
+func (p Pipeline) Run(in Input) (Decision, error) {
+	decision := Decision{Action: ActionAllow, Text: in.Text, Reasons: []string{}}
+	for _, f := range p {
+		res, err := f.Check(Input{UserID: in.UserID, Text: decision.Text})
+		if err != nil {
+			return decision, fmt.Errorf("filter %s: %w", f.Name(), err)
+		}
+		if res.Action == ActionAllow || res.Action == "" {
+			continue
+		}
+		decision.Reasons = append(decision.Reasons, f.Name()+": "+res.Reason)
+		if res.Action == ActionMask {
+			decision.Text = res.Text
+		}
+		if res.Action.severity() > decision.Action.severity() {
+			decision.Action = res.Action
+		}
+		if res.Action == ActionReject {
+			break
+		}
+	}
+	return decision, nil
+}
+
+// DuplicateChecker reports whether another user has already posted the text
+type DuplicateChecker func(text string, userID int) (bool, error)
+
+// DefaultSpec is the pipeline used when no configuration is given
+const DefaultSpec = "profanity:mask,spam:moderate,caps:moderate,duplicate:moderate"
+
+// NewPipeline builds a pipeline from a comma separated list of filter:action
+// pairs, e.g. "profanity:reject,caps:mask". Known filters are profanity, spam,
+// caps and duplicate. An empty spec disables filtering
+func NewPipeline(spec string, duplicates DuplicateChecker) (Pipeline, error) {
+	pipeline := Pipeline{}
+	for item := range strings.SplitSeq(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		name, actionStr, ok := strings.Cut(item, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid filter %q, expected filter:action", item)
+		}
+		action, err := parseAction(actionStr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid filter %q: %w", item, err)
+		}
+		var f Filter
+		switch name {
+		case "profanity":
+			f = NewProfanityFilter(action)
+		case "spam":
+			f = &SpamFilter{Action: action}
+		case "caps":
+			f = &CapsFilter{Action: action, MinLetters: 10, MaxRatio: 0.7}
+		case "duplicate":
+			if action == ActionMask {
+				return nil, fmt.Errorf("invalid filter %q: duplicate text can not be masked", item)
+			}
+			f = &DuplicateFilter{Action: action, MinLength: 20, Exists: duplicates}
+		default:
+			return nil, fmt.Errorf("unknown filter %q", name)
+		}
+		pipeline = append(pipeline, f)
+	}
+	return pipeline, nil
+}
@@ -0,0 +1,158 @@
+package contentfilter
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProfanityFilter(t *testing.T) {
+	f := NewProfanityFilter(ActionMask)
+	tests := []struct {
+		name   string
+		text   string
+		action Action
+		masked string
+	}{
+		{"Clean english", "Great phone, classic design", ActionAllow, ""},
+		{"Clean russian", "Отличный телефон, можно хлебать чай и страховать", ActionAllow, ""},
+		{"English", "This is bullshit!", ActionMask, "This is b*******!"},
+		{"Russian with ё", "Полное говнё", ActionMask, "Полное г****"},
+		{"Case insensitive", "FUCKING slow", ActionMask, "F****** slow"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := f.Check(Input{Text: tt.text})
+			assert.NoError(t, err)
+			assert.Equal(t, tt.action, res.Action)
+			if tt.action == ActionMask {
+				assert.Equal(t, tt.masked, res.Text)
+			}
+		})
+	}
+}
+
+func TestSpamFilter(t *testing.T) {
+	f := &SpamFilter{Action: ActionMask}
+	tests := []struct {
+		name   string
+		text   string
+		action Action
+		masked string
+	}{
+		{"Clean", "Battery lasts 2 days, 128 GB is enough", ActionAllow, ""},
+		{"Link", "Cheaper at https://example.com/shop", ActionMask, "Cheaper at ***"},
+		{"Bare domain", "see cheap-phones.ru", ActionMask, "see ***"},
+		{"Phone number", "Call +7 (913) 123-45-67 now", ActionMask, "Call *** now"},
+		{"Version numbers", "Updated from 17.1.2 to 18.0", ActionAllow, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := f.Check(Input{Text: tt.text})
+			assert.NoError(t, err)
+			assert.Equal(t, tt.action, res.Action)
+			if tt.action == ActionMask {
+				assert.Equal(t, tt.masked, res.Text)
+			}
+		})
+	}
+}
+
+func TestCapsFilter(t *testing.T) {
+	f := &CapsFilter{Action: ActionModerate, MinLetters: 10, MaxRatio: 0.7}
+	tests := []struct {
+		name   string
+		text   string
+		action Action
+	}{
+		{"Normal text", "Good phone, NFC works with my bank", ActionAllow},
+		{"Shouting", "WORST PHONE EVER DO NOT BUY", ActionModerate},
+		{"Short text", "OMG WOW", ActionAllow},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := f.Check(Input{Text: tt.text})
+			assert.NoError(t, err)
+			assert.Equal(t, tt.action, res.Action)
+		})
+	}
+}
+
+func TestDuplicateFilter(t *testing.T) {
+	dbErr := errors.New("db is down")
+	f := &DuplicateFilter{Action: ActionModerate, MinLength: 20, Exists: func(text string, userID int) (bool, error) {
+		switch userID {
+		case 2:
+			return true, nil
+		case 3:
+			return false, dbErr
+		}
+		return false, nil
+	}}
+	text := "Best phone I have ever had, buy it"
+	res, err := f.Check(Input{UserID: 1, Text: text})
+	assert.NoError(t, err)
+	assert.Equal(t, ActionAllow, res.Action)
+	res, err = f.Check(Input{UserID: 2, Text: text})
+	assert.NoError(t, err)
+	assert.Equal(t, ActionModerate, res.Action)
+	res, err = f.Check(Input{UserID: 2, Text: "Good"})
+	assert.NoError(t, err)
+	assert.Equal(t, ActionAllow, res.Action, "short texts are not checked")
+	_, err = f.Check(Input{UserID: 3, Text: text})
+	assert.ErrorIs(t, err, dbErr)
+}
+
+func TestPipeline(t *testing.T) {
+	p, err := NewPipeline("profanity:mask,spam:moderate,caps:reject", nil)
+	assert.NoError(t, err)
+	tests := []struct {
+		name    string
+		text    string
+		action  Action
+		text2   string
+		reasons int
+	}{
+		{"Clean", "Nice phone", ActionAllow, "Nice phone", 0},
+		{"Masked", "Shitty camera", ActionMask, "S***** camera", 1},
+		{"Masked and moderated", "Shitty, buy at www.example.com", ActionModerate, "S*****, buy at www.example.com", 2},
+		{"Rejected", "SHITTY PHONE DO NOT BUY IT", ActionReject, "S***** PHONE DO NOT BUY IT", 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := p.Run(Input{UserID: 1, Text: tt.text})
+			assert.NoError(t, err)
+			assert.Equal(t, tt.action, d.Action)
+			assert.Equal(t, tt.text2, d.Text)
+			assert.Len(t, d.Reasons, tt.reasons)
+		})
+	}
+}
+
+func TestNewPipeline(t *testing.T) {
+	tests := []struct {
+		name  string
+		spec  string
+		len   int
+		isErr bool
+	}{
+		{"Default", DefaultSpec, 4, false},
+		{"Disabled", "", 0, false},
+		{"Unknown filter", "links:mask", 0, true},
+		{"Unknown action", "caps:shout", 0, true},
+		{"Missing action", "caps", 0, true},
+		{"Masked duplicates", "duplicate:mask", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := NewPipeline(tt.spec, nil)
+			if tt.isErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Len(t, p, tt.len)
+		})
+	}
+}
@@ -0,0 +1,210 @@
+package contentfilter
+
+import (
+	"bufio"
+	_ "embed"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+//go:embed words_en.txt
+var wordsEN string
+
+//go:embed words_ru.txt
+var wordsRU string
+
+// ProfanityFilter looks for words from the word lists. A list entry matches a
+// whole word, entries ending with * match word prefixes and entries wrapped
+// in * match any part of a word. Matched words are masked with asterisks
+type ProfanityFilter struct {
+	Action   Action
+	exact    map[string]bool
+	prefixes []string
+	parts    []string
+}
+
+// NewProfanityFilter creates a filter with the built-in russian and english word lists
+func NewProfanityFilter(action Action) *ProfanityFilter {
+	f := &ProfanityFilter{Action: action, exact: map[string]bool{}}
+	f.AddWords(wordsEN)
+	f.AddWords(wordsRU)
+	return f
+}
+
+// AddWords adds newline separated entries to the word list, lines starting with # are skipped
+func (f *ProfanityFilter) AddWords(list string) {
+	sc := bufio.NewScanner(strings.NewReader(list))
+	for sc.Scan() {
+		entry := normalizeWord(strings.TrimSpace(sc.Text()))
+		if entry == "" || strings.HasPrefix(entry, "#") {
+			continue
+		}
+		switch {
+		case len(entry) > 2 && strings.HasPrefix(entry, "*") && strings.HasSuffix(entry, "*"):
+			f.parts = append(f.parts, strings.Trim(entry, "*"))
+		case strings.HasSuffix(entry, "*"):
+			f.prefixes = append(f.prefixes, strings.TrimSuffix(entry, "*"))
+		default:
+			f.exact[entry] = true
+		}
+	}
+}
+
+func (f *ProfanityFilter) Name() string { return "profanity" }
+
+func (f *ProfanityFilter) Check(in Input) (Result, error) {
+	var masked strings.Builder
+	found := []string{}
+	rest := in.Text
+	for len(rest) > 0 {
+		start := strings.IndexFunc(rest, isWordRune)
+		if start < 0 {
+			masked.WriteString(rest)
+			break
+		}
+		masked.WriteString(rest[:start])
+		rest = rest[start:]
+		end := strings.IndexFunc(rest, func(r rune) bool { return !isWordRune(r) })
+		if end < 0 {
+			end = len(rest)
+		}
+		word := rest[:end]
+		rest = rest[end:]
+		if !f.isProfane(normalizeWord(word)) {
+			masked.WriteString(word)
+			continue
+		}
+		found = append(found, word)
+		first, size := utf8.DecodeRuneInString(word)
+		masked.WriteRune(first)
+		masked.WriteString(strings.Repeat("*", utf8.RuneCountInString(word[size:])))
+	}
+	if len(found) == 0 {
+		return Result{Action: ActionAllow}, nil
+	}
+	return Result{
+		Action: f.Action,
+		Text:   masked.String(),
+		Reason: "offensive words",
+	}, nil
+}
+
+func (f *ProfanityFilter) isProfane(word string) bool {
+	if f.exact[word] {
+		return true
+	}
+	for _, prefix := range f.prefixes {
+		if strings.HasPrefix(word, prefix) {
+			return true
+		}
+	}
+	for _, part := range f.parts {
+		if strings.Contains(word, part) {
+			return true
+		}
+	}
+	return false
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '*'
+}
+
+func normalizeWord(word string) string {
+	return strings.ReplaceAll(strings.ToLower(word), "ё", "е")
+}
+
+var (
+	linkRe  = regexp.MustCompile(`(?i)(https?://\S+|www\.\S+|\b[a-z0-9-]+\.(ru|com|net|org|info|biz|io|su|рф)\b(/\S*)?|t\.me/\S+)`)
+	phoneRe = regexp.MustCompile(`\+?\d[\d\s()\-]{8,}\d`)
+)
+
+// SpamFilter looks for links and phone numbers, they are replaced with *** when masked
+type SpamFilter struct {
+	Action Action
+}
+
+func (f *SpamFilter) Name() string { return "spam" }
+
+func (f *SpamFilter) Check(in Input) (Result, error) {
+	var reasons []string
+	text := in.Text
+	if linkRe.MatchString(text) {
+		reasons = append(reasons, "links")
+		text = linkRe.ReplaceAllString(text, "***")
+	}
+	phones := 0
+	text = phoneRe.ReplaceAllStringFunc(text, func(s string) string {
+		digits := 0
+		for _, r := range s {
+			if unicode.IsDigit(r) {
+				digits++
+			}
+		}
+		if digits < 10 || digits > 15 {
+			return s
+		}
+		phones++
+		return "***"
+	})
+	if phones > 0 {
+		reasons = append(reasons, "phone numbers")
+	}
+	if len(reasons) == 0 {
+		return Result{Action: ActionAllow}, nil
+	}
+	return Result{Action: f.Action, Text: text, Reason: strings.Join(reasons, ", ")}, nil
+}
+
+// CapsFilter looks for texts written mostly in capital letters, such texts
+// are converted to lower case when masked. Texts shorter than MinLetters are skipped
+type CapsFilter struct {
+	Action     Action
+	MinLetters int
+	MaxRatio   float64
+}
+
+func (f *CapsFilter) Name() string { return "caps" }
+
+func (f *CapsFilter) Check(in Input) (Result, error) {
+	letters, upper := 0, 0
+	for _, r := range in.Text {
+		if unicode.IsLetter(r) {
+			letters++
+			if unicode.IsUpper(r) {
+				upper++
+			}
+		}
+	}
+	if letters < f.MinLetters || float64(upper)/float64(letters) <= f.MaxRatio {
+		return Result{Action: ActionAllow}, nil
+	}
+	return Result{Action: f.Action, Text: strings.ToLower(in.Text), Reason: "excessive caps"}, nil
+}
+
+// DuplicateFilter looks for the same text posted by another user. Texts
+// shorter than MinLength are skipped as short comments often coincide
+type DuplicateFilter struct {
+	Action    Action
+	MinLength int
+	Exists    DuplicateChecker
+}
+
+func (f *DuplicateFilter) Name() string { return "duplicate" }
+
+func (f *DuplicateFilter) Check(in Input) (Result, error) {
+	text := strings.TrimSpace(in.Text)
+	if f.Exists == nil || utf8.RuneCountInString(text) < f.MinLength {
+		return Result{Action: ActionAllow}, nil
+	}
+	exists, err := f.Exists(text, in.UserID)
+	if err != nil {
+		return Result{}, err
+	}
+	if !exists {
+		return Result{Action: ActionAllow}, nil
+	}
+	return Result{Action: f.Action, Text: in.Text, Reason: "text posted by another user"}, nil
+}
@@ -0,0 +1,104 @@
+// Package discount computes discounts given by promo codes
+package discount
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/sfu-teamproject/smartbuy/backend/models"
+)
+
+var (
+	ErrNotStarted    = errors.New("promo code is not active yet")
+	ErrExpired       = errors.New("promo code has expired")
+	ErrUsageLimit    = errors.New("promo code usage limit is reached")
+	ErrMinTotal      = errors.New("cart total is below the promo code minimum")
+	ErrNotApplicable = errors.New("promo code does not apply to any item")
+)
+
+// Line is a smartphone in a cart or an order
+type Line struct {
+	SmartphoneID int
+	Producer     string
+	Price        int
+	Quantity     int
+}
+
+// Usage is how many times a promo code was used overall and by the current user
+type Usage struct {
+	Total  int
+	ByUser int
+}
+
+// Apply checks that promo can be used for lines at the moment now and computes
+// the discount. A fixed discount is split between eligible lines in proportion
+// to their cost and never exceeds it
+func Apply(promo models.PromoCode, lines []Line, usage Usage, now time.Time) (models.DiscountBreakdown, error) {
+	breakdown := models.DiscountBreakdown{Code: promo.Code, Lines: []models.LineDiscount{}}
+	if promo.StartsAt != nil && now.Before(*promo.StartsAt) {
+		return breakdown, ErrNotStarted
+	}
+	if promo.EndsAt != nil && !now.Before(*promo.EndsAt) {
+		return breakdown, ErrExpired
+	}
+	if promo.UsageLimit != nil && usage.Total >= *promo.UsageLimit {
+		return breakdown, ErrUsageLimit
+	}
+	if promo.PerUserLimit != nil && usage.ByUser >= *promo.PerUserLimit {
+		return breakdown, ErrUsageLimit
+	}
+	eligible := 0
+	eligibleLines := []int{}
+	for i, line := range lines {
+		cost := line.Price * line.Quantity
+		breakdown.Subtotal += cost
+		if appliesTo(promo, line) && cost > 0 {
+			eligible += cost
+			eligibleLines = append(eligibleLines, i)
+		}
+	}
+	breakdown.Total = breakdown.Subtotal
+	if breakdown.Subtotal < promo.MinTotal {
+		return breakdown, fmt.Errorf("%w: %d < %d", ErrMinTotal, breakdown.Subtotal, promo.MinTotal)
+	}
+	if eligible == 0 {
+		return breakdown, ErrNotApplicable
+	}
+	var total int
+	switch promo.Type {
+	case models.DiscountPercent:
+		total = eligible * min(promo.Value, 100) / 100
+	case models.DiscountFixed:
+		total = min(promo.Value, eligible)
+	default:
+		return breakdown, fmt.Errorf("unknown discount type %q", promo.Type)
+	}
+	rest := total
+	for n, i := range eligibleLines {
+		line := lines[i]
+		d := total * line.Price * line.Quantity / eligible
+		if n == len(eligibleLines)-1 {
+			d = rest
+		}
+		rest -= d
+		breakdown.Lines = append(breakdown.Lines, models.LineDiscount{SmartphoneID: line.SmartphoneID, Discount: d})
+	}
+	breakdown.Discount = total
+	breakdown.Total = breakdown.Subtotal - total
+	return breakdown, nil
+}
+
+func appliesTo(promo models.PromoCode, line Line) bool {
+	if len(promo.Producers) == 0 && len(promo.SmartphoneIDs) == 0 {
+		return true
+	}
+	if slices.Contains(promo.SmartphoneIDs, line.SmartphoneID) {
+		return true
+	}
+	return slices.ContainsFunc(promo.Producers, func(p string) bool {
+		return strings.EqualFold(p, line.Producer)
+	})
+}
@@ -0,0 +1,62 @@
+package discount
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sfu-teamproject/smartbuy/backend/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApply(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	past, future := now.Add(-time.Hour), now.Add(time.Hour)
+	one := 1
+	lines := []Line{
+		{SmartphoneID: 1, Producer: "Apple", Price: 1000, Quantity: 2},
+		{SmartphoneID: 2, Producer: "Samsung", Price: 500, Quantity: 1},
+		{SmartphoneID: 3, Producer: "Xiaomi", Price: 300, Quantity: 1},
+	}
+	tests := []struct {
+		name     string
+		promo    models.PromoCode
+		usage    Usage
+		discount int
+		lines    []models.LineDiscount
+		err      error
+	}{
+		{"Percent on cart", models.PromoCode{Type: models.DiscountPercent, Value: 10}, Usage{},
+			280, []models.LineDiscount{{SmartphoneID: 1, Discount: 200}, {SmartphoneID: 2, Discount: 50}, {SmartphoneID: 3, Discount: 30}}, nil},
+		{"Percent on producer", models.PromoCode{Type: models.DiscountPercent, Value: 10, Producers: []string{"apple"}}, Usage{},
+			200, []models.LineDiscount{{SmartphoneID: 1, Discount: 200}}, nil},
+		{"Fixed split between smartphones", models.PromoCode{Type: models.DiscountFixed, Value: 100, SmartphoneIDs: []int{2, 3}}, Usage{},
+			100, []models.LineDiscount{{SmartphoneID: 2, Discount: 62}, {SmartphoneID: 3, Discount: 38}}, nil},
+		{"Fixed above eligible cost", models.PromoCode{Type: models.DiscountFixed, Value: 1000, SmartphoneIDs: []int{3}}, Usage{},
+			300, []models.LineDiscount{{SmartphoneID: 3, Discount: 300}}, nil},
+		{"Minimum total", models.PromoCode{Type: models.DiscountFixed, Value: 100, MinTotal: 5000}, Usage{},
+			0, nil, ErrMinTotal},
+		{"Not started", models.PromoCode{Type: models.DiscountFixed, Value: 100, StartsAt: &future}, Usage{},
+			0, nil, ErrNotStarted},
+		{"Expired", models.PromoCode{Type: models.DiscountFixed, Value: 100, EndsAt: &past}, Usage{},
+			0, nil, ErrExpired},
+		{"Usage limit", models.PromoCode{Type: models.DiscountFixed, Value: 100, UsageLimit: &one}, Usage{Total: 1},
+			0, nil, ErrUsageLimit},
+		{"Per user limit", models.PromoCode{Type: models.DiscountFixed, Value: 100, PerUserLimit: &one}, Usage{Total: 5, ByUser: 1},
+			0, nil, ErrUsageLimit},
+		{"Not applicable", models.PromoCode{Type: models.DiscountFixed, Value: 100, Producers: []string{"Nokia"}}, Usage{},
+			0, nil, ErrNotApplicable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := Apply(tt.promo, lines, tt.usage, now)
+			assert.ErrorIs(t, err, tt.err)
+			if tt.err != nil {
+				return
+			}
+			assert.Equal(t, 2800, b.Subtotal)
+			assert.Equal(t, tt.discount, b.Discount)
+			assert.Equal(t, 2800-tt.discount, b.Total)
+			assert.Equal(t, tt.lines, b.Lines)
+		})
+	}
+}
@@ -0,0 +1,183 @@
+// Package invoice renders PDF invoices of orders
+package invoice
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/sfu-teamproject/smartbuy/backend/models"
+)
+
+// Store holds the seller details printed on invoices
+type Store struct {
+	Name    string
+	Address string
+	Email   string
+	TaxID   string
+}
+
+var DefaultStore = Store{
+	Name:    "Smartbuy",
+	Address: "Krasnoyarsk, Svobodny pr. 79",
+	Email:   "smartbuy.store@mail.ru",
+}
+
+const (
+	margin     = 50
+	rowHeight  = 18
+	footerLine = 60
+	// columns of the items table, amounts are aligned to the right edge
+	colNumber = margin
+	colItem   = margin + 25
+	colPrice  = 400
+	colQty    = 450
+	colAmount = pageWidth - margin
+)
+
+// Number is the invoice number of the order
+func Number(orderID int) string {
+	return fmt.Sprintf("SB-%06d", orderID)
+}
+
+// FileName is the name of the invoice file of the order
+func FileName(orderID int) string {
+	return "invoice-" + Number(orderID) + ".pdf"
+}
+
+// Render draws the invoice of the order. Items come from the snapshot saved at
+// checkout, so the invoice does not change when the catalog does
+func Render(store Store, order models.Order, customer models.User) []byte {
+	d := &document{}
+	d.addPage()
+	y := float64(pageHeight - margin - 20)
+	d.text(margin, y, 22, true, "INVOICE")
+	d.textRight(colAmount, y, 14, true, store.Name)
+	sellerLines := []string{store.Address, store.Email}
+	if store.TaxID != "" {
+		sellerLines = append(sellerLines, "Tax ID: "+store.TaxID)
+	}
+	sellerY := y
+	for _, line := range sellerLines {
+		if line == "" {
+			continue
+		}
+		sellerY -= 14
+		d.textRight(colAmount, sellerY, 10, false, line)
+	}
+
+	y -= 34
+	d.text(margin, y, 10, false, "Invoice no.: "+Number(order.ID))
+	y -= 14
+	d.text(margin, y, 10, false, "Date: "+order.CreatedAt.Format("02.01.2006"))
+	y -= 14
+	d.text(margin, y, 10, false, fmt.Sprintf("Order no.: %d, status: %s", order.ID, order.Status))
+	y = min(y, sellerY) - 28
+	d.text(margin, y, 11, true, "Bill to")
+	y -= 14
+	d.text(margin, y, 10, false, customer.Name)
+	y -= 14
+	d.text(margin, y, 10, false, customer.Email)
+
+	y -= 30
+	y = itemsHeader(d, y)
+	subtotal := 0
+	for i, item := range order.Items {
+		if y < footerLine+rowHeight {
+			d.addPage()
+			y = itemsHeader(d, pageHeight-margin)
+		}
+		name := item.Model
+		if item.Producer != "" {
+			name = item.Producer + " " + item.Model
+		}
+		amount := item.Price * item.Quantity
+		subtotal += amount
+		d.text(colNumber, y, 10, false, strconv.Itoa(i+1))
+		d.text(colItem, y, 10, false, truncate(name, 10, colPrice-colItem-80))
+		d.textRight(colPrice, y, 10, false, money(item.Price))
+		d.textRight(colQty, y, 10, false, strconv.Itoa(item.Quantity))
+		d.textRight(colAmount, y, 10, false, money(amount))
+		y -= rowHeight
+	}
+	d.line(margin, y+rowHeight-4, colAmount, y+rowHeight-4)
+
+	// totals need three rows and a row per VAT rate
+	rows := 3
+	if order.Tax != nil {
+		rows += len(order.Tax.Rates)
+	}
+	if y < footerLine+float64(rows)*rowHeight {
+		d.addPage()
+		y = pageHeight - margin
+	}
+	y -= 4
+	d.textRight(colQty, y, 10, false, "Subtotal")
+	d.textRight(colAmount, y, 10, false, money(subtotal))
+	if order.Discount > 0 {
+		y -= rowHeight
+		d.textRight(colQty, y, 10, false, "Discount")
+		d.textRight(colAmount, y, 10, false, "-"+money(order.Discount))
+	}
+	// VAT added on top of prices goes before the total, VAT included in
+	// prices is shown after it
+	if order.Tax != nil && !order.Tax.PricesIncludeTax {
+		for _, rate := range order.Tax.Rates {
+			y -= rowHeight
+			d.textRight(colQty, y, 10, false, "VAT "+percent(rate.Rate))
+			d.textRight(colAmount, y, 10, false, money(rate.Tax))
+		}
+	}
+	y -= rowHeight
+	d.textRight(colQty, y, 11, true, "Total")
+	d.textRight(colAmount, y, 11, true, money(order.Total))
+	if order.Tax != nil && order.Tax.PricesIncludeTax {
+		for _, rate := range order.Tax.Rates {
+			y -= rowHeight
+			d.textRight(colQty, y, 10, false, "incl. VAT "+percent(rate.Rate))
+			d.textRight(colAmount, y, 10, false, money(rate.Tax))
+		}
+	}
+
+	for i, page := range d.pages {
+		d.page = page
+		d.line(margin, footerLine, colAmount, footerLine)
+		d.text(margin, footerLine-14, 8, false, "Thank you for shopping at "+store.Name+"!")
+		d.textRight(colAmount, footerLine-14, 8, false, fmt.Sprintf("Page %d of %d", i+1, len(d.pages)))
+	}
+	return d.bytes()
+}
+
+// itemsHeader draws the header of the items table and returns the position of
+// the first row
+func itemsHeader(d *document, y float64) float64 {
+	d.text(colNumber, y, 10, true, "#")
+	d.text(colItem, y, 10, true, "Item")
+	d.textRight(colPrice, y, 10, true, "Price")
+	d.textRight(colQty, y, 10, true, "Qty")
+	d.textRight(colAmount, y, 10, true, "Amount")
+	d.line(margin, y-6, colAmount, y-6)
+	return y - rowHeight - 4
+}
+
+// money formats whole rubles with thousands separated by spaces
+func money(amount int) string {
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+	digits := strconv.Itoa(amount)
+	grouped := []byte{}
+	for i := range len(digits) {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			grouped = append(grouped, ' ')
+		}
+		grouped = append(grouped, digits[i])
+	}
+	return sign + string(grouped) + " RUB"
+}
+
+// percent formats a rate without trailing zeros, e.g. 20% or 7.5%
+func percent(rate float64) string {
+	return strconv.FormatFloat(rate, 'f', -1, 64) + "%"
+}
@@ -0,0 +1,116 @@
+package invoice
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/sfu-teamproject/smartbuy/backend/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRender(t *testing.T) {
+	order := models.Order{ID: 12, UserID: 2, Status: models.OrderPaid, Total: 124990 - 5000, Discount: 5000,
+		CreatedAt: time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC),
+		Items: []models.OrderItem{
+			{Producer: "Apple", Model: "iPhone 15 (128 GB)", Price: 99990, Quantity: 1},
+			{Producer: "Xiaomi", Model: "Redmi Note 13", Price: 12500, Quantity: 2},
+		}}
+	customer := models.User{ID: 2, Name: "Иван Петров", Email: "ivan@example.com"}
+	pdf := Render(DefaultStore, order, customer)
+
+	assert.True(t, bytes.HasPrefix(pdf, []byte("%PDF-1.4\n")))
+	assert.True(t, bytes.HasSuffix(pdf, []byte("%%EOF\n")))
+	assert.Contains(t, string(pdf), "(INVOICE)")
+	assert.Contains(t, string(pdf), "(Invoice no.: SB-000012)")
+	assert.Contains(t, string(pdf), "(Date: 01.06.2025)")
+	assert.Contains(t, string(pdf), "(Ivan Petrov)")
+	assert.Contains(t, string(pdf), `(Apple iPhone 15 \(128 GB\))`)
+	assert.Contains(t, string(pdf), "(25 000 RUB)")
+	assert.Contains(t, string(pdf), "(-5 000 RUB)")
+	assert.Contains(t, string(pdf), "(119 990 RUB)")
+	assert.Contains(t, string(pdf), "(Page 1 of 1)")
+	assertValidXref(t, pdf)
+}
+
+func TestRenderTax(t *testing.T) {
+	order := models.Order{ID: 3, Status: models.OrderPaid, Total: 1215,
+		Items: []models.OrderItem{
+			{Model: "Phone", Price: 1000, Quantity: 1},
+			{Model: "Case", Price: 100, Quantity: 1},
+		},
+		Tax: &models.TaxBreakdown{Net: 1100, Tax: 215, Gross: 1215,
+			Rates: []models.RateTax{{Rate: 20, Net: 1000, Tax: 200}, {Rate: 15, Net: 100, Tax: 15}}},
+	}
+	pdf := string(Render(DefaultStore, order, models.User{Name: "user"}))
+	assert.Contains(t, pdf, "(VAT 20%)")
+	assert.Contains(t, pdf, "(VAT 15%)")
+	assert.Contains(t, pdf, "(1 215 RUB)")
+
+	order.Total = 1100
+	order.Tax = &models.TaxBreakdown{PricesIncludeTax: true, Net: 1023, Tax: 77, Gross: 1100,
+		Rates: []models.RateTax{{Rate: 7.5, Net: 1023, Tax: 77}}}
+	pdf = string(Render(DefaultStore, order, models.User{Name: "user"}))
+	assert.Contains(t, pdf, "(incl. VAT 7.5%)")
+	assert.Contains(t, pdf, "(77 RUB)")
+	assert.NotContains(t, pdf, "(VAT 7.5%)")
+}
+
+func TestRenderManyItems(t *testing.T) {
+	order := models.Order{ID: 1, Status: models.OrderCreated}
+	for i := range 60 {
+		order.Items = append(order.Items, models.OrderItem{Model: fmt.Sprintf("Phone %d", i), Price: 100, Quantity: 1})
+		order.Total += 100
+	}
+	pdf := Render(DefaultStore, order, models.User{Name: "user"})
+	assert.Contains(t, string(pdf), "/Count 2")
+	assert.Contains(t, string(pdf), "(Page 2 of 2)")
+	assert.Contains(t, string(pdf), "(Phone 59)")
+	assertValidXref(t, pdf)
+}
+
+// assertValidXref checks that every entry of the cross-reference table points
+// at the start of its object
+func assertValidXref(t *testing.T, pdf []byte) {
+	start := regexp.MustCompile(`startxref\n(\d+)\n`).FindSubmatch(pdf)
+	if !assert.NotNil(t, start) {
+		return
+	}
+	xref, err := strconv.Atoi(string(start[1]))
+	assert.NoError(t, err)
+	assert.True(t, bytes.HasPrefix(pdf[xref:], []byte("xref\n")))
+	entries := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllSubmatch(pdf[xref:], -1)
+	assert.NotEmpty(t, entries)
+	for i, entry := range entries {
+		offset, err := strconv.Atoi(string(entry[1]))
+		assert.NoError(t, err)
+		assert.True(t, bytes.HasPrefix(pdf[offset:], fmt.Appendf(nil, "%d 0 obj\n", i+1)), "object %d", i+1)
+	}
+}
+
+func TestEscape(t *testing.T) {
+	tests := []struct {
+		in  string
+		out string
+	}{
+		{"Galaxy S24", "Galaxy S24"},
+		{`a(b)\c`, `a\(b\)\\c`},
+		{"Щука Ёж", "Shchuka Ezh"},
+		{"café", `caf\351`},
+		{"手机", "??"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.out, escape(tt.in), tt.in)
+	}
+}
+
+func TestMoney(t *testing.T) {
+	assert.Equal(t, "0 RUB", money(0))
+	assert.Equal(t, "999 RUB", money(999))
+	assert.Equal(t, "1 000 RUB", money(1000))
+	assert.Equal(t, "1 234 567 RUB", money(1234567))
+	assert.Equal(t, "-5 000 RUB", money(-5000))
+}
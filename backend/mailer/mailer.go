@@ -0,0 +1,147 @@
+package mailer
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"net/smtp"
+	"net/textproto"
+	"os"
+)
+
+type Message struct {
+	To          string
+	Subject     string
+	Body        string
+	Attachments []Attachment
+}
+
+// Attachment is a file sent with a message, e.g. a PDF invoice
+type Attachment struct {
+	Name        string
+	ContentType string
+	Data        []byte
+}
+
+type Mailer interface {
+	Send(msg Message) error
+}
+
+type SMTPMailer struct {
+	Host     string
+	Port     string
+	From     string
+	Password string
+}
+
+func NewSMTPMailer() *SMTPMailer {
+	return &SMTPMailer{
+		Host:     "smtp.mail.ru",
+		Port:     "465",
+		From:     "smartbuy.store@mail.ru",
+		Password: os.Getenv("SMTP_PASSWORD"),
+	}
+}
+
+func (m *SMTPMailer) Send(msg Message) error {
+	// 1. Формирование сообщения
+	data, err := m.compose(msg)
+	if err != nil {
+		return fmt.Errorf("compose failed: %w", err)
+	}
+	// 2. Аутентификация
+	auth := smtp.PlainAuth("", m.From, m.Password, m.Host)
+	// 3. Установка безопасного TLS соединения (Implicit TLS для порта 465)
+	conn, err := tls.Dial("tcp", m.Host+":"+m.Port, &tls.Config{
+		ServerName: m.Host,
+	})
+	if err != nil {
+		return fmt.Errorf("TLS Dial failed: %w", err)
+	}
+	defer conn.Close()
+	// 4. Создание SMTP клиента
+	client, err := smtp.NewClient(conn, m.Host)
+	if err != nil {
+		return fmt.Errorf("NewClient failed: %w", err)
+	}
+	defer client.Close()
+	// 5. Аутентификация и отправка
+	if err = client.Auth(auth); err != nil {
+		return fmt.Errorf("SMTP Auth failed: %w", err)
+	}
+	if err = client.Mail(m.From); err != nil {
+		return fmt.Errorf("mail command failed: %w", err)
+	}
+	if err = client.Rcpt(msg.To); err != nil {
+		return fmt.Errorf("rcpt command failed: %w", err)
+	}
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("data command failed: %w", err)
+	}
+	_, err = w.Write(data)
+	if err != nil {
+		return fmt.Errorf("write failed: %w", err)
+	}
+	err = w.Close()
+	if err != nil {
+		return fmt.Errorf("close failed: %w", err)
+	}
+	return client.Quit()
+}
+
+// compose builds the message text, messages with attachments are sent as
+// multipart/mixed with base64 encoded files
+func (m *SMTPMailer) compose(msg Message) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString("To: " + msg.To + "\r\n" +
+		"From: " + m.From + "\r\n" +
+		"Subject: " + mime.QEncoding.Encode("utf-8", msg.Subject) + "\r\n" +
+		"MIME-Version: 1.0\r\n")
+	if len(msg.Attachments) == 0 {
+		buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n" + msg.Body + "\r\n")
+		return buf.Bytes(), nil
+	}
+	var body bytes.Buffer
+	parts := multipart.NewWriter(&body)
+	buf.WriteString("Content-Type: multipart/mixed; boundary=" + parts.Boundary() + "\r\n\r\n")
+	part, err := parts.CreatePart(textproto.MIMEHeader{
+		"Content-Type": {"text/plain; charset=utf-8"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	_, err = part.Write([]byte(msg.Body + "\r\n"))
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range msg.Attachments {
+		part, err := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {a.ContentType},
+			"Content-Transfer-Encoding": {"base64"},
+			"Content-Disposition":       {mime.FormatMediaType("attachment", map[string]string{"filename": a.Name})},
+		})
+		if err != nil {
+			return nil, err
+		}
+		encoded := base64.StdEncoding.EncodeToString(a.Data)
+		// lines of encoded data must not be longer than 76 characters
+		for len(encoded) > 76 {
+			part.Write([]byte(encoded[:76] + "\r\n"))
+			encoded = encoded[76:]
+		}
+		_, err = part.Write([]byte(encoded + "\r\n"))
+		if err != nil {
+			return nil, err
+		}
+	}
+	err = parts.Close()
+	if err != nil {
+		return nil, err
+	}
+	buf.Write(body.Bytes())
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,15 @@
+package mockmailer
+
+import (
+	"github.com/sfu-teamproject/smartbuy/backend/mailer"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockMailer struct {
+	mock.Mock
+}
+
+func (m *MockMailer) Send(msg mailer.Message) error {
+	args := m.Called(msg)
+	return args.Error(0)
+}
@@ -1,6 +1,8 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"log"
 	"net/http"
 	"os"
@@ -23,6 +25,9 @@ import (
 // @in header
 // @name Authorization
 func main() {
+	reconcileRatings := flag.Bool("reconcile-ratings", false,
+		"recompute ratings of all smartphones from reviews and exit")
+	flag.Parse()
 	godotenv.Load()
 	logger, err := logger.NewConsoleLogger()
 	if err != nil {
@@ -37,8 +42,25 @@ func main() {
 		logger.Errorf("Error creating database: %v", err)
 		os.Exit(1)
 	}
+	if *reconcileRatings {
+		fixed, err := postgres.ReconcileRatings()
+		if err != nil {
+			logger.Errorf("Error reconciling ratings: %v", err)
+			os.Exit(1)
+		}
+		for _, sm := range fixed {
+			logger.Infof("Smartphone %d: ratings_sum = %d, ratings_count = %d",
+				sm.ID, sm.RatingsSum, sm.RatingsCount)
+		}
+		logger.Infof("Ratings of %d smartphones reconciled", len(fixed))
+		return
+	}
 	a := app.NewApp(logger, server, postgres)
 	a.Server.Handler = a.NewRouter()
+	go a.StartCartReminders(context.Background())
+	go a.StartIdempotencyKeyCleanup(context.Background())
+	go a.StartRateLimitCleanup(context.Background())
+	go a.StartGuestCartCleanup(context.Background())
 	a.Log.Infof("Starting server on %s", a.Server.Addr)
 	err = a.Server.ListenAndServe()
 	if err != nil {
@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// Address is a saved shipping address of a user, one of them is the default
+type Address struct {
+	ID         int       `json:"id"`
+	UserID     int       `json:"user_id"`
+	Recipient  string    `json:"recipient"`
+	Phone      string    `json:"phone"`
+	City       string    `json:"city"`
+	Street     string    `json:"street"`
+	PostalCode string    `json:"postal_code"`
+	IsDefault  bool      `json:"is_default"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type AddressRequest struct {
+	Recipient  string `json:"recipient"`
+	Phone      string `json:"phone"`
+	City       string `json:"city"`
+	Street     string `json:"street"`
+	PostalCode string `json:"postal_code"`
+	IsDefault  bool   `json:"is_default"`
+}
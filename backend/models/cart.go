@@ -8,4 +8,43 @@ type Cart struct {
 	CreatedAt time.Time  `json:"created_at"`
 	UpdatedAt time.Time  `json:"updated_at"`
 	Items     []CartItem `json:"items,omitzero"`
+	// saved for later, not counted in totals
+	SavedItems []CartItem `json:"saved_items,omitzero"`
+	ItemCount  int        `json:"item_count"`
+	Subtotal   int        `json:"subtotal"`
+	// in grams
+	Weight int `json:"weight"`
+	// some items cost differently than when they were added
+	PriceChanged bool `json:"price_changed"`
+	// promo code applied to the cart and the discount it gives now
+	PromoCodeID *int               `json:"promo_code_id,omitempty"`
+	Discount    *DiscountBreakdown `json:"discount,omitempty"`
+	PromoError  string             `json:"promo_error,omitempty"`
+	// VAT of the items after the discount
+	Tax *TaxBreakdown `json:"tax,omitempty"`
+}
+
+// SetItems puts items into the cart separating saved for later ones, sums
+// quantities, line totals and weights of the rest and checks if their prices
+// changed
+func (c *Cart) SetItems(items []CartItem) {
+	c.Items = []CartItem{}
+	c.SavedItems = []CartItem{}
+	c.ItemCount = 0
+	c.Subtotal = 0
+	c.Weight = 0
+	c.PriceChanged = false
+	for _, item := range items {
+		if item.State == CartItemSaved {
+			c.SavedItems = append(c.SavedItems, item)
+			continue
+		}
+		c.Items = append(c.Items, item)
+		c.ItemCount += item.Quantity
+		c.Subtotal += item.LineTotal
+		if item.Smartphone != nil {
+			c.Weight += item.Smartphone.Weight * item.Quantity
+		}
+		c.PriceChanged = c.PriceChanged || item.PriceChange != 0
+	}
 }
@@ -1,10 +1,28 @@
 package models
 
+type CartItemState string
+
+const (
+	CartItemActive CartItemState = "active"
+	// saved for later items are kept in the cart but not counted or ordered
+	CartItemSaved CartItemState = "saved"
+)
+
 type CartItem struct {
 	ID           int `json:"id"`
 	CartID       int `json:"cart_id"`
 	SmartphoneID int `json:"smartphone_id"`
 	Quantity     int `json:"quantity"`
+	// price of the smartphone when the item was added to the cart
+	AddedPrice int           `json:"added_price"`
+	State      CartItemState `json:"state"`
+	// filled when items of a cart are listed, prices are current
+	Smartphone *SmartphoneSummary `json:"smartphone,omitempty"`
+	LineTotal  int                `json:"line_total,omitempty"`
+	// current price minus AddedPrice, positive if the price went up
+	PriceChange int `json:"price_change,omitempty"`
+	// VAT of the line after the promo discount, not set for saved items
+	Tax *LineTax `json:"tax,omitempty"`
 }
 
 type CartItemRequest struct {
@@ -15,3 +33,7 @@ type CartItemRequest struct {
 type SetItemQuantity struct {
 	Quantity int `json:"quantity"`
 }
+
+type SetItemState struct {
+	State CartItemState `json:"state"`
+}
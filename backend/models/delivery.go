@@ -0,0 +1,59 @@
+package models
+
+import "time"
+
+type DeliveryType string
+
+const (
+	DeliveryCourier DeliveryType = "courier"
+	DeliveryPickup  DeliveryType = "pickup"
+	DeliveryPost    DeliveryType = "post"
+)
+
+// DeliveryMethod costs BaseCost plus CostPerKg for every started kilogram of
+// the cart. Carts from FreeFrom are delivered for free, carts heavier than
+// MaxWeight grams can not be delivered. Nil limits are not checked
+type DeliveryMethod struct {
+	ID        int          `json:"id"`
+	Name      string       `json:"name"`
+	Type      DeliveryType `json:"type"`
+	BaseCost  int          `json:"base_cost"`
+	CostPerKg int          `json:"cost_per_kg"`
+	FreeFrom  *int         `json:"free_from"`
+	MaxWeight *int         `json:"max_weight"`
+	Active    bool         `json:"active"`
+	CreatedAt time.Time    `json:"created_at"`
+}
+
+type DeliveryMethodRequest struct {
+	Name      string       `json:"name"`
+	Type      DeliveryType `json:"type"`
+	BaseCost  int          `json:"base_cost"`
+	CostPerKg int          `json:"cost_per_kg"`
+	FreeFrom  *int         `json:"free_from"`
+	MaxWeight *int         `json:"max_weight"`
+	Active    bool         `json:"active"`
+}
+
+// ShippingQuote is the cost of delivering a cart with a delivery method
+type ShippingQuote struct {
+	Method DeliveryMethod `json:"method"`
+	Cost   int            `json:"cost"`
+}
+
+func (t DeliveryType) IsValid() bool {
+	return t == DeliveryCourier || t == DeliveryPickup || t == DeliveryPost
+}
+
+// Quote returns the cost of delivering a cart with the total price and the
+// weight in grams, ok is false if the method can not deliver it
+func (m DeliveryMethod) Quote(total, weight int) (cost int, ok bool) {
+	if m.MaxWeight != nil && weight > *m.MaxWeight {
+		return 0, false
+	}
+	if m.FreeFrom != nil && total >= *m.FreeFrom {
+		return 0, true
+	}
+	kilograms := (weight + 999) / 1000
+	return m.BaseCost + m.CostPerKg*kilograms, true
+}
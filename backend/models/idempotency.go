@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// IdempotencyKey is a request sent with the Idempotency-Key header and the
+// response to it. Keys are unique within a scope, which identifies the client
+// that sent the request
+type IdempotencyKey struct {
+	Scope       string `json:"scope"`
+	Key         string `json:"key"`
+	RequestHash string `json:"request_hash"`
+	// zero while the first request with the key is being handled
+	StatusCode int                 `json:"status_code"`
+	Headers    map[string][]string `json:"headers"`
+	Body       []byte              `json:"body"`
+	CreatedAt  time.Time           `json:"created_at"`
+}
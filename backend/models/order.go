@@ -0,0 +1,89 @@
+package models
+
+import (
+	"slices"
+	"time"
+)
+
+type OrderStatus string
+
+const (
+	OrderCreated   OrderStatus = "created"
+	OrderPaid      OrderStatus = "paid"
+	OrderShipped   OrderStatus = "shipped"
+	OrderDelivered OrderStatus = "delivered"
+	OrderCancelled OrderStatus = "cancelled"
+	OrderRefunded  OrderStatus = "refunded"
+)
+
+// orderTransitions lists statuses an order can move to from each status,
+// cancelled and refunded orders are final
+var orderTransitions = map[OrderStatus][]OrderStatus{
+	OrderCreated:   {OrderPaid, OrderCancelled},
+	OrderPaid:      {OrderShipped, OrderRefunded},
+	OrderShipped:   {OrderDelivered, OrderRefunded},
+	OrderDelivered: {OrderRefunded},
+}
+
+func (s OrderStatus) IsValid() bool {
+	switch s {
+	case OrderCreated, OrderPaid, OrderShipped, OrderDelivered, OrderCancelled, OrderRefunded:
+		return true
+	}
+	return false
+}
+
+// CanTransitionTo reports whether an order with status s can be moved to status to
+func (s OrderStatus) CanTransitionTo(to OrderStatus) bool {
+	return slices.Contains(orderTransitions[s], to)
+}
+
+type Order struct {
+	ID        int         `json:"id"`
+	UserID    int         `json:"user_id"`
+	Status    OrderStatus `json:"status"`
+	Total     int         `json:"total"`
+	CreatedAt time.Time   `json:"created_at"`
+	UpdatedAt time.Time   `json:"updated_at"`
+	// promo code used at checkout, Total already includes Discount
+	PromoCodeID *int `json:"promo_code_id,omitempty"`
+	Discount    int  `json:"discount"`
+	// VAT computed at checkout, Total is its Gross
+	Tax     *TaxBreakdown       `json:"tax,omitempty"`
+	Items   []OrderItem         `json:"items"`
+	History []OrderStatusChange `json:"history,omitempty"`
+}
+
+// OrderItem keeps the producer, the model name and the price of a smartphone
+// at the moment of checkout, SmartphoneID is nil if the smartphone was removed
+// from the catalog. Paid is the line total after the discount and with VAT
+type OrderItem struct {
+	ID           int    `json:"id"`
+	OrderID      int    `json:"order_id"`
+	SmartphoneID *int   `json:"smartphone_id"`
+	Model        string `json:"model"`
+	Price        int    `json:"price"`
+	Quantity     int    `json:"quantity"`
+	Producer     string `json:"producer"`
+	Paid         int    `json:"paid"`
+}
+
+// OrderStatusChange is an entry of the order history. ActorID is nil for
+// changes made by the system, e.g. by a payment provider
+type OrderStatusChange struct {
+	ID        int         `json:"id"`
+	OrderID   int         `json:"order_id"`
+	Status    OrderStatus `json:"status"`
+	ActorID   *int        `json:"actor_id"`
+	CreatedAt time.Time   `json:"created_at"`
+}
+
+// CreateOrderRequest is optional, without it checkout fails if prices of cart
+// items changed since they were added
+type CreateOrderRequest struct {
+	AcceptPriceChanges bool `json:"accept_price_changes"`
+}
+
+type OrderStatusRequest struct {
+	Status OrderStatus `json:"status"`
+}
@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+type PaymentStatus string
+
+const (
+	PaymentSucceeded PaymentStatus = "succeeded"
+	PaymentFailed    PaymentStatus = "failed"
+	PaymentRefunded  PaymentStatus = "refunded"
+)
+
+// Payment is a payment event received from the payment provider, EventID is
+// unique so that a redelivered event is recorded only once
+type Payment struct {
+	ID        int           `json:"id"`
+	OrderID   int           `json:"order_id"`
+	IntentID  string        `json:"intent_id"`
+	EventID   string        `json:"event_id"`
+	Status    PaymentStatus `json:"status"`
+	Amount    int           `json:"amount"`
+	CreatedAt time.Time     `json:"created_at"`
+}
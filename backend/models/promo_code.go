@@ -0,0 +1,60 @@
+package models
+
+import "time"
+
+type DiscountType string
+
+const (
+	DiscountPercent DiscountType = "percent"
+	DiscountFixed   DiscountType = "fixed"
+)
+
+// PromoCode gives a discount on the smartphones of the given producers or with
+// the given IDs, or on the whole cart if both lists are empty. Nil limits and
+// dates are not checked
+type PromoCode struct {
+	ID            int          `json:"id"`
+	Code          string       `json:"code"`
+	Type          DiscountType `json:"type"`
+	Value         int          `json:"value"`
+	MinTotal      int          `json:"min_total"`
+	StartsAt      *time.Time   `json:"starts_at"`
+	EndsAt        *time.Time   `json:"ends_at"`
+	UsageLimit    *int         `json:"usage_limit"`
+	PerUserLimit  *int         `json:"per_user_limit"`
+	Producers     []string     `json:"producers"`
+	SmartphoneIDs []int        `json:"smartphone_ids"`
+	UsedCount     int          `json:"used_count"`
+	CreatedAt     time.Time    `json:"created_at"`
+}
+
+type PromoCodeRequest struct {
+	Code          string       `json:"code"`
+	Type          DiscountType `json:"type"`
+	Value         int          `json:"value"`
+	MinTotal      int          `json:"min_total"`
+	StartsAt      *time.Time   `json:"starts_at"`
+	EndsAt        *time.Time   `json:"ends_at"`
+	UsageLimit    *int         `json:"usage_limit"`
+	PerUserLimit  *int         `json:"per_user_limit"`
+	Producers     []string     `json:"producers"`
+	SmartphoneIDs []int        `json:"smartphone_ids"`
+}
+
+type ApplyPromoCodeRequest struct {
+	Code string `json:"code"`
+}
+
+// DiscountBreakdown shows how a promo code changes the price of a cart or an order
+type DiscountBreakdown struct {
+	Code     string         `json:"code"`
+	Subtotal int            `json:"subtotal"`
+	Discount int            `json:"discount"`
+	Total    int            `json:"total"`
+	Lines    []LineDiscount `json:"lines"`
+}
+
+type LineDiscount struct {
+	SmartphoneID int `json:"smartphone_id"`
+	Discount     int `json:"discount"`
+}
@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// Purchase is a smartphone delivered to a user, OrderID is nil for purchases
+// added by hand. Price is the list price of one smartphone, Paid is what the
+// customer paid for all of them after discounts and with VAT. Refunded
+// purchases belong to a refunded order and can not be returned
+type Purchase struct {
+	ID           int       `json:"id"`
+	UserID       int       `json:"user_id"`
+	SmartphoneID int       `json:"smartphone_id"`
+	Quantity     int       `json:"quantity"`
+	Price        int       `json:"price"`
+	PurchasedAt  time.Time `json:"purchased_at"`
+	OrderID      *int      `json:"order_id"`
+	Paid         int       `json:"paid"`
+	Refunded     bool      `json:"refunded"`
+}
+
+type PurchaseRequest struct {
+	SmartphoneID int `json:"smartphone_id"`
+	Quantity     int `json:"quantity"`
+}
@@ -0,0 +1,96 @@
+package models
+
+import (
+	"slices"
+	"time"
+)
+
+type ReturnStatus string
+
+const (
+	ReturnRequested ReturnStatus = "requested"
+	ReturnApproved  ReturnStatus = "approved"
+	ReturnRejected  ReturnStatus = "rejected"
+	ReturnReceived  ReturnStatus = "received"
+	ReturnRefunded  ReturnStatus = "refunded"
+)
+
+// returnTransitions lists statuses a return can move to from each status,
+// rejected and refunded returns are final
+var returnTransitions = map[ReturnStatus][]ReturnStatus{
+	ReturnRequested: {ReturnApproved, ReturnRejected},
+	ReturnApproved:  {ReturnReceived},
+	ReturnReceived:  {ReturnRefunded},
+}
+
+func (s ReturnStatus) IsValid() bool {
+	switch s {
+	case ReturnRequested, ReturnApproved, ReturnRejected, ReturnReceived, ReturnRefunded:
+		return true
+	}
+	return false
+}
+
+// CanTransitionTo reports whether a return with status s can be moved to status to
+func (s ReturnStatus) CanTransitionTo(to ReturnStatus) bool {
+	return slices.Contains(returnTransitions[s], to)
+}
+
+type ReturnReason string
+
+const (
+	ReturnReasonDefective      ReturnReason = "defective"
+	ReturnReasonNotAsDescribed ReturnReason = "not_as_described"
+	ReturnReasonWrongItem      ReturnReason = "wrong_item"
+	ReturnReasonChangedMind    ReturnReason = "changed_mind"
+	ReturnReasonOther          ReturnReason = "other"
+)
+
+func (r ReturnReason) IsValid() bool {
+	switch r {
+	case ReturnReasonDefective, ReturnReasonNotAsDescribed, ReturnReasonWrongItem,
+		ReturnReasonChangedMind, ReturnReasonOther:
+		return true
+	}
+	return false
+}
+
+// Return is a request of a customer to send back smartphones of a purchase.
+// RefundAmount is set by an admin when the money is returned
+type Return struct {
+	ID           int           `json:"id"`
+	PurchaseID   int           `json:"purchase_id"`
+	UserID       int           `json:"user_id"`
+	Quantity     int           `json:"quantity"`
+	Reason       ReturnReason  `json:"reason"`
+	Comment      *string       `json:"comment,omitempty"`
+	Status       ReturnStatus  `json:"status"`
+	RefundAmount *int          `json:"refund_amount,omitempty"`
+	AdminComment *string       `json:"admin_comment,omitempty"`
+	CreatedAt    time.Time     `json:"created_at"`
+	UpdatedAt    time.Time     `json:"updated_at"`
+	Photos       []ReturnPhoto `json:"photos,omitempty"`
+}
+
+type ReturnPhoto struct {
+	ID            int       `json:"id"`
+	ReturnID      int       `json:"return_id"`
+	Path          string    `json:"path"`
+	ThumbnailPath string    `json:"thumbnail_path"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+type ReturnRequest struct {
+	PurchaseID int          `json:"purchase_id"`
+	Quantity   int          `json:"quantity"`
+	Reason     ReturnReason `json:"reason" example:"defective"`
+	Comment    *string      `json:"comment,omitempty"`
+}
+
+// ReturnStatusRequest moves a return to another status, RefundAmount is
+// required for refunded
+type ReturnStatusRequest struct {
+	Status       ReturnStatus `json:"status"`
+	RefundAmount *int         `json:"refund_amount,omitempty"`
+	Comment      *string      `json:"comment,omitempty"`
+}
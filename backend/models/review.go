@@ -3,17 +3,83 @@ package models
 import "time"
 
 type Review struct {
-	ID           int       `json:"id"`
-	SmartphoneID int       `json:"smartphone_id"`
-	UserID       int       `json:"user_id"`
-	UserName     string    `json:"user_name,omitzero"`
-	Rating       int       `json:"rating"`
-	Comment      *string   `json:"comment,omitempty"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
+	ID           int    `json:"id"`
+	SmartphoneID int    `json:"smartphone_id"`
+	UserID       int    `json:"user_id"`
+	UserName     string `json:"user_name,omitzero"`
+	// filled only in exports
+	SmartphoneModel string    `json:"smartphone_model,omitzero"`
+	Rating          int       `json:"rating"`
+	Comment         *string   `json:"comment,omitempty"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+	Verified        bool      `json:"verified"`
+	Hidden          bool      `json:"hidden,omitzero"`
+	// decision of the content filter about the comment
+	FilterAction  string        `json:"filter_action"`
+	FilterReasons []string      `json:"filter_reasons,omitempty"`
+	Photos        []ReviewPhoto `json:"photos,omitempty"`
+	Reply         *ReviewReply  `json:"reply,omitempty"`
+}
+
+type ReviewPhoto struct {
+	ID            int       `json:"id"`
+	ReviewID      int       `json:"review_id"`
+	Path          string    `json:"path"`
+	ThumbnailPath string    `json:"thumbnail_path"`
+	CreatedAt     time.Time `json:"created_at"`
 }
 
 type ReviewRequest struct {
 	Rating  int     `json:"rating"`
 	Comment *string `json:"comment,omitempty"`
 }
+
+type ReviewReply struct {
+	ID       int `json:"id"`
+	ReviewID int `json:"review_id"`
+	// admin who replied, nil if the admin was deleted, the reply stays
+	UserID    *int      `json:"user_id"`
+	Text      string    `json:"text"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type ReviewReplyRequest struct {
+	Text string `json:"text"`
+}
+
+type ReportReason string
+
+const (
+	ReportReasonSpam      ReportReason = "spam"
+	ReportReasonOffensive ReportReason = "offensive"
+	ReportReasonOther     ReportReason = "other"
+)
+
+func (r ReportReason) IsValid() bool {
+	return r == ReportReasonSpam || r == ReportReasonOffensive || r == ReportReasonOther
+}
+
+type ReviewReport struct {
+	ID        int          `json:"id"`
+	ReviewID  int          `json:"review_id"`
+	UserID    int          `json:"user_id"`
+	Reason    ReportReason `json:"reason"`
+	Comment   *string      `json:"comment,omitempty"`
+	CreatedAt time.Time    `json:"created_at"`
+}
+
+type ReviewReportRequest struct {
+	Reason  ReportReason `json:"reason" example:"spam"`
+	Comment *string      `json:"comment,omitempty"`
+}
+
+type ReportedReview struct {
+	Review
+	Reports []ReviewReport `json:"reports"`
+}
+
+type ModerateReviewRequest struct {
+	Hidden bool `json:"hidden"`
+}
@@ -1,16 +1,53 @@
 package models
 
+import "math"
+
+// ScorePriorWeight is the number of virtual reviews with the catalog average
+// rating that are added to every smartphone when its score is computed
+const ScorePriorWeight = 5
+
 type Smartphone struct {
-	ID           int      `json:"id"`
-	Model        string   `json:"model"`
-	Producer     string   `json:"producer"`
-	Memory       int      `json:"memory"`
-	Ram          int      `json:"ram"`
-	DisplaySize  float32  `json:"display_size"`
-	Price        int      `json:"price"`
-	RatingsSum   int      `json:"ratings_sum"`
-	RatingsCount int      `json:"ratings_count"`
-	ImagePath    string   `json:"image_path"`
-	Description  string   `json:"description"`
-	Reviews      []Review `json:"reviews,omitempty"`
+	ID           int     `json:"id"`
+	Model        string  `json:"model"`
+	Producer     string  `json:"producer"`
+	Memory       int     `json:"memory"`
+	Ram          int     `json:"ram"`
+	DisplaySize  float32 `json:"display_size"`
+	Price        int     `json:"price"`
+	RatingsSum   int     `json:"ratings_sum"`
+	RatingsCount int     `json:"ratings_count"`
+	ImagePath    string  `json:"image_path"`
+	Description  string  `json:"description"`
+	// in grams
+	Weight   int      `json:"weight"`
+	Category string   `json:"category"`
+	Rating   float64  `json:"rating"`
+	Score    float64  `json:"score"`
+	Reviews  []Review `json:"reviews,omitempty"`
+}
+
+// SmartphoneSummary is the part of a smartphone shown in carts
+type SmartphoneSummary struct {
+	ID        int    `json:"id"`
+	Model     string `json:"model"`
+	Producer  string `json:"producer"`
+	Price     int    `json:"price"`
+	ImagePath string `json:"image_path"`
+	Weight    int    `json:"weight"`
+	Category  string `json:"category"`
+}
+
+// SetRating computes the average rating and the bayesian score of the smartphone,
+// the score pulls ratings of smartphones with few reviews towards catalogMean
+func (sm *Smartphone) SetRating(catalogMean float64) {
+	sm.Rating = 0
+	if sm.RatingsCount > 0 {
+		sm.Rating = round2(float64(sm.RatingsSum) / float64(sm.RatingsCount))
+	}
+	score := (ScorePriorWeight*catalogMean + float64(sm.RatingsSum)) / float64(ScorePriorWeight+sm.RatingsCount)
+	sm.Score = round2(score)
+}
+
+func round2(x float64) float64 {
+	return math.Round(x*100) / 100
 }
@@ -0,0 +1,28 @@
+package models
+
+// LineTax is the VAT of a cart line. Net and Gross are the line cost after
+// the promo discount without and with the tax, rate is in percent
+type LineTax struct {
+	Category string  `json:"category"`
+	Rate     float64 `json:"rate"`
+	Net      int     `json:"net"`
+	Tax      int     `json:"tax"`
+	Gross    int     `json:"gross"`
+}
+
+// RateTax sums lines taxed with the same rate
+type RateTax struct {
+	Rate float64 `json:"rate"`
+	Net  int     `json:"net"`
+	Tax  int     `json:"tax"`
+}
+
+// TaxBreakdown is the VAT of a whole cart. If prices include the tax Gross is
+// what the customer pays, otherwise the tax is added on top of Net
+type TaxBreakdown struct {
+	PricesIncludeTax bool      `json:"prices_include_tax"`
+	Net              int       `json:"net"`
+	Tax              int       `json:"tax"`
+	Gross            int       `json:"gross"`
+	Rates            []RateTax `json:"rates"`
+}
@@ -2,12 +2,34 @@ package models
 
 import "time"
 
+// TmpPasswordPurpose tells what a one-time password can be used for
+type TmpPasswordPurpose string
+
+const (
+	// TmpPasswordSignup confirms the email of a new user and logs them in
+	TmpPasswordSignup TmpPasswordPurpose = "signup"
+	// TmpPasswordReset only allows to set a new password
+	TmpPasswordReset TmpPasswordPurpose = "reset"
+)
+
 type TmpPassword struct {
-	Password  string    `json:"password"`
-	Email     string    `json:"email"`
-	ExpiresAt time.Time `json:"expires_at"`
+	// Password is set only when the password is generated, the database
+	// keeps PasswordHash
+	Password     string             `json:"-"`
+	PasswordHash string             `json:"-"`
+	Email        string             `json:"email"`
+	ExpiresAt    time.Time          `json:"expires_at"`
+	Purpose      TmpPasswordPurpose `json:"purpose"`
+	// number of checks of the password
+	Attempts int `json:"attempts"`
 }
 
 type TmpRequest struct {
 	Email string `json:"email"`
 }
+
+type ResetPasswordRequest struct {
+	Email       string `json:"email"`
+	Password    string `json:"password"`
+	NewPassword string `json:"new_password"`
+}
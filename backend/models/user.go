@@ -15,7 +15,11 @@ type User struct {
 	Password  *string   `json:"-"`
 	Role      Role      `json:"role"`
 	CreatedAt time.Time `json:"created_at"`
-	Cart      Cart      `json:"cart,omitzero"`
+	// the user gets abandoned cart reminders
+	CartReminders bool `json:"cart_reminders"`
+	// access tokens issued before are not accepted
+	TokensRevokedAt *time.Time `json:"-"`
+	Cart            Cart       `json:"cart,omitzero"`
 }
 
 type SignUpRequest struct {
@@ -29,14 +33,16 @@ type LoginRequest struct {
 }
 
 type LoginResponse struct {
-	User  User   `json:"user"`
-	Token string `json:"token"`
+	User         User   `json:"user"`
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
 }
 
 type UpdateRequest struct {
-	Name     string `json:"name"`
-	Avatar   string `json:"avatar"`
-	Password string `json:"password"`
+	Name          string `json:"name"`
+	Avatar        string `json:"avatar"`
+	Password      string `json:"password"`
+	CartReminders bool   `json:"cart_reminders"`
 }
 
 type SetLang struct {
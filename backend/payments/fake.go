@@ -0,0 +1,103 @@
+package payments
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// FakeProvider is an in-memory provider for development and tests, it never
+// talks to the network. Webhook events are produced by SignedEvent
+type FakeProvider struct {
+	secret  []byte
+	mu      sync.Mutex
+	intents map[string]Intent
+}
+
+func NewFakeProvider(secret string) *FakeProvider {
+	return &FakeProvider{secret: []byte(secret), intents: map[string]Intent{}}
+}
+
+func (p *FakeProvider) CreateIntent(orderID, amount int) (Intent, error) {
+	if amount <= 0 {
+		return Intent{}, fmt.Errorf("invalid amount %d", amount)
+	}
+	intent := Intent{
+		ID:           "pi_" + randomHex(),
+		OrderID:      orderID,
+		Amount:       amount,
+		Status:       IntentRequiresCapture,
+		ClientSecret: "secret_" + randomHex(),
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.intents[intent.ID] = intent
+	return intent, nil
+}
+
+func (p *FakeProvider) Capture(intentID string) (Intent, error) {
+	return p.move(intentID, IntentRequiresCapture, IntentSucceeded)
+}
+
+func (p *FakeProvider) Refund(intentID string) (Intent, error) {
+	return p.move(intentID, IntentSucceeded, IntentRefunded)
+}
+
+func (p *FakeProvider) move(intentID string, from, to IntentStatus) (Intent, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	intent, ok := p.intents[intentID]
+	if !ok {
+		return Intent{}, fmt.Errorf("%w: %s", ErrUnknownIntent, intentID)
+	}
+	if intent.Status != from {
+		return intent, fmt.Errorf("%w: intent %s is %s", ErrInvalidState, intentID, intent.Status)
+	}
+	intent.Status = to
+	p.intents[intentID] = intent
+	return intent, nil
+}
+
+func (p *FakeProvider) VerifyWebhook(payload []byte, signature string) (Event, error) {
+	err := VerifySignature(p.secret, payload, signature)
+	if err != nil {
+		return Event{}, err
+	}
+	var event Event
+	err = json.Unmarshal(payload, &event)
+	if err != nil {
+		return Event{}, fmt.Errorf("error decoding event: %w", err)
+	}
+	return event, nil
+}
+
+// SignedEvent builds a webhook request body for an event of the intent the
+// way a real provider would send it
+func (p *FakeProvider) SignedEvent(intentID string, eventType EventType) (payload []byte, signature string, err error) {
+	p.mu.Lock()
+	intent, ok := p.intents[intentID]
+	p.mu.Unlock()
+	if !ok {
+		return nil, "", fmt.Errorf("%w: %s", ErrUnknownIntent, intentID)
+	}
+	event := Event{
+		ID:       "evt_" + randomHex(),
+		Type:     eventType,
+		IntentID: intent.ID,
+		OrderID:  intent.OrderID,
+		Amount:   intent.Amount,
+	}
+	payload, err = json.Marshal(event)
+	if err != nil {
+		return nil, "", err
+	}
+	return payload, Sign(p.secret, payload), nil
+}
+
+func randomHex() string {
+	b := make([]byte, 12)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
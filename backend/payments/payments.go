@@ -0,0 +1,88 @@
+// Package payments connects the shop to a payment provider
+package payments
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+)
+
+var (
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+	ErrUnknownIntent    = errors.New("unknown payment intent")
+	ErrInvalidState     = errors.New("payment intent is in invalid state")
+)
+
+type IntentStatus string
+
+const (
+	IntentRequiresCapture IntentStatus = "requires_capture"
+	IntentSucceeded       IntentStatus = "succeeded"
+	IntentRefunded        IntentStatus = "refunded"
+)
+
+// Intent is a payment of an order that the customer completes on the provider side
+type Intent struct {
+	ID           string       `json:"id"`
+	OrderID      int          `json:"order_id"`
+	Amount       int          `json:"amount"`
+	Status       IntentStatus `json:"status"`
+	ClientSecret string       `json:"client_secret"`
+}
+
+type EventType string
+
+const (
+	EventSucceeded EventType = "payment.succeeded"
+	EventFailed    EventType = "payment.failed"
+	EventRefunded  EventType = "payment.refunded"
+)
+
+// Event is a notification sent by the provider to the webhook, ID is unique
+// for every event and is used to skip redelivered events
+type Event struct {
+	ID       string    `json:"id"`
+	Type     EventType `json:"type"`
+	IntentID string    `json:"intent_id"`
+	OrderID  int       `json:"order_id"`
+	Amount   int       `json:"amount"`
+}
+
+// Provider is a payment provider. Amounts are in the same units as prices of smartphones
+type Provider interface {
+	// CreateIntent starts a payment of amount for the order
+	CreateIntent(orderID, amount int) (Intent, error)
+	// Capture charges the money authorized by the customer
+	Capture(intentID string) (Intent, error)
+	// Refund returns the money of a captured payment to the customer
+	Refund(intentID string) (Intent, error)
+	// VerifyWebhook checks the signature of a webhook request and decodes its event
+	VerifyWebhook(payload []byte, signature string) (Event, error)
+}
+
+// Sign returns the signature of payload in the "sha256=<hex hmac>" format
+func Sign(secret, payload []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifySignature checks a signature made by Sign in constant time
+func VerifySignature(secret, payload []byte, signature string) error {
+	hexMAC, ok := strings.CutPrefix(signature, "sha256=")
+	if !ok {
+		return ErrInvalidSignature
+	}
+	got, err := hex.DecodeString(hexMAC)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(payload)
+	if !hmac.Equal(got, mac.Sum(nil)) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
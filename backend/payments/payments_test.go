@@ -0,0 +1,75 @@
+package payments
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSignature(t *testing.T) {
+	secret := []byte("secret")
+	payload := []byte(`{"id":"evt_1"}`)
+	signature := Sign(secret, payload)
+	tests := []struct {
+		name      string
+		payload   []byte
+		signature string
+		err       error
+	}{
+		{"Valid signature", payload, signature, nil},
+		{"Modified payload", []byte(`{"id":"evt_2"}`), signature, ErrInvalidSignature},
+		{"Missing prefix", payload, signature[len("sha256="):], ErrInvalidSignature},
+		{"Not hex", payload, "sha256=zz", ErrInvalidSignature},
+		{"Empty signature", payload, "", ErrInvalidSignature},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := VerifySignature(secret, tt.payload, tt.signature)
+			assert.ErrorIs(t, err, tt.err)
+		})
+	}
+}
+
+func TestFakeProvider(t *testing.T) {
+	p := NewFakeProvider("secret")
+	intent, err := p.CreateIntent(1, 500)
+	assert.NoError(t, err)
+	assert.Equal(t, IntentRequiresCapture, intent.Status)
+	_, err = p.Refund(intent.ID)
+	assert.ErrorIs(t, err, ErrInvalidState, "refunded before capture")
+	intent, err = p.Capture(intent.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, IntentSucceeded, intent.Status)
+	intent, err = p.Refund(intent.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, IntentRefunded, intent.Status)
+	_, err = p.Capture("pi_unknown")
+	assert.ErrorIs(t, err, ErrUnknownIntent)
+	_, err = p.CreateIntent(1, 0)
+	assert.Error(t, err)
+}
+
+func TestFakeProviderWebhook(t *testing.T) {
+	p := NewFakeProvider("secret")
+	intent, err := p.CreateIntent(7, 500)
+	assert.NoError(t, err)
+	payload, signature, err := p.SignedEvent(intent.ID, EventSucceeded)
+	assert.NoError(t, err)
+	event, err := p.VerifyWebhook(payload, signature)
+	assert.NoError(t, err)
+	assert.Equal(t, EventSucceeded, event.Type)
+	assert.Equal(t, 7, event.OrderID)
+	assert.Equal(t, 500, event.Amount)
+
+	other := NewFakeProvider("other secret")
+	_, err = other.VerifyWebhook(payload, signature)
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+
+	var decoded map[string]any
+	assert.NoError(t, json.Unmarshal(payload, &decoded))
+	decoded["amount"] = 1
+	forged, _ := json.Marshal(decoded)
+	_, err = p.VerifyWebhook(forged, signature)
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+}
@@ -0,0 +1,73 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// sweepPeriod is how often MemoryStore drops expired counters by itself
+const sweepPeriod = time.Minute
+
+type counter struct {
+	count   int
+	resetAt time.Time
+}
+
+// MemoryStore keeps counters in memory of a single instance of the server
+type MemoryStore struct {
+	mu        sync.Mutex
+	counters  map[string]counter
+	lastSweep time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{counters: map[string]counter{}}
+}
+
+func (s *MemoryStore) Hit(key string, window time.Duration, now time.Time) (int, time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if now.Sub(s.lastSweep) >= sweepPeriod {
+		s.sweep(now)
+	}
+	c, ok := s.counters[key]
+	if !ok || !c.resetAt.After(now) {
+		c = counter{resetAt: now.Add(window)}
+	}
+	c.count++
+	s.counters[key] = c
+	return c.count, c.resetAt, nil
+}
+
+func (s *MemoryStore) Get(key string, now time.Time) (int, time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.counters[key]
+	if !ok || !c.resetAt.After(now) {
+		return 0, time.Time{}, nil
+	}
+	return c.count, c.resetAt, nil
+}
+
+func (s *MemoryStore) Reset(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.counters, key)
+	return nil
+}
+
+func (s *MemoryStore) Sweep(now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sweep(now)
+	return nil
+}
+
+func (s *MemoryStore) sweep(now time.Time) {
+	for key, c := range s.counters {
+		if !c.resetAt.After(now) {
+			delete(s.counters, key)
+		}
+	}
+	s.lastSweep = now
+}
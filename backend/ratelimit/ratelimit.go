@@ -0,0 +1,119 @@
+// Package ratelimit counts requests in fixed time windows and locks out keys
+// after repeated failures
+package ratelimit
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Store keeps hit counters of keys. Counters of expired windows are treated
+// as empty
+type Store interface {
+	// Hit counts a hit of the key and returns the number of hits in the
+	// current window and its end. A new window starts with the first hit
+	// after the previous one ends
+	Hit(key string, window time.Duration, now time.Time) (int, time.Time, error)
+	// Get returns the counter of the key without changing it
+	Get(key string, now time.Time) (int, time.Time, error)
+	Reset(key string) error
+	// Sweep deletes counters of windows that ended before now
+	Sweep(now time.Time) error
+}
+
+// Rule allows Limit hits per Window, a zero Limit disables the rule
+type Rule struct {
+	Limit  int
+	Window time.Duration
+}
+
+// ParseRule reads a rule written as limit/window, for example "5/1m"
+func ParseRule(spec string) (Rule, error) {
+	limit, window, ok := strings.Cut(spec, "/")
+	if !ok {
+		return Rule{}, fmt.Errorf("invalid rule %q, want limit/window", spec)
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(limit))
+	if err != nil || n < 0 {
+		return Rule{}, fmt.Errorf("invalid limit in rule %q", spec)
+	}
+	d, err := time.ParseDuration(strings.TrimSpace(window))
+	if err != nil || d <= 0 {
+		return Rule{}, fmt.Errorf("invalid window in rule %q", spec)
+	}
+	return Rule{Limit: n, Window: d}, nil
+}
+
+// Allow counts a hit of the key under the rule. If the limit is exceeded it
+// returns false and the time until the window ends
+func Allow(store Store, rule Rule, key string, now time.Time) (bool, time.Duration, error) {
+	if rule.Limit == 0 {
+		return true, 0, nil
+	}
+	count, resetAt, err := store.Hit(key, rule.Window, now)
+	if err != nil {
+		return false, 0, err
+	}
+	if count > rule.Limit {
+		return false, resetAt.Sub(now), nil
+	}
+	return true, 0, nil
+}
+
+// Lockout locks a key out after MaxFailures failures. The first lockout
+// lasts Base, every next failure after it doubles the lockout up to Max.
+// Failures are forgotten Window after the first one or after a success
+type Lockout struct {
+	Store       Store
+	MaxFailures int
+	Base        time.Duration
+	Max         time.Duration
+	Window      time.Duration
+}
+
+func failuresKey(key string) string { return "failures:" + key }
+func lockKey(key string) string     { return "lock:" + key }
+
+// Check returns how long the key stays locked out, zero if it is not
+func (l Lockout) Check(key string, now time.Time) (time.Duration, error) {
+	if l.MaxFailures == 0 {
+		return 0, nil
+	}
+	count, resetAt, err := l.Store.Get(lockKey(key), now)
+	if err != nil || count == 0 {
+		return 0, err
+	}
+	return resetAt.Sub(now), nil
+}
+
+// Fail records a failure of the key and returns the lockout it causes, zero
+// if there are fewer than MaxFailures failures
+func (l Lockout) Fail(key string, now time.Time) (time.Duration, error) {
+	if l.MaxFailures == 0 {
+		return 0, nil
+	}
+	failures, _, err := l.Store.Hit(failuresKey(key), l.Window, now)
+	if err != nil || failures < l.MaxFailures {
+		return 0, err
+	}
+	lockout := l.Base
+	for range failures - l.MaxFailures {
+		if lockout >= l.Max {
+			break
+		}
+		lockout *= 2
+	}
+	lockout = min(lockout, l.Max)
+	_, _, err = l.Store.Hit(lockKey(key), lockout, now)
+	return lockout, err
+}
+
+// Succeed forgets failures of the key
+func (l Lockout) Succeed(key string) error {
+	if l.MaxFailures == 0 {
+		return nil
+	}
+	return l.Store.Reset(failuresKey(key))
+}
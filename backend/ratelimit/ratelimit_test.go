@@ -0,0 +1,105 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRule(t *testing.T) {
+	rule, err := ParseRule(" 5 / 1m ")
+	assert.NoError(t, err)
+	assert.Equal(t, Rule{Limit: 5, Window: time.Minute}, rule)
+
+	rule, err = ParseRule("0/1h")
+	assert.NoError(t, err)
+	assert.Equal(t, Rule{Limit: 0, Window: time.Hour}, rule)
+
+	for _, spec := range []string{"5", "/1m", "abc/1m", "-1/1m", "5/abc", "5/0s", "5/-1m"} {
+		_, err := ParseRule(spec)
+		assert.Error(t, err, spec)
+	}
+}
+
+func TestAllow(t *testing.T) {
+	store := NewMemoryStore()
+	rule := Rule{Limit: 2, Window: time.Minute}
+	now := time.Now()
+	for range 2 {
+		allowed, _, err := Allow(store, rule, "key", now)
+		assert.NoError(t, err)
+		assert.True(t, allowed)
+	}
+	allowed, wait, err := Allow(store, rule, "key", now.Add(20*time.Second))
+	assert.NoError(t, err)
+	assert.False(t, allowed)
+	assert.Equal(t, 40*time.Second, wait)
+
+	allowed, _, err = Allow(store, rule, "other", now)
+	assert.NoError(t, err)
+	assert.True(t, allowed, "keys share a counter")
+
+	allowed, _, err = Allow(store, rule, "key", now.Add(time.Minute))
+	assert.NoError(t, err)
+	assert.True(t, allowed, "new window is not started")
+
+	allowed, _, err = Allow(store, Rule{Window: time.Minute}, "key", now)
+	assert.NoError(t, err)
+	assert.True(t, allowed, "zero limit is not disabled")
+}
+
+func TestLockout(t *testing.T) {
+	lockout := Lockout{Store: NewMemoryStore(), MaxFailures: 3, Base: time.Minute, Max: 5 * time.Minute,
+		Window: time.Hour}
+	now := time.Now()
+	for range 2 {
+		locked, err := lockout.Fail("key", now)
+		assert.NoError(t, err)
+		assert.Zero(t, locked)
+	}
+	// every failure after the lockout ends doubles the next one up to Max
+	for _, want := range []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute, 5 * time.Minute} {
+		locked, err := lockout.Fail("key", now)
+		assert.NoError(t, err)
+		assert.Equal(t, want, locked)
+		wait, err := lockout.Check("key", now.Add(30*time.Second))
+		assert.NoError(t, err)
+		assert.Equal(t, want-30*time.Second, wait)
+		now = now.Add(want)
+		wait, err = lockout.Check("key", now)
+		assert.NoError(t, err)
+		assert.Zero(t, wait, "lockout does not end")
+	}
+
+	now = now.Add(time.Hour)
+	locked, err := lockout.Fail("key", now)
+	assert.NoError(t, err)
+	assert.Zero(t, locked, "failures are not forgotten after the window")
+
+	assert.NoError(t, lockout.Succeed("key"))
+	for range 2 {
+		locked, err = lockout.Fail("key", now)
+		assert.NoError(t, err)
+		assert.Zero(t, locked, "failures are not forgotten after success")
+	}
+
+	disabled := Lockout{Store: NewMemoryStore(), Base: time.Minute, Max: time.Hour, Window: time.Hour}
+	for range 10 {
+		locked, err := disabled.Fail("key", now)
+		assert.NoError(t, err)
+		assert.Zero(t, locked, "disabled lockout locks")
+	}
+}
+
+func TestMemoryStoreSweep(t *testing.T) {
+	store := NewMemoryStore()
+	now := time.Now()
+	store.Hit("short", time.Second, now)
+	store.Hit("long", time.Hour, now)
+	assert.NoError(t, store.Sweep(now.Add(time.Minute)))
+	assert.Len(t, store.counters, 1)
+	count, _, err := store.Get("long", now.Add(time.Minute))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+}
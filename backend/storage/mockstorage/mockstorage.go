@@ -1,6 +1,8 @@
 package mockstorage
 
 import (
+	"time"
+
 	"github.com/sfu-teamproject/smartbuy/backend/models"
 	"github.com/stretchr/testify/mock"
 )
@@ -24,6 +26,11 @@ func (m *MockStorage) GetSmartphonesByIDs(IDs []int) ([]models.Smartphone, error
 	return args.Get(0).([]models.Smartphone), args.Error(1)
 }
 
+func (m *MockStorage) ReconcileRatings() ([]models.Smartphone, error) {
+	args := m.Called()
+	return args.Get(0).([]models.Smartphone), args.Error(1)
+}
+
 func (m *MockStorage) GetUser(ID int) (models.User, error) {
 	args := m.Called(ID)
 	return args.Get(0).(models.User), args.Error(1)
@@ -54,18 +61,48 @@ func (m *MockStorage) DeleteUser(userID int) (models.User, error) {
 	return args.Get(0).(models.User), args.Error(1)
 }
 
+func (m *MockStorage) CreateRefreshToken(token models.RefreshToken) (models.RefreshToken, error) {
+	args := m.Called(token)
+	return args.Get(0).(models.RefreshToken), args.Error(1)
+}
+
+func (m *MockStorage) RotateRefreshToken(tokenHash string, next models.RefreshToken) (models.RefreshToken, error) {
+	args := m.Called(tokenHash, next)
+	return args.Get(0).(models.RefreshToken), args.Error(1)
+}
+
+func (m *MockStorage) DeleteRefreshTokenFamily(userID int, tokenHash string) error {
+	args := m.Called(userID, tokenHash)
+	return args.Error(0)
+}
+
+func (m *MockStorage) RevokeUserTokens(userID int, at time.Time) error {
+	args := m.Called(userID, at)
+	return args.Error(0)
+}
+
+func (m *MockStorage) GetTokensRevokedAt(userID int) (*time.Time, error) {
+	args := m.Called(userID)
+	return args.Get(0).(*time.Time), args.Error(1)
+}
+
 func (m *MockStorage) CreateTmpPassword(token models.TmpPassword) (models.TmpPassword, error) {
 	args := m.Called(token)
 	return args.Get(0).(models.TmpPassword), args.Error(1)
 }
 
-func (m *MockStorage) GetTmpPassword(email string) (models.TmpPassword, error) {
-	args := m.Called(email)
+func (m *MockStorage) GetTmpPassword(email string, purpose models.TmpPasswordPurpose) (models.TmpPassword, error) {
+	args := m.Called(email, purpose)
 	return args.Get(0).(models.TmpPassword), args.Error(1)
 }
 
-func (m *MockStorage) DeleteTmpPassword(email string) (models.TmpPassword, error) {
-	args := m.Called(email)
+func (m *MockStorage) AttemptTmpPassword(email string, purpose models.TmpPasswordPurpose) (models.TmpPassword, error) {
+	args := m.Called(email, purpose)
+	return args.Get(0).(models.TmpPassword), args.Error(1)
+}
+
+func (m *MockStorage) DeleteTmpPassword(email string, purpose models.TmpPasswordPurpose) (models.TmpPassword, error) {
+	args := m.Called(email, purpose)
 	return args.Get(0).(models.TmpPassword), args.Error(1)
 }
 
@@ -79,6 +116,11 @@ func (m *MockStorage) GetReviews(smartphoneID int) ([]models.Review, error) {
 	return args.Get(0).([]models.Review), args.Error(1)
 }
 
+func (m *MockStorage) GetVerifiedReviews(smartphoneID int) ([]models.Review, error) {
+	args := m.Called(smartphoneID)
+	return args.Get(0).([]models.Review), args.Error(1)
+}
+
 func (m *MockStorage) CreateReview(review models.Review) (models.Review, error) {
 	args := m.Called(review)
 	return args.Get(0).(models.Review), args.Error(1)
@@ -94,6 +136,66 @@ func (m *MockStorage) DeleteReview(ID int) (models.Review, error) {
 	return args.Get(0).(models.Review), args.Error(1)
 }
 
+func (m *MockStorage) HasDuplicateReview(comment string, userID int) (bool, error) {
+	args := m.Called(comment, userID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockStorage) GetReviewPhotos(reviewID int) ([]models.ReviewPhoto, error) {
+	args := m.Called(reviewID)
+	return args.Get(0).([]models.ReviewPhoto), args.Error(1)
+}
+
+func (m *MockStorage) GetUserReviewPhotos(userID int) ([]models.ReviewPhoto, error) {
+	args := m.Called(userID)
+	return args.Get(0).([]models.ReviewPhoto), args.Error(1)
+}
+
+func (m *MockStorage) AddReviewPhoto(photo models.ReviewPhoto) (models.ReviewPhoto, error) {
+	args := m.Called(photo)
+	return args.Get(0).(models.ReviewPhoto), args.Error(1)
+}
+
+func (m *MockStorage) DeleteReviewPhoto(ID int) (models.ReviewPhoto, error) {
+	args := m.Called(ID)
+	return args.Get(0).(models.ReviewPhoto), args.Error(1)
+}
+
+func (m *MockStorage) CreateReviewReport(report models.ReviewReport) (models.ReviewReport, error) {
+	args := m.Called(report)
+	return args.Get(0).(models.ReviewReport), args.Error(1)
+}
+
+func (m *MockStorage) HideReportedReview(reviewID, threshold int) (bool, error) {
+	args := m.Called(reviewID, threshold)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockStorage) GetReportedReviews() ([]models.ReportedReview, error) {
+	args := m.Called()
+	return args.Get(0).([]models.ReportedReview), args.Error(1)
+}
+
+func (m *MockStorage) ModerateReview(reviewID int, hidden bool) (models.Review, error) {
+	args := m.Called(reviewID, hidden)
+	return args.Get(0).(models.Review), args.Error(1)
+}
+
+func (m *MockStorage) GetReviewReply(reviewID int) (models.ReviewReply, error) {
+	args := m.Called(reviewID)
+	return args.Get(0).(models.ReviewReply), args.Error(1)
+}
+
+func (m *MockStorage) SetReviewReply(reply models.ReviewReply) (models.ReviewReply, error) {
+	args := m.Called(reply)
+	return args.Get(0).(models.ReviewReply), args.Error(1)
+}
+
+func (m *MockStorage) DeleteReviewReply(reviewID int) (models.ReviewReply, error) {
+	args := m.Called(reviewID)
+	return args.Get(0).(models.ReviewReply), args.Error(1)
+}
+
 func (m *MockStorage) GetCarts() ([]models.Cart, error) {
 	args := m.Called()
 	return args.Get(0).([]models.Cart), args.Error(1)
@@ -109,6 +211,106 @@ func (m *MockStorage) GetCartByUserID(userID int) (models.Cart, error) {
 	return args.Get(0).(models.Cart), args.Error(1)
 }
 
+func (m *MockStorage) SetCartPromoCode(cartID int, promoCodeID *int) (models.Cart, error) {
+	args := m.Called(cartID, promoCodeID)
+	return args.Get(0).(models.Cart), args.Error(1)
+}
+
+func (m *MockStorage) CreateGuestCart() (models.Cart, error) {
+	args := m.Called()
+	return args.Get(0).(models.Cart), args.Error(1)
+}
+
+func (m *MockStorage) MergeCarts(guestCartID, toCartID, maxQuantity int) error {
+	args := m.Called(guestCartID, toCartID, maxQuantity)
+	return args.Error(0)
+}
+
+func (m *MockStorage) DeleteStaleGuestCarts(idleSince, createdBefore time.Time) (int, error) {
+	args := m.Called(idleSince, createdBefore)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockStorage) GetAbandonedCarts(idleSince time.Time) ([]models.Cart, error) {
+	args := m.Called(idleSince)
+	return args.Get(0).([]models.Cart), args.Error(1)
+}
+
+func (m *MockStorage) ClaimCartReminder(cartID int) (bool, error) {
+	args := m.Called(cartID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockStorage) ExportUsers(from, to time.Time, fn func(models.User) error) error {
+	args := m.Called(from, to)
+	for _, user := range args.Get(0).([]models.User) {
+		if err := fn(user); err != nil {
+			return err
+		}
+	}
+	return args.Error(1)
+}
+
+func (m *MockStorage) ExportReviews(from, to time.Time, fn func(models.Review) error) error {
+	args := m.Called(from, to)
+	for _, review := range args.Get(0).([]models.Review) {
+		if err := fn(review); err != nil {
+			return err
+		}
+	}
+	return args.Error(1)
+}
+
+func (m *MockStorage) ExportCarts(from, to time.Time, fn func(models.Cart) error) error {
+	args := m.Called(from, to)
+	for _, cart := range args.Get(0).([]models.Cart) {
+		if err := fn(cart); err != nil {
+			return err
+		}
+	}
+	return args.Error(1)
+}
+
+func (m *MockStorage) ClaimIdempotencyKey(key models.IdempotencyKey, expiredBefore time.Time) (models.IdempotencyKey, bool, error) {
+	args := m.Called(key, expiredBefore)
+	return args.Get(0).(models.IdempotencyKey), args.Bool(1), args.Error(2)
+}
+
+func (m *MockStorage) SaveIdempotentResponse(key models.IdempotencyKey) error {
+	args := m.Called(key)
+	return args.Error(0)
+}
+
+func (m *MockStorage) DeleteIdempotencyKey(scope, key string) error {
+	args := m.Called(scope, key)
+	return args.Error(0)
+}
+
+func (m *MockStorage) DeleteExpiredIdempotencyKeys(before time.Time) (int, error) {
+	args := m.Called(before)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockStorage) HitRateLimit(key string, window time.Duration, now time.Time) (int, time.Time, error) {
+	args := m.Called(key, window, now)
+	return args.Int(0), args.Get(1).(time.Time), args.Error(2)
+}
+
+func (m *MockStorage) GetRateLimit(key string, now time.Time) (int, time.Time, error) {
+	args := m.Called(key, now)
+	return args.Int(0), args.Get(1).(time.Time), args.Error(2)
+}
+
+func (m *MockStorage) ResetRateLimit(key string) error {
+	args := m.Called(key)
+	return args.Error(0)
+}
+
+func (m *MockStorage) DeleteExpiredRateLimits(before time.Time) (int, error) {
+	args := m.Called(before)
+	return args.Int(0), args.Error(1)
+}
+
 func (m *MockStorage) GetCartItem(ID int) (models.CartItem, error) {
 	args := m.Called(ID)
 	return args.Get(0).(models.CartItem), args.Error(1)
@@ -119,7 +321,22 @@ func (m *MockStorage) GetCartItems(cartID int) ([]models.CartItem, error) {
 	return args.Get(0).([]models.CartItem), args.Error(1)
 }
 
-func (m *MockStorage) AddToCart(cartItem models.CartItem) (models.CartItem, error) {
+func (m *MockStorage) AddToCart(cartItem models.CartItem, maxQuantity int) (models.CartItem, error) {
+	args := m.Called(cartItem, maxQuantity)
+	return args.Get(0).(models.CartItem), args.Error(1)
+}
+
+func (m *MockStorage) ReplaceCartItems(cartID int, items []models.CartItem) ([]models.CartItem, error) {
+	args := m.Called(cartID, items)
+	return args.Get(0).([]models.CartItem), args.Error(1)
+}
+
+func (m *MockStorage) ClearCart(cartID int) error {
+	args := m.Called(cartID)
+	return args.Error(0)
+}
+
+func (m *MockStorage) SetCartItemState(cartItem models.CartItem) (models.CartItem, error) {
 	args := m.Called(cartItem)
 	return args.Get(0).(models.CartItem), args.Error(1)
 }
@@ -133,3 +350,169 @@ func (m *MockStorage) DeleteFromCart(cartID, itemID int) (models.CartItem, error
 	args := m.Called(cartID, itemID)
 	return args.Get(0).(models.CartItem), args.Error(1)
 }
+
+func (m *MockStorage) GetPurchases(userID int) ([]models.Purchase, error) {
+	args := m.Called(userID)
+	return args.Get(0).([]models.Purchase), args.Error(1)
+}
+
+func (m *MockStorage) GetPurchase(ID int) (models.Purchase, error) {
+	args := m.Called(ID)
+	return args.Get(0).(models.Purchase), args.Error(1)
+}
+
+func (m *MockStorage) CreatePurchase(purchase models.Purchase) (models.Purchase, error) {
+	args := m.Called(purchase)
+	return args.Get(0).(models.Purchase), args.Error(1)
+}
+
+func (m *MockStorage) HasPurchased(userID, smartphoneID int) (bool, error) {
+	args := m.Called(userID, smartphoneID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockStorage) GetOrders() ([]models.Order, error) {
+	args := m.Called()
+	return args.Get(0).([]models.Order), args.Error(1)
+}
+
+func (m *MockStorage) GetUserOrders(userID int) ([]models.Order, error) {
+	args := m.Called(userID)
+	return args.Get(0).([]models.Order), args.Error(1)
+}
+
+func (m *MockStorage) GetOrder(ID int) (models.Order, error) {
+	args := m.Called(ID)
+	return args.Get(0).(models.Order), args.Error(1)
+}
+
+func (m *MockStorage) CreateOrder(order models.Order, cartItems []models.CartItem) (models.Order, error) {
+	args := m.Called(order, cartItems)
+	return args.Get(0).(models.Order), args.Error(1)
+}
+
+func (m *MockStorage) SetOrderStatus(orderID int, from, to models.OrderStatus, actorID *int) (models.Order, error) {
+	args := m.Called(orderID, from, to, actorID)
+	return args.Get(0).(models.Order), args.Error(1)
+}
+
+func (m *MockStorage) GetOrderPayments(orderID int) ([]models.Payment, error) {
+	args := m.Called(orderID)
+	return args.Get(0).([]models.Payment), args.Error(1)
+}
+
+func (m *MockStorage) RecordPayment(payment models.Payment) (models.Payment, error) {
+	args := m.Called(payment)
+	return args.Get(0).(models.Payment), args.Error(1)
+}
+
+func (m *MockStorage) GetPromoCodes() ([]models.PromoCode, error) {
+	args := m.Called()
+	return args.Get(0).([]models.PromoCode), args.Error(1)
+}
+
+func (m *MockStorage) GetPromoCode(ID int) (models.PromoCode, error) {
+	args := m.Called(ID)
+	return args.Get(0).(models.PromoCode), args.Error(1)
+}
+
+func (m *MockStorage) GetPromoCodeByCode(code string) (models.PromoCode, error) {
+	args := m.Called(code)
+	return args.Get(0).(models.PromoCode), args.Error(1)
+}
+
+func (m *MockStorage) CreatePromoCode(promo models.PromoCode) (models.PromoCode, error) {
+	args := m.Called(promo)
+	return args.Get(0).(models.PromoCode), args.Error(1)
+}
+
+func (m *MockStorage) DeletePromoCode(ID int) (models.PromoCode, error) {
+	args := m.Called(ID)
+	return args.Get(0).(models.PromoCode), args.Error(1)
+}
+
+func (m *MockStorage) GetPromoCodeUserUsage(promoCodeID, userID int) (int, error) {
+	args := m.Called(promoCodeID, userID)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockStorage) GetAddresses(userID int) ([]models.Address, error) {
+	args := m.Called(userID)
+	return args.Get(0).([]models.Address), args.Error(1)
+}
+
+func (m *MockStorage) GetAddress(ID int) (models.Address, error) {
+	args := m.Called(ID)
+	return args.Get(0).(models.Address), args.Error(1)
+}
+
+func (m *MockStorage) CreateAddress(address models.Address) (models.Address, error) {
+	args := m.Called(address)
+	return args.Get(0).(models.Address), args.Error(1)
+}
+
+func (m *MockStorage) UpdateAddress(address models.Address) (models.Address, error) {
+	args := m.Called(address)
+	return args.Get(0).(models.Address), args.Error(1)
+}
+
+func (m *MockStorage) DeleteAddress(ID int) (models.Address, error) {
+	args := m.Called(ID)
+	return args.Get(0).(models.Address), args.Error(1)
+}
+
+func (m *MockStorage) GetDeliveryMethods(onlyActive bool) ([]models.DeliveryMethod, error) {
+	args := m.Called(onlyActive)
+	return args.Get(0).([]models.DeliveryMethod), args.Error(1)
+}
+
+func (m *MockStorage) GetDeliveryMethod(ID int) (models.DeliveryMethod, error) {
+	args := m.Called(ID)
+	return args.Get(0).(models.DeliveryMethod), args.Error(1)
+}
+
+func (m *MockStorage) CreateDeliveryMethod(method models.DeliveryMethod) (models.DeliveryMethod, error) {
+	args := m.Called(method)
+	return args.Get(0).(models.DeliveryMethod), args.Error(1)
+}
+
+func (m *MockStorage) UpdateDeliveryMethod(method models.DeliveryMethod) (models.DeliveryMethod, error) {
+	args := m.Called(method)
+	return args.Get(0).(models.DeliveryMethod), args.Error(1)
+}
+
+func (m *MockStorage) DeleteDeliveryMethod(ID int) (models.DeliveryMethod, error) {
+	args := m.Called(ID)
+	return args.Get(0).(models.DeliveryMethod), args.Error(1)
+}
+
+func (m *MockStorage) GetReturns() ([]models.Return, error) {
+	args := m.Called()
+	return args.Get(0).([]models.Return), args.Error(1)
+}
+
+func (m *MockStorage) GetUserReturns(userID int) ([]models.Return, error) {
+	args := m.Called(userID)
+	return args.Get(0).([]models.Return), args.Error(1)
+}
+
+func (m *MockStorage) GetReturn(ID int) (models.Return, error) {
+	args := m.Called(ID)
+	return args.Get(0).(models.Return), args.Error(1)
+}
+
+func (m *MockStorage) CreateReturn(ret models.Return) (models.Return, error) {
+	args := m.Called(ret)
+	return args.Get(0).(models.Return), args.Error(1)
+}
+
+func (m *MockStorage) SetReturnStatus(ID int, from, to models.ReturnStatus, refundAmount *int,
+	adminComment *string) (models.Return, error) {
+	args := m.Called(ID, from, to, refundAmount, adminComment)
+	return args.Get(0).(models.Return), args.Error(1)
+}
+
+func (m *MockStorage) AddReturnPhoto(photo models.ReturnPhoto) (models.ReturnPhoto, error) {
+	args := m.Called(photo)
+	return args.Get(0).(models.ReturnPhoto), args.Error(1)
+}
@@ -0,0 +1,125 @@
+package postgres
+
+import (
+	"database/sql"
+
+	"github.com/sfu-teamproject/smartbuy/backend/models"
+)
+
+type Address = models.Address
+
+func (db *PostgresDB) GetAddresses(userID int) ([]Address, error) {
+	rows, err := db.Query("SELECT * FROM addresses WHERE user_id = $1 ORDER BY is_default DESC, id", userID)
+	if err != nil {
+		return nil, db.wrapError(err)
+	}
+	return db.extractAddresses(rows)
+}
+
+func (db *PostgresDB) GetAddress(ID int) (Address, error) {
+	row := db.QueryRow("SELECT * FROM addresses WHERE id = $1", ID)
+	return db.extractAddress(row)
+}
+
+// CreateAddress saves the address, the first address of the user becomes the
+// default one
+func (db *PostgresDB) CreateAddress(a Address) (Address, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return Address{}, db.wrapError(err)
+	}
+	defer tx.Rollback()
+	if a.IsDefault {
+		_, err = tx.Exec("UPDATE addresses SET is_default = FALSE WHERE user_id = $1", a.UserID)
+		if err != nil {
+			return Address{}, db.wrapError(err)
+		}
+	}
+	query := `
+	INSERT INTO addresses (user_id, recipient, phone, city, street, postal_code, is_default)
+	VALUES ($1, $2, $3, $4, $5, $6, $7 OR NOT EXISTS(SELECT 1 FROM addresses WHERE user_id = $1))
+	RETURNING *
+	`
+	row := tx.QueryRow(query, a.UserID, a.Recipient, a.Phone, a.City, a.Street, a.PostalCode, a.IsDefault)
+	newAddress, err := db.extractAddress(row)
+	if err != nil {
+		return newAddress, err
+	}
+	return newAddress, db.wrapError(tx.Commit())
+}
+
+// UpdateAddress changes the address. The default address can not be unset,
+// another address has to be made the default one instead
+func (db *PostgresDB) UpdateAddress(a Address) (Address, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return Address{}, db.wrapError(err)
+	}
+	defer tx.Rollback()
+	if a.IsDefault {
+		_, err = tx.Exec("UPDATE addresses SET is_default = FALSE WHERE user_id = $1 AND id != $2", a.UserID, a.ID)
+		if err != nil {
+			return Address{}, db.wrapError(err)
+		}
+	}
+	query := `
+	UPDATE addresses
+	SET recipient = $1, phone = $2, city = $3, street = $4, postal_code = $5, is_default = is_default OR $6
+	WHERE id = $7
+	RETURNING *
+	`
+	row := tx.QueryRow(query, a.Recipient, a.Phone, a.City, a.Street, a.PostalCode, a.IsDefault, a.ID)
+	updated, err := db.extractAddress(row)
+	if err != nil {
+		return updated, err
+	}
+	return updated, db.wrapError(tx.Commit())
+}
+
+// DeleteAddress deletes the address, if it was the default one the newest
+// remaining address becomes the default
+func (db *PostgresDB) DeleteAddress(ID int) (Address, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return Address{}, db.wrapError(err)
+	}
+	defer tx.Rollback()
+	row := tx.QueryRow("DELETE FROM addresses WHERE id = $1 RETURNING *", ID)
+	deleted, err := db.extractAddress(row)
+	if err != nil {
+		return deleted, err
+	}
+	if deleted.IsDefault {
+		query := `
+		UPDATE addresses SET is_default = TRUE
+		WHERE id = (SELECT id FROM addresses WHERE user_id = $1 ORDER BY created_at DESC, id DESC LIMIT 1)
+		`
+		_, err = tx.Exec(query, deleted.UserID)
+		if err != nil {
+			return Address{}, db.wrapError(err)
+		}
+	}
+	return deleted, db.wrapError(tx.Commit())
+}
+
+func (db *PostgresDB) extractAddress(row *sql.Row) (Address, error) {
+	a := Address{}
+	err := row.Scan(&a.ID, &a.UserID, &a.Recipient, &a.Phone, &a.City, &a.Street, &a.PostalCode,
+		&a.IsDefault, &a.CreatedAt)
+	return a, db.wrapError(err)
+}
+
+func (db *PostgresDB) extractAddresses(rows *sql.Rows) ([]Address, error) {
+	defer rows.Close()
+	addresses := []Address{}
+	for rows.Next() {
+		a := Address{}
+		err := rows.Scan(&a.ID, &a.UserID, &a.Recipient, &a.Phone, &a.City, &a.Street, &a.PostalCode,
+			&a.IsDefault, &a.CreatedAt)
+		if err != nil {
+			return nil, db.wrapError(err)
+		}
+		addresses = append(addresses, a)
+	}
+	return addresses, nil
+}
@@ -2,16 +2,41 @@ package postgres
 
 import (
 	"database/sql"
+	"fmt"
 
+	"github.com/lib/pq"
+	"github.com/sfu-teamproject/smartbuy/backend/apperrors"
 	"github.com/sfu-teamproject/smartbuy/backend/models"
 )
 
 type CartItem = models.CartItem
 
-func (db *PostgresDB) AddToCart(cartItem models.CartItem) (CartItem, error) {
-	row := db.QueryRow("INSERT INTO cart_items (cart_id, smartphone_id, quantity) values ($1, $2, $3) RETURNING *",
-		cartItem.CartID, cartItem.SmartphoneID, cartItem.Quantity)
-	return db.extractCartItem(row)
+// AddToCart adds the item with the current price of the smartphone. If the
+// smartphone is already in the cart its quantity is increased, the sum may not
+// exceed maxQuantity. A saved for later item is moved back to the cart
+func (db *PostgresDB) AddToCart(cartItem models.CartItem, maxQuantity int) (CartItem, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return CartItem{}, db.wrapError(err)
+	}
+	defer tx.Rollback()
+	query := `
+	INSERT INTO cart_items (cart_id, smartphone_id, quantity, added_price)
+	SELECT $1, id, $3, price FROM smartphones WHERE id = $2
+	ON CONFLICT (cart_id, smartphone_id)
+	DO UPDATE SET quantity = cart_items.quantity + EXCLUDED.quantity, state = 'active'
+	RETURNING *
+	`
+	row := tx.QueryRow(query, cartItem.CartID, cartItem.SmartphoneID, cartItem.Quantity)
+	ci, err := db.extractCartItem(row)
+	if err != nil {
+		return ci, err
+	}
+	if ci.Quantity > maxQuantity {
+		return CartItem{}, fmt.Errorf("%w: quantity of smartphone %d would be %d, the limit is %d",
+			apperrors.ErrBadRequest, ci.SmartphoneID, ci.Quantity, maxQuantity)
+	}
+	return ci, db.wrapError(tx.Commit())
 }
 
 func (db *PostgresDB) GetCartItem(ID int) (CartItem, error) {
@@ -19,12 +44,22 @@ func (db *PostgresDB) GetCartItem(ID int) (CartItem, error) {
 	return db.extractCartItem(row)
 }
 
+// GetCartItems returns items of the cart with current prices of their smartphones
+// and the change of the price since the item was added
 func (db *PostgresDB) GetCartItems(cartID int) ([]CartItem, error) {
-	rows, err := db.Query("SELECT * from cart_items where cart_id = $1", cartID)
+	query := `
+	SELECT ci.id, ci.cart_id, ci.smartphone_id, ci.quantity, ci.added_price, ci.state,
+	s.model, s.producer, s.price, s.image_path, s.weight, s.category, s.price * ci.quantity
+	FROM cart_items ci
+	JOIN smartphones s ON s.id = ci.smartphone_id
+	WHERE ci.cart_id = $1
+	ORDER BY ci.id
+	`
+	rows, err := db.Query(query, cartID)
 	if err != nil {
 		return nil, db.wrapError(err)
 	}
-	return db.extractCartItems(rows)
+	return db.extractPricedCartItems(rows)
 }
 
 func (db *PostgresDB) SetQuantity(cartItem CartItem) (CartItem, error) {
@@ -39,21 +74,81 @@ func (db *PostgresDB) DeleteFromCart(cartID, itemID int) (CartItem, error) {
 	return db.extractCartItem(row)
 }
 
+// ReplaceCartItems makes items the only content of the cart, saved for later
+// items are kept unless they are listed. Items of smartphones that stay in the
+// cart keep the price they were added with
+func (db *PostgresDB) ReplaceCartItems(cartID int, items []CartItem) ([]CartItem, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, db.wrapError(err)
+	}
+	defer tx.Rollback()
+	IDs := make([]int64, len(items))
+	for i, item := range items {
+		IDs[i] = int64(item.SmartphoneID)
+	}
+	_, err = tx.Exec("DELETE FROM cart_items WHERE cart_id = $1 AND state = 'active' AND NOT smartphone_id = ANY($2)",
+		cartID, pq.Array(IDs))
+	if err != nil {
+		return nil, db.wrapError(err)
+	}
+	query := `
+	INSERT INTO cart_items (cart_id, smartphone_id, quantity, added_price)
+	SELECT $1, id, $3, price FROM smartphones WHERE id = $2
+	ON CONFLICT (cart_id, smartphone_id) DO UPDATE SET quantity = EXCLUDED.quantity, state = 'active'
+	`
+	for _, item := range items {
+		res, err := tx.Exec(query, cartID, item.SmartphoneID, item.Quantity)
+		if err != nil {
+			return nil, db.wrapError(err)
+		}
+		inserted, err := res.RowsAffected()
+		if err != nil {
+			return nil, db.wrapError(err)
+		}
+		if inserted == 0 {
+			return nil, fmt.Errorf("%w: smartphone %d does not exist", apperrors.ErrBadRequest, item.SmartphoneID)
+		}
+	}
+	err = tx.Commit()
+	if err != nil {
+		return nil, db.wrapError(err)
+	}
+	return db.GetCartItems(cartID)
+}
+
+// ClearCart removes all items from the cart except saved for later ones
+func (db *PostgresDB) ClearCart(cartID int) error {
+	_, err := db.Exec("DELETE FROM cart_items WHERE cart_id = $1 AND state = 'active'", cartID)
+	return db.wrapError(err)
+}
+
+func (db *PostgresDB) SetCartItemState(cartItem CartItem) (CartItem, error) {
+	row := db.QueryRow("UPDATE cart_items SET state = $1 WHERE id = $2 and cart_id = $3 RETURNING *",
+		cartItem.State, cartItem.ID, cartItem.CartID)
+	return db.extractCartItem(row)
+}
+
 func (db *PostgresDB) extractCartItem(row *sql.Row) (CartItem, error) {
 	ci := CartItem{}
-	err := row.Scan(&ci.ID, &ci.CartID, &ci.SmartphoneID, &ci.Quantity)
+	err := row.Scan(&ci.ID, &ci.CartID, &ci.SmartphoneID, &ci.Quantity, &ci.AddedPrice, &ci.State)
 	return ci, db.wrapError(err)
 }
 
-func (db *PostgresDB) extractCartItems(rows *sql.Rows) ([]CartItem, error) {
+func (db *PostgresDB) extractPricedCartItems(rows *sql.Rows) ([]CartItem, error) {
 	defer rows.Close()
 	cis := []CartItem{}
 	for rows.Next() {
 		ci := CartItem{}
-		err := rows.Scan(&ci.ID, &ci.CartID, &ci.SmartphoneID, &ci.Quantity)
+		sm := models.SmartphoneSummary{}
+		err := rows.Scan(&ci.ID, &ci.CartID, &ci.SmartphoneID, &ci.Quantity, &ci.AddedPrice, &ci.State,
+			&sm.Model, &sm.Producer, &sm.Price, &sm.ImagePath, &sm.Weight, &sm.Category, &ci.LineTotal)
 		if err != nil {
 			return nil, db.wrapError(err)
 		}
+		sm.ID = ci.SmartphoneID
+		ci.Smartphone = &sm
+		ci.PriceChange = sm.Price - ci.AddedPrice
 		cis = append(cis, ci)
 	}
 	return cis, nil
@@ -1,8 +1,10 @@
 package postgres
 
 import (
+	"errors"
 	"testing"
 
+	"github.com/sfu-teamproject/smartbuy/backend/apperrors"
 	"github.com/sfu-teamproject/smartbuy/backend/models"
 	"github.com/stretchr/testify/assert"
 )
@@ -14,19 +16,34 @@ func TestCartItems(t *testing.T) {
 		CartID:       1,
 		SmartphoneID: 1,
 		Quantity:     1,
+		State:        models.CartItemActive,
 	}
 	t.Run("add cart item", func(t *testing.T) {
-		newCartItem, err := db.AddToCart(cartItem)
+		newCartItem, err := db.AddToCart(cartItem, 10)
 		assert.NoError(t, err, "adding cart item failed", err.Error())
 		assert.NotEmpty(t, newCartItem.ID, "cart item id is 0")
 		cartItem.ID = newCartItem.ID
+		assert.NotEmpty(t, newCartItem.AddedPrice, "price of smartphone is not saved")
+		cartItem.AddedPrice = newCartItem.AddedPrice
 		assert.Equal(t, cartItem, newCartItem, "cartItem is different")
 	})
 	t.Run("get cartItems", func(t *testing.T) {
 		cartItems, err := db.GetCartItems(1)
 		assert.NoError(t, err, "getting cart items failed", err.Error())
 		assert.Equal(t, 1, len(cartItems), "length of cart items is not 1")
-		assert.Equal(t, cartItem, cartItems[0], "cart item is not the same")
+		assert.Equal(t, cartItem.ID, cartItems[0].ID, "cart item is not the same")
+		assert.NotNil(t, cartItems[0].Smartphone, "smartphone of cart item is not attached")
+		assert.Equal(t, cartItems[0].Smartphone.Price*cartItem.Quantity, cartItems[0].LineTotal, "line total is wrong")
+		assert.Zero(t, cartItems[0].PriceChange, "price has not changed")
+	})
+	t.Run("add existing cart item", func(t *testing.T) {
+		newCartItem, err := db.AddToCart(cartItem, 10)
+		assert.NoError(t, err, "adding cart item again failed")
+		assert.Equal(t, cartItem.ID, newCartItem.ID, "new cart item is created")
+		assert.Equal(t, 2, newCartItem.Quantity, "quantity is not increased")
+		_, err = db.AddToCart(cartItem, 2)
+		assert.True(t, errors.Is(err, apperrors.ErrBadRequest), "quantity limit is exceeded")
+		cartItem.Quantity = 2
 	})
 	t.Run("set cartItem quantity", func(t *testing.T) {
 		cartItem.Quantity = 3
@@ -34,6 +51,16 @@ func TestCartItems(t *testing.T) {
 		assert.NoError(t, err, "setting cart item quantity failed", err.Error())
 		assert.Equal(t, 3, newCartItem.Quantity, "quantity of cart item is not 3")
 	})
+	t.Run("save cart item for later", func(t *testing.T) {
+		cartItem.State = models.CartItemSaved
+		savedCartItem, err := db.SetCartItemState(cartItem)
+		assert.NoError(t, err, "saving cart item failed")
+		assert.Equal(t, models.CartItemSaved, savedCartItem.State, "cart item is not saved")
+		err = db.ClearCart(1)
+		assert.NoError(t, err, "clearing cart failed")
+		_, err = db.GetCartItem(cartItem.ID)
+		assert.NoError(t, err, "saved cart item is cleared")
+	})
 	t.Run("delete cart item", func(t *testing.T) {
 		deletedCartItem, err := db.DeleteFromCart(1, 1)
 		assert.NoError(t, err, "deleting cart item failed", err.Error())
@@ -42,4 +69,17 @@ func TestCartItems(t *testing.T) {
 		assert.NoError(t, err, "getting cart items failed", err.Error())
 		assert.Empty(t, cartItems, "cart is not empty")
 	})
+	t.Run("replace and clear cart items", func(t *testing.T) {
+		items := []models.CartItem{{SmartphoneID: 2, Quantity: 2}, {SmartphoneID: 4, Quantity: 1}}
+		cartItems, err := db.ReplaceCartItems(1, items)
+		assert.NoError(t, err, "replacing cart items failed")
+		assert.Len(t, cartItems, 2, "cart should have 2 items")
+		_, err = db.ReplaceCartItems(1, []models.CartItem{{SmartphoneID: -1, Quantity: 1}})
+		assert.True(t, errors.Is(err, apperrors.ErrBadRequest), "nonexistent smartphone is added")
+		err = db.ClearCart(1)
+		assert.NoError(t, err, "clearing cart failed")
+		cartItems, err = db.GetCartItems(1)
+		assert.NoError(t, err, "getting cart items failed")
+		assert.Empty(t, cartItems, "cart is not empty")
+	})
 }
@@ -2,6 +2,8 @@ package postgres
 
 import (
 	"database/sql"
+	"errors"
+	"time"
 
 	"github.com/sfu-teamproject/smartbuy/backend/models"
 )
@@ -26,9 +28,103 @@ func (db *PostgresDB) GetCartByUserID(userID int) (Cart, error) {
 	return db.extractCart(row)
 }
 
+// SetCartPromoCode applies the promo code to the cart, nil promoCodeID removes it
+func (db *PostgresDB) SetCartPromoCode(cartID int, promoCodeID *int) (Cart, error) {
+	row := db.QueryRow("UPDATE carts SET promo_code_id = $1 WHERE id = $2 RETURNING *", promoCodeID, cartID)
+	return db.extractCart(row)
+}
+
+// CreateGuestCart creates a cart without a user
+func (db *PostgresDB) CreateGuestCart() (Cart, error) {
+	row := db.QueryRow("INSERT INTO carts (user_id) VALUES (NULL) RETURNING *")
+	return db.extractCart(row)
+}
+
+// MergeCarts moves items of the guest cart into the cart toCartID and deletes
+// the guest cart. Quantities of the same smartphone are summed up to
+// maxQuantity, the price of the item already in the cart is kept
+func (db *PostgresDB) MergeCarts(guestCartID, toCartID, maxQuantity int) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return db.wrapError(err)
+	}
+	defer tx.Rollback()
+	query := `
+	INSERT INTO cart_items (cart_id, smartphone_id, quantity, added_price, state)
+	SELECT $2, smartphone_id, LEAST(quantity, $3), added_price, state FROM cart_items WHERE cart_id = $1
+	ON CONFLICT (cart_id, smartphone_id)
+	DO UPDATE SET quantity = GREATEST(cart_items.quantity, LEAST(cart_items.quantity + EXCLUDED.quantity, $3))
+	`
+	_, err = tx.Exec(query, guestCartID, toCartID, maxQuantity)
+	if err != nil {
+		return db.wrapError(err)
+	}
+	var ID int
+	err = tx.QueryRow("DELETE FROM carts WHERE id = $1 AND user_id IS NULL RETURNING id", guestCartID).Scan(&ID)
+	if err != nil {
+		return db.wrapError(err)
+	}
+	return db.wrapError(tx.Commit())
+}
+
+// DeleteStaleGuestCarts deletes guest carts not changed since idleSince or
+// created before createdBefore, when their cookies expire, and returns how many
+// were deleted
+func (db *PostgresDB) DeleteStaleGuestCarts(idleSince, createdBefore time.Time) (int, error) {
+	result, err := db.Exec(`
+	DELETE FROM carts WHERE user_id IS NULL AND (updated_at < $1 OR created_at < $2)
+	`, idleSince, createdBefore)
+	if err != nil {
+		return 0, db.wrapError(err)
+	}
+	deleted, err := result.RowsAffected()
+	return int(deleted), db.wrapError(err)
+}
+
+// GetAbandonedCarts returns carts with active items that were not changed
+// since idleSince and whose owners get reminders, skipping carts that were
+// already reminded about after their last change
+func (db *PostgresDB) GetAbandonedCarts(idleSince time.Time) ([]Cart, error) {
+	rows, err := db.Query(`
+	SELECT carts.* FROM carts
+	JOIN users ON users.id = carts.user_id
+	LEFT JOIN sent_cart_reminders ON sent_cart_reminders.cart_id = carts.id
+	WHERE users.cart_reminders AND carts.updated_at < $1
+	AND (sent_cart_reminders.sent_at IS NULL OR sent_cart_reminders.sent_at < carts.updated_at)
+	AND EXISTS (SELECT 1 FROM cart_items WHERE cart_id = carts.id AND state = $2)
+	ORDER BY carts.id
+	`, idleSince, models.CartItemActive)
+	if err != nil {
+		return nil, db.wrapError(err)
+	}
+	return db.extractCarts(rows)
+}
+
+// ClaimCartReminder records that a reminder about the cart is being sent. It
+// returns false if a reminder was already sent after the last change of the
+// cart, e.g. by another instance of the server
+func (db *PostgresDB) ClaimCartReminder(cartID int) (bool, error) {
+	var ID int
+	err := db.QueryRow(`
+	INSERT INTO sent_cart_reminders (cart_id) VALUES ($1)
+	ON CONFLICT (cart_id) DO UPDATE SET sent_at = CURRENT_TIMESTAMP
+	WHERE sent_cart_reminders.sent_at < (SELECT updated_at FROM carts WHERE id = $1)
+	RETURNING cart_id
+	`, cartID).Scan(&ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, db.wrapError(err)
+	}
+	return true, nil
+}
+
 func (db *PostgresDB) extractCart(row *sql.Row) (Cart, error) {
 	cart := Cart{}
-	err := row.Scan(&cart.ID, &cart.UserID, &cart.CreatedAt, &cart.UpdatedAt)
+	var userID sql.NullInt64
+	err := row.Scan(&cart.ID, &userID, &cart.CreatedAt, &cart.UpdatedAt, &cart.PromoCodeID)
+	cart.UserID = int(userID.Int64)
 	return cart, db.wrapError(err)
 }
 
@@ -37,10 +133,12 @@ func (db *PostgresDB) extractCarts(rows *sql.Rows) ([]Cart, error) {
 	carts := []Cart{}
 	for rows.Next() {
 		cart := Cart{}
-		err := rows.Scan(&cart.ID, &cart.UserID, &cart.CreatedAt, &cart.UpdatedAt)
+		var userID sql.NullInt64
+		err := rows.Scan(&cart.ID, &userID, &cart.CreatedAt, &cart.UpdatedAt, &cart.PromoCodeID)
 		if err != nil {
 			return nil, db.wrapError(err)
 		}
+		cart.UserID = int(userID.Int64)
 		carts = append(carts, cart)
 	}
 	return carts, nil
@@ -2,6 +2,7 @@ package postgres
 
 import (
 	"testing"
+	"time"
 
 	"github.com/sfu-teamproject/smartbuy/backend/models"
 	"github.com/stretchr/testify/assert"
@@ -25,4 +26,59 @@ func TestCart(t *testing.T) {
 		assert.NoError(t, err, "getting carts failed", err.Error())
 		assert.NotEmpty(t, carts, "cart slice is empty")
 	})
+	t.Run("merge guest cart", func(t *testing.T) {
+		guest, err := db.CreateGuestCart()
+		assert.NoError(t, err, "creating guest cart failed")
+		assert.Zero(t, guest.UserID, "guest cart has a user")
+		_, err = db.AddToCart(models.CartItem{CartID: guest.ID, SmartphoneID: 1, Quantity: 8}, 10)
+		assert.NoError(t, err, "adding to guest cart failed")
+		_, err = db.AddToCart(models.CartItem{CartID: guest.ID, SmartphoneID: 5, Quantity: 1}, 10)
+		assert.NoError(t, err, "adding to guest cart failed")
+		user, err := db.GetCartByUserID(2)
+		assert.NoError(t, err, "getting cart failed")
+		before, err := db.GetCartItems(user.ID)
+		assert.NoError(t, err, "getting cart items failed")
+		err = db.MergeCarts(guest.ID, user.ID, 5)
+		assert.NoError(t, err, "merging carts failed")
+		after, err := db.GetCartItems(user.ID)
+		assert.NoError(t, err, "getting cart items failed")
+		assert.Len(t, after, len(before)+2, "guest items are not moved")
+		for _, item := range after {
+			assert.LessOrEqual(t, item.Quantity, 5, "quantity is over the limit")
+		}
+		_, err = db.GetCart(guest.ID)
+		assert.Error(t, err, "guest cart is not deleted")
+	})
+	t.Run("delete stale guest carts", func(t *testing.T) {
+		guest, err := db.CreateGuestCart()
+		assert.NoError(t, err, "creating guest cart failed")
+		_, err = db.DeleteStaleGuestCarts(time.Now().Add(-time.Hour), time.Now().Add(-time.Hour))
+		assert.NoError(t, err, "deleting stale guest carts failed")
+		_, err = db.GetCart(guest.ID)
+		assert.NoError(t, err, "fresh guest cart is deleted")
+		deleted, err := db.DeleteStaleGuestCarts(time.Now().Add(time.Minute), time.Now().Add(-time.Hour))
+		assert.NoError(t, err, "deleting stale guest carts failed")
+		assert.GreaterOrEqual(t, deleted, 1)
+		_, err = db.GetCart(guest.ID)
+		assert.Error(t, err, "idle guest cart is not deleted")
+		user, err := db.GetCartByUserID(2)
+		assert.NoError(t, err, "cart of a user is deleted")
+		assert.NotZero(t, user.ID)
+	})
+	t.Run("abandoned carts", func(t *testing.T) {
+		cart, err := db.GetCartByUserID(2)
+		assert.NoError(t, err, "getting cart failed")
+		carts, err := db.GetAbandonedCarts(time.Now().Add(time.Minute))
+		assert.NoError(t, err, "getting abandoned carts failed")
+		assert.Contains(t, carts, cart, "cart is not abandoned")
+		claimed, err := db.ClaimCartReminder(cart.ID)
+		assert.NoError(t, err, "claiming reminder failed")
+		assert.True(t, claimed, "reminder is not claimed")
+		claimed, err = db.ClaimCartReminder(cart.ID)
+		assert.NoError(t, err, "claiming reminder failed")
+		assert.False(t, claimed, "reminder is claimed twice for the same change")
+		carts, err = db.GetAbandonedCarts(time.Now().Add(time.Minute))
+		assert.NoError(t, err, "getting abandoned carts failed")
+		assert.NotContains(t, carts, cart, "cart is reminded about twice")
+	})
 }
@@ -104,11 +104,19 @@ values
     ('user1', 'user1@example.com', '$2a$10$f9sCd/oZg9GriPoHVrHMT.4KIr6dOwmQbU5FDCQdYgxYm3Xc6pQqa', 'user'),
     ('user2', 'user2@example.com', '$2a$10$FKAxhcBTV9/yZbNk9OhbpeDrW5RMSrNFKT8w1OHGENR.sV.kqgUEi', 'user');
 
-insert into cart_items (cart_id, smartphone_id)
-values
+insert into cart_items (cart_id, smartphone_id, added_price)
+select v.cart_id, v.smartphone_id, s.price
+from (values
     (1, 1),
     (1, 3),
-    (2, 2);
+    (2, 2)
+) as v(cart_id, smartphone_id)
+join smartphones s on s.id = v.smartphone_id;
+
+delete from purchases;
+SELECT setval(pg_get_serial_sequence('purchases', 'id'), coalesce(max(id),0) + 1, false) FROM purchases;
+insert into purchases (user_id, smartphone_id, quantity, price, paid)
+select 2, id, 1, price, price from smartphones where id in (1, 6);
 
 delete from reviews;
 SELECT setval(pg_get_serial_sequence('reviews', 'id'), coalesce(max(id),0) + 1, false) FROM reviews;
@@ -120,3 +128,16 @@ values
     (4, 3, 3, null),
     (6, 2, 5, 'Great camera, powerful CPU'),
     (7, 3, 5, '16 gb of RAM is absolutely insane');
+update reviews set verified = true
+where exists (
+    select 1 from purchases
+    where purchases.user_id = reviews.user_id and purchases.smartphone_id = reviews.smartphone_id
+);
+
+delete from delivery_methods;
+SELECT setval(pg_get_serial_sequence('delivery_methods', 'id'), coalesce(max(id),0) + 1, false) FROM delivery_methods;
+insert into delivery_methods (name, type, base_cost, cost_per_kg, free_from, max_weight)
+values
+    ('Курьер', 'courier', 300, 50, 100000, 20000),
+    ('Пункт выдачи', 'pickup', 150, 0, 50000, 15000),
+    ('Почта России', 'post', 200, 100, null, 30000);
@@ -0,0 +1,71 @@
+package postgres
+
+import (
+	"database/sql"
+
+	"github.com/sfu-teamproject/smartbuy/backend/models"
+)
+
+type DeliveryMethod = models.DeliveryMethod
+
+// GetDeliveryMethods returns all delivery methods or only active ones
+func (db *PostgresDB) GetDeliveryMethods(onlyActive bool) ([]DeliveryMethod, error) {
+	rows, err := db.Query("SELECT * FROM delivery_methods WHERE active OR NOT $1 ORDER BY id", onlyActive)
+	if err != nil {
+		return nil, db.wrapError(err)
+	}
+	return db.extractDeliveryMethods(rows)
+}
+
+func (db *PostgresDB) GetDeliveryMethod(ID int) (DeliveryMethod, error) {
+	row := db.QueryRow("SELECT * FROM delivery_methods WHERE id = $1", ID)
+	return db.extractDeliveryMethod(row)
+}
+
+func (db *PostgresDB) CreateDeliveryMethod(m DeliveryMethod) (DeliveryMethod, error) {
+	query := `
+	INSERT INTO delivery_methods (name, type, base_cost, cost_per_kg, free_from, max_weight, active)
+	VALUES ($1, $2, $3, $4, $5, $6, $7)
+	RETURNING *
+	`
+	row := db.QueryRow(query, m.Name, m.Type, m.BaseCost, m.CostPerKg, m.FreeFrom, m.MaxWeight, m.Active)
+	return db.extractDeliveryMethod(row)
+}
+
+func (db *PostgresDB) UpdateDeliveryMethod(m DeliveryMethod) (DeliveryMethod, error) {
+	query := `
+	UPDATE delivery_methods
+	SET name = $1, type = $2, base_cost = $3, cost_per_kg = $4, free_from = $5, max_weight = $6, active = $7
+	WHERE id = $8
+	RETURNING *
+	`
+	row := db.QueryRow(query, m.Name, m.Type, m.BaseCost, m.CostPerKg, m.FreeFrom, m.MaxWeight, m.Active, m.ID)
+	return db.extractDeliveryMethod(row)
+}
+
+func (db *PostgresDB) DeleteDeliveryMethod(ID int) (DeliveryMethod, error) {
+	row := db.QueryRow("DELETE FROM delivery_methods WHERE id = $1 RETURNING *", ID)
+	return db.extractDeliveryMethod(row)
+}
+
+func (db *PostgresDB) extractDeliveryMethod(row *sql.Row) (DeliveryMethod, error) {
+	m := DeliveryMethod{}
+	err := row.Scan(&m.ID, &m.Name, &m.Type, &m.BaseCost, &m.CostPerKg, &m.FreeFrom, &m.MaxWeight,
+		&m.Active, &m.CreatedAt)
+	return m, db.wrapError(err)
+}
+
+func (db *PostgresDB) extractDeliveryMethods(rows *sql.Rows) ([]DeliveryMethod, error) {
+	defer rows.Close()
+	methods := []DeliveryMethod{}
+	for rows.Next() {
+		m := DeliveryMethod{}
+		err := rows.Scan(&m.ID, &m.Name, &m.Type, &m.BaseCost, &m.CostPerKg, &m.FreeFrom, &m.MaxWeight,
+			&m.Active, &m.CreatedAt)
+		if err != nil {
+			return nil, db.wrapError(err)
+		}
+		methods = append(methods, m)
+	}
+	return methods, nil
+}
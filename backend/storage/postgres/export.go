@@ -0,0 +1,140 @@
+package postgres
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/sfu-teamproject/smartbuy/backend/models"
+)
+
+// Export methods pass rows created in [from, to) to fn one by one as they are
+// read, so exports of any size do not have to fit in memory. An error returned
+// by fn stops the export and is returned as is
+
+func (db *PostgresDB) ExportUsers(from, to time.Time, fn func(User) error) error {
+	rows, err := db.Query(`
+	SELECT id, name, email, avatar, role, created_at, cart_reminders FROM users
+	WHERE created_at >= $1 AND created_at < $2
+	ORDER BY id
+	`, from, to)
+	if err != nil {
+		return db.wrapError(err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		user := User{}
+		err := rows.Scan(&user.ID, &user.Name, &user.Email, &user.Avatar, &user.Role, &user.CreatedAt,
+			&user.CartReminders)
+		if err != nil {
+			return db.wrapError(err)
+		}
+		if err := fn(user); err != nil {
+			return err
+		}
+	}
+	return db.wrapError(rows.Err())
+}
+
+// ExportReviews passes reviews together with names of their authors and
+// models of the smartphones, hidden reviews are included
+func (db *PostgresDB) ExportReviews(from, to time.Time, fn func(Review) error) error {
+	rows, err := db.Query(`
+	SELECT reviews.id, smartphone_id, user_id, users.name, smartphones.model, rating, comment,
+	reviews.created_at, reviews.updated_at, reviews.verified, reviews.hidden,
+	reviews.filter_action, reviews.filter_reasons
+	FROM reviews
+	JOIN users ON user_id = users.id
+	JOIN smartphones ON smartphone_id = smartphones.id
+	WHERE reviews.created_at >= $1 AND reviews.created_at < $2
+	ORDER BY reviews.id
+	`, from, to)
+	if err != nil {
+		return db.wrapError(err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		review := Review{}
+		err := rows.Scan(&review.ID, &review.SmartphoneID, &review.UserID, &review.UserName,
+			&review.SmartphoneModel, &review.Rating, &review.Comment, &review.CreatedAt, &review.UpdatedAt,
+			&review.Verified, &review.Hidden, &review.FilterAction, pq.Array(&review.FilterReasons))
+		if err != nil {
+			return db.wrapError(err)
+		}
+		if err := fn(review); err != nil {
+			return err
+		}
+	}
+	return db.wrapError(rows.Err())
+}
+
+// ExportCarts passes carts with items priced like in GetCartItems. Carts and
+// their items are read with a single query, a cart is passed once its last
+// item is read
+func (db *PostgresDB) ExportCarts(from, to time.Time, fn func(Cart) error) error {
+	rows, err := db.Query(`
+	SELECT carts.id, carts.user_id, carts.created_at, carts.updated_at, carts.promo_code_id,
+	ci.id, ci.smartphone_id, ci.quantity, ci.added_price, ci.state,
+	s.model, s.producer, s.price, s.image_path, s.weight, s.category, s.price * ci.quantity
+	FROM carts
+	LEFT JOIN cart_items ci ON ci.cart_id = carts.id
+	LEFT JOIN smartphones s ON s.id = ci.smartphone_id
+	WHERE carts.created_at >= $1 AND carts.created_at < $2
+	ORDER BY carts.id, ci.id
+	`, from, to)
+	if err != nil {
+		return db.wrapError(err)
+	}
+	defer rows.Close()
+	var cart *Cart
+	for rows.Next() {
+		c := Cart{}
+		var userID, itemID, smartphoneID, quantity, addedPrice, price, weight, lineTotal sql.NullInt64
+		var state, model, producer, imagePath, category sql.NullString
+		err := rows.Scan(&c.ID, &userID, &c.CreatedAt, &c.UpdatedAt, &c.PromoCodeID,
+			&itemID, &smartphoneID, &quantity, &addedPrice, &state,
+			&model, &producer, &price, &imagePath, &weight, &category, &lineTotal)
+		if err != nil {
+			return db.wrapError(err)
+		}
+		if cart == nil || cart.ID != c.ID {
+			if cart != nil {
+				if err := fn(*cart); err != nil {
+					return err
+				}
+			}
+			c.UserID = int(userID.Int64)
+			c.Items = []CartItem{}
+			cart = &c
+		}
+		if !itemID.Valid {
+			continue
+		}
+		cart.Items = append(cart.Items, CartItem{
+			ID:           int(itemID.Int64),
+			CartID:       cart.ID,
+			SmartphoneID: int(smartphoneID.Int64),
+			Quantity:     int(quantity.Int64),
+			AddedPrice:   int(addedPrice.Int64),
+			State:        models.CartItemState(state.String),
+			Smartphone: &models.SmartphoneSummary{
+				ID:        int(smartphoneID.Int64),
+				Model:     model.String,
+				Producer:  producer.String,
+				Price:     int(price.Int64),
+				ImagePath: imagePath.String,
+				Weight:    int(weight.Int64),
+				Category:  category.String,
+			},
+			LineTotal:   int(lineTotal.Int64),
+			PriceChange: int(price.Int64 - addedPrice.Int64),
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return db.wrapError(err)
+	}
+	if cart != nil {
+		return fn(*cart)
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package postgres
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sfu-teamproject/smartbuy/backend/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExport(t *testing.T) {
+	db, err := NewPostgresDB(true)
+	assert.NoError(t, err, "postgres db creating failed")
+	from, to := time.Time{}, time.Now().Add(time.Minute)
+	t.Run("export users", func(t *testing.T) {
+		users := []models.User{}
+		err := db.ExportUsers(from, to, func(user models.User) error {
+			users = append(users, user)
+			return nil
+		})
+		assert.NoError(t, err, "exporting users failed")
+		assert.NotEmpty(t, users, "no users exported")
+		for _, user := range users {
+			assert.Nil(t, user.Password, "password is exported")
+		}
+	})
+	t.Run("export reviews", func(t *testing.T) {
+		reviews := []models.Review{}
+		err := db.ExportReviews(from, to, func(review models.Review) error {
+			reviews = append(reviews, review)
+			return nil
+		})
+		assert.NoError(t, err, "exporting reviews failed")
+		for _, review := range reviews {
+			assert.NotEmpty(t, review.SmartphoneModel, "smartphone model is empty")
+		}
+	})
+	t.Run("export carts", func(t *testing.T) {
+		carts := []models.Cart{}
+		err := db.ExportCarts(from, to, func(cart models.Cart) error {
+			carts = append(carts, cart)
+			return nil
+		})
+		assert.NoError(t, err, "exporting carts failed")
+		assert.NotEmpty(t, carts, "no carts exported")
+		for i := 1; i < len(carts); i++ {
+			assert.Less(t, carts[i-1].ID, carts[i].ID, "cart is exported twice")
+		}
+		items, err := db.GetCartItems(carts[0].ID)
+		assert.NoError(t, err, "getting cart items failed")
+		assert.Equal(t, items, carts[0].Items, "exported items differ")
+	})
+	t.Run("stop export", func(t *testing.T) {
+		stop := assert.AnError
+		n := 0
+		err := db.ExportUsers(from, to, func(user models.User) error {
+			n++
+			return stop
+		})
+		assert.ErrorIs(t, err, stop, "error of fn is not returned")
+		assert.Equal(t, 1, n, "export is not stopped")
+	})
+	t.Run("empty range", func(t *testing.T) {
+		err := db.ExportCarts(to, to.Add(time.Hour), func(cart models.Cart) error {
+			t.Errorf("cart %d is out of range", cart.ID)
+			return nil
+		})
+		assert.NoError(t, err, "exporting carts failed")
+	})
+}
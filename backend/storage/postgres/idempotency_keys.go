@@ -0,0 +1,78 @@
+package postgres
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/sfu-teamproject/smartbuy/backend/models"
+)
+
+type IdempotencyKey = models.IdempotencyKey
+
+// ClaimIdempotencyKey saves the key of a request that is about to be handled
+// and reports true. If the key is already saved and was created after
+// expiredBefore, the saved key is returned with false. Expired keys are
+// claimed again as if they were new
+func (db *PostgresDB) ClaimIdempotencyKey(key IdempotencyKey, expiredBefore time.Time) (IdempotencyKey, bool, error) {
+	row := db.QueryRow(`
+	INSERT INTO idempotency_keys (scope, key, request_hash) VALUES ($1, $2, $3)
+	ON CONFLICT (scope, key) DO UPDATE SET request_hash = EXCLUDED.request_hash, status_code = 0,
+	headers = '{}', body = '', created_at = CURRENT_TIMESTAMP
+	WHERE idempotency_keys.created_at < $4
+	RETURNING *
+	`, key.Scope, key.Key, key.RequestHash, expiredBefore)
+	claimed, err := db.extractIdempotencyKey(row)
+	if err == nil {
+		return claimed, true, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return IdempotencyKey{}, false, db.wrapError(err)
+	}
+	row = db.QueryRow("SELECT * FROM idempotency_keys WHERE scope = $1 AND key = $2", key.Scope, key.Key)
+	saved, err := db.extractIdempotencyKey(row)
+	return saved, false, db.wrapError(err)
+}
+
+// SaveIdempotentResponse stores the response to the request with the key
+func (db *PostgresDB) SaveIdempotentResponse(key IdempotencyKey) error {
+	headers, err := json.Marshal(key.Headers)
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec(`
+	UPDATE idempotency_keys SET status_code = $1, headers = $2, body = $3
+	WHERE scope = $4 AND key = $5
+	`, key.StatusCode, headers, key.Body, key.Scope, key.Key)
+	return db.wrapError(err)
+}
+
+func (db *PostgresDB) DeleteIdempotencyKey(scope, key string) error {
+	_, err := db.Exec("DELETE FROM idempotency_keys WHERE scope = $1 AND key = $2", scope, key)
+	return db.wrapError(err)
+}
+
+// DeleteExpiredIdempotencyKeys deletes keys created before the given time and
+// returns how many were deleted
+func (db *PostgresDB) DeleteExpiredIdempotencyKeys(before time.Time) (int, error) {
+	result, err := db.Exec("DELETE FROM idempotency_keys WHERE created_at < $1", before)
+	if err != nil {
+		return 0, db.wrapError(err)
+	}
+	deleted, err := result.RowsAffected()
+	return int(deleted), db.wrapError(err)
+}
+
+// extractIdempotencyKey returns sql.ErrNoRows as is, ClaimIdempotencyKey
+// tells a conflicting key by it
+func (db *PostgresDB) extractIdempotencyKey(row *sql.Row) (IdempotencyKey, error) {
+	k := IdempotencyKey{}
+	var headers []byte
+	err := row.Scan(&k.Scope, &k.Key, &k.RequestHash, &k.StatusCode, &headers, &k.Body, &k.CreatedAt)
+	if err != nil {
+		return k, err
+	}
+	err = json.Unmarshal(headers, &k.Headers)
+	return k, err
+}
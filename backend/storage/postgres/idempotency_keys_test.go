@@ -0,0 +1,52 @@
+package postgres
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sfu-teamproject/smartbuy/backend/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIdempotencyKeys(t *testing.T) {
+	db, err := NewPostgresDB(true)
+	assert.NoError(t, err, "postgres db creating failed")
+	key := models.IdempotencyKey{Scope: "scope", Key: "key", RequestHash: "hash"}
+	longAgo := time.Now().Add(-time.Hour)
+	t.Run("claim new key", func(t *testing.T) {
+		_, claimed, err := db.ClaimIdempotencyKey(key, longAgo)
+		assert.NoError(t, err, "claiming key failed")
+		assert.True(t, claimed, "new key is not claimed")
+		saved, claimed, err := db.ClaimIdempotencyKey(key, longAgo)
+		assert.NoError(t, err, "claiming key failed")
+		assert.False(t, claimed, "key is claimed twice")
+		assert.Zero(t, saved.StatusCode, "key is not in progress")
+	})
+	t.Run("save response", func(t *testing.T) {
+		key.StatusCode = 201
+		key.Headers = map[string][]string{"Content-Type": {"application/json"}}
+		key.Body = []byte(`{"id":1}`)
+		err := db.SaveIdempotentResponse(key)
+		assert.NoError(t, err, "saving response failed")
+		saved, _, err := db.ClaimIdempotencyKey(key, longAgo)
+		assert.NoError(t, err, "claiming key failed")
+		assert.Equal(t, 201, saved.StatusCode, "status is not saved")
+		assert.Equal(t, key.Headers, saved.Headers, "headers are not saved")
+		assert.Equal(t, key.Body, saved.Body, "body is not saved")
+	})
+	t.Run("claim expired key", func(t *testing.T) {
+		_, claimed, err := db.ClaimIdempotencyKey(key, time.Now().Add(time.Minute))
+		assert.NoError(t, err, "claiming key failed")
+		assert.True(t, claimed, "expired key is not claimed")
+	})
+	t.Run("delete keys", func(t *testing.T) {
+		err := db.DeleteIdempotencyKey(key.Scope, key.Key)
+		assert.NoError(t, err, "deleting key failed")
+		_, claimed, err := db.ClaimIdempotencyKey(key, longAgo)
+		assert.NoError(t, err, "claiming key failed")
+		assert.True(t, claimed, "deleted key is not claimed")
+		deleted, err := db.DeleteExpiredIdempotencyKeys(time.Now().Add(time.Minute))
+		assert.NoError(t, err, "deleting expired keys failed")
+		assert.GreaterOrEqual(t, deleted, 1, "expired key is not deleted")
+	})
+}
@@ -0,0 +1,295 @@
+package postgres
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+	"github.com/sfu-teamproject/smartbuy/backend/apperrors"
+	"github.com/sfu-teamproject/smartbuy/backend/models"
+)
+
+type Order = models.Order
+type OrderItem = models.OrderItem
+
+func (db *PostgresDB) GetOrders() ([]Order, error) {
+	rows, err := db.Query("SELECT * FROM orders ORDER BY id")
+	if err != nil {
+		return nil, db.wrapError(err)
+	}
+	return db.extractOrders(rows)
+}
+
+func (db *PostgresDB) GetUserOrders(userID int) ([]Order, error) {
+	rows, err := db.Query("SELECT * FROM orders WHERE user_id = $1 ORDER BY id", userID)
+	if err != nil {
+		return nil, db.wrapError(err)
+	}
+	return db.extractOrders(rows)
+}
+
+func (db *PostgresDB) GetOrder(ID int) (Order, error) {
+	row := db.QueryRow("SELECT * FROM orders WHERE id = $1", ID)
+	order, err := db.extractOrder(row)
+	if err != nil {
+		return order, err
+	}
+	orders := []Order{order}
+	err = db.attachOrderItems(orders)
+	if err != nil {
+		return order, err
+	}
+	order = orders[0]
+	order.History, err = db.getOrderHistory(order.ID)
+	return order, err
+}
+
+// SetOrderStatus moves the order from status from to status to and records the
+// change in the order history. Delivered orders are recorded as purchases of
+// the customer, purchases of refunded orders are marked refunded, so they can
+// not be returned. An order with refunded returns can not be refunded. If the
+// order is not in status from anymore nothing is changed
+func (db *PostgresDB) SetOrderStatus(orderID int, from, to models.OrderStatus, actorID *int) (Order, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return Order{}, db.wrapError(err)
+	}
+	defer tx.Rollback()
+	row := tx.QueryRow(`
+	UPDATE orders SET status = $1, updated_at = CURRENT_TIMESTAMP
+	WHERE id = $2 AND status = $3
+	RETURNING *
+	`, to, orderID, from)
+	_, err = db.extractOrder(row)
+	if errors.Is(err, apperrors.ErrNotFound) {
+		return Order{}, fmt.Errorf("%w: order %d is not in status %s", apperrors.ErrBadRequest, orderID, from)
+	}
+	if err != nil {
+		return Order{}, err
+	}
+	_, err = tx.Exec("INSERT INTO order_status_history (order_id, status, actor_id) VALUES ($1, $2, $3)",
+		orderID, to, actorID)
+	if err != nil {
+		return Order{}, db.wrapError(err)
+	}
+	if to == models.OrderCancelled {
+		// a cancelled order gives its use of the promo code back
+		_, err = tx.Exec(`
+		UPDATE promo_codes SET used_count = GREATEST(used_count - 1, 0)
+		WHERE id = (SELECT promo_code_id FROM orders WHERE id = $1)
+		`, orderID)
+		if err != nil {
+			return Order{}, db.wrapError(err)
+		}
+	}
+	if to == models.OrderDelivered {
+		_, err = tx.Exec(`
+		INSERT INTO purchases (user_id, smartphone_id, quantity, price, order_id, paid)
+		SELECT orders.user_id, order_items.smartphone_id, order_items.quantity, order_items.price, orders.id,
+		order_items.paid
+		FROM order_items JOIN orders ON orders.id = order_items.order_id
+		WHERE order_items.order_id = $1 AND order_items.smartphone_id IS NOT NULL
+		`, orderID)
+		if err != nil {
+			return Order{}, db.wrapError(err)
+		}
+	}
+	if to == models.OrderRefunded {
+		// purchases are locked first, so a return of them can not be refunded
+		// concurrently
+		_, err = tx.Exec("UPDATE purchases SET refunded = true WHERE order_id = $1", orderID)
+		if err != nil {
+			return Order{}, db.wrapError(err)
+		}
+		var refundedReturns bool
+		err = tx.QueryRow(`
+		SELECT EXISTS(
+			SELECT 1 FROM returns JOIN purchases ON purchases.id = returns.purchase_id
+			WHERE purchases.order_id = $1 AND returns.status = $2
+		)
+		`, orderID, models.ReturnRefunded).Scan(&refundedReturns)
+		if err != nil {
+			return Order{}, db.wrapError(err)
+		}
+		if refundedReturns {
+			return Order{}, fmt.Errorf("%w: order %d has refunded returns", apperrors.ErrBadRequest, orderID)
+		}
+	}
+	err = tx.Commit()
+	if err != nil {
+		return Order{}, db.wrapError(err)
+	}
+	return db.GetOrder(orderID)
+}
+
+func (db *PostgresDB) getOrderHistory(orderID int) ([]models.OrderStatusChange, error) {
+	rows, err := db.Query("SELECT * FROM order_status_history WHERE order_id = $1 ORDER BY id", orderID)
+	if err != nil {
+		return nil, db.wrapError(err)
+	}
+	defer rows.Close()
+	history := []models.OrderStatusChange{}
+	for rows.Next() {
+		c := models.OrderStatusChange{}
+		err := rows.Scan(&c.ID, &c.OrderID, &c.Status, &c.ActorID, &c.CreatedAt)
+		if err != nil {
+			return nil, db.wrapError(err)
+		}
+		history = append(history, c)
+	}
+	return history, nil
+}
+
+// CreateOrder saves the order with its items, removes cartItems and the promo
+// code from the cart and counts the use of the promo code in one transaction.
+// If any of cartItems is already gone, e.g. the cart was checked out
+// concurrently, or the promo code is used up, nothing is saved
+func (db *PostgresDB) CreateOrder(order Order, cartItems []models.CartItem) (Order, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return Order{}, db.wrapError(err)
+	}
+	defer tx.Rollback()
+	cartItemIDs := make([]int64, len(cartItems))
+	for i, ci := range cartItems {
+		cartItemIDs[i] = int64(ci.ID)
+	}
+	res, err := tx.Exec("DELETE FROM cart_items WHERE id = ANY($1)", pq.Array(cartItemIDs))
+	if err != nil {
+		return Order{}, db.wrapError(err)
+	}
+	deleted, err := res.RowsAffected()
+	if err != nil {
+		return Order{}, db.wrapError(err)
+	}
+	if deleted != int64(len(cartItems)) {
+		return Order{}, fmt.Errorf("%w: cart has changed during checkout", apperrors.ErrBadRequest)
+	}
+	if order.PromoCodeID != nil {
+		res, err := tx.Exec(`
+		UPDATE promo_codes SET used_count = used_count + 1
+		WHERE id = $1 AND (usage_limit IS NULL OR used_count < usage_limit)
+		`, order.PromoCodeID)
+		if err != nil {
+			return Order{}, db.wrapError(err)
+		}
+		updated, err := res.RowsAffected()
+		if err != nil {
+			return Order{}, db.wrapError(err)
+		}
+		if updated == 0 {
+			return Order{}, fmt.Errorf("%w: promo code %d is used up", apperrors.ErrBadRequest, *order.PromoCodeID)
+		}
+	}
+	_, err = tx.Exec("UPDATE carts SET promo_code_id = NULL WHERE user_id = $1", order.UserID)
+	if err != nil {
+		return Order{}, db.wrapError(err)
+	}
+	var tax []byte
+	if order.Tax != nil {
+		tax, err = json.Marshal(order.Tax)
+		if err != nil {
+			return Order{}, err
+		}
+	}
+	row := tx.QueryRow(`
+	INSERT INTO orders (user_id, status, total, promo_code_id, discount, tax)
+	VALUES ($1, $2, $3, $4, $5, $6)
+	RETURNING *
+	`, order.UserID, order.Status, order.Total, order.PromoCodeID, order.Discount, tax)
+	newOrder, err := db.extractOrder(row)
+	if err != nil {
+		return Order{}, err
+	}
+	_, err = tx.Exec("INSERT INTO order_status_history (order_id, status, actor_id) VALUES ($1, $2, $3)",
+		newOrder.ID, newOrder.Status, newOrder.UserID)
+	if err != nil {
+		return Order{}, db.wrapError(err)
+	}
+	newOrder.Items = make([]OrderItem, 0, len(order.Items))
+	for _, item := range order.Items {
+		row := tx.QueryRow(`
+		INSERT INTO order_items (order_id, smartphone_id, model, price, quantity, producer, paid)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING *
+		`, newOrder.ID, item.SmartphoneID, item.Model, item.Price, item.Quantity, item.Producer, item.Paid)
+		newItem := OrderItem{}
+		err := row.Scan(&newItem.ID, &newItem.OrderID, &newItem.SmartphoneID, &newItem.Model,
+			&newItem.Price, &newItem.Quantity, &newItem.Producer, &newItem.Paid)
+		if err != nil {
+			return Order{}, db.wrapError(err)
+		}
+		newOrder.Items = append(newOrder.Items, newItem)
+	}
+	err = tx.Commit()
+	if err != nil {
+		return Order{}, db.wrapError(err)
+	}
+	return newOrder, nil
+}
+
+// attachOrderItems loads items of all given orders with a single query
+func (db *PostgresDB) attachOrderItems(orders []Order) error {
+	if len(orders) == 0 {
+		return nil
+	}
+	IDs := make([]int64, len(orders))
+	byID := make(map[int]*Order, len(orders))
+	for i := range orders {
+		IDs[i] = int64(orders[i].ID)
+		orders[i].Items = []OrderItem{}
+		byID[orders[i].ID] = &orders[i]
+	}
+	rows, err := db.Query("SELECT * FROM order_items WHERE order_id = ANY($1) ORDER BY id", pq.Array(IDs))
+	if err != nil {
+		return db.wrapError(err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		item := OrderItem{}
+		err := rows.Scan(&item.ID, &item.OrderID, &item.SmartphoneID, &item.Model, &item.Price, &item.Quantity,
+			&item.Producer, &item.Paid)
+		if err != nil {
+			return db.wrapError(err)
+		}
+		order := byID[item.OrderID]
+		order.Items = append(order.Items, item)
+	}
+	return nil
+}
+
+func (db *PostgresDB) extractOrders(rows *sql.Rows) ([]Order, error) {
+	defer rows.Close()
+	orders := []Order{}
+	for rows.Next() {
+		o, err := db.extractOrder(rows)
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, o)
+	}
+	err := db.attachOrderItems(orders)
+	if err != nil {
+		return nil, err
+	}
+	return orders, nil
+}
+
+// extractOrder reads an order, orders created before taxes were saved have
+// no tax
+func (db *PostgresDB) extractOrder(row scanner) (Order, error) {
+	o := Order{}
+	var tax []byte
+	err := row.Scan(&o.ID, &o.UserID, &o.Status, &o.Total, &o.CreatedAt, &o.UpdatedAt, &o.PromoCodeID, &o.Discount,
+		&tax)
+	if err != nil {
+		return o, db.wrapError(err)
+	}
+	if tax != nil {
+		o.Tax = &models.TaxBreakdown{}
+		err = json.Unmarshal(tax, o.Tax)
+	}
+	return o, err
+}
@@ -0,0 +1,80 @@
+package postgres
+
+import (
+	"testing"
+
+	"github.com/sfu-teamproject/smartbuy/backend/apperrors"
+	"github.com/sfu-teamproject/smartbuy/backend/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOrders(t *testing.T) {
+	db, err := NewPostgresDB(true)
+	assert.NoError(t, err, "postgres db creating failed", err.Error())
+	smartphoneID := 2
+	order := models.Order{
+		UserID: 3,
+		Status: models.OrderCreated,
+		Total:  200,
+		Items:  []models.OrderItem{{SmartphoneID: &smartphoneID, Model: "model", Price: 100, Quantity: 2, Paid: 200}},
+		Tax: &models.TaxBreakdown{PricesIncludeTax: true, Net: 167, Tax: 33, Gross: 200,
+			Rates: []models.RateTax{{Rate: 20, Net: 167, Tax: 33}}},
+	}
+	t.Run("create order", func(t *testing.T) {
+		cart, err := db.GetCartByUserID(3)
+		assert.NoError(t, err, "getting cart failed")
+		cartItem, err := db.AddToCart(models.CartItem{CartID: cart.ID, SmartphoneID: 2, Quantity: 2}, 10)
+		assert.NoError(t, err, "adding cart item failed")
+		newOrder, err := db.CreateOrder(order, []models.CartItem{cartItem})
+		assert.NoError(t, err, "creating order failed")
+		assert.NotEmpty(t, newOrder.ID, "order id is 0")
+		assert.Len(t, newOrder.Items, 1, "order should have 1 item")
+		order.ID = newOrder.ID
+		cartItems, err := db.GetCartItems(cart.ID)
+		assert.NoError(t, err, "getting cart items failed")
+		assert.Empty(t, cartItems, "cart is not emptied")
+		_, err = db.CreateOrder(order, []models.CartItem{cartItem})
+		assert.Error(t, err, "cart is checked out twice")
+	})
+	t.Run("get order", func(t *testing.T) {
+		o, err := db.GetOrder(order.ID)
+		assert.NoError(t, err, "getting order failed")
+		assert.Equal(t, order.Total, o.Total, "total is different")
+		assert.Equal(t, "model", o.Items[0].Model, "model is different")
+		assert.Equal(t, order.Tax, o.Tax, "tax is different")
+	})
+	t.Run("get user orders", func(t *testing.T) {
+		orders, err := db.GetUserOrders(3)
+		assert.NoError(t, err, "getting orders failed")
+		assert.NotEmpty(t, orders, "order slice is empty")
+	})
+	t.Run("set order status", func(t *testing.T) {
+		adminID := 1
+		o, err := db.SetOrderStatus(order.ID, models.OrderCreated, models.OrderPaid, &adminID)
+		assert.NoError(t, err, "setting order status failed")
+		assert.Equal(t, models.OrderPaid, o.Status, "status is not changed")
+		assert.Len(t, o.History, 2, "history should have 2 entries")
+		_, err = db.SetOrderStatus(order.ID, models.OrderCreated, models.OrderCancelled, &adminID)
+		assert.Error(t, err, "status is changed from a stale status")
+	})
+	t.Run("refund delivered order", func(t *testing.T) {
+		_, err := db.SetOrderStatus(order.ID, models.OrderPaid, models.OrderShipped, nil)
+		assert.NoError(t, err, "shipping order failed")
+		_, err = db.SetOrderStatus(order.ID, models.OrderShipped, models.OrderDelivered, nil)
+		assert.NoError(t, err, "delivering order failed")
+		_, err = db.SetOrderStatus(order.ID, models.OrderDelivered, models.OrderRefunded, nil)
+		assert.NoError(t, err, "refunding order failed")
+		purchases, err := db.GetPurchases(3)
+		assert.NoError(t, err, "getting purchases failed")
+		for _, p := range purchases {
+			if p.OrderID == nil || *p.OrderID != order.ID {
+				continue
+			}
+			assert.True(t, p.Refunded, "purchase is not refunded")
+			assert.Equal(t, 200, p.Paid, "paid is different")
+			_, err = db.CreateReturn(models.Return{PurchaseID: p.ID, UserID: 3, Quantity: 1,
+				Reason: models.ReturnReasonDefective})
+			assert.ErrorIs(t, err, apperrors.ErrBadRequest, "refunded purchase is returned")
+		}
+	})
+}
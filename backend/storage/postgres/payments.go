@@ -0,0 +1,49 @@
+package postgres
+
+import (
+	"database/sql"
+
+	"github.com/sfu-teamproject/smartbuy/backend/models"
+)
+
+type Payment = models.Payment
+
+func (db *PostgresDB) GetOrderPayments(orderID int) ([]Payment, error) {
+	rows, err := db.Query("SELECT * FROM payments WHERE order_id = $1 ORDER BY id", orderID)
+	if err != nil {
+		return nil, db.wrapError(err)
+	}
+	return db.extractPayments(rows)
+}
+
+// RecordPayment saves a payment event, an event that is already recorded
+// results in apperrors.ErrAlreadyExists
+func (db *PostgresDB) RecordPayment(payment Payment) (Payment, error) {
+	query := `
+	INSERT INTO payments (order_id, intent_id, event_id, status, amount)
+	VALUES ($1, $2, $3, $4, $5)
+	RETURNING *
+	`
+	row := db.QueryRow(query, payment.OrderID, payment.IntentID, payment.EventID, payment.Status, payment.Amount)
+	return db.extractPayment(row)
+}
+
+func (db *PostgresDB) extractPayment(row *sql.Row) (Payment, error) {
+	p := Payment{}
+	err := row.Scan(&p.ID, &p.OrderID, &p.IntentID, &p.EventID, &p.Status, &p.Amount, &p.CreatedAt)
+	return p, db.wrapError(err)
+}
+
+func (db *PostgresDB) extractPayments(rows *sql.Rows) ([]Payment, error) {
+	defer rows.Close()
+	payments := []Payment{}
+	for rows.Next() {
+		p := Payment{}
+		err := rows.Scan(&p.ID, &p.OrderID, &p.IntentID, &p.EventID, &p.Status, &p.Amount, &p.CreatedAt)
+		if err != nil {
+			return nil, db.wrapError(err)
+		}
+		payments = append(payments, p)
+	}
+	return payments, nil
+}
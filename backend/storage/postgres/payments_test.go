@@ -0,0 +1,36 @@
+package postgres
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/sfu-teamproject/smartbuy/backend/apperrors"
+	"github.com/sfu-teamproject/smartbuy/backend/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPayments(t *testing.T) {
+	db, err := NewPostgresDB(true)
+	assert.NoError(t, err, "postgres db creating failed", err.Error())
+	order, err := db.CreateOrder(models.Order{UserID: 2, Status: models.OrderCreated, Total: 100}, nil)
+	assert.NoError(t, err, "creating order failed")
+	payment := models.Payment{
+		OrderID:  order.ID,
+		IntentID: "pi_1",
+		EventID:  "evt_1",
+		Status:   models.PaymentSucceeded,
+		Amount:   100,
+	}
+	t.Run("record payment", func(t *testing.T) {
+		newPayment, err := db.RecordPayment(payment)
+		assert.NoError(t, err, "recording payment failed")
+		assert.NotEmpty(t, newPayment.ID, "payment id is 0")
+		_, err = db.RecordPayment(payment)
+		assert.True(t, errors.Is(err, apperrors.ErrAlreadyExists), "event is recorded twice")
+	})
+	t.Run("get order payments", func(t *testing.T) {
+		payments, err := db.GetOrderPayments(order.ID)
+		assert.NoError(t, err, "getting payments failed")
+		assert.Len(t, payments, 1, "order should have 1 payment")
+	})
+}
@@ -0,0 +1,100 @@
+package postgres
+
+import (
+	"database/sql"
+
+	"github.com/lib/pq"
+	"github.com/sfu-teamproject/smartbuy/backend/models"
+)
+
+type PromoCode = models.PromoCode
+
+func (db *PostgresDB) GetPromoCodes() ([]PromoCode, error) {
+	rows, err := db.Query("SELECT * FROM promo_codes ORDER BY id")
+	if err != nil {
+		return nil, db.wrapError(err)
+	}
+	return db.extractPromoCodes(rows)
+}
+
+func (db *PostgresDB) GetPromoCode(ID int) (PromoCode, error) {
+	row := db.QueryRow("SELECT * FROM promo_codes WHERE id = $1", ID)
+	return db.extractPromoCode(row)
+}
+
+// GetPromoCodeByCode finds a promo code ignoring the case of the code
+func (db *PostgresDB) GetPromoCodeByCode(code string) (PromoCode, error) {
+	row := db.QueryRow("SELECT * FROM promo_codes WHERE UPPER(code) = UPPER($1)", code)
+	return db.extractPromoCode(row)
+}
+
+func (db *PostgresDB) CreatePromoCode(promo PromoCode) (PromoCode, error) {
+	query := `
+	INSERT INTO promo_codes (code, type, value, min_total, starts_at, ends_at,
+	usage_limit, per_user_limit, producers, smartphone_ids)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, COALESCE($9::TEXT[], '{}'), $10)
+	RETURNING *
+	`
+	row := db.QueryRow(query, promo.Code, promo.Type, promo.Value, promo.MinTotal, promo.StartsAt, promo.EndsAt,
+		promo.UsageLimit, promo.PerUserLimit, pq.Array(promo.Producers), pq.Array(toInt64s(promo.SmartphoneIDs)))
+	return db.extractPromoCode(row)
+}
+
+func (db *PostgresDB) DeletePromoCode(ID int) (PromoCode, error) {
+	row := db.QueryRow("DELETE FROM promo_codes WHERE id = $1 RETURNING *", ID)
+	return db.extractPromoCode(row)
+}
+
+// GetPromoCodeUserUsage returns how many orders of the user used the promo
+// code, cancelled orders are not counted
+func (db *PostgresDB) GetPromoCodeUserUsage(promoCodeID, userID int) (int, error) {
+	var count int
+	err := db.QueryRow(`
+	SELECT COUNT(*) FROM orders WHERE promo_code_id = $1 AND user_id = $2 AND status <> $3
+	`, promoCodeID, userID, models.OrderCancelled).Scan(&count)
+	return count, db.wrapError(err)
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func (db *PostgresDB) scanPromoCode(row scanner) (PromoCode, error) {
+	p := PromoCode{}
+	var IDs pq.Int64Array
+	err := row.Scan(&p.ID, &p.Code, &p.Type, &p.Value, &p.MinTotal, &p.StartsAt, &p.EndsAt,
+		&p.UsageLimit, &p.PerUserLimit, pq.Array(&p.Producers), &IDs, &p.UsedCount, &p.CreatedAt)
+	if err != nil {
+		return p, db.wrapError(err)
+	}
+	p.SmartphoneIDs = make([]int, len(IDs))
+	for i, ID := range IDs {
+		p.SmartphoneIDs[i] = int(ID)
+	}
+	return p, nil
+}
+
+func (db *PostgresDB) extractPromoCode(row *sql.Row) (PromoCode, error) {
+	return db.scanPromoCode(row)
+}
+
+func (db *PostgresDB) extractPromoCodes(rows *sql.Rows) ([]PromoCode, error) {
+	defer rows.Close()
+	promos := []PromoCode{}
+	for rows.Next() {
+		p, err := db.scanPromoCode(rows)
+		if err != nil {
+			return nil, err
+		}
+		promos = append(promos, p)
+	}
+	return promos, nil
+}
+
+func toInt64s(ints []int) []int64 {
+	res := make([]int64, len(ints))
+	for i, v := range ints {
+		res[i] = int64(v)
+	}
+	return res
+}
@@ -0,0 +1,54 @@
+package postgres
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/sfu-teamproject/smartbuy/backend/apperrors"
+	"github.com/sfu-teamproject/smartbuy/backend/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPromoCodes(t *testing.T) {
+	db, err := NewPostgresDB(true)
+	assert.NoError(t, err, "postgres db creating failed", err.Error())
+	limit := 1
+	promo, err := db.CreatePromoCode(models.PromoCode{
+		Code:       "Sale10",
+		Type:       models.DiscountPercent,
+		Value:      10,
+		UsageLimit: &limit,
+		Producers:  []string{"Apple"},
+	})
+	assert.NoError(t, err, "creating promo code failed")
+	t.Run("code is case-insensitive", func(t *testing.T) {
+		found, err := db.GetPromoCodeByCode("SALE10")
+		assert.NoError(t, err, "getting promo code failed")
+		assert.Equal(t, promo.ID, found.ID)
+		assert.Equal(t, []string{"Apple"}, found.Producers)
+		_, err = db.CreatePromoCode(models.PromoCode{Code: "sale10", Type: models.DiscountFixed, Value: 1})
+		assert.True(t, errors.Is(err, apperrors.ErrAlreadyExists), "duplicate code is created")
+	})
+	t.Run("promo code without producers", func(t *testing.T) {
+		created, err := db.CreatePromoCode(models.PromoCode{Code: "Fix5", Type: models.DiscountFixed, Value: 5})
+		assert.NoError(t, err, "creating promo code failed")
+		assert.Empty(t, created.Producers)
+	})
+	t.Run("order uses promo code", func(t *testing.T) {
+		order := models.Order{UserID: 2, Status: models.OrderCreated, Total: 90, PromoCodeID: &promo.ID, Discount: 10}
+		created, err := db.CreateOrder(order, nil)
+		assert.NoError(t, err, "creating order failed")
+		usage, err := db.GetPromoCodeUserUsage(promo.ID, 2)
+		assert.NoError(t, err, "getting usage failed")
+		assert.Equal(t, 1, usage)
+		_, err = db.CreateOrder(order, nil)
+		assert.True(t, errors.Is(err, apperrors.ErrBadRequest), "usage limit is exceeded")
+		_, err = db.SetOrderStatus(created.ID, models.OrderCreated, models.OrderCancelled, nil)
+		assert.NoError(t, err, "cancelling order failed")
+		usage, err = db.GetPromoCodeUserUsage(promo.ID, 2)
+		assert.NoError(t, err, "getting usage failed")
+		assert.Equal(t, 0, usage)
+		_, err = db.CreateOrder(order, nil)
+		assert.NoError(t, err, "cancelled order still uses promo code")
+	})
+}
@@ -0,0 +1,61 @@
+package postgres
+
+import (
+	"database/sql"
+
+	"github.com/sfu-teamproject/smartbuy/backend/models"
+)
+
+type Purchase = models.Purchase
+
+func (db *PostgresDB) GetPurchases(userID int) ([]Purchase, error) {
+	rows, err := db.Query("SELECT * FROM purchases WHERE user_id = $1 ORDER BY purchased_at", userID)
+	if err != nil {
+		return nil, db.wrapError(err)
+	}
+	return db.extractPurchases(rows)
+}
+
+func (db *PostgresDB) GetPurchase(ID int) (Purchase, error) {
+	row := db.QueryRow("SELECT * FROM purchases WHERE id = $1", ID)
+	return db.extractPurchase(row)
+}
+
+func (db *PostgresDB) CreatePurchase(purchase Purchase) (Purchase, error) {
+	query := `
+	INSERT INTO purchases (user_id, smartphone_id, quantity, price, paid)
+	VALUES ($1, $2, $3, $4, $5)
+	RETURNING *
+	`
+	row := db.QueryRow(query, purchase.UserID, purchase.SmartphoneID, purchase.Quantity, purchase.Price, purchase.Paid)
+	return db.extractPurchase(row)
+}
+
+func (db *PostgresDB) HasPurchased(userID, smartphoneID int) (bool, error) {
+	var purchased bool
+	err := db.QueryRow("SELECT EXISTS(SELECT 1 FROM purchases WHERE user_id = $1 AND smartphone_id = $2)",
+		userID, smartphoneID).Scan(&purchased)
+	return purchased, db.wrapError(err)
+}
+
+func (db *PostgresDB) extractPurchase(row *sql.Row) (Purchase, error) {
+	p := Purchase{}
+	err := row.Scan(&p.ID, &p.UserID, &p.SmartphoneID, &p.Quantity, &p.Price, &p.PurchasedAt, &p.OrderID, &p.Paid,
+		&p.Refunded)
+	return p, db.wrapError(err)
+}
+
+func (db *PostgresDB) extractPurchases(rows *sql.Rows) ([]Purchase, error) {
+	defer rows.Close()
+	purchases := []Purchase{}
+	for rows.Next() {
+		p := Purchase{}
+		err := rows.Scan(&p.ID, &p.UserID, &p.SmartphoneID, &p.Quantity, &p.Price, &p.PurchasedAt, &p.OrderID, &p.Paid,
+			&p.Refunded)
+		if err != nil {
+			return nil, db.wrapError(err)
+		}
+		purchases = append(purchases, p)
+	}
+	return purchases, nil
+}
@@ -0,0 +1,39 @@
+package postgres
+
+import (
+	"testing"
+
+	"github.com/sfu-teamproject/smartbuy/backend/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPurchases(t *testing.T) {
+	db, err := NewPostgresDB(true)
+	assert.NoError(t, err, "postgres db creating failed", err.Error())
+	purchase := models.Purchase{
+		UserID:       3,
+		SmartphoneID: 2,
+		Quantity:     1,
+		Price:        100,
+		Paid:         100,
+	}
+	t.Run("create purchase", func(t *testing.T) {
+		newPurchase, err := db.CreatePurchase(purchase)
+		assert.NoError(t, err, "creating purchase failed", err.Error())
+		assert.NotEmpty(t, newPurchase.ID, "purchase id is 0")
+		assert.Equal(t, purchase.SmartphoneID, newPurchase.SmartphoneID, "smartphone id is different")
+	})
+	t.Run("has purchased", func(t *testing.T) {
+		purchased, err := db.HasPurchased(3, 2)
+		assert.NoError(t, err, "checking purchase failed", err.Error())
+		assert.True(t, purchased, "purchase is not found")
+		purchased, err = db.HasPurchased(3, 5)
+		assert.NoError(t, err, "checking purchase failed", err.Error())
+		assert.False(t, purchased, "purchase is found")
+	})
+	t.Run("get purchases", func(t *testing.T) {
+		purchases, err := db.GetPurchases(3)
+		assert.NoError(t, err, "getting purchases failed", err.Error())
+		assert.NotEmpty(t, purchases, "purchase slice is empty")
+	})
+}
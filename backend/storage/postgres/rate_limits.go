@@ -0,0 +1,51 @@
+package postgres
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// HitRateLimit counts a hit of the key and returns the number of hits in the
+// current window and its end. The counter is shared by all instances of the
+// server
+func (db *PostgresDB) HitRateLimit(key string, window time.Duration, now time.Time) (int, time.Time, error) {
+	var count int
+	var resetAt time.Time
+	err := db.QueryRow(`
+	INSERT INTO rate_limits (key, count, reset_at) VALUES ($1, 1, $3)
+	ON CONFLICT (key) DO UPDATE SET
+	count = CASE WHEN rate_limits.reset_at <= $2 THEN 1 ELSE rate_limits.count + 1 END,
+	reset_at = CASE WHEN rate_limits.reset_at <= $2 THEN EXCLUDED.reset_at ELSE rate_limits.reset_at END
+	RETURNING count, reset_at
+	`, key, now, now.Add(window)).Scan(&count, &resetAt)
+	return count, resetAt, db.wrapError(err)
+}
+
+// GetRateLimit returns the counter of the key, zero if its window has ended
+func (db *PostgresDB) GetRateLimit(key string, now time.Time) (int, time.Time, error) {
+	var count int
+	var resetAt time.Time
+	err := db.QueryRow("SELECT count, reset_at FROM rate_limits WHERE key = $1 AND reset_at > $2",
+		key, now).Scan(&count, &resetAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, time.Time{}, nil
+	}
+	return count, resetAt, db.wrapError(err)
+}
+
+func (db *PostgresDB) ResetRateLimit(key string) error {
+	_, err := db.Exec("DELETE FROM rate_limits WHERE key = $1", key)
+	return db.wrapError(err)
+}
+
+// DeleteExpiredRateLimits deletes counters of windows that ended before the
+// given time and returns how many were deleted
+func (db *PostgresDB) DeleteExpiredRateLimits(before time.Time) (int, error) {
+	result, err := db.Exec("DELETE FROM rate_limits WHERE reset_at <= $1", before)
+	if err != nil {
+		return 0, db.wrapError(err)
+	}
+	deleted, err := result.RowsAffected()
+	return int(deleted), db.wrapError(err)
+}
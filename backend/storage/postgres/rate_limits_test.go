@@ -0,0 +1,49 @@
+package postgres
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimits(t *testing.T) {
+	db, err := NewPostgresDB(true)
+	assert.NoError(t, err, "postgres db creating failed")
+	now := time.Now().Truncate(time.Millisecond)
+	t.Run("hit", func(t *testing.T) {
+		for i := 1; i <= 3; i++ {
+			count, resetAt, err := db.HitRateLimit("key", time.Minute, now)
+			assert.NoError(t, err, "hitting rate limit failed")
+			assert.Equal(t, i, count, "hits are not counted")
+			assert.WithinDuration(t, now.Add(time.Minute), resetAt, time.Millisecond, "window is moved")
+		}
+		count, _, err := db.GetRateLimit("key", now)
+		assert.NoError(t, err, "getting rate limit failed")
+		assert.Equal(t, 3, count, "counter is changed by get")
+	})
+	t.Run("new window", func(t *testing.T) {
+		later := now.Add(time.Minute)
+		count, _, err := db.GetRateLimit("key", later)
+		assert.NoError(t, err, "getting rate limit failed")
+		assert.Zero(t, count, "ended window is counted")
+		count, resetAt, err := db.HitRateLimit("key", time.Minute, later)
+		assert.NoError(t, err, "hitting rate limit failed")
+		assert.Equal(t, 1, count, "new window is not started")
+		assert.WithinDuration(t, later.Add(time.Minute), resetAt, time.Millisecond, "window is not moved")
+	})
+	t.Run("reset", func(t *testing.T) {
+		err := db.ResetRateLimit("key")
+		assert.NoError(t, err, "resetting rate limit failed")
+		count, _, err := db.GetRateLimit("key", now)
+		assert.NoError(t, err, "getting rate limit failed")
+		assert.Zero(t, count, "counter is not reset")
+	})
+	t.Run("delete expired", func(t *testing.T) {
+		_, _, err := db.HitRateLimit("key", time.Minute, now)
+		assert.NoError(t, err, "hitting rate limit failed")
+		deleted, err := db.DeleteExpiredRateLimits(now.Add(time.Minute))
+		assert.NoError(t, err, "deleting expired rate limits failed")
+		assert.Equal(t, 1, deleted, "expired counter is not deleted")
+	})
+}
@@ -0,0 +1,125 @@
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/sfu-teamproject/smartbuy/backend/apperrors"
+	"github.com/sfu-teamproject/smartbuy/backend/models"
+)
+
+type RefreshToken = models.RefreshToken
+
+// CreateRefreshToken saves a token and deletes expired tokens of its user
+func (db *PostgresDB) CreateRefreshToken(token RefreshToken) (RefreshToken, error) {
+	_, err := db.Exec("DELETE FROM refresh_tokens WHERE user_id = $1 AND expires_at < CURRENT_TIMESTAMP", token.UserID)
+	if err != nil {
+		return RefreshToken{}, db.wrapError(err)
+	}
+	row := db.QueryRow(`
+	INSERT INTO refresh_tokens (user_id, token_hash, family_id, expires_at)
+	VALUES ($1, $2, $3, $4)
+	RETURNING *
+	`, token.UserID, token.TokenHash, token.FamilyID, token.ExpiresAt)
+	return db.extractRefreshToken(row)
+}
+
+// RotateRefreshToken marks the token with the hash as used and saves next in
+// its family for its user. A token that was already used means it was stolen,
+// so the whole family is deleted and the user has to log in again
+func (db *PostgresDB) RotateRefreshToken(tokenHash string, next RefreshToken) (RefreshToken, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return RefreshToken{}, db.wrapError(err)
+	}
+	defer tx.Rollback()
+	row := tx.QueryRow("SELECT * FROM refresh_tokens WHERE token_hash = $1 FOR UPDATE", tokenHash)
+	token, err := db.extractRefreshToken(row)
+	if err != nil {
+		return RefreshToken{}, err
+	}
+	if token.UsedAt != nil {
+		_, err = tx.Exec("DELETE FROM refresh_tokens WHERE family_id = $1", token.FamilyID)
+		if err != nil {
+			return RefreshToken{}, db.wrapError(err)
+		}
+		err = tx.Commit()
+		if err != nil {
+			return RefreshToken{}, db.wrapError(err)
+		}
+		return RefreshToken{}, fmt.Errorf("%w: refresh token %d was already used, its family is revoked",
+			apperrors.ErrUnauthorized, token.ID)
+	}
+	if !token.ExpiresAt.After(time.Now()) {
+		return RefreshToken{}, fmt.Errorf("%w: refresh token %d expired at %s",
+			apperrors.ErrUnauthorized, token.ID, token.ExpiresAt)
+	}
+	_, err = tx.Exec("UPDATE refresh_tokens SET used_at = CURRENT_TIMESTAMP WHERE id = $1", token.ID)
+	if err != nil {
+		return RefreshToken{}, db.wrapError(err)
+	}
+	row = tx.QueryRow(`
+	INSERT INTO refresh_tokens (user_id, token_hash, family_id, expires_at)
+	VALUES ($1, $2, $3, $4)
+	RETURNING *
+	`, token.UserID, next.TokenHash, token.FamilyID, next.ExpiresAt)
+	rotated, err := db.extractRefreshToken(row)
+	if err != nil {
+		return RefreshToken{}, err
+	}
+	return rotated, db.wrapError(tx.Commit())
+}
+
+// DeleteRefreshTokenFamily deletes all tokens rotated from the same login as
+// the token with the hash, if the token belongs to the user
+func (db *PostgresDB) DeleteRefreshTokenFamily(userID int, tokenHash string) error {
+	result, err := db.Exec(`
+	DELETE FROM refresh_tokens WHERE user_id = $1
+	AND family_id = (SELECT family_id FROM refresh_tokens WHERE token_hash = $2)
+	`, userID, tokenHash)
+	if err != nil {
+		return db.wrapError(err)
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return db.wrapError(err)
+	}
+	if deleted == 0 {
+		return fmt.Errorf("%w: refresh token of user %d", apperrors.ErrNotFound, userID)
+	}
+	return nil
+}
+
+// RevokeUserTokens rejects access tokens of the user issued before at and
+// deletes all refresh tokens of the user
+func (db *PostgresDB) RevokeUserTokens(userID int, at time.Time) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return db.wrapError(err)
+	}
+	defer tx.Rollback()
+	var ID int
+	err = tx.QueryRow("UPDATE users SET tokens_revoked_at = $1 WHERE id = $2 RETURNING id", at, userID).Scan(&ID)
+	if err != nil {
+		return db.wrapError(err)
+	}
+	_, err = tx.Exec("DELETE FROM refresh_tokens WHERE user_id = $1", userID)
+	if err != nil {
+		return db.wrapError(err)
+	}
+	return db.wrapError(tx.Commit())
+}
+
+// GetTokensRevokedAt returns nil if tokens of the user were never revoked
+func (db *PostgresDB) GetTokensRevokedAt(userID int) (*time.Time, error) {
+	var revokedAt *time.Time
+	err := db.QueryRow("SELECT tokens_revoked_at FROM users WHERE id = $1", userID).Scan(&revokedAt)
+	return revokedAt, db.wrapError(err)
+}
+
+func (db *PostgresDB) extractRefreshToken(row *sql.Row) (RefreshToken, error) {
+	t := RefreshToken{}
+	err := row.Scan(&t.ID, &t.UserID, &t.TokenHash, &t.FamilyID, &t.ExpiresAt, &t.UsedAt, &t.CreatedAt)
+	return t, db.wrapError(err)
+}
@@ -0,0 +1,57 @@
+package postgres
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sfu-teamproject/smartbuy/backend/apperrors"
+	"github.com/sfu-teamproject/smartbuy/backend/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRefreshTokens(t *testing.T) {
+	db, err := NewPostgresDB(true)
+	assert.NoError(t, err, "postgres db creating failed")
+	expires := time.Now().Add(time.Hour)
+	t.Run("rotate token", func(t *testing.T) {
+		_, err := db.CreateRefreshToken(models.RefreshToken{UserID: 1, TokenHash: "first", FamilyID: "family", ExpiresAt: expires})
+		assert.NoError(t, err, "creating refresh token failed")
+		rotated, err := db.RotateRefreshToken("first", models.RefreshToken{TokenHash: "second", ExpiresAt: expires})
+		assert.NoError(t, err, "rotating refresh token failed")
+		assert.Equal(t, 1, rotated.UserID, "user is not kept")
+		assert.Equal(t, "family", rotated.FamilyID, "family is not kept")
+	})
+	t.Run("reuse token", func(t *testing.T) {
+		_, err := db.RotateRefreshToken("first", models.RefreshToken{TokenHash: "third", ExpiresAt: expires})
+		assert.ErrorIs(t, err, apperrors.ErrUnauthorized, "used token is accepted")
+		_, err = db.RotateRefreshToken("second", models.RefreshToken{TokenHash: "third", ExpiresAt: expires})
+		assert.ErrorIs(t, err, apperrors.ErrNotFound, "family is not revoked")
+	})
+	t.Run("expired token", func(t *testing.T) {
+		_, err := db.CreateRefreshToken(models.RefreshToken{UserID: 1, TokenHash: "expired", FamilyID: "old",
+			ExpiresAt: time.Now().Add(-time.Hour)})
+		assert.NoError(t, err, "creating refresh token failed")
+		_, err = db.RotateRefreshToken("expired", models.RefreshToken{TokenHash: "fourth", ExpiresAt: expires})
+		assert.ErrorIs(t, err, apperrors.ErrUnauthorized, "expired token is accepted")
+	})
+	t.Run("logout", func(t *testing.T) {
+		_, err := db.CreateRefreshToken(models.RefreshToken{UserID: 1, TokenHash: "session", FamilyID: "session", ExpiresAt: expires})
+		assert.NoError(t, err, "creating refresh token failed")
+		err = db.DeleteRefreshTokenFamily(2, "session")
+		assert.ErrorIs(t, err, apperrors.ErrNotFound, "token of another user is deleted")
+		err = db.DeleteRefreshTokenFamily(1, "session")
+		assert.NoError(t, err, "deleting refresh token failed")
+	})
+	t.Run("revoke tokens", func(t *testing.T) {
+		_, err := db.CreateRefreshToken(models.RefreshToken{UserID: 1, TokenHash: "revoked", FamilyID: "revoked", ExpiresAt: expires})
+		assert.NoError(t, err, "creating refresh token failed")
+		now := time.Now().Truncate(time.Microsecond)
+		err = db.RevokeUserTokens(1, now)
+		assert.NoError(t, err, "revoking tokens failed")
+		revokedAt, err := db.GetTokensRevokedAt(1)
+		assert.NoError(t, err, "getting revocation time failed")
+		assert.True(t, now.Equal(*revokedAt), "revocation time is not saved")
+		_, err = db.RotateRefreshToken("revoked", models.RefreshToken{TokenHash: "fifth", ExpiresAt: expires})
+		assert.ErrorIs(t, err, apperrors.ErrNotFound, "refresh token is not deleted")
+	})
+}
@@ -0,0 +1,210 @@
+package postgres
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+	"github.com/sfu-teamproject/smartbuy/backend/apperrors"
+	"github.com/sfu-teamproject/smartbuy/backend/models"
+)
+
+type Return = models.Return
+type ReturnPhoto = models.ReturnPhoto
+
+func (db *PostgresDB) GetReturns() ([]Return, error) {
+	rows, err := db.Query("SELECT * FROM returns ORDER BY id")
+	if err != nil {
+		return nil, db.wrapError(err)
+	}
+	return db.extractReturns(rows)
+}
+
+func (db *PostgresDB) GetUserReturns(userID int) ([]Return, error) {
+	rows, err := db.Query("SELECT * FROM returns WHERE user_id = $1 ORDER BY id", userID)
+	if err != nil {
+		return nil, db.wrapError(err)
+	}
+	return db.extractReturns(rows)
+}
+
+func (db *PostgresDB) GetReturn(ID int) (Return, error) {
+	row := db.QueryRow("SELECT * FROM returns WHERE id = $1", ID)
+	ret, err := db.extractReturn(row)
+	if err != nil {
+		return ret, err
+	}
+	returns := []Return{ret}
+	err = db.attachReturnPhotos(returns)
+	return returns[0], err
+}
+
+// CreateReturn saves a return of the purchase. The purchase is locked, so
+// concurrent requests can not return more smartphones than were purchased;
+// rejected returns do not count. Purchases of refunded orders can not be
+// returned
+func (db *PostgresDB) CreateReturn(ret Return) (Return, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return Return{}, db.wrapError(err)
+	}
+	defer tx.Rollback()
+	var purchased, returned int
+	var refunded bool
+	err = tx.QueryRow("SELECT quantity, refunded FROM purchases WHERE id = $1 FOR UPDATE", ret.PurchaseID).
+		Scan(&purchased, &refunded)
+	if err != nil {
+		return Return{}, db.wrapError(err)
+	}
+	if refunded {
+		return Return{}, fmt.Errorf("%w: order of purchase %d is refunded", apperrors.ErrBadRequest, ret.PurchaseID)
+	}
+	err = tx.QueryRow(`
+	SELECT COALESCE(SUM(quantity), 0) FROM returns
+	WHERE purchase_id = $1 AND status <> $2
+	`, ret.PurchaseID, models.ReturnRejected).Scan(&returned)
+	if err != nil {
+		return Return{}, db.wrapError(err)
+	}
+	if returned+ret.Quantity > purchased {
+		return Return{}, fmt.Errorf("%w: only %d of %d smartphones of purchase %d can be returned",
+			apperrors.ErrBadRequest, purchased-returned, purchased, ret.PurchaseID)
+	}
+	row := tx.QueryRow(`
+	INSERT INTO returns (purchase_id, user_id, quantity, reason, comment)
+	VALUES ($1, $2, $3, $4, $5)
+	RETURNING *
+	`, ret.PurchaseID, ret.UserID, ret.Quantity, ret.Reason, ret.Comment)
+	newReturn, err := db.extractReturn(row)
+	if err != nil {
+		return Return{}, err
+	}
+	return newReturn, db.wrapError(tx.Commit())
+}
+
+// SetReturnStatus moves the return from status from to status to. The refund
+// amount and the admin comment are kept if nil. If the return is not in
+// status from anymore nothing is changed. Refunds lock the purchase, so
+// refunds of all its returns together never exceed what was paid for it, and
+// returns of a refunded order are not refunded again
+func (db *PostgresDB) SetReturnStatus(ID int, from, to models.ReturnStatus, refundAmount *int,
+	adminComment *string) (Return, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return Return{}, db.wrapError(err)
+	}
+	defer tx.Rollback()
+	if to == models.ReturnRefunded && refundAmount != nil {
+		var purchaseID, paid, refunded int
+		var orderRefunded bool
+		err = tx.QueryRow(`
+		SELECT purchases.id, purchases.paid, purchases.refunded
+		FROM purchases JOIN returns ON returns.purchase_id = purchases.id
+		WHERE returns.id = $1
+		FOR UPDATE OF purchases
+		`, ID).Scan(&purchaseID, &paid, &orderRefunded)
+		if err != nil {
+			return Return{}, db.wrapError(err)
+		}
+		if orderRefunded {
+			return Return{}, fmt.Errorf("%w: order of purchase %d is already refunded",
+				apperrors.ErrBadRequest, purchaseID)
+		}
+		err = tx.QueryRow(`
+		SELECT COALESCE(SUM(refund_amount), 0) FROM returns
+		WHERE purchase_id = $1 AND status = $2
+		`, purchaseID, models.ReturnRefunded).Scan(&refunded)
+		if err != nil {
+			return Return{}, db.wrapError(err)
+		}
+		if refunded+*refundAmount > paid {
+			return Return{}, fmt.Errorf("%w: only %d of %d paid for purchase %d can be refunded",
+				apperrors.ErrBadRequest, paid-refunded, paid, purchaseID)
+		}
+	}
+	row := tx.QueryRow(`
+	UPDATE returns SET status = $1, refund_amount = COALESCE($2, refund_amount),
+	admin_comment = COALESCE($3, admin_comment), updated_at = CURRENT_TIMESTAMP
+	WHERE id = $4 AND status = $5
+	RETURNING *
+	`, to, refundAmount, adminComment, ID, from)
+	_, err = db.extractReturn(row)
+	if errors.Is(err, apperrors.ErrNotFound) {
+		return Return{}, fmt.Errorf("%w: return %d is not in status %s", apperrors.ErrBadRequest, ID, from)
+	}
+	if err != nil {
+		return Return{}, err
+	}
+	err = tx.Commit()
+	if err != nil {
+		return Return{}, db.wrapError(err)
+	}
+	return db.GetReturn(ID)
+}
+
+func (db *PostgresDB) AddReturnPhoto(photo ReturnPhoto) (ReturnPhoto, error) {
+	query := `
+	INSERT INTO return_photos (return_id, path, thumbnail_path)
+	VALUES ($1, $2, $3)
+	RETURNING *
+	`
+	p := ReturnPhoto{}
+	err := db.QueryRow(query, photo.ReturnID, photo.Path, photo.ThumbnailPath).
+		Scan(&p.ID, &p.ReturnID, &p.Path, &p.ThumbnailPath, &p.CreatedAt)
+	return p, db.wrapError(err)
+}
+
+// attachReturnPhotos loads photos of all given returns with a single query
+func (db *PostgresDB) attachReturnPhotos(returns []Return) error {
+	if len(returns) == 0 {
+		return nil
+	}
+	IDs := make([]int64, len(returns))
+	byID := make(map[int]*Return, len(returns))
+	for i := range returns {
+		IDs[i] = int64(returns[i].ID)
+		byID[returns[i].ID] = &returns[i]
+	}
+	rows, err := db.Query("SELECT * FROM return_photos WHERE return_id = ANY($1) ORDER BY id", pq.Array(IDs))
+	if err != nil {
+		return db.wrapError(err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		p := ReturnPhoto{}
+		err := rows.Scan(&p.ID, &p.ReturnID, &p.Path, &p.ThumbnailPath, &p.CreatedAt)
+		if err != nil {
+			return db.wrapError(err)
+		}
+		ret := byID[p.ReturnID]
+		ret.Photos = append(ret.Photos, p)
+	}
+	return nil
+}
+
+func (db *PostgresDB) extractReturn(row *sql.Row) (Return, error) {
+	r := Return{}
+	err := row.Scan(&r.ID, &r.PurchaseID, &r.UserID, &r.Quantity, &r.Reason, &r.Comment, &r.Status,
+		&r.RefundAmount, &r.AdminComment, &r.CreatedAt, &r.UpdatedAt)
+	return r, db.wrapError(err)
+}
+
+func (db *PostgresDB) extractReturns(rows *sql.Rows) ([]Return, error) {
+	defer rows.Close()
+	returns := []Return{}
+	for rows.Next() {
+		r := Return{}
+		err := rows.Scan(&r.ID, &r.PurchaseID, &r.UserID, &r.Quantity, &r.Reason, &r.Comment, &r.Status,
+			&r.RefundAmount, &r.AdminComment, &r.CreatedAt, &r.UpdatedAt)
+		if err != nil {
+			return nil, db.wrapError(err)
+		}
+		returns = append(returns, r)
+	}
+	err := db.attachReturnPhotos(returns)
+	if err != nil {
+		return nil, err
+	}
+	return returns, nil
+}
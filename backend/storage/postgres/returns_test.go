@@ -0,0 +1,52 @@
+package postgres
+
+import (
+	"testing"
+
+	"github.com/sfu-teamproject/smartbuy/backend/apperrors"
+	"github.com/sfu-teamproject/smartbuy/backend/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReturns(t *testing.T) {
+	db, err := NewPostgresDB(true)
+	assert.NoError(t, err, "postgres db creating failed")
+	purchase, err := db.CreatePurchase(models.Purchase{UserID: 3, SmartphoneID: 2, Quantity: 2, Price: 100,
+		Paid: 180})
+	assert.NoError(t, err, "creating purchase failed")
+	ret := models.Return{PurchaseID: purchase.ID, UserID: 3, Quantity: 1, Reason: models.ReturnReasonDefective}
+	t.Run("create return", func(t *testing.T) {
+		newReturn, err := db.CreateReturn(ret)
+		assert.NoError(t, err, "creating return failed")
+		assert.NotEmpty(t, newReturn.ID, "return id is 0")
+		assert.Equal(t, models.ReturnRequested, newReturn.Status, "status is different")
+		ret.ID = newReturn.ID
+		ret.Quantity = 2
+		_, err = db.CreateReturn(ret)
+		assert.ErrorIs(t, err, apperrors.ErrBadRequest, "returned more than purchased")
+	})
+	t.Run("set return status", func(t *testing.T) {
+		comment := "ok"
+		updated, err := db.SetReturnStatus(ret.ID, models.ReturnRequested, models.ReturnApproved, nil, &comment)
+		assert.NoError(t, err, "setting status failed")
+		assert.Equal(t, models.ReturnApproved, updated.Status, "status is different")
+		assert.Equal(t, comment, *updated.AdminComment, "comment is different")
+		_, err = db.SetReturnStatus(ret.ID, models.ReturnRequested, models.ReturnRejected, nil, nil)
+		assert.ErrorIs(t, err, apperrors.ErrBadRequest, "status changed twice")
+	})
+	t.Run("refund", func(t *testing.T) {
+		other, err := db.CreateReturn(models.Return{PurchaseID: purchase.ID, UserID: 3, Quantity: 1,
+			Reason: models.ReturnReasonDefective})
+		assert.NoError(t, err, "creating return failed")
+		amount := 100
+		_, err = db.SetReturnStatus(ret.ID, models.ReturnApproved, models.ReturnRefunded, &amount, nil)
+		assert.NoError(t, err, "refunding failed")
+		_, err = db.SetReturnStatus(other.ID, models.ReturnRequested, models.ReturnRefunded, &amount, nil)
+		assert.ErrorIs(t, err, apperrors.ErrBadRequest, "refunded more than paid")
+	})
+	t.Run("get returns", func(t *testing.T) {
+		returns, err := db.GetUserReturns(3)
+		assert.NoError(t, err, "getting returns failed")
+		assert.NotEmpty(t, returns, "return slice is empty")
+	})
+}
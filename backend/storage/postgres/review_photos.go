@@ -0,0 +1,92 @@
+package postgres
+
+import (
+	"database/sql"
+
+	"github.com/lib/pq"
+	"github.com/sfu-teamproject/smartbuy/backend/models"
+)
+
+type ReviewPhoto = models.ReviewPhoto
+
+func (db *PostgresDB) GetReviewPhotos(reviewID int) ([]ReviewPhoto, error) {
+	rows, err := db.Query("SELECT * FROM review_photos WHERE review_id = $1 ORDER BY id", reviewID)
+	if err != nil {
+		return nil, db.wrapError(err)
+	}
+	return db.extractReviewPhotos(rows)
+}
+
+func (db *PostgresDB) GetUserReviewPhotos(userID int) ([]ReviewPhoto, error) {
+	rows, err := db.Query(`
+	SELECT review_photos.* FROM review_photos
+	JOIN reviews ON review_id = reviews.id
+	WHERE reviews.user_id = $1
+	ORDER BY review_photos.id
+	`, userID)
+	if err != nil {
+		return nil, db.wrapError(err)
+	}
+	return db.extractReviewPhotos(rows)
+}
+
+func (db *PostgresDB) AddReviewPhoto(photo ReviewPhoto) (ReviewPhoto, error) {
+	query := `
+	INSERT INTO review_photos (review_id, path, thumbnail_path)
+	VALUES ($1, $2, $3)
+	RETURNING *
+	`
+	row := db.QueryRow(query, photo.ReviewID, photo.Path, photo.ThumbnailPath)
+	return db.extractReviewPhoto(row)
+}
+
+func (db *PostgresDB) DeleteReviewPhoto(ID int) (ReviewPhoto, error) {
+	row := db.QueryRow("DELETE FROM review_photos WHERE id = $1 RETURNING *", ID)
+	return db.extractReviewPhoto(row)
+}
+
+// attachPhotos loads photos of all given reviews with a single query
+func (db *PostgresDB) attachPhotos(reviews []Review) error {
+	if len(reviews) == 0 {
+		return nil
+	}
+	IDs := make([]int64, len(reviews))
+	byID := make(map[int]*Review, len(reviews))
+	for i := range reviews {
+		IDs[i] = int64(reviews[i].ID)
+		byID[reviews[i].ID] = &reviews[i]
+	}
+	rows, err := db.Query("SELECT * FROM review_photos WHERE review_id = ANY($1) ORDER BY id", pq.Array(IDs))
+	if err != nil {
+		return db.wrapError(err)
+	}
+	photos, err := db.extractReviewPhotos(rows)
+	if err != nil {
+		return err
+	}
+	for _, photo := range photos {
+		review := byID[photo.ReviewID]
+		review.Photos = append(review.Photos, photo)
+	}
+	return nil
+}
+
+func (db *PostgresDB) extractReviewPhoto(row *sql.Row) (ReviewPhoto, error) {
+	p := ReviewPhoto{}
+	err := row.Scan(&p.ID, &p.ReviewID, &p.Path, &p.ThumbnailPath, &p.CreatedAt)
+	return p, db.wrapError(err)
+}
+
+func (db *PostgresDB) extractReviewPhotos(rows *sql.Rows) ([]ReviewPhoto, error) {
+	defer rows.Close()
+	photos := []ReviewPhoto{}
+	for rows.Next() {
+		p := ReviewPhoto{}
+		err := rows.Scan(&p.ID, &p.ReviewID, &p.Path, &p.ThumbnailPath, &p.CreatedAt)
+		if err != nil {
+			return nil, db.wrapError(err)
+		}
+		photos = append(photos, p)
+	}
+	return photos, nil
+}
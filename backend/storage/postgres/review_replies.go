@@ -0,0 +1,66 @@
+package postgres
+
+import (
+	"database/sql"
+
+	"github.com/lib/pq"
+	"github.com/sfu-teamproject/smartbuy/backend/models"
+)
+
+type ReviewReply = models.ReviewReply
+
+func (db *PostgresDB) GetReviewReply(reviewID int) (ReviewReply, error) {
+	row := db.QueryRow("SELECT * FROM review_replies WHERE review_id = $1", reviewID)
+	return db.extractReviewReply(row)
+}
+
+// SetReviewReply creates a reply to a review or replaces the text of the existing one
+func (db *PostgresDB) SetReviewReply(reply ReviewReply) (ReviewReply, error) {
+	query := `
+	INSERT INTO review_replies (review_id, user_id, text)
+	VALUES ($1, $2, $3)
+	ON CONFLICT (review_id) DO UPDATE
+	SET user_id = EXCLUDED.user_id, text = EXCLUDED.text, updated_at = CURRENT_TIMESTAMP
+	RETURNING *
+	`
+	row := db.QueryRow(query, reply.ReviewID, reply.UserID, reply.Text)
+	return db.extractReviewReply(row)
+}
+
+func (db *PostgresDB) DeleteReviewReply(reviewID int) (ReviewReply, error) {
+	row := db.QueryRow("DELETE FROM review_replies WHERE review_id = $1 RETURNING *", reviewID)
+	return db.extractReviewReply(row)
+}
+
+// attachReplies loads replies of all given reviews with a single query
+func (db *PostgresDB) attachReplies(reviews []Review) error {
+	if len(reviews) == 0 {
+		return nil
+	}
+	IDs := make([]int64, len(reviews))
+	byID := make(map[int]*Review, len(reviews))
+	for i := range reviews {
+		IDs[i] = int64(reviews[i].ID)
+		byID[reviews[i].ID] = &reviews[i]
+	}
+	rows, err := db.Query("SELECT * FROM review_replies WHERE review_id = ANY($1)", pq.Array(IDs))
+	if err != nil {
+		return db.wrapError(err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		reply := ReviewReply{}
+		err := rows.Scan(&reply.ID, &reply.ReviewID, &reply.UserID, &reply.Text, &reply.CreatedAt, &reply.UpdatedAt)
+		if err != nil {
+			return db.wrapError(err)
+		}
+		byID[reply.ReviewID].Reply = &reply
+	}
+	return nil
+}
+
+func (db *PostgresDB) extractReviewReply(row *sql.Row) (ReviewReply, error) {
+	reply := ReviewReply{}
+	err := row.Scan(&reply.ID, &reply.ReviewID, &reply.UserID, &reply.Text, &reply.CreatedAt, &reply.UpdatedAt)
+	return reply, db.wrapError(err)
+}
@@ -0,0 +1,111 @@
+package postgres
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/lib/pq"
+	"github.com/sfu-teamproject/smartbuy/backend/models"
+)
+
+type ReviewReport = models.ReviewReport
+
+func (db *PostgresDB) CreateReviewReport(report ReviewReport) (ReviewReport, error) {
+	query := `
+	INSERT INTO review_reports (review_id, user_id, reason, comment)
+	VALUES ($1, $2, $3, $4)
+	RETURNING *
+	`
+	row := db.QueryRow(query, report.ReviewID, report.UserID, report.Reason, report.Comment)
+	return db.extractReviewReport(row)
+}
+
+// HideReportedReview hides a review if it has at least threshold reports,
+// returns true if the review was hidden by this call
+func (db *PostgresDB) HideReportedReview(reviewID, threshold int) (bool, error) {
+	query := `
+	UPDATE reviews SET hidden = TRUE
+	WHERE id = $1 AND NOT hidden
+	AND (SELECT COUNT(*) FROM review_reports WHERE review_id = $1) >= $2
+	RETURNING id
+	`
+	var ID int
+	err := db.QueryRow(query, reviewID, threshold).Scan(&ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	return err == nil, db.wrapError(err)
+}
+
+// GetReportedReviews returns hidden reviews and reviews with reports,
+// the most reported ones first
+func (db *PostgresDB) GetReportedReviews() ([]models.ReportedReview, error) {
+	rows, err := db.Query(selectReviews + `
+	LEFT JOIN (
+		SELECT review_id, COUNT(*) AS reports_count FROM review_reports GROUP BY review_id
+	) AS counts ON counts.review_id = reviews.id
+	WHERE reviews.hidden OR counts.reports_count > 0
+	ORDER BY reviews.hidden DESC, counts.reports_count DESC NULLS LAST, reviews.id
+	`)
+	if err != nil {
+		return nil, db.wrapError(err)
+	}
+	reviews, err := db.extractReviews(rows)
+	if err != nil {
+		return nil, err
+	}
+	reported := make([]models.ReportedReview, len(reviews))
+	IDs := make([]int64, len(reviews))
+	index := make(map[int]int, len(reviews))
+	for i, review := range reviews {
+		reported[i] = models.ReportedReview{Review: review, Reports: []ReviewReport{}}
+		IDs[i] = int64(review.ID)
+		index[review.ID] = i
+	}
+	rows, err = db.Query("SELECT * FROM review_reports WHERE review_id = ANY($1) ORDER BY id", pq.Array(IDs))
+	if err != nil {
+		return nil, db.wrapError(err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		report := ReviewReport{}
+		err := rows.Scan(&report.ID, &report.ReviewID, &report.UserID, &report.Reason,
+			&report.Comment, &report.CreatedAt)
+		if err != nil {
+			return nil, db.wrapError(err)
+		}
+		i := index[report.ReviewID]
+		reported[i].Reports = append(reported[i].Reports, report)
+	}
+	return reported, nil
+}
+
+// ModerateReview sets visibility of a review and resolves all its reports
+func (db *PostgresDB) ModerateReview(reviewID int, hidden bool) (Review, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return Review{}, db.wrapError(err)
+	}
+	defer tx.Rollback()
+	var ID int
+	err = tx.QueryRow("UPDATE reviews SET hidden = $1 WHERE id = $2 RETURNING id", hidden, reviewID).Scan(&ID)
+	if err != nil {
+		return Review{}, db.wrapError(err)
+	}
+	_, err = tx.Exec("DELETE FROM review_reports WHERE review_id = $1", reviewID)
+	if err != nil {
+		return Review{}, db.wrapError(err)
+	}
+	err = tx.Commit()
+	if err != nil {
+		return Review{}, db.wrapError(err)
+	}
+	return db.GetReview(reviewID)
+}
+
+func (db *PostgresDB) extractReviewReport(row *sql.Row) (ReviewReport, error) {
+	report := ReviewReport{}
+	err := row.Scan(&report.ID, &report.ReviewID, &report.UserID, &report.Reason,
+		&report.Comment, &report.CreatedAt)
+	return report, db.wrapError(err)
+}
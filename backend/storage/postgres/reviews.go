@@ -3,31 +3,45 @@ package postgres
 import (
 	"database/sql"
 
+	"github.com/lib/pq"
 	"github.com/sfu-teamproject/smartbuy/backend/models"
 )
 
 type Review = models.Review
 
-func (db *PostgresDB) GetReview(id int) (Review, error) {
-	row := db.QueryRow(`
-	SELECT reviews.id, smartphone_id, user_id, users.name, rating, comment, reviews.created_at, reviews.updated_at
+// selectReviews selects reviews together with names of their authors
+const selectReviews = `
+	SELECT reviews.id, smartphone_id, user_id, users.name, rating, comment,
+	reviews.created_at, reviews.updated_at, reviews.verified, reviews.hidden,
+	reviews.filter_action, reviews.filter_reasons
 	FROM reviews
 	JOIN users on user_id = users.id
-	WHERE reviews.id = $1
-	`, id)
+	`
+
+func (db *PostgresDB) GetReview(id int) (Review, error) {
+	row := db.QueryRow(selectReviews+"WHERE reviews.id = $1", id)
 	review := Review{}
 	err := row.Scan(&review.ID, &review.SmartphoneID, &review.UserID, &review.UserName,
-		&review.Rating, &review.Comment, &review.CreatedAt, &review.UpdatedAt)
-	return review, db.wrapError(err)
+		&review.Rating, &review.Comment, &review.CreatedAt, &review.UpdatedAt, &review.Verified, &review.Hidden,
+		&review.FilterAction, pq.Array(&review.FilterReasons))
+	if err != nil {
+		return review, db.wrapError(err)
+	}
+	reviews := []Review{review}
+	err = db.attachDetails(reviews)
+	return reviews[0], err
 }
 
 func (db *PostgresDB) GetReviews(smartphoneID int) ([]Review, error) {
-	rows, err := db.Query(`
-	SELECT reviews.id, smartphone_id, user_id, users.name, rating, comment, reviews.created_at, reviews.updated_at
-	FROM reviews
-	JOIN users on user_id = users.id
-	WHERE smartphone_id = $1;
-	`, smartphoneID)
+	rows, err := db.Query(selectReviews+"WHERE smartphone_id = $1 AND NOT hidden", smartphoneID)
+	if err != nil {
+		return nil, db.wrapError(err)
+	}
+	return db.extractReviews(rows)
+}
+
+func (db *PostgresDB) GetVerifiedReviews(smartphoneID int) ([]Review, error) {
+	rows, err := db.Query(selectReviews+"WHERE smartphone_id = $1 AND verified AND NOT hidden", smartphoneID)
 	if err != nil {
 		return nil, db.wrapError(err)
 	}
@@ -42,28 +56,46 @@ func (db *PostgresDB) DeleteReview(ID int) (Review, error) {
 func (db *PostgresDB) UpdateReview(review Review) (Review, error) {
 	query := `
 	UPDATE reviews
-	SET rating = $1, comment = $2, updated_at = CURRENT_TIMESTAMP
-	WHERE id = $3
+	SET rating = $1, comment = $2, updated_at = CURRENT_TIMESTAMP,
+	hidden = hidden OR $3, filter_action = $4, filter_reasons = COALESCE($5::TEXT[], '{}')
+	WHERE id = $6
 	RETURNING *
 	`
-	row := db.QueryRow(query, review.Rating, review.Comment, review.ID)
+	row := db.QueryRow(query, review.Rating, review.Comment, review.Hidden,
+		review.FilterAction, pq.Array(review.FilterReasons), review.ID)
 	return db.extractReview(row)
 }
 
 func (db *PostgresDB) CreateReview(review Review) (Review, error) {
 	query := `
-	INSERT INTO reviews (smartphone_id, user_id, rating, comment)
-	VALUES ($1, $2, $3, $4)
+	INSERT INTO reviews (smartphone_id, user_id, rating, comment, verified,
+	hidden, filter_action, filter_reasons)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, COALESCE($8::TEXT[], '{}'))
 	RETURNING *
 	`
-	row := db.QueryRow(query, review.SmartphoneID, review.UserID, review.Rating, review.Comment)
+	row := db.QueryRow(query, review.SmartphoneID, review.UserID, review.Rating, review.Comment, review.Verified,
+		review.Hidden, review.FilterAction, pq.Array(review.FilterReasons))
 	return db.extractReview(row)
 }
 
+// HasDuplicateReview reports whether a user other than userID has posted the same comment
+func (db *PostgresDB) HasDuplicateReview(comment string, userID int) (bool, error) {
+	query := `
+	SELECT EXISTS(
+		SELECT 1 FROM reviews
+		WHERE user_id <> $1 AND LOWER(BTRIM(comment)) = LOWER(BTRIM($2))
+	)
+	`
+	var exists bool
+	err := db.QueryRow(query, userID, comment).Scan(&exists)
+	return exists, db.wrapError(err)
+}
+
 func (db *PostgresDB) extractReview(row *sql.Row) (Review, error) {
 	review := Review{}
 	err := row.Scan(&review.ID, &review.SmartphoneID, &review.UserID,
-		&review.Rating, &review.Comment, &review.CreatedAt, &review.UpdatedAt)
+		&review.Rating, &review.Comment, &review.CreatedAt, &review.UpdatedAt, &review.Verified, &review.Hidden,
+		&review.FilterAction, pq.Array(&review.FilterReasons))
 	return review, db.wrapError(err)
 }
 
@@ -73,11 +105,25 @@ func (db *PostgresDB) extractReviews(rows *sql.Rows) ([]Review, error) {
 	for rows.Next() {
 		review := Review{}
 		err := rows.Scan(&review.ID, &review.SmartphoneID, &review.UserID, &review.UserName,
-			&review.Rating, &review.Comment, &review.CreatedAt, &review.UpdatedAt)
+			&review.Rating, &review.Comment, &review.CreatedAt, &review.UpdatedAt, &review.Verified, &review.Hidden,
+			&review.FilterAction, pq.Array(&review.FilterReasons))
 		if err != nil {
 			return nil, db.wrapError(err)
 		}
 		reviews = append(reviews, review)
 	}
+	err := db.attachDetails(reviews)
+	if err != nil {
+		return nil, err
+	}
 	return reviews, nil
 }
+
+// attachDetails loads photos and replies of reviews
+func (db *PostgresDB) attachDetails(reviews []Review) error {
+	err := db.attachPhotos(reviews)
+	if err != nil {
+		return err
+	}
+	return db.attachReplies(reviews)
+}
@@ -0,0 +1,29 @@
+package postgres
+
+import (
+	"testing"
+
+	"github.com/sfu-teamproject/smartbuy/backend/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReviews(t *testing.T) {
+	db, err := NewPostgresDB(true)
+	assert.NoError(t, err, "postgres db creating failed")
+	comment := "Nice phone"
+	review := models.Review{SmartphoneID: 2, UserID: 2, Rating: 5, Comment: &comment, FilterAction: "allow"}
+	t.Run("create review with clean comment", func(t *testing.T) {
+		created, err := db.CreateReview(review)
+		assert.NoError(t, err, "creating review failed")
+		assert.Empty(t, created.FilterReasons, "clean comment has filter reasons")
+		review = created
+	})
+	t.Run("update review with clean comment", func(t *testing.T) {
+		review.FilterReasons = nil
+		review.Rating = 4
+		updated, err := db.UpdateReview(review)
+		assert.NoError(t, err, "updating review failed")
+		assert.Equal(t, 4, updated.Rating, "rating is not updated")
+		assert.Empty(t, updated.FilterReasons, "clean comment has filter reasons")
+	})
+}
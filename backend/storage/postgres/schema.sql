@@ -14,7 +14,11 @@ CREATE TABLE smartphones (
     ratings_sum INTEGER DEFAULT 0,
     ratings_count INTEGER DEFAULT 0,
     image_path TEXT NOT NULL,
-    description TEXT NOT NULL
+    description TEXT NOT NULL,
+    weight INTEGER NOT NULL DEFAULT 200,
+    CHECK(weight >= 0),
+    -- product category, VAT rates are configured per category
+    category TEXT NOT NULL DEFAULT 'smartphone'
 );
 
 DROP TABLE IF EXISTS users cascade;
@@ -28,15 +32,22 @@ CREATE TABLE users (
     CHECK(LENGTH(password) >= 5),
     role VARCHAR(10) DEFAULT 'user',
     CHECK (role IN ('admin', 'user')),
-    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    cart_reminders BOOLEAN NOT NULL DEFAULT TRUE,
+    -- access tokens issued before are rejected
+    tokens_revoked_at TIMESTAMP WITH TIME ZONE
 );
 CREATE UNIQUE INDEX ON users(LOWER(email));
 
 DROP TABLE IF EXISTS tmp_passwords;
 CREATE TABLE tmp_passwords (
     email TEXT NOT NULL REFERENCES users(email) ON DELETE CASCADE,
-    password TEXT NOT NULL,
-    expires_at TIMESTAMP WITH TIME ZONE NOT NULL
+    -- HMAC-SHA256 of the password, the password itself is only sent by email
+    password_hash TEXT NOT NULL,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    purpose TEXT NOT NULL DEFAULT 'signup',
+    attempts INT NOT NULL DEFAULT 0,
+    UNIQUE (email, purpose)
 );
 CREATE INDEX ON tmp_passwords(LOWER(email));
 
@@ -49,10 +60,48 @@ CREATE TABLE reviews (
     CHECK (rating >= 1 and rating <= 5),
     comment TEXT,
     created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
-    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    verified BOOLEAN NOT NULL DEFAULT FALSE,
+    hidden BOOLEAN NOT NULL DEFAULT FALSE,
+    filter_action VARCHAR(10) NOT NULL DEFAULT 'allow',
+    CHECK (filter_action IN ('allow', 'mask', 'moderate')),
+    filter_reasons TEXT[] NOT NULL DEFAULT '{}'
 );
 CREATE UNIQUE INDEX ON reviews (smartphone_id, user_id);
 
+DROP TABLE IF EXISTS review_photos cascade;
+CREATE TABLE review_photos (
+    id SERIAL PRIMARY KEY,
+    review_id INT NOT NULL REFERENCES reviews ON DELETE CASCADE,
+    path TEXT NOT NULL,
+    thumbnail_path TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX ON review_photos(review_id);
+
+DROP TABLE IF EXISTS review_reports cascade;
+CREATE TABLE review_reports (
+    id SERIAL PRIMARY KEY,
+    review_id INT NOT NULL REFERENCES reviews ON DELETE CASCADE,
+    user_id INT NOT NULL REFERENCES users ON DELETE CASCADE,
+    reason VARCHAR(20) NOT NULL,
+    CHECK (reason IN ('spam', 'offensive', 'other')),
+    comment TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+CREATE UNIQUE INDEX ON review_reports(review_id, user_id);
+
+DROP TABLE IF EXISTS review_replies cascade;
+CREATE TABLE review_replies (
+    id SERIAL PRIMARY KEY,
+    review_id INT NOT NULL UNIQUE REFERENCES reviews ON DELETE CASCADE,
+    user_id INT REFERENCES users ON DELETE SET NULL,
+    text TEXT NOT NULL,
+    CHECK(LENGTH(text) > 0),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
 CREATE OR REPLACE FUNCTION update_smartphone_rating()
 RETURNS TRIGGER AS $$
 BEGIN
@@ -64,7 +113,12 @@ BEGIN
 
     ELSIF TG_OP = 'UPDATE' THEN
         UPDATE smartphones
-        SET ratings_sum = ratings_sum - OLD.rating + NEW.rating
+        SET ratings_sum = ratings_sum - OLD.rating,
+            ratings_count = ratings_count - 1
+        WHERE id = OLD.smartphone_id;
+        UPDATE smartphones
+        SET ratings_sum = ratings_sum + NEW.rating,
+            ratings_count = ratings_count + 1
         WHERE id = NEW.smartphone_id;
 
     ELSIF TG_OP = 'DELETE' THEN
@@ -78,16 +132,63 @@ END;
 $$ LANGUAGE plpgsql;
 
 CREATE TRIGGER trigger_update_smarthpone_rating
-AFTER INSERT OR UPDATE OF rating OR DELETE ON reviews
+AFTER INSERT OR UPDATE OF rating, smartphone_id OR DELETE ON reviews
 FOR EACH ROW
 EXECUTE FUNCTION update_smartphone_rating();
 
+DROP TABLE IF EXISTS addresses cascade;
+CREATE TABLE addresses (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users ON DELETE CASCADE,
+    recipient TEXT NOT NULL,
+    phone TEXT NOT NULL,
+    city TEXT NOT NULL,
+    street TEXT NOT NULL,
+    postal_code TEXT NOT NULL,
+    is_default BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+CREATE UNIQUE INDEX ON addresses(user_id) WHERE is_default;
+
+DROP TABLE IF EXISTS delivery_methods cascade;
+CREATE TABLE delivery_methods (
+    id SERIAL PRIMARY KEY,
+    name TEXT NOT NULL,
+    type VARCHAR(10) NOT NULL CHECK (type IN ('courier', 'pickup', 'post')),
+    base_cost INT NOT NULL CHECK (base_cost >= 0),
+    cost_per_kg INT NOT NULL DEFAULT 0 CHECK (cost_per_kg >= 0),
+    free_from INT CHECK (free_from >= 0),
+    max_weight INT CHECK (max_weight > 0),
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+DROP TABLE IF EXISTS promo_codes cascade;
+CREATE TABLE promo_codes (
+    id SERIAL PRIMARY KEY,
+    code TEXT NOT NULL,
+    type VARCHAR(10) NOT NULL,
+    CHECK (type IN ('percent', 'fixed')),
+    value INT NOT NULL CHECK (value > 0),
+    min_total INT NOT NULL DEFAULT 0 CHECK (min_total >= 0),
+    starts_at TIMESTAMP WITH TIME ZONE,
+    ends_at TIMESTAMP WITH TIME ZONE,
+    usage_limit INT CHECK (usage_limit > 0),
+    per_user_limit INT CHECK (per_user_limit > 0),
+    producers TEXT[] NOT NULL DEFAULT '{}',
+    smartphone_ids INT[] NOT NULL DEFAULT '{}',
+    used_count INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+CREATE UNIQUE INDEX ON promo_codes(UPPER(code));
+
 DROP TABLE IF EXISTS carts cascade;
 CREATE TABLE carts (
     id SERIAL PRIMARY KEY,
-    user_id INT NOT NULL REFERENCES users ON DELETE CASCADE,
+    user_id INT REFERENCES users ON DELETE CASCADE,
     created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
-    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    promo_code_id INT REFERENCES promo_codes ON DELETE SET NULL
 );
 CREATE UNIQUE INDEX ON carts(user_id);
 
@@ -96,10 +197,19 @@ CREATE TABLE cart_items (
     id SERIAL PRIMARY KEY,
     cart_id INT NOT NULL REFERENCES carts ON DELETE CASCADE,
     smartphone_id INT NOT NULL REFERENCES smartphones ON DELETE CASCADE,
-    quantity INT NOT NULL DEFAULT 1 CHECK (quantity > 0)
+    quantity INT NOT NULL DEFAULT 1 CHECK (quantity > 0),
+    added_price INT NOT NULL CHECK (added_price >= 0),
+    state VARCHAR(10) NOT NULL DEFAULT 'active' CHECK (state IN ('active', 'saved'))
 );
 CREATE UNIQUE INDEX ON cart_items(cart_id, smartphone_id);
 
+-- last abandoned cart reminder of a cart, a new one is sent only after the cart changes
+DROP TABLE IF EXISTS sent_cart_reminders cascade;
+CREATE TABLE sent_cart_reminders (
+    cart_id INT PRIMARY KEY REFERENCES carts ON DELETE CASCADE,
+    sent_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
 CREATE OR REPLACE FUNCTION create_cart_for_new_user()
 RETURNS TRIGGER AS $$
 BEGIN
@@ -133,4 +243,135 @@ $$ LANGUAGE plpgsql;
 CREATE TRIGGER trigger_update_cart_updated_at
 AFTER INSERT OR UPDATE OR DELETE ON cart_items
 FOR EACH ROW
-EXECUTE FUNCTION update_cart_updated_at();
\ No newline at end of file
+EXECUTE FUNCTION update_cart_updated_at();
+
+DROP TABLE IF EXISTS purchases cascade;
+CREATE TABLE purchases (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users ON DELETE CASCADE,
+    smartphone_id INT NOT NULL REFERENCES smartphones ON DELETE CASCADE,
+    quantity INT NOT NULL DEFAULT 1 CHECK (quantity > 0),
+    price INT NOT NULL CHECK (price >= 0),
+    purchased_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    order_id INT,
+    paid INT NOT NULL CHECK (paid >= 0),
+    refunded BOOLEAN NOT NULL DEFAULT false
+);
+CREATE INDEX ON purchases(user_id, smartphone_id);
+
+DROP TABLE IF EXISTS orders cascade;
+CREATE TABLE orders (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL DEFAULT 'created',
+    CHECK (status IN ('created', 'paid', 'shipped', 'delivered', 'cancelled', 'refunded')),
+    total INT NOT NULL CHECK (total >= 0),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    promo_code_id INT REFERENCES promo_codes ON DELETE SET NULL,
+    discount INT NOT NULL DEFAULT 0 CHECK (discount >= 0),
+    tax JSONB
+);
+CREATE INDEX ON orders(user_id);
+
+DROP TABLE IF EXISTS order_items cascade;
+CREATE TABLE order_items (
+    id SERIAL PRIMARY KEY,
+    order_id INT NOT NULL REFERENCES orders ON DELETE CASCADE,
+    smartphone_id INT REFERENCES smartphones ON DELETE SET NULL,
+    model TEXT NOT NULL,
+    price INT NOT NULL CHECK (price >= 0),
+    quantity INT NOT NULL CHECK (quantity > 0),
+    producer TEXT NOT NULL DEFAULT '',
+    paid INT NOT NULL CHECK (paid >= 0)
+);
+CREATE INDEX ON order_items(order_id);
+ALTER TABLE purchases ADD FOREIGN KEY (order_id) REFERENCES orders ON DELETE SET NULL;
+
+DROP TABLE IF EXISTS order_status_history cascade;
+CREATE TABLE order_status_history (
+    id SERIAL PRIMARY KEY,
+    order_id INT NOT NULL REFERENCES orders ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL,
+    actor_id INT REFERENCES users ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX ON order_status_history(order_id);
+
+DROP TABLE IF EXISTS payments cascade;
+CREATE TABLE payments (
+    id SERIAL PRIMARY KEY,
+    order_id INT NOT NULL REFERENCES orders ON DELETE CASCADE,
+    intent_id TEXT NOT NULL,
+    event_id TEXT NOT NULL UNIQUE,
+    status VARCHAR(20) NOT NULL,
+    CHECK (status IN ('succeeded', 'failed', 'refunded')),
+    amount INT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX ON payments(order_id);
+
+DROP TABLE IF EXISTS returns cascade;
+CREATE TABLE returns (
+    id SERIAL PRIMARY KEY,
+    purchase_id INT NOT NULL REFERENCES purchases ON DELETE CASCADE,
+    user_id INT NOT NULL REFERENCES users ON DELETE CASCADE,
+    quantity INT NOT NULL CHECK (quantity > 0),
+    reason VARCHAR(20) NOT NULL,
+    CHECK (reason IN ('defective', 'not_as_described', 'wrong_item', 'changed_mind', 'other')),
+    comment TEXT,
+    status VARCHAR(20) NOT NULL DEFAULT 'requested',
+    CHECK (status IN ('requested', 'approved', 'rejected', 'received', 'refunded')),
+    refund_amount INT CHECK (refund_amount >= 0),
+    admin_comment TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX ON returns(user_id);
+CREATE INDEX ON returns(purchase_id);
+
+DROP TABLE IF EXISTS return_photos cascade;
+CREATE TABLE return_photos (
+    id SERIAL PRIMARY KEY,
+    return_id INT NOT NULL REFERENCES returns ON DELETE CASCADE,
+    path TEXT NOT NULL,
+    thumbnail_path TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX ON return_photos(return_id);
+
+DROP TABLE IF EXISTS idempotency_keys cascade;
+CREATE TABLE idempotency_keys (
+    scope TEXT NOT NULL,
+    key TEXT NOT NULL,
+    request_hash TEXT NOT NULL,
+    -- 0 while the first request is being handled
+    status_code INT NOT NULL DEFAULT 0,
+    headers JSONB NOT NULL DEFAULT '{}',
+    body BYTEA NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (scope, key)
+);
+CREATE INDEX ON idempotency_keys(created_at);
+
+DROP TABLE IF EXISTS refresh_tokens cascade;
+CREATE TABLE refresh_tokens (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users ON DELETE CASCADE,
+    token_hash TEXT NOT NULL UNIQUE,
+    -- tokens rotated from the same login
+    family_id TEXT NOT NULL,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    used_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX ON refresh_tokens(user_id);
+CREATE INDEX ON refresh_tokens(family_id);
+
+DROP TABLE IF EXISTS rate_limits cascade;
+CREATE TABLE rate_limits (
+    key TEXT PRIMARY KEY,
+    count INT NOT NULL,
+    reset_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+CREATE INDEX ON rate_limits(reset_at);
@@ -12,7 +12,7 @@ import (
 type Smartphone = models.Smartphone
 
 func (db *PostgresDB) GetSmartphones() ([]Smartphone, error) {
-	rows, err := db.Query("SELECT * FROM smartphones order by price")
+	rows, err := db.Query("SELECT *, " + catalogMean + " FROM smartphones order by price")
 	if err != nil {
 		return nil, db.wrapError(err)
 	}
@@ -27,7 +27,8 @@ func (db *PostgresDB) GetSmartphonesByIDs(IDs []int) ([]Smartphone, error) {
 		}
 		IDsStr.WriteString(strconv.Itoa(ID))
 	}
-	query := fmt.Sprintf("SELECT * FROM smartphones WHERE id IN (%s) order by price", IDsStr.String())
+	query := fmt.Sprintf("SELECT *, %s FROM smartphones WHERE id IN (%s) order by price", catalogMean,
+		IDsStr.String())
 	rows, err := db.Query(query)
 	if err != nil {
 		return nil, db.wrapError(err)
@@ -36,12 +37,12 @@ func (db *PostgresDB) GetSmartphonesByIDs(IDs []int) ([]Smartphone, error) {
 }
 
 func (db *PostgresDB) GetSmartphone(id int) (Smartphone, error) {
-	row := db.QueryRow("SELECT * FROM smartphones WHERE id = $1", id)
+	row := db.QueryRow("SELECT *, "+catalogMean+" FROM smartphones WHERE id = $1", id)
 	return db.extractSmartphone(row)
 }
 
 func (db *PostgresDB) DeleteSmartphone(id int) (Smartphone, error) {
-	row := db.QueryRow("DELETE FROM smartphones where id = $1 returning *", id)
+	row := db.QueryRow("DELETE FROM smartphones where id = $1 returning *, "+catalogMean, id)
 	return db.extractSmartphone(row)
 }
 
@@ -49,32 +50,68 @@ func (db *PostgresDB) UpdateSmartphone(sm Smartphone) (Smartphone, error) {
 	query := `
 	UPDATE smartphones
 	SET model = $1, producer = $2, memory = $3, ram = $4, display_size = $5,
-	ratings_sum = $6, ratings_count = $7, price = $8, image_path = $9, description = $10
-	WHERE id = $11
-	RETURNING *
+	ratings_sum = $6, ratings_count = $7, price = $8, image_path = $9, description = $10, weight = $11,
+	category = COALESCE(NULLIF($12, ''), category)
+	WHERE id = $13
+	RETURNING *, ` + catalogMean + `
 	`
 	row := db.QueryRow(query, sm.Model, sm.Producer, sm.Memory, sm.Ram, sm.DisplaySize,
-		sm.RatingsSum, sm.RatingsCount, sm.Price, sm.ImagePath, sm.Description, sm.ID)
+		sm.RatingsSum, sm.RatingsCount, sm.Price, sm.ImagePath, sm.Description, sm.Weight, sm.Category, sm.ID)
 	return db.extractSmartphone(row)
 }
 
 func (db *PostgresDB) CreateSmartphone(sm Smartphone) (Smartphone, error) {
 	query := `
 	INSERT INTO smartphones (model, producer, memory, ram, display_size,
-	ratings_sum, ratings_count, price, image_path, description)
-	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
-	RETURNING *
+	ratings_sum, ratings_count, price, image_path, description, weight, category)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, COALESCE(NULLIF($12, ''), 'smartphone'))
+	RETURNING *, ` + catalogMean + `
 	`
 	row := db.QueryRow(query, sm.Model, sm.Producer, sm.Memory, sm.Ram, sm.DisplaySize,
-		sm.RatingsSum, sm.RatingsCount, sm.Price, sm.ImagePath, sm.Description)
+		sm.RatingsSum, sm.RatingsCount, sm.Price, sm.ImagePath, sm.Description, sm.Weight, sm.Category)
 	return db.extractSmartphone(row)
 }
 
+// ReconcileRatings recomputes ratings_sum and ratings_count of all smartphones
+// from their reviews, returns the smartphones whose values have drifted
+func (db *PostgresDB) ReconcileRatings() ([]Smartphone, error) {
+	query := `
+	UPDATE smartphones
+	SET ratings_sum = actual.ratings_sum, ratings_count = actual.ratings_count
+	FROM (
+		SELECT smartphones.id, COALESCE(SUM(reviews.rating), 0) AS ratings_sum,
+		COUNT(reviews.id) AS ratings_count
+		FROM smartphones LEFT JOIN reviews ON reviews.smartphone_id = smartphones.id
+		GROUP BY smartphones.id
+	) AS actual
+	WHERE smartphones.id = actual.id
+	AND (smartphones.ratings_sum IS DISTINCT FROM actual.ratings_sum
+	OR smartphones.ratings_count IS DISTINCT FROM actual.ratings_count)
+	RETURNING smartphones.*, ` + catalogMean + `
+	`
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, db.wrapError(err)
+	}
+	return db.extractSmartphones(rows)
+}
+
+// catalogMean selects the average rating over all reviews of all smartphones
+// along with every smartphone, so reading smartphones takes a single query.
+// Statements that change smartphones return the mean from before the change
+const catalogMean = `(SELECT COALESCE(SUM(ratings_sum)::FLOAT / NULLIF(SUM(ratings_count), 0), 0) FROM smartphones)`
+
 func (db *PostgresDB) extractSmartphone(row *sql.Row) (Smartphone, error) {
 	sm := Smartphone{}
+	var mean float64
 	err := row.Scan(&sm.ID, &sm.Model, &sm.Producer, &sm.Memory, &sm.Ram, &sm.DisplaySize,
-		&sm.Price, &sm.RatingsSum, &sm.RatingsCount, &sm.ImagePath, &sm.Description)
-	return sm, db.wrapError(err)
+		&sm.Price, &sm.RatingsSum, &sm.RatingsCount, &sm.ImagePath, &sm.Description, &sm.Weight,
+		&sm.Category, &mean)
+	if err != nil {
+		return sm, db.wrapError(err)
+	}
+	sm.SetRating(mean)
+	return sm, nil
 }
 
 func (db *PostgresDB) extractSmartphones(rows *sql.Rows) ([]Smartphone, error) {
@@ -82,11 +119,14 @@ func (db *PostgresDB) extractSmartphones(rows *sql.Rows) ([]Smartphone, error) {
 	smartphones := []Smartphone{}
 	for rows.Next() {
 		sm := Smartphone{}
+		var mean float64
 		err := rows.Scan(&sm.ID, &sm.Model, &sm.Producer, &sm.Memory, &sm.Ram, &sm.DisplaySize,
-			&sm.Price, &sm.RatingsSum, &sm.RatingsCount, &sm.ImagePath, &sm.Description)
+			&sm.Price, &sm.RatingsSum, &sm.RatingsCount, &sm.ImagePath, &sm.Description, &sm.Weight,
+			&sm.Category, &mean)
 		if err != nil {
 			return nil, db.wrapError(err)
 		}
+		sm.SetRating(mean)
 		smartphones = append(smartphones, sm)
 	}
 	return smartphones, nil
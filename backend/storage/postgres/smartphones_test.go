@@ -20,4 +20,14 @@ func TestSmartphones(t *testing.T) {
 		assert.NoError(t, err, "getting smartphone failed", err.Error())
 		assert.NotEqual(t, smartphone, models.Smartphone{}, "smartphone is an empty struct")
 	})
+	t.Run("reconcile ratings", func(t *testing.T) {
+		_, err := db.Exec("UPDATE smartphones SET ratings_sum = ratings_sum + 100 WHERE id = 1")
+		assert.NoError(t, err, "corrupting rating failed")
+		fixed, err := db.ReconcileRatings()
+		assert.NoError(t, err, "reconciling ratings failed")
+		assert.Len(t, fixed, 1, "only smartphone 1 should be fixed")
+		fixed, err = db.ReconcileRatings()
+		assert.NoError(t, err, "reconciling ratings failed")
+		assert.Empty(t, fixed, "ratings should be consistent after reconcile")
+	})
 }
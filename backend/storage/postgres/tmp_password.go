@@ -6,31 +6,45 @@ import (
 	"github.com/sfu-teamproject/smartbuy/backend/models"
 )
 
+// CreateTmpPassword saves the password, replacing the previous password of the
+// email with the same purpose
 func (db *PostgresDB) CreateTmpPassword(tmpPassword models.TmpPassword) (models.TmpPassword, error) {
 	query := `
-	INSERT INTO tmp_passwords (email, password, expires_at)
-	VALUES ($1, $2, $3)
+	INSERT INTO tmp_passwords (email, password_hash, expires_at, purpose)
+	VALUES ($1, $2, $3, $4)
+	ON CONFLICT (email, purpose) DO UPDATE SET password_hash = EXCLUDED.password_hash,
+	expires_at = EXCLUDED.expires_at, attempts = 0
 	RETURNING *
 	`
-	row := db.QueryRow(query, tmpPassword.Email, tmpPassword.Password, tmpPassword.ExpiresAt)
+	row := db.QueryRow(query, tmpPassword.Email, tmpPassword.PasswordHash, tmpPassword.ExpiresAt, tmpPassword.Purpose)
 	return db.extractTmpPassword(row)
 }
 
-func (db *PostgresDB) GetTmpPassword(email string) (models.TmpPassword, error) {
+func (db *PostgresDB) GetTmpPassword(email string, purpose models.TmpPasswordPurpose) (models.TmpPassword, error) {
 	query := `
-	SELECT * FROM tmp_passwords WHERE email = $1
+	SELECT * FROM tmp_passwords WHERE email = $1 AND purpose = $2
 	`
-	row := db.QueryRow(query, email)
+	row := db.QueryRow(query, email, purpose)
 	return db.extractTmpPassword(row)
 }
 
-func (db *PostgresDB) DeleteTmpPassword(email string) (models.TmpPassword, error) {
-	row := db.QueryRow("DELETE FROM tmp_passwords where email = $1 returning *", email)
+// AttemptTmpPassword counts a check of the password and returns it. Attempts
+// are counted before the check, so concurrent guesses can't exceed the limit
+func (db *PostgresDB) AttemptTmpPassword(email string, purpose models.TmpPasswordPurpose) (models.TmpPassword, error) {
+	row := db.QueryRow(`
+	UPDATE tmp_passwords SET attempts = attempts + 1 WHERE email = $1 AND purpose = $2
+	RETURNING *
+	`, email, purpose)
+	return db.extractTmpPassword(row)
+}
+
+func (db *PostgresDB) DeleteTmpPassword(email string, purpose models.TmpPasswordPurpose) (models.TmpPassword, error) {
+	row := db.QueryRow("DELETE FROM tmp_passwords where email = $1 AND purpose = $2 returning *", email, purpose)
 	return db.extractTmpPassword(row)
 }
 
 func (db *PostgresDB) extractTmpPassword(row *sql.Row) (models.TmpPassword, error) {
 	t := models.TmpPassword{}
-	err := row.Scan(&t.Email, &t.Password, &t.ExpiresAt)
+	err := row.Scan(&t.Email, &t.PasswordHash, &t.ExpiresAt, &t.Purpose, &t.Attempts)
 	return t, db.wrapError(err)
 }
@@ -0,0 +1,47 @@
+package postgres
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sfu-teamproject/smartbuy/backend/apperrors"
+	"github.com/sfu-teamproject/smartbuy/backend/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTmpPasswords(t *testing.T) {
+	db, err := NewPostgresDB(true)
+	assert.NoError(t, err, "postgres db creating failed")
+	email := "admin@example.com"
+	expires := time.Now().Add(time.Hour)
+	t.Run("create per purpose", func(t *testing.T) {
+		for _, purpose := range []models.TmpPasswordPurpose{models.TmpPasswordSignup, models.TmpPasswordReset} {
+			_, err := db.CreateTmpPassword(models.TmpPassword{Email: email, PasswordHash: string(purpose),
+				ExpiresAt: expires, Purpose: purpose})
+			assert.NoError(t, err, "creating tmp password failed")
+		}
+		reset, err := db.GetTmpPassword(email, models.TmpPasswordReset)
+		assert.NoError(t, err, "getting tmp password failed")
+		assert.Equal(t, "reset", reset.PasswordHash, "passwords of purposes are mixed")
+	})
+	t.Run("attempts", func(t *testing.T) {
+		for i := 1; i <= 2; i++ {
+			attempted, err := db.AttemptTmpPassword(email, models.TmpPasswordReset)
+			assert.NoError(t, err, "attempting tmp password failed")
+			assert.Equal(t, i, attempted.Attempts, "attempts are not counted")
+		}
+		replaced, err := db.CreateTmpPassword(models.TmpPassword{Email: email, PasswordHash: "new",
+			ExpiresAt: expires, Purpose: models.TmpPasswordReset})
+		assert.NoError(t, err, "replacing tmp password failed")
+		assert.Equal(t, "new", replaced.PasswordHash, "password is not replaced")
+		assert.Zero(t, replaced.Attempts, "attempts are not reset")
+	})
+	t.Run("delete", func(t *testing.T) {
+		_, err := db.DeleteTmpPassword(email, models.TmpPasswordReset)
+		assert.NoError(t, err, "deleting tmp password failed")
+		_, err = db.AttemptTmpPassword(email, models.TmpPasswordReset)
+		assert.ErrorIs(t, err, apperrors.ErrNotFound, "deleted password is attempted")
+		_, err = db.GetTmpPassword(email, models.TmpPasswordSignup)
+		assert.NoError(t, err, "password of another purpose is deleted")
+	})
+}
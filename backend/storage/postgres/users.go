@@ -64,7 +64,8 @@ func (db *PostgresDB) CreateUser(user User) (User, error) {
 
 func (db *PostgresDB) extractUser(row *sql.Row) (User, error) {
 	user := User{}
-	err := row.Scan(&user.ID, &user.Name, &user.Email, &user.Avatar, &user.Password, &user.Role, &user.CreatedAt)
+	err := row.Scan(&user.ID, &user.Name, &user.Email, &user.Avatar, &user.Password, &user.Role, &user.CreatedAt,
+		&user.CartReminders, &user.TokensRevokedAt)
 	return user, db.wrapError(err)
 }
 
@@ -73,7 +74,8 @@ func (db *PostgresDB) extractUsers(rows *sql.Rows) ([]User, error) {
 	users := []User{}
 	for rows.Next() {
 		user := User{}
-		err := rows.Scan(&user.ID, &user.Name, &user.Email, &user.Avatar, &user.Password, &user.Role, &user.CreatedAt)
+		err := rows.Scan(&user.ID, &user.Name, &user.Email, &user.Avatar, &user.Password, &user.Role, &user.CreatedAt,
+			&user.CartReminders, &user.TokensRevokedAt)
 		if err != nil {
 			return nil, db.wrapError(err)
 		}
@@ -1,6 +1,8 @@
 package storage
 
 import (
+	"time"
+
 	"github.com/sfu-teamproject/smartbuy/backend/models"
 )
 
@@ -8,6 +10,7 @@ type Storage interface {
 	GetSmartphone(ID int) (models.Smartphone, error)
 	GetSmartphones() ([]models.Smartphone, error)
 	GetSmartphonesByIDs(IDs []int) ([]models.Smartphone, error)
+	ReconcileRatings() ([]models.Smartphone, error)
 
 	GetUser(ID int) (models.User, error)
 	GetUsers() ([]models.User, error)
@@ -16,23 +19,109 @@ type Storage interface {
 	UpdateUser(userID int, updates map[string]any) (models.User, error)
 	DeleteUser(id int) (models.User, error)
 
-	GetTmpPassword(email string) (models.TmpPassword, error)
+	CreateRefreshToken(token models.RefreshToken) (models.RefreshToken, error)
+	RotateRefreshToken(tokenHash string, next models.RefreshToken) (models.RefreshToken, error)
+	DeleteRefreshTokenFamily(userID int, tokenHash string) error
+	RevokeUserTokens(userID int, at time.Time) error
+	GetTokensRevokedAt(userID int) (*time.Time, error)
+
+	GetTmpPassword(email string, purpose models.TmpPasswordPurpose) (models.TmpPassword, error)
 	CreateTmpPassword(TmpPassword models.TmpPassword) (models.TmpPassword, error)
-	DeleteTmpPassword(email string) (models.TmpPassword, error)
+	AttemptTmpPassword(email string, purpose models.TmpPasswordPurpose) (models.TmpPassword, error)
+	DeleteTmpPassword(email string, purpose models.TmpPasswordPurpose) (models.TmpPassword, error)
 
 	GetReview(ID int) (models.Review, error)
 	GetReviews(smartphoneID int) ([]models.Review, error)
+	GetVerifiedReviews(smartphoneID int) ([]models.Review, error)
 	CreateReview(review models.Review) (models.Review, error)
 	UpdateReview(review models.Review) (models.Review, error)
 	DeleteReview(ID int) (models.Review, error)
+	HasDuplicateReview(comment string, userID int) (bool, error)
+
+	GetReviewPhotos(reviewID int) ([]models.ReviewPhoto, error)
+	GetUserReviewPhotos(userID int) ([]models.ReviewPhoto, error)
+	AddReviewPhoto(photo models.ReviewPhoto) (models.ReviewPhoto, error)
+	DeleteReviewPhoto(ID int) (models.ReviewPhoto, error)
+
+	CreateReviewReport(report models.ReviewReport) (models.ReviewReport, error)
+	HideReportedReview(reviewID, threshold int) (bool, error)
+	GetReportedReviews() ([]models.ReportedReview, error)
+	ModerateReview(reviewID int, hidden bool) (models.Review, error)
+
+	GetReviewReply(reviewID int) (models.ReviewReply, error)
+	SetReviewReply(reply models.ReviewReply) (models.ReviewReply, error)
+	DeleteReviewReply(reviewID int) (models.ReviewReply, error)
 
 	GetCarts() ([]models.Cart, error)
 	GetCart(ID int) (models.Cart, error)
 	GetCartByUserID(userID int) (models.Cart, error)
+	SetCartPromoCode(cartID int, promoCodeID *int) (models.Cart, error)
+	CreateGuestCart() (models.Cart, error)
+	MergeCarts(guestCartID, toCartID, maxQuantity int) error
+	DeleteStaleGuestCarts(idleSince, createdBefore time.Time) (int, error)
+	GetAbandonedCarts(idleSince time.Time) ([]models.Cart, error)
+	ClaimCartReminder(cartID int) (bool, error)
+
+	ExportUsers(from, to time.Time, fn func(models.User) error) error
+	ExportReviews(from, to time.Time, fn func(models.Review) error) error
+	ExportCarts(from, to time.Time, fn func(models.Cart) error) error
+
+	ClaimIdempotencyKey(key models.IdempotencyKey, expiredBefore time.Time) (models.IdempotencyKey, bool, error)
+	SaveIdempotentResponse(key models.IdempotencyKey) error
+	DeleteIdempotencyKey(scope, key string) error
+	DeleteExpiredIdempotencyKeys(before time.Time) (int, error)
+
+	HitRateLimit(key string, window time.Duration, now time.Time) (int, time.Time, error)
+	GetRateLimit(key string, now time.Time) (int, time.Time, error)
+	ResetRateLimit(key string) error
+	DeleteExpiredRateLimits(before time.Time) (int, error)
 
 	GetCartItem(ID int) (models.CartItem, error)
 	GetCartItems(cartID int) ([]models.CartItem, error)
-	AddToCart(cartItem models.CartItem) (models.CartItem, error)
+	AddToCart(cartItem models.CartItem, maxQuantity int) (models.CartItem, error)
+	ReplaceCartItems(cartID int, items []models.CartItem) ([]models.CartItem, error)
+	ClearCart(cartID int) error
+	SetCartItemState(cartItem models.CartItem) (models.CartItem, error)
 	SetQuantity(cartItem models.CartItem) (models.CartItem, error)
 	DeleteFromCart(cartID, itemID int) (models.CartItem, error)
+
+	GetPurchases(userID int) ([]models.Purchase, error)
+	GetPurchase(ID int) (models.Purchase, error)
+	CreatePurchase(purchase models.Purchase) (models.Purchase, error)
+	HasPurchased(userID, smartphoneID int) (bool, error)
+
+	GetOrders() ([]models.Order, error)
+	GetUserOrders(userID int) ([]models.Order, error)
+	GetOrder(ID int) (models.Order, error)
+	CreateOrder(order models.Order, cartItems []models.CartItem) (models.Order, error)
+	SetOrderStatus(orderID int, from, to models.OrderStatus, actorID *int) (models.Order, error)
+
+	GetOrderPayments(orderID int) ([]models.Payment, error)
+	RecordPayment(payment models.Payment) (models.Payment, error)
+
+	GetPromoCodes() ([]models.PromoCode, error)
+	GetPromoCode(ID int) (models.PromoCode, error)
+	GetPromoCodeByCode(code string) (models.PromoCode, error)
+	CreatePromoCode(promo models.PromoCode) (models.PromoCode, error)
+	DeletePromoCode(ID int) (models.PromoCode, error)
+	GetPromoCodeUserUsage(promoCodeID, userID int) (int, error)
+
+	GetAddresses(userID int) ([]models.Address, error)
+	GetAddress(ID int) (models.Address, error)
+	CreateAddress(address models.Address) (models.Address, error)
+	UpdateAddress(address models.Address) (models.Address, error)
+	DeleteAddress(ID int) (models.Address, error)
+
+	GetDeliveryMethods(onlyActive bool) ([]models.DeliveryMethod, error)
+	GetDeliveryMethod(ID int) (models.DeliveryMethod, error)
+	CreateDeliveryMethod(method models.DeliveryMethod) (models.DeliveryMethod, error)
+	UpdateDeliveryMethod(method models.DeliveryMethod) (models.DeliveryMethod, error)
+	DeleteDeliveryMethod(ID int) (models.DeliveryMethod, error)
+
+	GetReturns() ([]models.Return, error)
+	GetUserReturns(userID int) ([]models.Return, error)
+	GetReturn(ID int) (models.Return, error)
+	CreateReturn(ret models.Return) (models.Return, error)
+	SetReturnStatus(ID int, from, to models.ReturnStatus, refundAmount *int, adminComment *string) (models.Return, error)
+	AddReturnPhoto(photo models.ReturnPhoto) (models.ReturnPhoto, error)
 }
@@ -0,0 +1,96 @@
+// Package tax computes VAT of carts
+package tax
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/sfu-teamproject/smartbuy/backend/models"
+)
+
+// DefaultRates is used when no rates are configured
+const DefaultRates = "smartphone=20"
+
+// Config holds VAT rates in percent per product category. Categories without
+// a rate are taxed with DefaultRate
+type Config struct {
+	Rates            map[string]float64
+	DefaultRate      float64
+	PricesIncludeTax bool
+}
+
+// ParseRates reads rates written as comma separated category=percent pairs,
+// for example "smartphone=20,accessory=10"
+func ParseRates(spec string) (map[string]float64, error) {
+	rates := map[string]float64{}
+	for pair := range strings.SplitSeq(spec, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		category, value, ok := strings.Cut(pair, "=")
+		category = strings.TrimSpace(category)
+		if !ok || category == "" {
+			return nil, fmt.Errorf("invalid rate %q, want category=percent", pair)
+		}
+		rate, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil || rate < 0 || rate > 100 {
+			return nil, fmt.Errorf("invalid rate of category %s: %q", category, value)
+		}
+		rates[category] = rate
+	}
+	return rates, nil
+}
+
+// Rate is the rate of the category in percent
+func (c Config) Rate(category string) float64 {
+	rate, ok := c.Rates[category]
+	if !ok {
+		return c.DefaultRate
+	}
+	return rate
+}
+
+// Line computes the tax of a line costing amount. The tax is rounded to whole
+// rubles per line, so totals are sums of rounded lines
+func (c Config) Line(category string, amount int) models.LineTax {
+	rate := c.Rate(category)
+	line := models.LineTax{Category: category, Rate: rate}
+	if c.PricesIncludeTax {
+		line.Tax = int(math.Round(float64(amount) * rate / (100 + rate)))
+		line.Gross = amount
+		line.Net = amount - line.Tax
+	} else {
+		line.Tax = int(math.Round(float64(amount) * rate / 100))
+		line.Net = amount
+		line.Gross = amount + line.Tax
+	}
+	return line
+}
+
+// Total sums lines into a breakdown, rates are sorted from the highest
+func (c Config) Total(lines []models.LineTax) models.TaxBreakdown {
+	breakdown := models.TaxBreakdown{PricesIncludeTax: c.PricesIncludeTax, Rates: []models.RateTax{}}
+	byRate := map[float64]int{}
+	for _, line := range lines {
+		breakdown.Net += line.Net
+		breakdown.Tax += line.Tax
+		breakdown.Gross += line.Gross
+		i, ok := byRate[line.Rate]
+		if !ok {
+			i = len(breakdown.Rates)
+			byRate[line.Rate] = i
+			breakdown.Rates = append(breakdown.Rates, models.RateTax{Rate: line.Rate})
+		}
+		breakdown.Rates[i].Net += line.Net
+		breakdown.Rates[i].Tax += line.Tax
+	}
+	slices.SortFunc(breakdown.Rates, func(a, b models.RateTax) int {
+		return cmp.Compare(b.Rate, a.Rate)
+	})
+	return breakdown
+}
@@ -0,0 +1,60 @@
+package tax
+
+import (
+	"testing"
+
+	"github.com/sfu-teamproject/smartbuy/backend/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRates(t *testing.T) {
+	rates, err := ParseRates(" smartphone=20, accessory = 10,,book=0 ")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]float64{"smartphone": 20, "accessory": 10, "book": 0}, rates)
+
+	for _, spec := range []string{"smartphone", "=20", "smartphone=abc", "smartphone=-1", "smartphone=101"} {
+		_, err := ParseRates(spec)
+		assert.Error(t, err, spec)
+	}
+}
+
+func TestLine(t *testing.T) {
+	rates := map[string]float64{"smartphone": 20, "accessory": 10}
+	tests := []struct {
+		name     string
+		config   Config
+		category string
+		amount   int
+		want     models.LineTax
+	}{
+		{"Included", Config{Rates: rates, PricesIncludeTax: true}, "smartphone", 1200,
+			models.LineTax{Category: "smartphone", Rate: 20, Net: 1000, Tax: 200, Gross: 1200}},
+		{"Included rounded", Config{Rates: rates, PricesIncludeTax: true}, "accessory", 999,
+			models.LineTax{Category: "accessory", Rate: 10, Net: 908, Tax: 91, Gross: 999}},
+		{"Excluded", Config{Rates: rates}, "accessory", 1005,
+			models.LineTax{Category: "accessory", Rate: 10, Net: 1005, Tax: 101, Gross: 1106}},
+		{"Default rate", Config{Rates: rates, DefaultRate: 5}, "book", 100,
+			models.LineTax{Category: "book", Rate: 5, Net: 100, Tax: 5, Gross: 105}},
+		{"Zero amount", Config{Rates: rates, PricesIncludeTax: true}, "smartphone", 0,
+			models.LineTax{Category: "smartphone", Rate: 20}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.config.Line(tt.category, tt.amount))
+		})
+	}
+}
+
+func TestTotal(t *testing.T) {
+	config := Config{Rates: map[string]float64{"smartphone": 20, "accessory": 10}, PricesIncludeTax: true}
+	lines := []models.LineTax{
+		config.Line("accessory", 1100),
+		config.Line("smartphone", 1200),
+		config.Line("smartphone", 600),
+	}
+	assert.Equal(t, models.TaxBreakdown{
+		PricesIncludeTax: true, Net: 2500, Tax: 400, Gross: 2900,
+		Rates: []models.RateTax{{Rate: 20, Net: 1500, Tax: 300}, {Rate: 10, Net: 1000, Tax: 100}},
+	}, config.Total(lines))
+	assert.Equal(t, models.TaxBreakdown{PricesIncludeTax: true, Rates: []models.RateTax{}}, config.Total(nil))
+}